@@ -2,26 +2,23 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
+	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/httpserver"
 	"github.com/shipitai/shipitai/review"
+	"github.com/shipitai/shipitai/storage/memory"
 )
 
 var (
 	logger         *slog.Logger
-	webhookHandler *github.WebhookHandler
-	reviewer       *review.Reviewer
-	githubClient   *github.Client
-	botName        string
+	webhookHandler *httpserver.Handler
 )
 
 func main() {
@@ -34,7 +31,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	http.HandleFunc("/webhooks/github", handleWebhook)
+	http.Handle("/webhooks/github", webhookHandler)
 	http.HandleFunc("/health", handleHealth)
 
 	port := os.Getenv("PORT")
@@ -84,228 +81,78 @@ func initialize() error {
 	}
 
 	// Bot name for mention detection (defaults to "shipitai")
-	botName = os.Getenv("BOT_NAME")
+	botName := os.Getenv("BOT_NAME")
 	if botName == "" {
 		botName = "shipitai"
 	}
 
 	// Initialize components
-	webhookHandler = github.NewWebhookHandler(webhookSecret)
-	githubClient = github.NewClient(appID, privateKey)
-
-	// No database in local mode
-	reviewer = review.NewReviewer(githubClient, claudeAPIKey, nil, logger)
-	reviewer.SetBotName(botName)
-
-	// Optional: override the default Claude model
-	if model := os.Getenv("ANTHROPIC_MODEL"); model != "" {
-		reviewer.SetModel(model)
-	}
-
-	logger.Info("initialized", "app_id", appID, "bot_name", botName)
-	return nil
-}
-
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok"))
-}
-
-func handleWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Read body
-	payload, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Error("failed to read body", "error", err)
-		http.Error(w, "failed to read body", http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
-
-	// Get event type
-	eventType := r.Header.Get("X-GitHub-Event")
-	if eventType == "" {
-		http.Error(w, "missing X-GitHub-Event header", http.StatusBadRequest)
-		return
-	}
-
-	logger.Info("received webhook", "event", eventType, "size", len(payload))
-
-	// Verify signature
-	signature := r.Header.Get("X-Hub-Signature-256")
-	if err := webhookHandler.VerifySignature(payload, signature); err != nil {
-		logger.Error("signature verification failed", "error", err)
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
-		return
-	}
-
-	// Handle ping
-	if eventType == "ping" {
-		logger.Info("received ping")
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "pong"})
-		return
-	}
-
-	// Handle review comment events (for @mentions)
-	if eventType == "pull_request_review_comment" {
-		handleReviewComment(w, payload)
-		return
-	}
-
-	// Only handle pull_request events
-	if eventType != "pull_request" {
-		logger.Info("ignoring event", "type", eventType)
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "event ignored"})
-		return
-	}
-
-	// Parse event
-	event, err := webhookHandler.ParsePullRequestEvent(payload)
-	if err != nil {
-		logger.Error("failed to parse event", "error", err)
-		http.Error(w, "failed to parse event", http.StatusBadRequest)
-		return
+	webhooks := github.NewWebhookHandler(webhookSecret)
+	if v := os.Getenv("WEBHOOK_MAX_PAYLOAD_BYTES"); v != "" {
+		maxBytes, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || maxBytes <= 0 {
+			return fmt.Errorf("invalid WEBHOOK_MAX_PAYLOAD_BYTES: %s", v)
+		}
+		webhooks.SetMaxPayloadSize(maxBytes)
 	}
+	githubClient := github.NewClient(appID, privateKey)
 
-	// Check if we should process
-	if !webhookHandler.ShouldProcess(eventType, event) {
-		logger.Info("skipping event", "action", event.Action)
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "event skipped"})
-		return
+	// No database in local mode; in-memory storage keeps review context for the
+	// lifetime of the process so incremental reviews and replies work
+	store := memory.New()
+	reviewer := review.NewReviewer(githubClient, claudeAPIKey, store, logger)
+	reviewer.SetBotName(botName)
+	if v := os.Getenv("MAX_CONCURRENT_REVIEWS_PER_INSTALLATION"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid MAX_CONCURRENT_REVIEWS_PER_INSTALLATION: %s", v)
+		}
+		reviewer.SetMaxConcurrentReviewsPerInstallation(limit)
 	}
-
-	logger.Info("processing PR",
-		"repo", event.Repository.FullName,
-		"pr", event.Number,
-		"action", event.Action,
-	)
-
-	// Respond immediately to GitHub, then process async
-	// (Claude API can take longer than GitHub's 10s timeout)
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "review started"})
-
-	// Perform review in background with detached context
-	input := &review.ReviewInput{
-		InstallationID: event.Installation.ID,
-		Owner:          event.Repository.Owner.Login,
-		Repo:           event.Repository.Name,
-		PRNumber:       event.Number,
-		PRTitle:        event.PullRequest.Title,
-		PRBody:         event.PullRequest.Body,
-		HeadSHA:        event.PullRequest.Head.SHA,
-		DefaultBranch:  event.Repository.DefaultBranch,
+	if v := os.Getenv("MAX_CONCURRENT_CHUNKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid MAX_CONCURRENT_CHUNKS: %s", v)
+		}
+		reviewer.SetMaxConcurrentChunks(n)
 	}
-
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-		defer cancel()
-
-		result, err := reviewer.Review(ctx, input)
-		if err != nil {
-			logger.Error("review failed", "error", err)
-			return
+	if v := os.Getenv("CONTEXT_FETCH_FRACTION"); v != "" {
+		fraction, err := strconv.ParseFloat(v, 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			return fmt.Errorf("invalid CONTEXT_FETCH_FRACTION: %s", v)
 		}
-
-		if result == nil {
-			logger.Info("review skipped (not enabled)")
-			return
+		reviewer.SetContextFetchFraction(fraction)
+	}
+	if v := os.Getenv("REPLY_DEBOUNCE_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window < 0 {
+			return fmt.Errorf("invalid REPLY_DEBOUNCE_WINDOW: %s", v)
 		}
-
-		logger.Info("review posted",
-			"review_id", result.ReviewID,
-			"comments", result.CommentCount,
-			"url", result.ReviewURL,
-		)
-	}()
-}
-
-func handleReviewComment(w http.ResponseWriter, payload []byte) {
-	event, err := webhookHandler.ParseReviewCommentEvent(payload)
-	if err != nil {
-		logger.Error("failed to parse review comment event", "error", err)
-		http.Error(w, "failed to parse event", http.StatusBadRequest)
-		return
+		reviewer.SetReplyDebounceWindow(window)
 	}
 
-	// Check if we should process this comment
-	if !webhookHandler.ShouldProcessComment(event, botName) {
-		logger.Info("ignoring comment (no mention or not created)",
-			"action", event.Action,
-			"body_preview", truncate(event.Comment.Body, 50),
-		)
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "comment ignored"})
-		return
+	// Optional: override the default Claude model
+	if model := os.Getenv("ANTHROPIC_MODEL"); model != "" {
+		reviewer.SetModel(model)
 	}
 
-	logger.Info("processing @mention",
-		"repo", event.Repository.FullName,
-		"pr", event.PullRequest.Number,
-		"comment_id", event.Comment.ID,
-		"user", event.Sender.Login,
-	)
-
-	// Respond immediately
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "reply started"})
-
-	// Process in background
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-		defer cancel()
-
-		// Fetch all comments to build thread context
-		comments, err := githubClient.GetReviewComments(
-			ctx,
-			event.Installation.ID,
-			event.Repository.Owner.Login,
-			event.Repository.Name,
-			event.PullRequest.Number,
-		)
-		if err != nil {
-			logger.Error("failed to fetch comments", "error", err)
-			return
-		}
-
-		threadContext := review.BuildThreadContext(comments, event.Comment.ID)
-		userQuestion := github.ExtractMentionContext(event.Comment.Body, botName)
-
-		input := &review.ReplyInput{
-			InstallationID: event.Installation.ID,
-			Owner:          event.Repository.Owner.Login,
-			Repo:           event.Repository.Name,
-			PRNumber:       event.PullRequest.Number,
-			CommentID:      event.Comment.ID,
-			DiffHunk:       event.Comment.DiffHunk,
-			FilePath:       event.Comment.Path,
-			UserQuestion:   userQuestion,
-			ThreadContext:  threadContext,
-		}
+	// Optional: opt into Anthropic beta features
+	if betas := os.Getenv("ANTHROPIC_BETA"); betas != "" {
+		reviewer.SetClaudeOptions(option.WithHeader("anthropic-beta", betas))
+	}
 
-		result, err := reviewer.Reply(ctx, input)
-		if err != nil {
-			logger.Error("reply failed", "error", err)
-			return
-		}
+	// Dry run prints reviews to stdout instead of posting them
+	dryRun := os.Getenv("DRY_RUN") == "true"
 
-		logger.Info("reply posted",
-			"comment_id", result.CommentID,
-			"url", result.CommentURL,
-		)
-	}()
-}
+	webhookHandler = httpserver.New(reviewer, webhooks, githubClient, botName, store, logger)
+	webhookHandler.SetDryRun(dryRun)
+	webhookHandler.SetProgressReactions(os.Getenv("PROGRESS_REACTIONS") == "true")
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
+	logger.Info("initialized", "app_id", appID, "bot_name", botName, "dry_run", dryRun)
+	return nil
 }
 
-func jsonResponse(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(data)
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/httpserver"
+	"github.com/shipitai/shipitai/review"
+)
+
+// appTargetIDHeader carries the ID of the GitHub App a webhook delivery was sent for.
+const appTargetIDHeader = "X-GitHub-Hook-Installation-Target-ID"
+
+// appConfig describes one GitHub App served by this process, as listed in GITHUB_APPS_FILE.
+type appConfig struct {
+	AppID          int64  `yaml:"app_id"`
+	WebhookSecret  string `yaml:"webhook_secret"`
+	PrivateKey     string `yaml:"private_key"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	BotName        string `yaml:"bot_name"`
+	Model          string `yaml:"model"`
+	Instructions   string `yaml:"instructions"`
+}
+
+// app holds the components that handle deliveries for a single GitHub App.
+type app struct {
+	id           int64
+	botName      string
+	handler      *httpserver.Handler
+	githubClient *github.Client
+	reviewer     *review.Reviewer
+}
+
+// appRegistry routes webhook deliveries to the GitHub App they were sent for.
+type appRegistry struct {
+	apps map[int64]*app
+}
+
+func newAppRegistry() *appRegistry {
+	return &appRegistry{apps: make(map[int64]*app)}
+}
+
+// register adds an app, replacing any existing app with the same ID.
+func (r *appRegistry) register(a *app) {
+	r.apps[a.id] = a
+}
+
+// route returns the app a delivery is addressed to, based on its target ID header.
+// Deliveries without the header are accepted only when a single app is registered.
+func (r *appRegistry) route(header http.Header) (*app, error) {
+	v := header.Get(appTargetIDHeader)
+	if v == "" {
+		if len(r.apps) == 1 {
+			for _, a := range r.apps {
+				return a, nil
+			}
+		}
+		return nil, fmt.Errorf("missing %s header", appTargetIDHeader)
+	}
+
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %s", appTargetIDHeader, v)
+	}
+	a, ok := r.apps[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown app ID %d", id)
+	}
+	return a, nil
+}
+
+// get returns the app with the given ID. An ID of 0 selects the only registered app.
+func (r *appRegistry) get(id int64) (*app, error) {
+	if id == 0 {
+		if len(r.apps) == 1 {
+			for _, a := range r.apps {
+				return a, nil
+			}
+		}
+		return nil, fmt.Errorf("app_id is required when serving several apps")
+	}
+	a, ok := r.apps[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown app ID %d", id)
+	}
+	return a, nil
+}
+
+// loadAppConfigs reads the GitHub Apps listed in a YAML file.
+func loadAppConfigs(path string) ([]appConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apps file: %w", err)
+	}
+	return parseAppConfigs(data)
+}
+
+// parseAppConfigs parses and validates a list of GitHub App configs.
+func parseAppConfigs(data []byte) ([]appConfig, error) {
+	var configs []appConfig
+	if err := yaml.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse apps file: %w", err)
+	}
+	if len(configs) == 0 {
+		return nil, errors.New("apps file lists no apps")
+	}
+
+	seen := make(map[int64]bool)
+	for i, c := range configs {
+		switch {
+		case c.AppID <= 0:
+			return nil, fmt.Errorf("app %d: app_id is required", i)
+		case seen[c.AppID]:
+			return nil, fmt.Errorf("app %d: duplicate app_id %d", i, c.AppID)
+		case c.WebhookSecret == "":
+			return nil, fmt.Errorf("app %d: webhook_secret is required", c.AppID)
+		case c.PrivateKey == "" && c.PrivateKeyPath == "":
+			return nil, fmt.Errorf("app %d: private_key or private_key_path is required", c.AppID)
+		}
+		seen[c.AppID] = true
+	}
+	return configs, nil
+}
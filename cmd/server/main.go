@@ -2,14 +2,28 @@
 //
 // Configuration via environment variables:
 //
-//	GITHUB_APP_ID        - GitHub App ID (required)
-//	GITHUB_WEBHOOK_SECRET - Webhook signature verification secret (required)
-//	GITHUB_PRIVATE_KEY   - GitHub App private key in PEM format (required)
+//	GITHUB_APP_ID        - GitHub App ID (required unless GITHUB_APPS_FILE is set)
+//	GITHUB_WEBHOOK_SECRET - Webhook signature verification secret (required unless GITHUB_APPS_FILE is set)
+//	GITHUB_PRIVATE_KEY   - GitHub App private key in PEM format (required unless GITHUB_APPS_FILE is set)
+//	GITHUB_APPS_FILE     - YAML file listing several GitHub Apps to serve from one process (optional)
 //	ANTHROPIC_API_KEY    - Anthropic API key for Claude (required)
-//	ANTHROPIC_MODEL      - Claude model for reviews (default: claude-sonnet-4-20250514)
+//	ANTHROPIC_MODEL      - Claude model for reviews (default: claude-sonnet-4-20250514; per-app in GITHUB_APPS_FILE)
+//	ANTHROPIC_BETA       - Anthropic beta features to opt into, sent as the anthropic-beta header (optional, comma-separated)
 //	DATABASE_URL         - PostgreSQL connection string (required)
 //	PORT                 - HTTP server port (default: 8080)
-//	BOT_NAME             - Bot username for @mentions (default: shipitai)
+//	BOT_NAME             - Bot username for @mentions (default: shipitai; per-app in GITHUB_APPS_FILE)
+//	WEBHOOK_MAX_PAYLOAD_BYTES - Maximum webhook payload size in bytes (default: 5MB)
+//	MAX_CONCURRENT_REVIEWS_PER_INSTALLATION - Concurrent reviews allowed per installation (default: 3, 0 disables)
+//	MAX_CONCURRENT_REVIEWS - Reviews run at once across all installations, queuing the rest (default: 0, unbounded)
+//	MAX_CONCURRENT_CHUNKS - Chunks of a large review sent to Claude at once (default: 5)
+//	CONTEXT_FETCH_FRACTION - Share of the remaining review deadline for fetching rich context (default: 0.25, capped at 90s)
+//	REPLY_DEBOUNCE_WINDOW - How long to wait for more @mentions in a thread before replying once to all of them (default: 3s, 0 disables)
+//	TRACK_COMMENT_OUTCOMES - Record whether authors resolved earlier bot comments on each subsequent review: "true" to enable (default: off)
+//	EVENT_LOGGING        - Log webhook event outcomes (no payloads) for analytics: "true" to enable (default: off)
+//	ADMIN_TOKEN          - Bearer token for the /admin/events summary endpoint (endpoint disabled if unset)
+//	REVIEW_API_TOKEN     - Bearer token for the POST /review manual trigger endpoint (endpoint disabled if unset)
+//	METRICS_ENABLED      - Serve Prometheus metrics at /metrics: "true" to enable (default: off)
+//	READY_CHECK_ANTHROPIC - Make /ready also validate the Anthropic API key, at most once a minute: "true" to enable (default: off)
 //
 // Usage:
 //
@@ -18,10 +32,10 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -30,21 +44,31 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/anthropics/anthropic-sdk-go/option"
 	_ "github.com/lib/pq" // PostgreSQL driver
 
 	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/httpserver"
+	"github.com/shipitai/shipitai/metrics"
 	"github.com/shipitai/shipitai/review"
 	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/buffered"
 	"github.com/shipitai/shipitai/storage/postgres"
 )
 
 var (
 	logger         *slog.Logger
-	webhookHandler *github.WebhookHandler
-	reviewer       *review.Reviewer
-	githubClient   *github.Client
+	apps           *appRegistry
 	pgStorage      *postgres.PostgreSQL
-	botName        string
+	reviewBuffer   *buffered.Store // nil unless ASYNC_STORAGE is set
+	readiness      *readinessChecker
+	eventLogging   bool
+	adminToken     string
+	reviewAPIToken string
+
+	// manualReviewTimeout bounds a review run through POST /review, and is raised
+	// at startup to cover the configured phase timeouts.
+	manualReviewTimeout = httpserver.ReviewTimeout
 )
 
 func main() {
@@ -62,6 +86,13 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/webhooks/github", handleWebhook)
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/ready", handleReady)
+	mux.HandleFunc("/admin/events", handleAdminEvents)
+	mux.HandleFunc("/review", handleManualReview)
+	if os.Getenv("METRICS_ENABLED") == "true" {
+		mux.Handle("/metrics", metrics.Handler())
+		logger.Info("serving Prometheus metrics", "path", "/metrics")
+	}
 	mux.HandleFunc("/", handleRoot)
 
 	port := os.Getenv("PORT")
@@ -98,18 +129,52 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("shutdown failed", "error", err)
 	}
+	drain(ctx, apps, reviewBuffer)
 }
 
-func initialize() error {
-	// Load required config from environment
-	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if webhookSecret == "" {
-		return fmt.Errorf("GITHUB_WEBHOOK_SECRET is required")
+// drain lets background work finish before the process exits: each app's
+// reviews and replies, bounded by ctx, then their review archives, and last the
+// buffered review writes, so what those reviews stored reaches the database.
+func drain(ctx context.Context, registry *appRegistry, buffer *buffered.Store) {
+	handled := make(chan struct{})
+	go func() {
+		for _, a := range registry.apps {
+			a.handler.Wait()
+		}
+		close(handled)
+	}()
+	select {
+	case <-handled:
+		// No review is left to start an archive write; each write has its own timeout
+		for _, a := range registry.apps {
+			a.reviewer.Wait()
+		}
+	case <-ctx.Done():
+		logger.Warn("shutdown timed out waiting for background reviews", "error", ctx.Err())
 	}
 
-	privateKey := os.Getenv("GITHUB_PRIVATE_KEY")
-	if privateKey == "" {
-		return fmt.Errorf("GITHUB_PRIVATE_KEY is required")
+	if buffer != nil {
+		if err := buffer.Close(); err != nil {
+			logger.Error("failed to flush buffered reviews", "error", err)
+		}
+	}
+}
+
+func initialize() error {
+	// Load required config from environment
+	var appConfigs []appConfig
+	if path := os.Getenv("GITHUB_APPS_FILE"); path != "" {
+		configs, err := loadAppConfigs(path)
+		if err != nil {
+			return err
+		}
+		appConfigs = configs
+	} else {
+		config, err := appConfigFromEnv()
+		if err != nil {
+			return err
+		}
+		appConfigs = []appConfig{*config}
 	}
 
 	claudeAPIKey := os.Getenv("ANTHROPIC_API_KEY")
@@ -117,25 +182,106 @@ func initialize() error {
 		return fmt.Errorf("ANTHROPIC_API_KEY is required")
 	}
 
-	appIDStr := os.Getenv("GITHUB_APP_ID")
-	if appIDStr == "" {
-		return fmt.Errorf("GITHUB_APP_ID is required")
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		return fmt.Errorf("DATABASE_URL is required")
 	}
 
-	appID, err := strconv.ParseInt(appIDStr, 10, 64)
-	if err != nil {
-		return fmt.Errorf("invalid GITHUB_APP_ID: %w", err)
+	var maxPayloadBytes int64
+	if v := os.Getenv("WEBHOOK_MAX_PAYLOAD_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid WEBHOOK_MAX_PAYLOAD_BYTES: %s", v)
+		}
+		maxPayloadBytes = n
 	}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return fmt.Errorf("DATABASE_URL is required")
+	maxConcurrentReviews := -1 // unset: keep the reviewer default
+	if v := os.Getenv("MAX_CONCURRENT_REVIEWS_PER_INSTALLATION"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid MAX_CONCURRENT_REVIEWS_PER_INSTALLATION: %s", v)
+		}
+		maxConcurrentReviews = limit
+	}
+
+	var reviewQueue *httpserver.ReviewQueue // shared by all apps; nil when unbounded
+	if v := os.Getenv("MAX_CONCURRENT_REVIEWS"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid MAX_CONCURRENT_REVIEWS: %s", v)
+		}
+		if limit > 0 {
+			reviewQueue = httpserver.NewReviewQueue(limit)
+		}
+	}
+
+	var maxConcurrentChunks int
+	if v := os.Getenv("MAX_CONCURRENT_CHUNKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid MAX_CONCURRENT_CHUNKS: %s", v)
+		}
+		maxConcurrentChunks = n
+	}
+
+	var contextFetchFraction float64
+	if v := os.Getenv("CONTEXT_FETCH_FRACTION"); v != "" {
+		fraction, err := strconv.ParseFloat(v, 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			return fmt.Errorf("invalid CONTEXT_FETCH_FRACTION: %s", v)
+		}
+		contextFetchFraction = fraction
+	}
+
+	replyDebounceWindow := time.Duration(-1) // unset: keep the reviewer default
+	if v := os.Getenv("REPLY_DEBOUNCE_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window < 0 {
+			return fmt.Errorf("invalid REPLY_DEBOUNCE_WINDOW: %s", v)
+		}
+		replyDebounceWindow = window
 	}
 
-	// Bot name for @mentions
-	botName = os.Getenv("BOT_NAME")
-	if botName == "" {
-		botName = "shipitai"
+	var contextTimeout time.Duration // unset: scale with the review deadline
+	if v := os.Getenv("CONTEXT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid CONTEXT_TIMEOUT: %s", v)
+		}
+		contextTimeout = d
+	}
+
+	claudeTimeout := review.ClaudeAPITimeout
+	if v := os.Getenv("CLAUDE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid CLAUDE_TIMEOUT: %s", v)
+		}
+		claudeTimeout = d
+	}
+
+	reviewTimeout, replyTimeout := backgroundTimeouts(contextTimeout, claudeTimeout)
+	manualReviewTimeout = reviewTimeout
+
+	trackCommentOutcomes := os.Getenv("TRACK_COMMENT_OUTCOMES") == "true"
+	eventLogging = os.Getenv("EVENT_LOGGING") == "true"
+	progressReactions := os.Getenv("PROGRESS_REACTIONS") == "true"
+	asyncStorage := os.Getenv("ASYNC_STORAGE") == "true"
+
+	// Archive reviews of repositories that enable review_artifacts to a directory
+	var artifactSink review.ArtifactSink
+	if dir := os.Getenv("ARTIFACT_DIR"); dir != "" {
+		baseURL := os.Getenv("ARTIFACT_BASE_URL")
+		if baseURL == "" {
+			return fmt.Errorf("ARTIFACT_BASE_URL is required when ARTIFACT_DIR is set")
+		}
+		artifactSink = review.NewFileArtifactSink(dir, baseURL)
+	}
+
+	var claudeOptions []option.RequestOption
+	if betas := os.Getenv("ANTHROPIC_BETA"); betas != "" {
+		claudeOptions = append(claudeOptions, option.WithHeader("anthropic-beta", betas))
 	}
 
 	// Initialize PostgreSQL storage
@@ -149,33 +295,152 @@ func initialize() error {
 	}
 
 	pgStorage = postgres.New(db)
+	readiness = newReadinessChecker(pgStorage, claudeAPIKey, os.Getenv("READY_CHECK_ANTHROPIC") == "true")
 
 	// Run migrations
 	if err := pgStorage.Migrate(context.Background()); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	// Initialize GitHub components
-	webhookHandler = github.NewWebhookHandler(webhookSecret)
-	githubClient = github.NewClient(appID, []byte(privateKey))
+	// Optionally batch review writes behind a buffer, trading durability for fewer writes
+	var store storage.Storage = pgStorage
+	if asyncStorage {
+		reviewBuffer = buffered.New(pgStorage, 0, 0, logger)
+		store = reviewBuffer
+	}
+
+	// Initialize GitHub components and a reviewer for each app, sharing PostgreSQL storage
+	apps = newAppRegistry()
+	for _, c := range appConfigs {
+		privateKey := c.PrivateKey
+		if privateKey == "" {
+			data, err := os.ReadFile(c.PrivateKeyPath)
+			if err != nil {
+				return fmt.Errorf("app %d: failed to read private key: %w", c.AppID, err)
+			}
+			privateKey = string(data)
+		}
+
+		// BOT_NAME and ANTHROPIC_MODEL are defaults for apps that don't set their own
+		botName := c.BotName
+		if botName == "" {
+			botName = os.Getenv("BOT_NAME")
+		}
+		if botName == "" {
+			botName = "shipitai"
+		}
+		model := c.Model
+		if model == "" {
+			model = os.Getenv("ANTHROPIC_MODEL")
+		}
+
+		verifier, err := github.NewSignatureVerifier(os.Getenv("WEBHOOK_SIGNATURE_SCHEME"), c.WebhookSecret)
+		if err != nil {
+			return fmt.Errorf("invalid WEBHOOK_SIGNATURE_SCHEME: %w", err)
+		}
+		webhooks := github.NewWebhookHandlerWithVerifier(verifier)
+		if maxPayloadBytes > 0 {
+			webhooks.SetMaxPayloadSize(maxPayloadBytes)
+		}
+		githubClient := github.NewClient(c.AppID, []byte(privateKey))
 
-	// Initialize reviewer with PostgreSQL storage
-	reviewer = review.NewReviewer(githubClient, claudeAPIKey, pgStorage, logger)
-	reviewer.SetBotName(botName)
+		reviewer := review.NewReviewer(githubClient, claudeAPIKey, store, logger)
+		reviewer.SetBotName(botName)
+		reviewer.SetInstructions(c.Instructions)
+		if maxConcurrentReviews >= 0 {
+			reviewer.SetMaxConcurrentReviewsPerInstallation(maxConcurrentReviews)
+		}
+		if maxConcurrentChunks > 0 {
+			reviewer.SetMaxConcurrentChunks(maxConcurrentChunks)
+		}
+		if contextFetchFraction > 0 {
+			reviewer.SetContextFetchFraction(contextFetchFraction)
+		}
+		if replyDebounceWindow >= 0 {
+			reviewer.SetReplyDebounceWindow(replyDebounceWindow)
+		}
+		reviewer.SetContextFetchTimeout(contextTimeout)
+		reviewer.SetClaudeTimeout(claudeTimeout)
+		reviewer.SetTrackCommentOutcomes(trackCommentOutcomes)
+		reviewer.SetClaudeOptions(claudeOptions...)
+		reviewer.SetArtifactSink(artifactSink)
+		// Optional: override the default Claude model
+		if model != "" {
+			reviewer.SetModel(model)
+		}
 
-	// Optional: override the default Claude model
-	if model := os.Getenv("ANTHROPIC_MODEL"); model != "" {
-		reviewer.SetModel(model)
+		handler := httpserver.New(reviewer, webhooks, githubClient, botName, store, logger.With("app_id", c.AppID))
+		handler.SetEventLogging(eventLogging)
+		handler.SetProgressReactions(progressReactions)
+		if reviewQueue != nil {
+			handler.SetReviewQueue(reviewQueue)
+		}
+		handler.SetTimeouts(reviewTimeout, replyTimeout)
+
+		apps.register(&app{
+			id:           c.AppID,
+			botName:      botName,
+			handler:      handler,
+			githubClient: githubClient,
+			reviewer:     reviewer,
+		})
+		logger.Info("registered app", "app_id", c.AppID, "bot_name", botName)
 	}
 
+	adminToken = os.Getenv("ADMIN_TOKEN")
+	reviewAPIToken = os.Getenv("REVIEW_API_TOKEN")
+
 	logger.Info("initialized",
-		"app_id", appID,
-		"bot_name", botName,
+		"apps", len(appConfigs),
+		"event_logging", eventLogging,
+		"async_storage", asyncStorage,
+		"review_timeout", reviewTimeout.String(),
 	)
 
 	return nil
 }
 
+// backgroundTimeouts returns the outer timeouts for a review and a reply. They're
+// at least the sum of the phases they run, so a slow context fetch followed by a
+// slow Claude call isn't cut off before posting. A contextTimeout of 0 means the
+// fetch scales with the deadline, up to review.MaxContextFetchTimeout.
+func backgroundTimeouts(contextTimeout, claudeTimeout time.Duration) (reviewTimeout, replyTimeout time.Duration) {
+	contextPhase := review.MaxContextFetchTimeout
+	if contextTimeout > 0 {
+		contextPhase = contextTimeout
+	}
+	return max(httpserver.ReviewTimeout, contextPhase+claudeTimeout), max(httpserver.ReplyTimeout, claudeTimeout)
+}
+
+// appConfigFromEnv builds the config for a single GitHub App from environment variables.
+func appConfigFromEnv() (*appConfig, error) {
+	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		return nil, fmt.Errorf("GITHUB_WEBHOOK_SECRET is required")
+	}
+
+	privateKey := os.Getenv("GITHUB_PRIVATE_KEY")
+	if privateKey == "" {
+		return nil, fmt.Errorf("GITHUB_PRIVATE_KEY is required")
+	}
+
+	appIDStr := os.Getenv("GITHUB_APP_ID")
+	if appIDStr == "" {
+		return nil, fmt.Errorf("GITHUB_APP_ID is required")
+	}
+
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GITHUB_APP_ID: %w", err)
+	}
+
+	return &appConfig{
+		AppID:         appID,
+		WebhookSecret: webhookSecret,
+		PrivateKey:    privateKey,
+	}, nil
+}
+
 func handleRoot(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
@@ -188,206 +453,177 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleHealth reports that the process is up, for liveness probes. It checks
+// no dependencies; see handleReady.
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, map[string]string{"status": "healthy"})
 }
 
-func handleWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleAdminEvents returns logged webhook events aggregated by type, action, and
+// result. The optional "since" query parameter is a Go duration (default: 24h).
+func handleAdminEvents(w http.ResponseWriter, r *http.Request) {
+	if adminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+adminToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Read body
-	payload, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Error("failed to read body", "error", err)
-		http.Error(w, "failed to read body", http.StatusBadRequest)
-		return
+	window := 24 * time.Hour
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid since duration", http.StatusBadRequest)
+			return
+		}
+		window = d
 	}
-	defer r.Body.Close()
+	since := time.Now().Add(-window)
 
-	// Get event type
-	eventType := r.Header.Get("X-GitHub-Event")
-	if eventType == "" {
-		http.Error(w, "missing X-GitHub-Event header", http.StatusBadRequest)
+	summaries, err := pgStorage.SummarizeEvents(r.Context(), since)
+	if err != nil {
+		logger.Error("failed to summarize events", "error", err)
+		http.Error(w, "failed to summarize events", http.StatusInternalServerError)
 		return
 	}
+	if summaries == nil {
+		summaries = []storage.EventSummary{}
+	}
 
-	logger.Info("received webhook", "event", eventType, "size", len(payload))
+	jsonResponse(w, http.StatusOK, map[string]any{
+		"since":  since.UTC().Format(time.RFC3339),
+		"events": summaries,
+	})
+}
 
-	// Verify signature
-	signature := r.Header.Get("X-Hub-Signature-256")
-	if err := webhookHandler.VerifySignature(payload, signature); err != nil {
-		logger.Error("signature verification failed", "error", err)
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
+// manualReviewRequest is the body of POST /review. AppID selects the GitHub App
+// when several are served and may be omitted otherwise.
+type manualReviewRequest struct {
+	AppID          int64  `json:"app_id"`
+	InstallationID int64  `json:"installation_id"`
+	Owner          string `json:"owner"`
+	Repo           string `json:"repo"`
+	PRNumber       int    `json:"pr_number"`
+}
+
+// handleManualReview reviews a pull request on demand, e.g. to backfill reviews or
+// from an internal ops tool. It runs synchronously and returns the review result.
+func handleManualReview(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if reviewAPIToken == "" {
+		http.NotFound(w, r)
 		return
 	}
-
-	// Handle ping
-	if eventType == "ping" {
-		logger.Info("received ping")
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "pong"})
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+reviewAPIToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-
-	// Handle review comment events (for @mentions)
-	if eventType == "pull_request_review_comment" {
-		handleReviewComment(w, payload)
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Only handle pull_request events
-	if eventType != "pull_request" {
-		logger.Info("ignoring event", "type", eventType)
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "event ignored"})
+	var req manualReviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-
-	// Parse event
-	event, err := webhookHandler.ParsePullRequestEvent(payload)
-	if err != nil {
-		logger.Error("failed to parse event", "error", err)
-		http.Error(w, "failed to parse event", http.StatusBadRequest)
+	if req.InstallationID <= 0 || req.Owner == "" || req.Repo == "" || req.PRNumber <= 0 {
+		http.Error(w, "installation_id, owner, repo, and pr_number are required", http.StatusBadRequest)
 		return
 	}
-
-	// Check if we should process
-	if !webhookHandler.ShouldProcess(eventType, event) {
-		logger.Info("skipping event", "action", event.Action)
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "event skipped"})
+	a, err := apps.get(req.AppID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	logger.Info("processing PR",
-		"repo", event.Repository.FullName,
-		"pr", event.Number,
-		"action", event.Action,
-	)
+	ctx, cancel := context.WithTimeout(r.Context(), manualReviewTimeout)
+	defer cancel()
 
-	// Respond immediately to GitHub
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "review started"})
+	pr, err := a.githubClient.GetPullRequest(ctx, req.InstallationID, req.Owner, req.Repo, req.PRNumber)
+	if err != nil {
+		logger.Error("failed to fetch pull request", "error", err)
+		http.Error(w, "failed to fetch pull request", http.StatusBadGateway)
+		return
+	}
 
-	// Perform review in background
 	input := &review.ReviewInput{
-		InstallationID: event.Installation.ID,
-		Owner:          event.Repository.Owner.Login,
-		Repo:           event.Repository.Name,
-		PRNumber:       event.Number,
-		PRTitle:        event.PullRequest.Title,
-		PRBody:         event.PullRequest.Body,
-		HeadSHA:        event.PullRequest.Head.SHA,
-		DefaultBranch:  event.Repository.DefaultBranch,
-	}
-
-	// Create or update installation record
-	ctx := context.Background()
-	install, _ := pgStorage.GetInstallation(ctx, event.Installation.ID)
-	if install == nil {
-		// Auto-create installation for self-hosted (always active)
-		install = &storage.Installation{
-			InstallationID: event.Installation.ID,
-			OrgLogin:       event.Repository.Owner.Login,
-			InstalledAt:    time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := pgStorage.SaveInstallation(ctx, install); err != nil {
-			logger.Error("failed to save installation", "error", err)
-		}
+		InstallationID: req.InstallationID,
+		Owner:          req.Owner,
+		Repo:           req.Repo,
+		PRNumber:       req.PRNumber,
+		PRTitle:        pr.Title,
+		PRBody:         pr.Body,
+		HeadSHA:        pr.Head.SHA,
+		PRCreatedAt:    pr.CreatedAt,
+		Labels:         pr.LabelNames(),
+		Draft:          pr.Draft,
+		Merged:         pr.Merged,
+		Requested:      true,
+	}
+	if pr.Base != nil && pr.Base.Repo != nil {
+		input.DefaultBranch = pr.Base.Repo.DefaultBranch
 	}
 
-	go func() {
-		reviewCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-
-		result, err := reviewer.Review(reviewCtx, input)
-		if err != nil {
-			logger.Error("review failed", "error", err)
-			return
-		}
-
-		if result == nil {
-			logger.Info("review skipped (not enabled)")
-			return
-		}
+	logger.Info("manual review requested", "repo", req.Owner+"/"+req.Repo, "pr", req.PRNumber)
+	result, err := a.reviewer.Review(ctx, input)
+	recordEvent("manual", "review", req.Owner, req.Repo, review.Outcome(result, err), start)
+	if err != nil {
+		logger.Error("review failed", "error", err)
+		http.Error(w, "review failed", http.StatusInternalServerError)
+		return
+	}
+	if result == nil {
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "reviews are disabled for this repository"})
+		return
+	}
 
-		logger.Info("review posted",
-			"review_id", result.ReviewID,
-			"comments", result.CommentCount,
-			"url", result.ReviewURL,
-		)
-	}()
+	jsonResponse(w, http.StatusOK, result)
 }
 
-func handleReviewComment(w http.ResponseWriter, payload []byte) {
-	event, err := webhookHandler.ParseReviewCommentEvent(payload)
-	if err != nil {
-		logger.Error("failed to parse review comment event", "error", err)
-		http.Error(w, "failed to parse event", http.StatusBadRequest)
+func handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Check if we should process this comment
-	if !webhookHandler.ShouldProcessComment(event, botName) {
-		logger.Info("ignoring comment",
-			"action", event.Action,
-		)
-		jsonResponse(w, http.StatusOK, map[string]string{"message": "comment ignored"})
+	// Find the app this delivery is for; its secret verifies the signature
+	a, err := apps.route(r.Header)
+	if err != nil {
+		logger.Warn("failed to route webhook", "error", err)
+		http.Error(w, "unknown app", http.StatusBadRequest)
 		return
 	}
+	a.handler.ServeHTTP(w, r)
+}
 
-	logger.Info("processing @mention",
-		"repo", event.Repository.FullName,
-		"pr", event.PullRequest.Number,
-		"comment_id", event.Comment.ID,
-		"user", event.Sender.Login,
-	)
-
-	// Respond immediately
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "reply started"})
-
-	// Process in background
+// recordEvent logs a handled webhook's outcome for analytics when EVENT_LOGGING is on.
+// It runs in the background so a slow or failing database never affects webhook handling.
+func recordEvent(eventType, action, owner, repo, result string, start time.Time) {
+	if !eventLogging {
+		return
+	}
+	event := &storage.Event{
+		EventType:  eventType,
+		Action:     action,
+		Owner:      owner,
+		Repo:       repo,
+		Result:     result,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-
-		// Fetch all comments to build thread context
-		comments, err := githubClient.GetReviewComments(
-			ctx,
-			event.Installation.ID,
-			event.Repository.Owner.Login,
-			event.Repository.Name,
-			event.PullRequest.Number,
-		)
-		if err != nil {
-			logger.Error("failed to fetch comments", "error", err)
-			return
-		}
-
-		threadContext := review.BuildThreadContext(comments, event.Comment.ID)
-		userQuestion := github.ExtractMentionContext(event.Comment.Body, botName)
-
-		input := &review.ReplyInput{
-			InstallationID: event.Installation.ID,
-			Owner:          event.Repository.Owner.Login,
-			Repo:           event.Repository.Name,
-			PRNumber:       event.PullRequest.Number,
-			CommentID:      event.Comment.ID,
-			DiffHunk:       event.Comment.DiffHunk,
-			FilePath:       event.Comment.Path,
-			UserQuestion:   userQuestion,
-			ThreadContext:  threadContext,
+		if err := pgStorage.LogEvent(ctx, event); err != nil {
+			logger.Warn("failed to log event", "error", err)
 		}
-
-		result, err := reviewer.Reply(ctx, input)
-		if err != nil {
-			logger.Error("reply failed", "error", err)
-			return
-		}
-
-		logger.Info("reply posted",
-			"comment_id", result.CommentID,
-			"url", result.CommentURL,
-		)
 	}()
 }
 
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/httpserver"
+	"github.com/shipitai/shipitai/review"
+	"github.com/shipitai/shipitai/storage/buffered"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestHandleManualReview(t *testing.T) {
+	// A repository with reviews disabled: the PR is looked up, then the review is a no-op
+	var prFetched bool
+	client := github.NewClientWithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"message":"Not Found"}`
+		status := http.StatusNotFound
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/pulls/7"):
+			prFetched = true
+			body, status = `{"number":7,"title":"Fix","head":{"sha":"abc123"},"base":{"ref":"main","repo":{"default_branch":"main"}}}`, http.StatusOK
+		case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+			content := base64.StdEncoding.EncodeToString([]byte("enabled: false"))
+			body, status = fmt.Sprintf(`{"content":%q,"encoding":"base64"}`, content), http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: req}, nil
+	}))
+
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	apps = newAppRegistry()
+	apps.register(&app{id: 1, githubClient: client, reviewer: review.NewReviewer(client, "key", nil, logger)})
+	t.Cleanup(func() { apps, reviewAPIToken = nil, "" })
+
+	valid := `{"installation_id":5,"owner":"owner","repo":"repo","pr_number":7}`
+	tests := []struct {
+		name       string
+		token      string
+		auth       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"disabled without a token", "", "Bearer secret", http.MethodPost, valid, http.StatusNotFound},
+		{"wrong token", "secret", "Bearer nope", http.MethodPost, valid, http.StatusUnauthorized},
+		{"wrong method", "secret", "Bearer secret", http.MethodGet, valid, http.StatusMethodNotAllowed},
+		{"malformed body", "secret", "Bearer secret", http.MethodPost, `{`, http.StatusBadRequest},
+		{"missing PR number", "secret", "Bearer secret", http.MethodPost, `{"installation_id":5,"owner":"owner","repo":"repo"}`, http.StatusBadRequest},
+		{"unknown app", "secret", "Bearer secret", http.MethodPost, `{"app_id":9,"installation_id":5,"owner":"owner","repo":"repo","pr_number":7}`, http.StatusBadRequest},
+		{"runs the review", "secret", "Bearer secret", http.MethodPost, valid, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviewAPIToken = tt.token
+			prFetched = false
+			req := httptest.NewRequest(tt.method, "/review", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+
+			handleManualReview(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if prFetched {
+					t.Error("rejected request fetched the pull request")
+				}
+				return
+			}
+			var resp map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || !prFetched || !strings.Contains(resp["message"], "disabled") {
+				t.Errorf("response = %v (%v), fetched PR = %v, want the disabled result", resp, err, prFetched)
+			}
+		})
+	}
+}
+
+func TestBackgroundTimeouts(t *testing.T) {
+	tests := []struct {
+		name           string
+		contextTimeout time.Duration
+		claudeTimeout  time.Duration
+		wantReview     time.Duration
+		wantReply      time.Duration
+	}{
+		{"defaults", 0, review.ClaudeAPITimeout, 5 * time.Minute, 3 * time.Minute},
+		{"short phases keep the defaults", 10 * time.Second, time.Minute, 5 * time.Minute, 2 * time.Minute},
+		{"long context fetch", 4 * time.Minute, review.ClaudeAPITimeout, 7 * time.Minute, 3 * time.Minute},
+		{"long Claude calls", 0, 10 * time.Minute, 10*time.Minute + review.MaxContextFetchTimeout, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotReview, gotReply := backgroundTimeouts(tt.contextTimeout, tt.claudeTimeout)
+			if gotReview != tt.wantReview || gotReply != tt.wantReply {
+				t.Errorf("backgroundTimeouts() = %v, %v, want %v, %v", gotReview, gotReply, tt.wantReview, tt.wantReply)
+			}
+		})
+	}
+}
+
+func TestDrainFlushesInFlightReviews(t *testing.T) {
+	claude := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":          "msg_1",
+			"type":        "message",
+			"role":        "assistant",
+			"model":       "claude-test",
+			"content":     []map[string]any{{"type": "text", "text": `{"summary":"Looks fine.","approval":"approve","comments":[]}`}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]any{"input_tokens": 100, "output_tokens": 10},
+		})
+	}))
+	defer claude.Close()
+	t.Setenv("ANTHROPIC_BASE_URL", claude.URL)
+
+	// The diff fetch holds the review until shutdown has started
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	client := github.NewClientWithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, status := `{"message":"Not Found"}`, http.StatusNotFound
+		switch {
+		case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/pulls/7"):
+			once.Do(func() { close(started) })
+			<-release
+			body, status = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n", http.StatusOK
+		case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/pulls/7/reviews"):
+			body, status = `{"id":100}`, http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: req}, nil
+	}))
+
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	inner := memory.New()
+	buffer := buffered.New(inner, time.Hour, 0, logger)
+	reviewer := review.NewReviewer(client, "key", buffer, logger)
+	handler := httpserver.New(reviewer, github.NewWebhookHandler("secret"), client, "shipitai", buffer, logger)
+	registry := newAppRegistry()
+	registry.register(&app{id: 1, handler: handler, githubClient: client, reviewer: reviewer})
+
+	payload := `{"action":"opened","number":7,"pull_request":{"number":7,"title":"Fix","head":{"sha":"abc123"}},
+		"repository":{"name":"repo","full_name":"owner/repo","default_branch":"main","owner":{"login":"owner"}},
+		"installation":{"id":5}}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(payload))
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("review didn't start")
+	}
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	drain(ctx, registry, buffer)
+
+	reviews, err := inner.ListReviewsForPR(context.Background(), 5, "owner", "repo", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 || reviews[0].ReviewID != 100 {
+		t.Errorf("stored reviews = %+v, want the in-flight review flushed", reviews)
+	}
+}
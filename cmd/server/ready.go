@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shipitai/shipitai/anthropic"
+)
+
+const (
+	// readyCheckTimeout bounds each dependency check behind /ready.
+	readyCheckTimeout = 5 * time.Second
+
+	// anthropicCheckInterval is how long an Anthropic check result is reused, since
+	// each check is a (tiny) billed API call.
+	anthropicCheckInterval = time.Minute
+)
+
+// pinger is a dependency whose connectivity can be checked.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// readinessChecker checks the server's dependencies for GET /ready.
+type readinessChecker struct {
+	db pinger
+
+	// validateKey checks the Anthropic API key; nil skips the Anthropic check
+	validateKey func(ctx context.Context) error
+
+	mu                 sync.Mutex // Held during an Anthropic check so concurrent probes share it
+	anthropicErr       error
+	anthropicCheckedAt time.Time
+}
+
+// newReadinessChecker creates a checker pinging db, and checking apiKey against
+// the Anthropic API if checkAnthropic is set.
+func newReadinessChecker(db pinger, apiKey string, checkAnthropic bool) *readinessChecker {
+	c := &readinessChecker{db: db}
+	if checkAnthropic {
+		c.validateKey = func(ctx context.Context) error {
+			return anthropic.ValidateAPIKey(ctx, apiKey)
+		}
+	}
+	return c
+}
+
+// check returns each dependency's status ("ok" or "failed") and whether all are ok.
+func (c *readinessChecker) check(ctx context.Context) (map[string]string, bool) {
+	checks := make(map[string]string)
+	ready := true
+	record := func(name string, err error) {
+		if err != nil {
+			logger.Warn("readiness check failed", "dependency", name, "error", err)
+			checks[name] = "failed"
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	record("database", c.db.Ping(dbCtx))
+	cancel()
+
+	if c.validateKey != nil {
+		record("anthropic", c.checkAnthropic())
+	}
+	return checks, ready
+}
+
+// checkAnthropic validates the API key at most once per anthropicCheckInterval,
+// returning the cached result in between. The check doesn't use the probe's
+// context: its result is shared, so a probe that disconnects mustn't cache a
+// cancellation as the key's status.
+func (c *readinessChecker) checkAnthropic() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.anthropicCheckedAt.IsZero() && time.Since(c.anthropicCheckedAt) < anthropicCheckInterval {
+		return c.anthropicErr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readyCheckTimeout)
+	defer cancel()
+	c.anthropicErr = c.validateKey(ctx)
+	c.anthropicCheckedAt = time.Now()
+	return c.anthropicErr
+}
+
+// handleReady reports whether the server can serve reviews, for readiness
+// probes: 200 when every dependency is reachable, otherwise 503 listing which
+// failed. Unlike /health, it touches the database and optionally Anthropic.
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	checks, ready := readiness.check(r.Context())
+	if !ready {
+		jsonResponse(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "checks": checks})
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]any{"status": "ready", "checks": checks})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error { return f(ctx) }
+
+func TestHandleReady(t *testing.T) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	t.Cleanup(func() { readiness = nil })
+
+	down := errors.New("connection refused")
+	tests := []struct {
+		name         string
+		dbErr        error
+		anthropicErr error
+		checkKey     bool
+		wantStatus   int
+		wantChecks   map[string]string
+	}{
+		{"database only", nil, nil, false, http.StatusOK, map[string]string{"database": "ok"}},
+		{"database down", down, nil, false, http.StatusServiceUnavailable, map[string]string{"database": "failed"}},
+		{"with anthropic", nil, nil, true, http.StatusOK, map[string]string{"database": "ok", "anthropic": "ok"}},
+		{"anthropic down", nil, down, true, http.StatusServiceUnavailable, map[string]string{"database": "ok", "anthropic": "failed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness = &readinessChecker{db: pingFunc(func(ctx context.Context) error { return tt.dbErr })}
+			keyChecks := 0
+			if tt.checkKey {
+				readiness.validateKey = func(ctx context.Context) error {
+					keyChecks++
+					return tt.anthropicErr
+				}
+			}
+
+			// The second probe reuses the cached Anthropic result
+			for i := 0; i < 2; i++ {
+				rec := httptest.NewRecorder()
+				handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+				}
+				var resp struct {
+					Checks map[string]string `json:"checks"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if len(resp.Checks) != len(tt.wantChecks) {
+					t.Fatalf("checks = %v, want %v", resp.Checks, tt.wantChecks)
+				}
+				for name, want := range tt.wantChecks {
+					if resp.Checks[name] != want {
+						t.Errorf("checks[%q] = %q, want %q", name, resp.Checks[name], want)
+					}
+				}
+			}
+			if tt.checkKey && keyChecks != 1 {
+				t.Errorf("validated the API key %d times, want 1", keyChecks)
+			}
+		})
+	}
+}
+
+func TestHandleReadyIgnoresProbeCancellation(t *testing.T) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	t.Cleanup(func() { readiness = nil })
+
+	readiness = &readinessChecker{
+		db: pingFunc(func(ctx context.Context) error { return nil }),
+		validateKey: func(ctx context.Context) error {
+			return ctx.Err()
+		},
+	}
+
+	// A probe that has already gone away doesn't fail the shared Anthropic check
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handleReady(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ready", nil).WithContext(ctx))
+
+	rec := httptest.NewRecorder()
+	handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/shipitai/shipitai/github"
 	"gopkg.in/yaml.v3"
@@ -19,8 +21,73 @@ const (
 	TriggerAuto = "auto"
 	// TriggerOnRequest triggers a review only when requested.
 	TriggerOnRequest = "on-request"
+
+	// FindingsOutputComments posts findings as inline review comments.
+	FindingsOutputComments = "comments"
+	// FindingsOutputAnnotations posts findings as Check Run annotations.
+	FindingsOutputAnnotations = "annotations"
+
+	// TriageCommentOff posts no findings checklist.
+	TriageCommentOff = "off"
+	// TriageCommentAlongside posts a findings checklist as well as inline comments.
+	TriageCommentAlongside = "alongside"
+	// TriageCommentInstead posts a findings checklist in place of inline comments.
+	TriageCommentInstead = "instead"
+
+	// SeverityBadgePrefix puts the severity badge before the comment text.
+	SeverityBadgePrefix = "prefix"
+	// SeverityBadgeSuffix puts the severity badge after the comment text.
+	SeverityBadgeSuffix = "suffix"
+	// SeverityBadgeNone omits the severity badge.
+	SeverityBadgeNone = "none"
+
+	// ApprovalRequestChanges is a severity level whose findings block the PR.
+	ApprovalRequestChanges = "request_changes"
+	// ApprovalComment is a severity level whose findings leave a comment review.
+	ApprovalComment = "comment"
+	// ApprovalApprove is a severity level whose findings still allow approval.
+	ApprovalApprove = "approve"
+
+	// RequestChangesNever is the request_changes_at value that never blocks merges.
+	RequestChangesNever = "never"
+
+	// PermissionEveryone lets anyone who can comment run a command.
+	PermissionEveryone = "everyone"
+	// PermissionContributors restricts a command to users with write access.
+	PermissionContributors = "contributors"
+
+	// DefaultMaxOutputTokens caps Claude's review responses when max_output_tokens is unset.
+	DefaultMaxOutputTokens = 8192
+	// MaxOutputTokensLimit is the largest max_output_tokens any supported model accepts.
+	MaxOutputTokensLimit = 128000
+
+	// MaxSystemPromptAffixLength caps system_prompt_prefix and system_prompt_suffix, in bytes.
+	MaxSystemPromptAffixLength = 8 * 1024
 )
 
+// defaultCommandPermissions lists who may run each comment command when not configured.
+// Plain @mention replies are treated as "explain" and stay open to everyone.
+var defaultCommandPermissions = map[string]string{
+	github.CommandReview:    PermissionContributors,
+	github.CommandRereview:  PermissionContributors,
+	github.CommandExplain:   PermissionEveryone,
+	github.CommandSummarize: PermissionContributors,
+	github.CommandResolve:   PermissionContributors,
+	github.CommandRetry:     PermissionContributors,
+	github.CommandIgnore:    PermissionContributors,
+	github.CommandUnignore:  PermissionContributors,
+}
+
+// alwaysContributorCommand reports whether a command runs or stops reviews, and
+// so always requires contributor access regardless of command_permissions.
+func alwaysContributorCommand(command string) bool {
+	switch command {
+	case github.CommandReview, github.CommandRereview, github.CommandRetry, github.CommandIgnore, github.CommandUnignore:
+		return true
+	}
+	return false
+}
+
 // ConfigParseError indicates a configuration file exists but contains invalid content.
 // This is distinct from "file not found" errors, which should use default config.
 type ConfigParseError struct {
@@ -46,9 +113,29 @@ type Config struct {
 	// Exclude is a list of glob patterns for files to skip during review.
 	// Example: ["vendor/**", "*.gen.go", "docs/**"]
 	Exclude []string `yaml:"exclude"`
+	// Include limits review to files matching at least one of these glob patterns,
+	// matched like Exclude. Exclude still applies to included files. Empty reviews
+	// every file that isn't excluded.
+	// Example: ["src/**", "api/**"]
+	Include []string `yaml:"include,omitempty"`
+	// OnlyOwnedBy limits reviews to files that CODEOWNERS assigns to one of these
+	// teams or users (e.g. "@org/payments"). Unowned files are skipped. Empty
+	// reviews every file.
+	OnlyOwnedBy []string `yaml:"only_owned_by,omitempty"`
+	// MaxFileDiffBytes skips files whose diff has more than this many bytes of
+	// added and context lines, e.g. lockfiles and generated code. 0 disables.
+	// Binary files are always skipped.
+	MaxFileDiffBytes int `yaml:"max_file_diff_bytes,omitempty"`
 	// Instructions provides custom guidance for the reviewer.
 	// Example: "Focus on security. We use sqlc for DB queries."
 	Instructions string `yaml:"instructions"`
+	// SystemPromptPrefix is placed before the built-in review system prompt, e.g.
+	// for a custom persona. The built-in prompt, including its response format,
+	// is always kept.
+	SystemPromptPrefix string `yaml:"system_prompt_prefix,omitempty"`
+	// SystemPromptSuffix is placed right after the built-in review system prompt,
+	// ahead of CLAUDE.md and instructions.
+	SystemPromptSuffix string `yaml:"system_prompt_suffix,omitempty"`
 	// Context configures rich context fetching for reviews.
 	// If nil, defaults are used (all enabled).
 	Context *ContextConfig `yaml:"context,omitempty"`
@@ -56,11 +143,143 @@ type Config struct {
 	// Non-contributors can have their PRs reviewed when a contributor comments "@shipitai review".
 	// If nil, defaults to true (protection enabled).
 	ContributorProtection *bool `yaml:"contributor_protection,omitempty"`
+	// RepliesEnabled controls whether the bot answers @mentions in review threads.
+	// Reviews and commands like "resolve" are unaffected. If nil, defaults to true.
+	RepliesEnabled *bool `yaml:"replies_enabled,omitempty"`
+	// MaxRepliesPerPR caps how many @mentions the bot answers on a pull request;
+	// mentions past it get a short canned reply, once per thread, instead of a
+	// Claude call. 0 disables.
+	MaxRepliesPerPR int `yaml:"max_replies_per_pr,omitempty"`
+	// WelcomeMessage posts a one-time comment explaining how ShipItAI works on the
+	// first pull request it sees after being installed. If nil, defaults to true.
+	WelcomeMessage *bool `yaml:"welcome_message,omitempty"`
+	// SkipDrafts skips automatic reviews of draft pull requests; marking one ready
+	// for review triggers its review. If nil, defaults to true.
+	SkipDrafts *bool `yaml:"skip_drafts,omitempty"`
+	// MaxPRAge skips automatic reviews of pull requests opened longer ago than this.
+	// Accepts Go durations ("720h") or a number of days ("90d"). Empty means no limit.
+	// Explicitly requested reviews ("@shipitai review") are not affected.
+	MaxPRAge string `yaml:"max_pr_age,omitempty"`
+	// SummarizeSuggestions adds a line to the review summary counting inline
+	// one-click suggestions. Defaults to false.
+	SummarizeSuggestions bool `yaml:"summarize_suggestions,omitempty"`
+	// ShowDiffStats starts the review summary with the size of the reviewed diff
+	// (after exclude), e.g. "+120/−45 across 8 files". Defaults to false.
+	ShowDiffStats bool `yaml:"show_diff_stats,omitempty"`
+	// FindingsOutput selects where review findings are posted.
+	// Valid values: "comments" (inline review comments, default), "annotations" (Check Run annotations)
+	FindingsOutput string `yaml:"findings_output,omitempty"`
+	// SummaryAsComment also posts the review summary, with a count of findings by
+	// severity, as a PR comment; later reviews edit that comment. Defaults to false.
+	SummaryAsComment bool `yaml:"summary_as_comment,omitempty"`
+	// ReviewArtifacts archives each review (summary and findings, as Markdown and
+	// JSON) to the server's artifact store and links it from the review body. It
+	// has no effect unless the server has an artifact store. Defaults to false.
+	ReviewArtifacts bool `yaml:"review_artifacts,omitempty"`
+	// TriageComment posts every finding as a checkbox in a PR comment that reviewers
+	// tick off as they address them; later reviews edit it, keeping ticked items.
+	// Valid values: "off" (default), "alongside" (with inline comments), "instead" (replaces them)
+	TriageComment string `yaml:"triage_comment,omitempty"`
+	// LightReviewReverts skips the full review of PRs that look like reverts and
+	// posts a short note instead. Defaults to false.
+	LightReviewReverts bool `yaml:"light_review_reverts,omitempty"`
+	// PartialChunkReviews posts a chunked review even when some chunks fail, listing
+	// the files that weren't reviewed; "@shipitai retry" re-reviews them. Defaults to false.
+	PartialChunkReviews bool `yaml:"partial_chunk_reviews,omitempty"`
+	// SkipDeletionRatio skips the full review of PRs whose changed lines are at least
+	// this fraction deletions (e.g. 0.9) and posts a short note instead. 0 disables.
+	SkipDeletionRatio float64 `yaml:"skip_deletion_ratio,omitempty"`
+	// MinChangedLines skips automatic reviews of PRs changing fewer lines (additions
+	// plus deletions, after exclude filtering) than this, e.g. typo fixes. 0 disables.
+	MinChangedLines int `yaml:"min_changed_lines,omitempty"`
+	// SkipIfOnlyFiles skips automatic reviews of PRs whose changed files all match
+	// one of these glob patterns, matched like Exclude.
+	// Example: ["go.mod", "go.sum", "*.md"]
+	SkipIfOnlyFiles []string `yaml:"skip_if_only_files,omitempty"`
+	// CommandPermissions sets who may run each comment command: "everyone" or "contributors".
+	// Example: {"summarize": "everyone"}. "review", "rereview", "retry", "ignore", and "unignore"
+	// always require write access.
+	CommandPermissions map[string]string `yaml:"command_permissions,omitempty"`
+	// MinSeverity drops comments below this severity before they're posted.
+	// Valid values: a level of SeverityScale ("low", "medium", "high", "critical"
+	// by default). Empty posts everything.
+	MinSeverity string `yaml:"min_severity,omitempty"`
+	// RequestChangesAt overrides which findings request changes: those at or above
+	// this level of SeverityScale do, and less severe levels that would block only
+	// comment. "never" only ever comments. Empty keeps the scale's approvals.
+	RequestChangesAt string `yaml:"request_changes_at,omitempty"`
+	// SeverityScale replaces the built-in severity levels with the repository's
+	// own, listed most severe first. Each level sets the review outcome its
+	// findings lead to. Empty uses DefaultSeverityScale.
+	SeverityScale SeverityScale `yaml:"severity_scale,omitempty"`
+	// SeverityBadge sets where the severity badge goes on follow-up review comments.
+	// Valid values: "prefix" (default), "suffix", "none"
+	SeverityBadge string `yaml:"severity_badge,omitempty"`
+	// SeverityEmoji adds a colored emoji to the severity badge. Defaults to false.
+	SeverityEmoji bool `yaml:"severity_emoji,omitempty"`
+	// ExplainConfidence asks Claude to rate its confidence in each finding and shows
+	// it on the comment. Defaults to false to keep prompts lean.
+	ExplainConfidence bool `yaml:"explain_confidence,omitempty"`
+	// MinConfidence drops comments Claude is less confident in than this.
+	// Valid values: "low", "medium", "high". Setting it also requests confidence ratings.
+	MinConfidence string `yaml:"min_confidence,omitempty"`
+	// SnapCommentLines moves a comment Claude placed just outside the diff to the
+	// nearest line inside it (up to SnapCommentWindow lines away) instead of
+	// dropping it. Defaults to false.
+	SnapCommentLines bool `yaml:"snap_comment_lines,omitempty"`
+	// CaseInsensitivePaths keeps a comment whose path differs from a diff path only
+	// in case ("Main.go" for "main.go") by moving it to the diff's path, when exactly
+	// one diff path matches. Defaults to false, which drops such comments.
+	CaseInsensitivePaths bool `yaml:"case_insensitive_paths,omitempty"`
+	// ReviewMergeResult reviews the PR's test-merge commit against base (what will
+	// actually land) instead of the branch diff. Falls back to the branch diff when
+	// there's no merge commit, e.g. on conflicts. Defaults to false.
+	ReviewMergeResult bool `yaml:"review_merge_result,omitempty"`
+	// RequireStorage skips a review when stored review history can't be read,
+	// rather than risking a duplicate first review on a PR the bot already reviewed.
+	// Defaults to false, which falls back to checking the PR's reviews on GitHub.
+	RequireStorage bool `yaml:"require_storage,omitempty"`
+	// WaitForChecks posts an approving review as a comment while other check runs on
+	// the head commit are still running, so the bot's approval can't merge a PR
+	// before CI finishes. Defaults to false.
+	WaitForChecks bool `yaml:"wait_for_checks,omitempty"`
+	// RespectTODOs drops comments whose concern the author already acknowledged
+	// with a nearby TODO/FIXME on an added line. Defaults to false.
+	RespectTODOs bool `yaml:"respect_todos,omitempty"`
+	// DescriptionCheck notes in a first review's summary when the PR description is
+	// empty or only an unfilled template. Defaults to false.
+	DescriptionCheck bool `yaml:"description_check,omitempty"`
+	// LabelContext tells Claude which labels the PR carries (e.g. "security",
+	// "breaking-change") so it can weigh its review accordingly.
+	// If nil, defaults to true.
+	LabelContext *bool `yaml:"label_context,omitempty"`
+	// MaxOutputTokens caps the length of Claude's review responses. Raise it if large
+	// PRs with many findings get truncated. 0 uses the default (8192).
+	MaxOutputTokens int `yaml:"max_output_tokens,omitempty"`
 	// ClaudeMD contains the contents of the repository's CLAUDE.md file.
 	// This provides project-specific context for code reviews.
 	ClaudeMD string `yaml:"-"`
 }
 
+// SnapCommentWindow is how many lines snap_comment_lines may move a comment.
+const SnapCommentWindow = 2
+
+// CommentSnapWindow returns how many lines a comment outside the diff may be
+// moved to land on a line inside it: SnapCommentWindow with snap_comment_lines,
+// otherwise 0.
+func (c *Config) CommentSnapWindow() int {
+	if c.SnapCommentLines {
+		return SnapCommentWindow
+	}
+	return 0
+}
+
+// RequestsConfidence returns true if reviews should ask Claude for per-finding
+// confidence, either to show it or to filter on it.
+func (c *Config) RequestsConfidence() bool {
+	return c.ExplainConfidence || c.MinConfidence != ""
+}
+
 // IsContributorProtectionEnabled returns true if contributor protection is enabled.
 // Defaults to true if not explicitly set.
 func (c *Config) IsContributorProtectionEnabled() bool {
@@ -70,6 +289,177 @@ func (c *Config) IsContributorProtectionEnabled() bool {
 	return *c.ContributorProtection
 }
 
+// IsLabelContextEnabled returns true if the PR's labels should be included in the prompt.
+// Defaults to true if not explicitly set.
+func (c *Config) IsLabelContextEnabled() bool {
+	if c.LabelContext == nil {
+		return true
+	}
+	return *c.LabelContext
+}
+
+// ShouldSkipDrafts returns true if automatic reviews should skip draft PRs.
+// Defaults to true if not explicitly set.
+func (c *Config) ShouldSkipDrafts() bool {
+	if c.SkipDrafts == nil {
+		return true
+	}
+	return *c.SkipDrafts
+}
+
+// AreRepliesEnabled returns true if the bot should reply to @mentions.
+// Defaults to true if not explicitly set.
+func (c *Config) AreRepliesEnabled() bool {
+	if c.RepliesEnabled == nil {
+		return true
+	}
+	return *c.RepliesEnabled
+}
+
+// IsWelcomeMessageEnabled returns true if the first pull request of a new
+// installation should get a welcome comment. Defaults to true if not explicitly set.
+func (c *Config) IsWelcomeMessageEnabled() bool {
+	if c.WelcomeMessage == nil {
+		return true
+	}
+	return *c.WelcomeMessage
+}
+
+// SeverityLevel is one level of a severity scale.
+type SeverityLevel struct {
+	// Name is the value Claude gives a finding's severity, e.g. "P0".
+	Name string `yaml:"name"`
+	// Approval is the review outcome findings at this level lead to:
+	// "request_changes" (blocks), "comment", or "approve".
+	Approval string `yaml:"approval"`
+	// Description tells Claude which findings belong at this level.
+	Description string `yaml:"description,omitempty"`
+}
+
+// SeverityScale lists severity levels from most to least severe.
+// An empty scale behaves as DefaultSeverityScale.
+type SeverityScale []SeverityLevel
+
+// DefaultSeverityScale is the built-in severity scale.
+var DefaultSeverityScale = SeverityScale{
+	{Name: "critical", Approval: ApprovalRequestChanges, Description: "Bugs, security vulnerabilities, or problems that MUST be fixed before merging"},
+	{Name: "high", Approval: ApprovalRequestChanges, Description: "Important issues that should be addressed before merging"},
+	{Name: "medium", Approval: ApprovalComment, Description: "Improvements that would be nice but aren't required"},
+	{Name: "low", Approval: ApprovalApprove, Description: "Very minor issues, style preferences, or optional enhancements"},
+}
+
+// levels returns the scale's levels, falling back to DefaultSeverityScale.
+func (s SeverityScale) levels() SeverityScale {
+	if len(s) == 0 {
+		return DefaultSeverityScale
+	}
+	return s
+}
+
+// IsCustom returns true if the repository configured its own scale.
+func (s SeverityScale) IsCustom() bool {
+	return len(s) > 0
+}
+
+// Names returns the level names, most severe first.
+func (s SeverityScale) Names() []string {
+	levels := s.levels()
+	names := make([]string, len(levels))
+	for i, level := range levels {
+		names[i] = level.Name
+	}
+	return names
+}
+
+// Has returns true if name is a level of the scale.
+func (s SeverityScale) Has(name string) bool {
+	for _, level := range s.levels() {
+		if level.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultLevel returns the level given to findings with a missing or unknown
+// severity: the most severe level that leads to a comment, or the least severe
+// level if none does ("medium" on the default scale).
+func (s SeverityScale) DefaultLevel() string {
+	levels := s.levels()
+	for _, level := range levels {
+		if level.Approval == ApprovalComment {
+			return level.Name
+		}
+	}
+	return levels[len(levels)-1].Name
+}
+
+// Rank orders levels from least (1) to most (len of the scale) severe.
+// Unknown severities rank as DefaultLevel.
+func (s SeverityScale) Rank(name string) int {
+	levels := s.levels()
+	if !s.Has(name) {
+		name = s.DefaultLevel()
+	}
+	for i, level := range levels {
+		if level.Name == name {
+			return len(levels) - i
+		}
+	}
+	return 0
+}
+
+// Level returns the level named name, or DefaultLevel's for unknown names.
+func (s SeverityScale) Level(name string) SeverityLevel {
+	levels := s.levels()
+	return levels[len(levels)-s.Rank(name)]
+}
+
+// Approval returns the review outcome a finding of the given severity leads to.
+// Unknown severities are treated as DefaultLevel.
+func (s SeverityScale) Approval(name string) string {
+	return s.Level(name).Approval
+}
+
+// ApprovalWithThreshold returns the review outcome a finding of the given
+// severity leads to when findings at or above requestChangesAt request changes
+// (see Config.RequestChangesAt). An empty threshold keeps the scale's outcome.
+func (s SeverityScale) ApprovalWithThreshold(name, requestChangesAt string) string {
+	approval := s.Approval(name)
+	switch {
+	case requestChangesAt == "":
+		return approval
+	case requestChangesAt != RequestChangesNever && s.Rank(name) >= s.Rank(requestChangesAt):
+		return ApprovalRequestChanges
+	case approval == ApprovalRequestChanges:
+		return ApprovalComment
+	}
+	return approval
+}
+
+// validate checks that every level is named once and that no level leads to a
+// stronger outcome than a more severe one.
+func (s SeverityScale) validate() error {
+	strength := map[string]int{ApprovalApprove: 1, ApprovalComment: 2, ApprovalRequestChanges: 3}
+	seen := make(map[string]bool, len(s))
+	for i, level := range s {
+		if level.Name == "" {
+			return fmt.Errorf("invalid severity_scale entry %d: name is required", i+1)
+		}
+		if seen[level.Name] {
+			return fmt.Errorf("invalid severity_scale entry %d: duplicate name %q", i+1, level.Name)
+		}
+		seen[level.Name] = true
+		if strength[level.Approval] == 0 {
+			return fmt.Errorf("invalid severity_scale approval for %q: %s (must be 'request_changes', 'comment', or 'approve')", level.Name, level.Approval)
+		}
+		if i > 0 && strength[level.Approval] > strength[s[i-1].Approval] {
+			return fmt.Errorf("invalid severity_scale entry %q: must not lead to a stronger outcome than the more severe %q", level.Name, s[i-1].Name)
+		}
+	}
+	return nil
+}
+
 // ContextConfig configures the rich context feature for reviews.
 type ContextConfig struct {
 	// Enabled controls whether rich context is fetched at all.
@@ -84,6 +474,22 @@ type ContextConfig struct {
 	// History controls whether commit history is fetched.
 	// If nil, defaults to true.
 	History *bool `yaml:"history,omitempty"`
+	// MaxFileSize overrides the per-file size cap in bytes for fetched files.
+	// If zero, defaults to 50KB.
+	MaxFileSize int `yaml:"max_file_size,omitempty"`
+	// MaxImportFiles caps the candidate paths fetched for local imports per review.
+	// If zero, defaults to 20.
+	MaxImportFiles int `yaml:"max_import_files,omitempty"`
+	// MaxHistoryFiles caps the files whose commit history is fetched per review.
+	// Only files included as full-file context are considered. If zero, defaults to 20.
+	MaxHistoryFiles int `yaml:"max_history_files,omitempty"`
+	// JavaBasePackage is the package prefix (e.g. "com.company") of the project's own
+	// classes, so Java and Kotlin imports under it are fetched as related files.
+	// If empty, the first two segments of each file's package are used.
+	JavaBasePackage string `yaml:"java_base_package,omitempty"`
+	// SmartGoContext sends only the top-level declarations a diff touches for
+	// changed Go files instead of the whole file. Defaults to false.
+	SmartGoContext bool `yaml:"smart_go_context,omitempty"`
 }
 
 // DefaultConfig returns the default configuration.
@@ -94,13 +500,19 @@ func DefaultConfig() *Config {
 	}
 }
 
+// FileFetcher fetches a file's content at a ref, returning "" if it doesn't exist.
+// Both the GitHub and GitLab clients implement it.
+type FileFetcher interface {
+	FetchFileContent(ctx context.Context, installationID int64, owner, repo, path, ref string) (string, error)
+}
+
 // Loader loads configuration from repositories.
 type Loader struct {
-	client *github.Client
+	client FileFetcher
 }
 
 // NewLoader creates a new config loader.
-func NewLoader(client *github.Client) *Loader {
+func NewLoader(client FileFetcher) *Loader {
 	return &Loader{client: client}
 }
 
@@ -165,9 +577,181 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid trigger value: %s (must be 'auto' or 'on-request')", c.Trigger)
 	}
 
+	if c.MaxPRAge != "" {
+		if _, err := parseAge(c.MaxPRAge); err != nil {
+			return fmt.Errorf("invalid max_pr_age value: %s (use a duration like '720h' or '90d')", c.MaxPRAge)
+		}
+	}
+
+	if c.Context != nil && c.Context.MaxFileSize < 0 {
+		return fmt.Errorf("invalid context.max_file_size value: %d (must not be negative)", c.Context.MaxFileSize)
+	}
+
+	if c.Context != nil && c.Context.MaxImportFiles < 0 {
+		return fmt.Errorf("invalid context.max_import_files value: %d (must not be negative)", c.Context.MaxImportFiles)
+	}
+
+	if c.Context != nil && c.Context.MaxHistoryFiles < 0 {
+		return fmt.Errorf("invalid context.max_history_files value: %d (must not be negative)", c.Context.MaxHistoryFiles)
+	}
+
+	if c.MaxOutputTokens < 0 || c.MaxOutputTokens > MaxOutputTokensLimit {
+		return fmt.Errorf("invalid max_output_tokens value: %d (must be between 0 and %d)", c.MaxOutputTokens, MaxOutputTokensLimit)
+	}
+
+	if c.SkipDeletionRatio < 0 || c.SkipDeletionRatio > 1 {
+		return fmt.Errorf("invalid skip_deletion_ratio value: %v (must be between 0 and 1)", c.SkipDeletionRatio)
+	}
+
+	if c.MaxFileDiffBytes < 0 {
+		return fmt.Errorf("invalid max_file_diff_bytes value: %d (must not be negative)", c.MaxFileDiffBytes)
+	}
+
+	if c.MinChangedLines < 0 {
+		return fmt.Errorf("invalid min_changed_lines value: %d (must not be negative)", c.MinChangedLines)
+	}
+
+	if c.MaxRepliesPerPR < 0 {
+		return fmt.Errorf("invalid max_replies_per_pr value: %d (must not be negative)", c.MaxRepliesPerPR)
+	}
+
+	if len(c.SystemPromptPrefix) > MaxSystemPromptAffixLength {
+		return fmt.Errorf("invalid system_prompt_prefix: %d bytes (must be at most %d)", len(c.SystemPromptPrefix), MaxSystemPromptAffixLength)
+	}
+
+	if len(c.SystemPromptSuffix) > MaxSystemPromptAffixLength {
+		return fmt.Errorf("invalid system_prompt_suffix: %d bytes (must be at most %d)", len(c.SystemPromptSuffix), MaxSystemPromptAffixLength)
+	}
+
+	if err := c.SeverityScale.validate(); err != nil {
+		return err
+	}
+
+	if c.MinSeverity != "" && !c.SeverityScale.Has(c.MinSeverity) {
+		return fmt.Errorf("invalid min_severity value: %s (must be one of: %s)", c.MinSeverity, strings.Join(c.SeverityScale.Names(), ", "))
+	}
+
+	if c.RequestChangesAt != "" && c.RequestChangesAt != RequestChangesNever && !c.SeverityScale.Has(c.RequestChangesAt) {
+		return fmt.Errorf("invalid request_changes_at value: %s (must be 'never' or one of: %s)", c.RequestChangesAt, strings.Join(c.SeverityScale.Names(), ", "))
+	}
+
+	switch c.MinConfidence {
+	case "", "low", "medium", "high":
+	default:
+		return fmt.Errorf("invalid min_confidence value: %s (must be 'low', 'medium', or 'high')", c.MinConfidence)
+	}
+
+	switch c.SeverityBadge {
+	case SeverityBadgePrefix, SeverityBadgeSuffix, SeverityBadgeNone:
+	case "":
+		c.SeverityBadge = SeverityBadgePrefix
+	default:
+		return fmt.Errorf("invalid severity_badge value: %s (must be 'prefix', 'suffix', or 'none')", c.SeverityBadge)
+	}
+
+	switch c.FindingsOutput {
+	case FindingsOutputComments, FindingsOutputAnnotations:
+	case "":
+		c.FindingsOutput = FindingsOutputComments
+	default:
+		return fmt.Errorf("invalid findings_output value: %s (must be 'comments' or 'annotations')", c.FindingsOutput)
+	}
+
+	switch c.TriageComment {
+	case TriageCommentOff, TriageCommentAlongside, TriageCommentInstead:
+	case "":
+		c.TriageComment = TriageCommentOff
+	default:
+		return fmt.Errorf("invalid triage_comment value: %s (must be 'off', 'alongside', or 'instead')", c.TriageComment)
+	}
+
+	for command, permission := range c.CommandPermissions {
+		if _, ok := defaultCommandPermissions[command]; !ok {
+			return fmt.Errorf("invalid command_permissions entry: unknown command %q", command)
+		}
+		switch permission {
+		case PermissionContributors:
+		case PermissionEveryone:
+			if alwaysContributorCommand(command) {
+				return fmt.Errorf("invalid command_permissions entry: %q always requires contributor access", command)
+			}
+		default:
+			return fmt.Errorf("invalid command_permissions value for %q: %s (must be 'everyone' or 'contributors')", command, permission)
+		}
+	}
+
 	return nil
 }
 
+// UsesAnnotations returns true if findings should be posted as Check Run annotations
+// instead of inline review comments.
+func (c *Config) UsesAnnotations() bool {
+	return c.FindingsOutput == FindingsOutputAnnotations
+}
+
+// UsesTriageComment returns true if findings should be listed in a triage checklist comment.
+func (c *Config) UsesTriageComment() bool {
+	return c.TriageComment == TriageCommentAlongside || c.TriageComment == TriageCommentInstead
+}
+
+// IsCommandAllowed returns true if a user may run the given comment command.
+// Contributors may run any command; others only those configured for everyone.
+func (c *Config) IsCommandAllowed(command string, isContributor bool) bool {
+	if isContributor {
+		return true
+	}
+	if alwaysContributorCommand(command) {
+		return false
+	}
+	permission, ok := c.CommandPermissions[command]
+	if !ok {
+		permission = defaultCommandPermissions[command]
+	}
+	return permission == PermissionEveryone
+}
+
+// IsStalePR returns true if a pull request created at createdAt is older than MaxPRAge.
+// Always returns false when MaxPRAge is unset or the creation time is unknown.
+func (c *Config) IsStalePR(createdAt, now time.Time) bool {
+	if c.MaxPRAge == "" || createdAt.IsZero() {
+		return false
+	}
+	maxAge, err := parseAge(c.MaxPRAge)
+	if err != nil || maxAge <= 0 {
+		return false
+	}
+	return now.Sub(createdAt) > maxAge
+}
+
+// OutputTokens returns the configured max_output_tokens, or the default when unset.
+func (c *Config) OutputTokens() int {
+	if c.MaxOutputTokens <= 0 {
+		return DefaultMaxOutputTokens
+	}
+	return c.MaxOutputTokens
+}
+
+// IsMostlyDeletions returns true if deletions make up at least SkipDeletionRatio
+// of a diff's changed lines. Always returns false when the ratio is unset.
+func (c *Config) IsMostlyDeletions(additions, deletions int) bool {
+	if c.SkipDeletionRatio <= 0 || deletions == 0 {
+		return false
+	}
+	return float64(deletions)/float64(additions+deletions) >= c.SkipDeletionRatio
+}
+
+// parseAge parses a Go duration string, additionally accepting a whole number of days ("90d").
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid day count: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
 // ShouldReviewOnEvent returns true if a review should be triggered for automatic events.
 func (c *Config) ShouldReviewOnEvent() bool {
 	return c.Enabled && c.Trigger == TriggerAuto
@@ -175,7 +759,41 @@ func (c *Config) ShouldReviewOnEvent() bool {
 
 // ShouldExcludeFile returns true if the file path matches any exclude pattern.
 func (c *Config) ShouldExcludeFile(path string) bool {
-	for _, pattern := range c.Exclude {
+	return matchesAnyPattern(path, c.Exclude)
+}
+
+// ShouldIncludeFile returns true if the file should be reviewed: it matches an
+// include pattern (when any are set) and no exclude pattern.
+func (c *Config) ShouldIncludeFile(path string) bool {
+	if len(c.Include) > 0 && !matchesAnyPattern(path, c.Include) {
+		return false
+	}
+	return !c.ShouldExcludeFile(path)
+}
+
+// IsBelowMinChangedLines returns true if a diff changes fewer lines than
+// MinChangedLines. Always returns false when the minimum is unset.
+func (c *Config) IsBelowMinChangedLines(additions, deletions int) bool {
+	return c.MinChangedLines > 0 && additions+deletions < c.MinChangedLines
+}
+
+// OnlyTouchesSkipFiles returns true if every path matches a SkipIfOnlyFiles pattern.
+// Always returns false when no patterns are set or there are no paths.
+func (c *Config) OnlyTouchesSkipFiles(paths []string) bool {
+	if len(c.SkipIfOnlyFiles) == 0 || len(paths) == 0 {
+		return false
+	}
+	for _, path := range paths {
+		if !matchesAnyPattern(path, c.SkipIfOnlyFiles) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAnyPattern returns true if the file path matches any of the glob patterns.
+func matchesAnyPattern(path string, patterns []string) bool {
+	for _, pattern := range patterns {
 		// Handle ** patterns by checking if any path segment matches
 		if strings.Contains(pattern, "**") {
 			// Convert ** pattern to check directory prefix
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParse(t *testing.T) {
@@ -83,6 +85,325 @@ func TestParse(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:    "max_pr_age in days",
+			content: "max_pr_age: 90d",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.MaxPRAge != "90d" {
+					t.Errorf("MaxPRAge = %v, want 90d", c.MaxPRAge)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "summarize_suggestions",
+			content: "summarize_suggestions: true",
+			wantErr: false,
+			check: func(c *Config) error {
+				if !c.SummarizeSuggestions {
+					t.Error("SummarizeSuggestions = false, want true")
+				}
+				return nil
+			},
+		},
+		{
+			name:    "command_permissions",
+			content: "command_permissions:\n  summarize: everyone\n  explain: contributors",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.CommandPermissions["summarize"] != PermissionEveryone {
+					t.Errorf("CommandPermissions[summarize] = %v, want everyone", c.CommandPermissions["summarize"])
+				}
+				return nil
+			},
+		},
+		{
+			name:    "command_permissions cannot open review",
+			content: "command_permissions:\n  review: everyone",
+			wantErr: true,
+		},
+		{
+			name:    "command_permissions unknown command",
+			content: "command_permissions:\n  deploy: everyone",
+			wantErr: true,
+		},
+		{
+			name:    "command_permissions invalid value",
+			content: "command_permissions:\n  explain: admins",
+			wantErr: true,
+		},
+		{
+			name:    "context max_file_size",
+			content: "context:\n  max_file_size: 204800",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.Context == nil || c.Context.MaxFileSize != 204800 {
+					t.Errorf("Context = %+v, want MaxFileSize 204800", c.Context)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "negative context max_file_size",
+			content: "context:\n  max_file_size: -1",
+			wantErr: true,
+		},
+		{
+			name:    "only_owned_by",
+			content: "only_owned_by:\n  - \"@org/payments\"\n  - alice",
+			wantErr: false,
+			check: func(c *Config) error {
+				if len(c.OnlyOwnedBy) != 2 || c.OnlyOwnedBy[0] != "@org/payments" {
+					t.Errorf("OnlyOwnedBy = %v, want [@org/payments alice]", c.OnlyOwnedBy)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "context max_import_files",
+			content: "context:\n  max_import_files: 50",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.Context == nil || c.Context.MaxImportFiles != 50 {
+					t.Errorf("Context = %+v, want MaxImportFiles 50", c.Context)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "negative context max_import_files",
+			content: "context:\n  max_import_files: -1",
+			wantErr: true,
+		},
+		{
+			name:    "negative context max_history_files",
+			content: "context:\n  max_history_files: -1",
+			wantErr: true,
+		},
+		{
+			name:    "skip_deletion_ratio",
+			content: "skip_deletion_ratio: 0.9",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.SkipDeletionRatio != 0.9 {
+					t.Errorf("SkipDeletionRatio = %v, want 0.9", c.SkipDeletionRatio)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "skip_deletion_ratio out of range",
+			content: "skip_deletion_ratio: 1.5",
+			wantErr: true,
+		},
+		{
+			name:    "min_changed_lines and skip_if_only_files",
+			content: "min_changed_lines: 5\nskip_if_only_files:\n  - go.sum\n  - \"*.md\"",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.MinChangedLines != 5 || len(c.SkipIfOnlyFiles) != 2 {
+					t.Errorf("MinChangedLines = %d, SkipIfOnlyFiles = %v", c.MinChangedLines, c.SkipIfOnlyFiles)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "negative min_changed_lines",
+			content: "min_changed_lines: -1",
+			wantErr: true,
+		},
+		{
+			name:    "max_file_diff_bytes",
+			content: "max_file_diff_bytes: 100000",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.MaxFileDiffBytes != 100000 {
+					t.Errorf("MaxFileDiffBytes = %d, want 100000", c.MaxFileDiffBytes)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "negative max_file_diff_bytes",
+			content: "max_file_diff_bytes: -1",
+			wantErr: true,
+		},
+		{
+			name:    "max_output_tokens",
+			content: "max_output_tokens: 16000",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.OutputTokens() != 16000 {
+					t.Errorf("OutputTokens() = %d, want 16000", c.OutputTokens())
+				}
+				return nil
+			},
+		},
+		{
+			name:    "max_output_tokens unset uses default",
+			content: "enabled: true",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.OutputTokens() != DefaultMaxOutputTokens {
+					t.Errorf("OutputTokens() = %d, want %d", c.OutputTokens(), DefaultMaxOutputTokens)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "max_output_tokens above limit",
+			content: "max_output_tokens: 500000",
+			wantErr: true,
+		},
+		{
+			name:    "negative max_output_tokens",
+			content: "max_output_tokens: -1",
+			wantErr: true,
+		},
+		{
+			name:    "findings_output annotations",
+			content: "findings_output: annotations",
+			wantErr: false,
+			check: func(c *Config) error {
+				if !c.UsesAnnotations() {
+					t.Error("UsesAnnotations() = false, want true")
+				}
+				return nil
+			},
+		},
+		{
+			name:    "invalid findings_output",
+			content: "findings_output: email",
+			wantErr: true,
+		},
+		{
+			name:    "triage_comment instead",
+			content: "triage_comment: instead",
+			wantErr: false,
+			check: func(c *Config) error {
+				if !c.UsesTriageComment() || c.TriageComment != TriageCommentInstead {
+					t.Errorf("TriageComment = %q, want %q", c.TriageComment, TriageCommentInstead)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "triage_comment defaults to off",
+			content: "enabled: true",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.UsesTriageComment() || c.TriageComment != TriageCommentOff {
+					t.Errorf("TriageComment = %q, want %q", c.TriageComment, TriageCommentOff)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "invalid triage_comment",
+			content: "triage_comment: sometimes",
+			wantErr: true,
+		},
+		{
+			name:    "min_severity high",
+			content: "min_severity: high",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.MinSeverity != "high" {
+					t.Errorf("MinSeverity = %v, want high", c.MinSeverity)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "invalid min_severity",
+			content: "min_severity: nitpick",
+			wantErr: true,
+		},
+		{
+			name: "severity_scale",
+			content: `severity_scale:
+  - name: P0
+    approval: request_changes
+    description: Outage or data loss
+  - name: P1
+    approval: request_changes
+  - name: P2
+    approval: comment
+  - name: P3
+    approval: approve
+min_severity: P2`,
+			wantErr: false,
+			check: func(c *Config) error {
+				if got := strings.Join(c.SeverityScale.Names(), ","); got != "P0,P1,P2,P3" {
+					t.Errorf("SeverityScale.Names() = %v, want P0,P1,P2,P3", got)
+				}
+				if c.SeverityScale.DefaultLevel() != "P2" || c.SeverityScale.Approval("P1") != ApprovalRequestChanges {
+					t.Errorf("DefaultLevel() = %v, Approval(P1) = %v", c.SeverityScale.DefaultLevel(), c.SeverityScale.Approval("P1"))
+				}
+				if c.SeverityScale.Rank("P0") <= c.SeverityScale.Rank("P3") {
+					t.Error("P0 should rank above P3")
+				}
+				return nil
+			},
+		},
+		{
+			name:    "min_severity outside severity_scale",
+			content: "severity_scale:\n  - name: P0\n    approval: request_changes\nmin_severity: high",
+			wantErr: true,
+		},
+		{
+			name:    "severity_scale invalid approval",
+			content: "severity_scale:\n  - name: P0\n    approval: block",
+			wantErr: true,
+		},
+		{
+			name:    "severity_scale duplicate name",
+			content: "severity_scale:\n  - name: P0\n    approval: comment\n  - name: P0\n    approval: approve",
+			wantErr: true,
+		},
+		{
+			name:    "severity_scale less severe level blocks",
+			content: "severity_scale:\n  - name: P0\n    approval: comment\n  - name: P1\n    approval: request_changes",
+			wantErr: true,
+		},
+		{
+			name:    "severity_badge suffix",
+			content: "severity_badge: suffix\nseverity_emoji: true",
+			wantErr: false,
+			check: func(c *Config) error {
+				if c.SeverityBadge != SeverityBadgeSuffix || !c.SeverityEmoji {
+					t.Errorf("SeverityBadge = %v, SeverityEmoji = %v, want suffix, true", c.SeverityBadge, c.SeverityEmoji)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "invalid severity_badge",
+			content: "severity_badge: label",
+			wantErr: true,
+		},
+		{
+			name:    "min_confidence requests confidence",
+			content: "min_confidence: medium",
+			wantErr: false,
+			check: func(c *Config) error {
+				if !c.RequestsConfidence() {
+					t.Error("RequestsConfidence() = false, want true")
+				}
+				return nil
+			},
+		},
+		{
+			name:    "invalid min_confidence",
+			content: "min_confidence: certain",
+			wantErr: true,
+		},
+		{
+			name:    "invalid max_pr_age",
+			content: "max_pr_age: two years",
+			wantErr: true,
+		},
 		{
 			name:    "with instructions",
 			content: "enabled: true\ninstructions: Focus on security",
@@ -94,6 +415,45 @@ func TestParse(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:    "with system prompt prefix and suffix",
+			content: "system_prompt_prefix: You are a Go expert.\nsystem_prompt_suffix: Be terse.",
+			check: func(c *Config) error {
+				if c.SystemPromptPrefix != "You are a Go expert." || c.SystemPromptSuffix != "Be terse." {
+					t.Errorf("SystemPromptPrefix, SystemPromptSuffix = %q, %q", c.SystemPromptPrefix, c.SystemPromptSuffix)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "request_changes_at never",
+			content: "request_changes_at: never",
+			check: func(c *Config) error {
+				if c.RequestChangesAt != RequestChangesNever {
+					t.Errorf("RequestChangesAt = %q, want never", c.RequestChangesAt)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "request_changes_at on a custom scale",
+			content: "severity_scale:\n  - name: P0\n    approval: request_changes\n  - name: P1\n    approval: comment\nrequest_changes_at: P1",
+		},
+		{
+			name:    "invalid request_changes_at",
+			content: "request_changes_at: sometimes",
+			wantErr: true,
+		},
+		{
+			name:    "system_prompt_prefix too long",
+			content: "system_prompt_prefix: " + strings.Repeat("x", MaxSystemPromptAffixLength+1),
+			wantErr: true,
+		},
+		{
+			name:    "system_prompt_suffix too long",
+			content: "system_prompt_suffix: " + strings.Repeat("x", MaxSystemPromptAffixLength+1),
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -245,6 +605,76 @@ func TestShouldExcludeFile(t *testing.T) {
 	}
 }
 
+func TestShouldIncludeFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		want    bool
+	}{
+		{
+			name: "no patterns",
+			path: "src/main.go",
+			want: true,
+		},
+		{
+			name:    "exclude only",
+			exclude: []string{"vendor/**"},
+			path:    "vendor/foo.go",
+			want:    false,
+		},
+		{
+			name:    "include directory match",
+			include: []string{"src/**", "api/**"},
+			path:    "api/handlers/users.go",
+			want:    true,
+		},
+		{
+			name:    "outside include patterns",
+			include: []string{"src/**", "api/**"},
+			path:    "scripts/deploy.sh",
+			want:    false,
+		},
+		{
+			name:    "include extension match",
+			include: []string{"*.go"},
+			path:    "internal/types.go",
+			want:    true,
+		},
+		{
+			name:    "included but excluded",
+			include: []string{"src/**"},
+			exclude: []string{"*.gen.go"},
+			path:    "src/types.gen.go",
+			want:    false,
+		},
+		{
+			name:    "included and not excluded",
+			include: []string{"src/**"},
+			exclude: []string{"*.gen.go"},
+			path:    "src/types.go",
+			want:    true,
+		},
+		{
+			name:    "excluded directory inside include",
+			include: []string{"src/**"},
+			exclude: []string{"src/vendor/**"},
+			path:    "src/vendor/lib.go",
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Include: tt.include, Exclude: tt.exclude}
+			if got := cfg.ShouldIncludeFile(tt.path); got != tt.want {
+				t.Errorf("ShouldIncludeFile(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestConfigParseError(t *testing.T) {
 	t.Run("error message includes path and underlying error", func(t *testing.T) {
 		underlying := fmt.Errorf("yaml: line 1: could not find expected ':'")
@@ -272,6 +702,33 @@ func TestConfigParseError(t *testing.T) {
 	})
 }
 
+func TestAreRepliesEnabled(t *testing.T) {
+	if !(&Config{}).AreRepliesEnabled() {
+		t.Error("AreRepliesEnabled() = false by default, want true")
+	}
+	if (&Config{RepliesEnabled: boolPtr(false)}).AreRepliesEnabled() {
+		t.Error("AreRepliesEnabled() = true with replies_enabled: false, want false")
+	}
+}
+
+func TestIsWelcomeMessageEnabled(t *testing.T) {
+	if !(&Config{}).IsWelcomeMessageEnabled() {
+		t.Error("IsWelcomeMessageEnabled() = false by default, want true")
+	}
+	if (&Config{WelcomeMessage: boolPtr(false)}).IsWelcomeMessageEnabled() {
+		t.Error("IsWelcomeMessageEnabled() = true with welcome_message: false, want false")
+	}
+}
+
+func TestIsLabelContextEnabled(t *testing.T) {
+	if !(&Config{}).IsLabelContextEnabled() {
+		t.Error("IsLabelContextEnabled() = false by default, want true")
+	}
+	if (&Config{LabelContext: boolPtr(false)}).IsLabelContextEnabled() {
+		t.Error("IsLabelContextEnabled() = true with label_context: false, want false")
+	}
+}
+
 func TestIsContributorProtectionEnabled(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -304,6 +761,179 @@ func TestIsContributorProtectionEnabled(t *testing.T) {
 	}
 }
 
+func TestIsMostlyDeletions(t *testing.T) {
+	tests := []struct {
+		name      string
+		ratio     float64
+		additions int
+		deletions int
+		want      bool
+	}{
+		{"deletion only", 0.9, 0, 500, true},
+		{"deletion dominated", 0.9, 10, 490, true},
+		{"balanced", 0.9, 250, 250, false},
+		{"just under ratio", 0.9, 11, 89, false},
+		{"no changes", 0.9, 0, 0, false},
+		{"disabled", 0, 0, 500, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SkipDeletionRatio: tt.ratio}
+			if got := cfg.IsMostlyDeletions(tt.additions, tt.deletions); got != tt.want {
+				t.Errorf("IsMostlyDeletions(%d, %d) = %v, want %v", tt.additions, tt.deletions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBelowMinChangedLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		min       int
+		additions int
+		deletions int
+		want      bool
+	}{
+		{"one-line fix", 3, 1, 1, true},
+		{"at minimum", 3, 2, 1, false},
+		{"above minimum", 3, 40, 10, false},
+		{"disabled", 0, 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{MinChangedLines: tt.min}
+			if got := cfg.IsBelowMinChangedLines(tt.additions, tt.deletions); got != tt.want {
+				t.Errorf("IsBelowMinChangedLines(%d, %d) = %v, want %v", tt.additions, tt.deletions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnlyTouchesSkipFiles(t *testing.T) {
+	patterns := []string{"go.mod", "go.sum", "*.md", "docs/**"}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		paths    []string
+		want     bool
+	}{
+		{"dependency bump", patterns, []string{"go.mod", "go.sum"}, true},
+		{"docs only", patterns, []string{"README.md", "docs/setup/install.txt"}, true},
+		{"code alongside skipped files", patterns, []string{"go.mod", "main.go"}, false},
+		{"no changed files", patterns, nil, false},
+		{"no patterns", nil, []string{"README.md"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SkipIfOnlyFiles: tt.patterns}
+			if got := cfg.OnlyTouchesSkipFiles(tt.paths); got != tt.want {
+				t.Errorf("OnlyTouchesSkipFiles(%v) = %v, want %v", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsStalePR(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		maxPRAge  string
+		createdAt time.Time
+		want      bool
+	}{
+		{
+			name:      "old PR is skipped",
+			maxPRAge:  "90d",
+			createdAt: now.AddDate(-2, 0, 0),
+			want:      true,
+		},
+		{
+			name:      "fresh PR is reviewed",
+			maxPRAge:  "90d",
+			createdAt: now.Add(-48 * time.Hour),
+			want:      false,
+		},
+		{
+			name:      "go duration syntax",
+			maxPRAge:  "24h",
+			createdAt: now.Add(-25 * time.Hour),
+			want:      true,
+		},
+		{
+			name:      "no limit configured",
+			maxPRAge:  "",
+			createdAt: now.AddDate(-2, 0, 0),
+			want:      false,
+		},
+		{
+			name:      "unknown creation time",
+			maxPRAge:  "90d",
+			createdAt: time.Time{},
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{MaxPRAge: tt.maxPRAge}
+			if got := cfg.IsStalePR(tt.createdAt, now); got != tt.want {
+				t.Errorf("IsStalePR() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestIsCommandAllowed(t *testing.T) {
+	defaults := DefaultConfig()
+	openSummarize := &Config{CommandPermissions: map[string]string{"summarize": PermissionEveryone}}
+	closedExplain := &Config{CommandPermissions: map[string]string{"explain": PermissionContributors}}
+
+	tests := []struct {
+		name          string
+		cfg           *Config
+		command       string
+		isContributor bool
+		want          bool
+	}{
+		{"default review contributor", defaults, "review", true, true},
+		{"default review non-contributor", defaults, "review", false, false},
+		{"default rereview contributor", defaults, "rereview", true, true},
+		{"default rereview non-contributor", defaults, "rereview", false, false},
+		{"default explain contributor", defaults, "explain", true, true},
+		{"default explain non-contributor", defaults, "explain", false, true},
+		{"default summarize contributor", defaults, "summarize", true, true},
+		{"default summarize non-contributor", defaults, "summarize", false, false},
+		{"default resolve contributor", defaults, "resolve", true, true},
+		{"default resolve non-contributor", defaults, "resolve", false, false},
+		{"default retry contributor", defaults, "retry", true, true},
+		{"default retry non-contributor", defaults, "retry", false, false},
+		{"forced open retry stays closed", &Config{CommandPermissions: map[string]string{"retry": PermissionEveryone}}, "retry", false, false},
+		{"default ignore contributor", defaults, "ignore", true, true},
+		{"forced open ignore stays closed", &Config{CommandPermissions: map[string]string{"ignore": PermissionEveryone}}, "ignore", false, false},
+		{"forced open unignore stays closed", &Config{CommandPermissions: map[string]string{"unignore": PermissionEveryone}}, "unignore", false, false},
+		{"open summarize non-contributor", openSummarize, "summarize", false, true},
+		{"open summarize keeps explain open", openSummarize, "explain", false, true},
+		{"open summarize keeps review closed", openSummarize, "review", false, false},
+		{"closed explain non-contributor", closedExplain, "explain", false, false},
+		{"closed explain contributor", closedExplain, "explain", true, true},
+		{"forced open review stays closed", &Config{CommandPermissions: map[string]string{"review": PermissionEveryone}}, "review", false, false},
+		{"unknown command non-contributor", defaults, "deploy", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.IsCommandAllowed(tt.command, tt.isContributor); got != tt.want {
+				t.Errorf("IsCommandAllowed(%q, %v) = %v, want %v", tt.command, tt.isContributor, got, tt.want)
+			}
+		})
+	}
+}
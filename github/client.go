@@ -5,10 +5,13 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +20,14 @@ import (
 
 const (
 	baseURL = "https://api.github.com"
+
+	// fileFetchRetryDelay is the pause before retrying a file fetch that failed transiently.
+	fileFetchRetryDelay = 250 * time.Millisecond
+
+	// pullRequestFilesPerPage is the page size used when listing a PR's files.
+	pullRequestFilesPerPage = 100
+	// maxPullRequestFilePages covers the 3000 files GitHub lists at most.
+	maxPullRequestFilePages = 30
 )
 
 // Client provides methods to interact with the GitHub API.
@@ -24,6 +35,8 @@ type Client struct {
 	httpClient *http.Client
 	appID      int64
 	privateKey []byte
+	transport  http.RoundTripper // if set, used instead of GitHub App auth
+	breaker    *rateLimitBreaker
 }
 
 // NewClient creates a new GitHub API client.
@@ -33,16 +46,35 @@ func NewClient(appID int64, privateKey []byte) *Client {
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 		appID:      appID,
 		privateKey: privateKey,
+		breaker:    newRateLimitBreaker(),
+	}
+}
+
+// NewClientWithTransport creates a client that sends every request through the given
+// transport without GitHub App authentication. Useful for tests and authenticating proxies.
+func NewClientWithTransport(transport http.RoundTripper) *Client {
+	return &Client{
+		httpClient: &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		transport:  transport,
+		breaker:    newRateLimitBreaker(),
 	}
 }
 
 // getInstallationClient returns an HTTP client authenticated for the given installation.
+// Its requests pause while the installation is rate limited (see rateLimitBreaker).
 func (c *Client) getInstallationClient(installationID int64) (*http.Client, error) {
-	transport, err := ghinstallation.New(http.DefaultTransport, c.appID, installationID, c.privateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create installation transport: %w", err)
-	}
-	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+	transport := c.transport
+	if transport == nil {
+		t, err := ghinstallation.New(http.DefaultTransport, c.appID, installationID, c.privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create installation transport: %w", err)
+		}
+		transport = t
+	}
+	return &http.Client{
+		Transport: &breakerTransport{base: transport, breaker: c.breaker, installationID: installationID},
+		Timeout:   30 * time.Second,
+	}, nil
 }
 
 // FetchDiff fetches the diff for a pull request.
@@ -66,8 +98,7 @@ func (c *Client) FetchDiff(ctx context.Context, installationID int64, owner, rep
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to fetch diff: status %d, body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to fetch diff: %w", statusError(resp))
 	}
 
 	diff, err := io.ReadAll(resp.Body)
@@ -78,34 +109,77 @@ func (c *Client) FetchDiff(ctx context.Context, installationID int64, owner, rep
 	return string(diff), nil
 }
 
-// FetchPullRequestFiles fetches the list of files changed in a pull request.
-func (c *Client) FetchPullRequestFiles(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]PullRequestFile, error) {
+// CompareDiff fetches the diff between two commits (base...head).
+func (c *Client) CompareDiff(ctx context.Context, installationID int64, owner, repo, base, head string) (string, error) {
 	client, err := c.getInstallationClient(installationID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files", baseURL, owner, repo, prNumber)
+	url := fmt.Sprintf("%s/repos/%s/%s/compare/%s...%s", baseURL, owner, repo, base, head)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Accept", "application/vnd.github.diff")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch files: %w", err)
+		return "", fmt.Errorf("failed to fetch compare diff: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch files: status %d, body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to fetch compare diff: %w", statusError(resp))
+	}
+
+	diff, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	return string(diff), nil
+}
+
+// FetchPullRequestFiles fetches the list of files changed in a pull request,
+// following pagination up to GitHub's limit of 3000 files.
+func (c *Client) FetchPullRequestFiles(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]PullRequestFile, error) {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return nil, err
 	}
 
 	var files []PullRequestFile
-	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
-		return nil, fmt.Errorf("failed to decode files: %w", err)
+	for page := 1; page <= maxPullRequestFilePages; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=%d&page=%d", baseURL, owner, repo, prNumber, pullRequestFilesPerPage, page)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch files: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err := statusError(resp)
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to fetch files: %w", err)
+		}
+
+		var batch []PullRequestFile
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode files: %w", err)
+		}
+
+		files = append(files, batch...)
+		if len(batch) < pullRequestFilesPerPage {
+			break
+		}
 	}
 
 	return files, nil
@@ -136,8 +210,7 @@ func (c *Client) FetchFileContent(ctx context.Context, installationID int64, own
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to fetch file: status %d, body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to fetch file: %w", statusError(resp))
 	}
 
 	var content FileContent
@@ -157,6 +230,49 @@ func (c *Client) FetchFileContent(ctx context.Context, installationID int64, own
 	return string(decoded), nil
 }
 
+// ListDirectory returns the paths of the files directly in a repository
+// directory at ref; subdirectories are left out. Returns nil with no error if the
+// directory doesn't exist.
+func (c *Client) ListDirectory(ctx context.Context, installationID int64, owner, repo, dir, ref string) ([]string, error) {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", baseURL, owner, repo, dir, ref)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list directory: %w", statusError(resp))
+	}
+
+	var entries []FileContent
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode directory listing: %w", err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.Type == "file" {
+			files = append(files, e.Path)
+		}
+	}
+	return files, nil
+}
+
 // CreateReview posts a review on a pull request.
 func (c *Client) CreateReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, review *ReviewRequest) (*Review, error) {
 	client, err := c.getInstallationClient(installationID)
@@ -185,8 +301,7 @@ func (c *Client) CreateReview(ctx context.Context, installationID int64, owner,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to create review: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("failed to create review: %w", statusError(resp))
 	}
 
 	var createdReview Review
@@ -218,8 +333,7 @@ func (c *Client) GetPullRequest(ctx context.Context, installationID int64, owner
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch pull request: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to fetch pull request: %w", statusError(resp))
 	}
 
 	var pr PullRequest
@@ -230,6 +344,21 @@ func (c *Client) GetPullRequest(ctx context.Context, installationID int64, owner
 	return &pr, nil
 }
 
+// GetMergeCommit returns the pull request's test-merge commit, or nil if there is
+// none (merge conflicts, or GitHub hasn't finished computing mergeability).
+func (c *Client) GetMergeCommit(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*MergeCommit, error) {
+	pr, err := c.GetPullRequest(ctx, installationID, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr.Mergeable == nil || !*pr.Mergeable || pr.MergeCommitSHA == "" || pr.Base == nil {
+		return nil, nil
+	}
+
+	return &MergeCommit{SHA: pr.MergeCommitSHA, BaseSHA: pr.Base.SHA}, nil
+}
+
 // CreateReplyComment posts a reply to a review comment.
 func (c *Client) CreateReplyComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64, body string) (*PullRequestComment, error) {
 	client, err := c.getInstallationClient(installationID)
@@ -258,8 +387,7 @@ func (c *Client) CreateReplyComment(ctx context.Context, installationID int64, o
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to create reply: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("failed to create reply: %w", statusError(resp))
 	}
 
 	var comment PullRequestComment
@@ -291,8 +419,7 @@ func (c *Client) GetReviewComments(ctx context.Context, installationID int64, ow
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch comments: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to fetch comments: %w", statusError(resp))
 	}
 
 	var comments []PullRequestComment
@@ -336,8 +463,7 @@ func (c *Client) FetchFileCommits(ctx context.Context, installationID int64, own
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch commits: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to fetch commits: %w", statusError(resp))
 	}
 
 	var commits []Commit
@@ -382,8 +508,7 @@ func (c *Client) GetUserPermission(ctx context.Context, installationID int64, ow
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to get user permission: status %d, body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to get user permission: %w", statusError(resp))
 	}
 
 	var perm UserPermission
@@ -446,8 +571,7 @@ func (c *Client) CreateIssueComment(ctx context.Context, installationID int64, o
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to create comment: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("failed to create comment: %w", statusError(resp))
 	}
 
 	var comment IssueCommentResponse
@@ -458,6 +582,155 @@ func (c *Client) CreateIssueComment(ctx context.Context, installationID int64, o
 	return &comment, nil
 }
 
+// GetIssueComment fetches a PR comment (via the issues API). prNumber is unused
+// here, since GitHub comment IDs are unique within a repository.
+func (c *Client) GetIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64) (*IssueCommentResponse, error) {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", baseURL, owner, repo, commentID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get comment: %w", statusError(resp))
+	}
+
+	var comment IssueCommentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&comment); err != nil {
+		return nil, fmt.Errorf("failed to decode comment response: %w", err)
+	}
+
+	return &comment, nil
+}
+
+// UpdateIssueComment replaces the body of an existing PR comment (via the issues API).
+// prNumber is unused here, as in GetIssueComment.
+func (c *Client) UpdateIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64, body string) error {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", baseURL, owner, repo, commentID)
+
+	reqBody, err := json.Marshal(IssueCommentRequest{Body: body})
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update comment: %w", statusError(resp))
+	}
+
+	return nil
+}
+
+// Reaction contents used to show review progress on a PR.
+const (
+	ReactionEyes   = "eyes"
+	ReactionRocket = "rocket"
+)
+
+// Reaction is a reaction on an issue or PR.
+type Reaction struct {
+	ID      int64  `json:"id"`
+	Content string `json:"content"`
+}
+
+// CreateReaction adds a reaction (e.g. ReactionEyes) to a PR via the issues API.
+// If the app already reacted with the same content, that reaction is returned.
+func (c *Client) CreateReaction(ctx context.Context, installationID int64, owner, repo string, prNumber int, content string) (*Reaction, error) {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/reactions", baseURL, owner, repo, prNumber)
+
+	reqBody, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reaction: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reaction: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to create reaction: %w", statusError(resp))
+	}
+
+	var reaction Reaction
+	if err := json.NewDecoder(resp.Body).Decode(&reaction); err != nil {
+		return nil, fmt.Errorf("failed to decode reaction response: %w", err)
+	}
+
+	return &reaction, nil
+}
+
+// DeleteReaction removes a reaction from a PR via the issues API.
+func (c *Client) DeleteReaction(ctx context.Context, installationID int64, owner, repo string, prNumber int, reactionID int64) error {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/reactions/%d", baseURL, owner, repo, prNumber, reactionID)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete reaction: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete reaction: %w", statusError(resp))
+	}
+
+	return nil
+}
+
 // ListPRReviews fetches all reviews for a pull request.
 func (c *Client) ListPRReviews(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]Review, error) {
 	client, err := c.getInstallationClient(installationID)
@@ -479,8 +752,7 @@ func (c *Client) ListPRReviews(ctx context.Context, installationID int64, owner,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch reviews: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to fetch reviews: %w", statusError(resp))
 	}
 
 	var reviews []Review
@@ -520,13 +792,93 @@ func (c *Client) UpdateReviewBody(ctx context.Context, installationID int64, own
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update review: status %d, body: %s", resp.StatusCode, string(respBody))
+		return fmt.Errorf("failed to update review: %w", statusError(resp))
 	}
 
 	return nil
 }
 
+// CreateCheckRun creates a check run on a commit.
+// The Checks API accepts at most 50 annotations per request; use UpdateCheckRun to add more.
+func (c *Client) CreateCheckRun(ctx context.Context, installationID int64, owner, repo string, checkRun *CheckRunRequest) (*CheckRun, error) {
+	return c.sendCheckRun(ctx, installationID, "POST", fmt.Sprintf("%s/repos/%s/%s/check-runs", baseURL, owner, repo), checkRun, http.StatusCreated)
+}
+
+// UpdateCheckRun updates an existing check run. Annotations in the output are
+// appended to those already on the check run.
+func (c *Client) UpdateCheckRun(ctx context.Context, installationID int64, owner, repo string, checkRunID int64, checkRun *CheckRunRequest) (*CheckRun, error) {
+	return c.sendCheckRun(ctx, installationID, "PATCH", fmt.Sprintf("%s/repos/%s/%s/check-runs/%d", baseURL, owner, repo, checkRunID), checkRun, http.StatusOK)
+}
+
+// GetCheckRuns lists the check runs on a commit (up to 100, the API's page size).
+func (c *Client) GetCheckRuns(ctx context.Context, installationID int64, owner, repo, ref string) ([]CheckRun, error) {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s/check-runs?per_page=100", baseURL, owner, repo, ref)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch check runs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch check runs: %w", statusError(resp))
+	}
+
+	var list CheckRunList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode check runs: %w", err)
+	}
+
+	return list.CheckRuns, nil
+}
+
+// sendCheckRun sends a check run create or update request.
+func (c *Client) sendCheckRun(ctx context.Context, installationID int64, method, url string, checkRun *CheckRunRequest, wantStatus int) (*CheckRun, error) {
+	client, err := c.getInstallationClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(checkRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal check run: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send check run: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		return nil, fmt.Errorf("failed to send check run: %w", statusError(resp))
+	}
+
+	var result CheckRun
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode check run: %w", err)
+	}
+
+	return &result, nil
+}
+
 // FetchMultipleFiles fetches multiple files in parallel.
 // Returns a map of path -> content. Missing files are not included in the map.
 func (c *Client) FetchMultipleFiles(ctx context.Context, installationID int64, owner, repo string, paths []string, ref string) (map[string]string, error) {
@@ -544,7 +896,9 @@ func (c *Client) FetchMultipleFiles(ctx context.Context, installationID int64, o
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			content, err := c.FetchFileContent(ctx, installationID, owner, repo, p, ref)
+			content, err := fetchFileWithRetry(ctx, func() (string, error) {
+				return c.FetchFileContent(ctx, installationID, owner, repo, p, ref)
+			})
 			if err != nil {
 				// Log but don't fail - missing files are expected
 				return
@@ -560,3 +914,130 @@ func (c *Client) FetchMultipleFiles(ctx context.Context, installationID int64, o
 	wg.Wait()
 	return result, nil
 }
+
+// APIError is returned (wrapped) by client methods when GitHub responds with an
+// unexpected HTTP status, so callers can branch on the status code.
+type APIError struct {
+	StatusCode int
+	Body       string
+	Endpoint   string // Method and path, e.g. "GET /repos/o/r/pulls/1"
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status %d, body: %s", e.StatusCode, e.Body)
+}
+
+// newAPIError builds an APIError from an unexpected response, consuming its body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	endpoint := ""
+	if resp.Request != nil {
+		endpoint = resp.Request.Method + " " + resp.Request.URL.Path
+	}
+	return &APIError{StatusCode: resp.StatusCode, Body: string(body), Endpoint: endpoint}
+}
+
+// RateLimitError is returned (wrapped) when GitHub rejects a request with a 403 or
+// 429 carrying rate-limit headers, as it does for secondary rate limits.
+// RetryAfter is how long GitHub asked callers to wait.
+type RateLimitError struct {
+	APIError
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited (retry after %s): %s", e.RetryAfter, e.APIError.Error())
+}
+
+// Unwrap exposes the underlying APIError so status helpers like IsNotFound work.
+func (e *RateLimitError) Unwrap() error {
+	return &e.APIError
+}
+
+// statusError builds the error for an unexpected response: a RateLimitError when
+// GitHub says when to retry, otherwise an APIError. It consumes the response body.
+func statusError(resp *http.Response) error {
+	apiErr := newAPIError(resp)
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return apiErr
+	}
+	if retryAfter, ok := rateLimitDelay(resp.Header, time.Now()); ok {
+		return &RateLimitError{APIError: *apiErr, RetryAfter: retryAfter}
+	}
+	return apiErr
+}
+
+// rateLimitDelay reads how long to wait from GitHub's rate-limit headers: Retry-After
+// (seconds or an HTTP date), or x-ratelimit-reset when no requests remain.
+func rateLimitDelay(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// IsNotFound reports whether err is a GitHub 404, e.g. a deleted PR or lost access.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsDiffTooLarge reports whether err is GitHub refusing to render a pull request
+// diff because it's too large (over 3000 files or 20000 lines).
+func IsDiffTooLarge(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotAcceptable ||
+		(apiErr.StatusCode == http.StatusUnprocessableEntity && strings.Contains(apiErr.Body, "too_large"))
+}
+
+// IsUnprocessable reports whether err is a GitHub 422, e.g. a review comment on a
+// line that isn't part of the diff.
+func IsUnprocessable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity
+}
+
+// isTransientFetchError reports whether a file fetch failure is worth retrying:
+// server errors, rate limiting, or a network-level failure.
+func isTransientFetchError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// fetchFileWithRetry runs fetch, retrying once after a short delay on transient errors
+// so a single blip doesn't silently drop context.
+func fetchFileWithRetry(ctx context.Context, fetch func() (string, error)) (string, error) {
+	content, err := fetch()
+	if err == nil || !isTransientFetchError(ctx, err) {
+		return content, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return "", err
+	case <-time.After(fileFetchRetryDelay):
+	}
+	return fetch()
+}
@@ -0,0 +1,319 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchFileWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error // error returned by each successive call; nil means success
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "success first try",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "transient 502 then success",
+			errs:      []error{&APIError{StatusCode: http.StatusBadGateway}, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "rate limited twice",
+			errs:      []error{&APIError{StatusCode: http.StatusTooManyRequests}, &APIError{StatusCode: http.StatusTooManyRequests}},
+			wantCalls: 2,
+			wantErr:   true,
+		},
+		{
+			name:      "permission error not retried",
+			errs:      []error{&APIError{StatusCode: http.StatusForbidden}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "decode error not retried",
+			errs:      []error{errors.New("failed to decode file content")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			content, err := fetchFileWithRetry(context.Background(), func() (string, error) {
+				err := tt.errs[calls]
+				calls++
+				if err != nil {
+					return "", err
+				}
+				return "content", nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && content != "content" {
+				t.Errorf("content = %q, want %q", content, "content")
+			}
+		})
+	}
+}
+
+func TestStatusErrorRateLimit(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		status        int
+		header        http.Header
+		wantRateLimit bool
+		wantAfter     time.Duration
+	}{
+		{
+			name:          "secondary limit with Retry-After seconds",
+			status:        http.StatusForbidden,
+			header:        http.Header{"Retry-After": {"30"}},
+			wantRateLimit: true,
+			wantAfter:     30 * time.Second,
+		},
+		{
+			name:          "429 with Retry-After",
+			status:        http.StatusTooManyRequests,
+			header:        http.Header{"Retry-After": {"5"}},
+			wantRateLimit: true,
+			wantAfter:     5 * time.Second,
+		},
+		{
+			name:   "primary limit exhausted with reset",
+			status: http.StatusForbidden,
+			header: http.Header{
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(now.Add(time.Minute).Unix(), 10)},
+			},
+			wantRateLimit: true,
+			wantAfter:     time.Minute,
+		},
+		{
+			name:   "plain permission error",
+			status: http.StatusForbidden,
+			header: http.Header{},
+		},
+		{
+			name:   "server error with Retry-After",
+			status: http.StatusServiceUnavailable,
+			header: http.Header{"Retry-After": {"30"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     tt.header,
+				Body:       io.NopCloser(strings.NewReader("limited")),
+			}
+			err := statusError(resp)
+
+			var rl *RateLimitError
+			if errors.As(err, &rl) != tt.wantRateLimit {
+				t.Fatalf("statusError() = %v, want rate limit %v", err, tt.wantRateLimit)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("statusError() should unwrap to an APIError with status %d", tt.status)
+			}
+			if !tt.wantRateLimit {
+				return
+			}
+			// Allow for the clock moving between building the header and parsing it
+			if diff := tt.wantAfter - rl.RetryAfter; diff < 0 || diff > 2*time.Second {
+				t.Errorf("RetryAfter = %v, want about %v", rl.RetryAfter, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestCreateReviewUnprocessable(t *testing.T) {
+	client := NewClientWithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       io.NopCloser(strings.NewReader(`{"message":"Unprocessable Entity","errors":["Line could not be resolved"]}`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	}))
+
+	_, err := client.CreateReview(context.Background(), 1, "owner", "repo", 7, &ReviewRequest{Body: "x"})
+	if !IsUnprocessable(err) {
+		t.Fatalf("IsUnprocessable(%v) = false, want true", err)
+	}
+	if IsNotFound(err) {
+		t.Errorf("IsNotFound(%v) = true, want false", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Endpoint != "POST /repos/owner/repo/pulls/7/reviews" {
+		t.Errorf("error = %v, want an APIError for the reviews endpoint", err)
+	}
+}
+
+func TestFetchDiffTooLarge(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"too many files", http.StatusNotAcceptable, `{"message":"Sorry, the diff exceeded the maximum number of files (3000)."}`, true},
+		{"too many lines", http.StatusUnprocessableEntity, `{"message":"Server Error: Sorry, this diff is taking too long to generate.","errors":[{"resource":"PullRequest","field":"diff","code":"too_large"}]}`, true},
+		{"other unprocessable", http.StatusUnprocessableEntity, `{"message":"Validation Failed"}`, false},
+		{"server error", http.StatusBadGateway, "bad gateway", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: tt.status,
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+					Header:     make(http.Header),
+					Request:    req,
+				}, nil
+			}))
+
+			_, err := client.FetchDiff(context.Background(), 1, "owner", "repo", 7)
+			if err == nil {
+				t.Fatal("FetchDiff() error = nil, want an error")
+			}
+			if got := IsDiffTooLarge(err); got != tt.want {
+				t.Errorf("IsDiffTooLarge(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+
+	if IsDiffTooLarge(errors.New("connection reset")) {
+		t.Error("IsDiffTooLarge() = true for a non-API error, want false")
+	}
+}
+
+func TestFindThreadForComment(t *testing.T) {
+	threads := []ReviewThread{
+		{ID: "T1", Comments: []ThreadComment{{DatabaseID: 10}, {DatabaseID: 11}}},
+		{ID: "T2", Comments: []ThreadComment{{DatabaseID: 20}}},
+	}
+
+	if got := FindThreadForComment(threads, 11); got == nil || got.ID != "T1" {
+		t.Errorf("FindThreadForComment(11) = %+v, want T1", got)
+	}
+	if got := FindThreadForComment(threads, 20); got == nil || got.ID != "T2" {
+		t.Errorf("FindThreadForComment(20) = %+v, want T2", got)
+	}
+	if got := FindThreadForComment(threads, 99); got != nil {
+		t.Errorf("FindThreadForComment(99) = %+v, want nil", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRateLimitBreaker(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var calls int
+	limited := true
+	client := NewClientWithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if limited {
+			return &http.Response{
+				StatusCode: http.StatusForbidden,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(strings.NewReader(`{"message":"You have exceeded a secondary rate limit."}`)),
+				Request:    req,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(`{"number":1}`)),
+			Request:    req,
+		}, nil
+	}))
+	client.breaker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	// A 403 secondary rate limit opens the breaker for the installation
+	_, err := client.GetPullRequest(ctx, 1, "owner", "repo", 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || !strings.Contains(apiErr.Body, "secondary rate limit") {
+		t.Fatalf("GetPullRequest() error = %v, want the 403 with its body intact", err)
+	}
+	if got := client.breaker.remaining(1); got != secondaryRateLimitPause {
+		t.Fatalf("breaker pause = %s, want %s", got, secondaryRateLimitPause)
+	}
+
+	// Requests that can't wait out the pause fail without reaching GitHub
+	limited = false
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetPullRequest(shortCtx, 1, "owner", "repo", 1)
+	var rl *RateLimitError
+	if !errors.As(err, &rl) || calls != 1 {
+		t.Fatalf("GetPullRequest() while paused = %v after %d calls, want a rate limit error and no request", err, calls)
+	}
+
+	// Other installations are unaffected
+	if _, err := client.GetPullRequest(ctx, 2, "owner", "repo", 1); err != nil {
+		t.Errorf("GetPullRequest() for another installation error = %v", err)
+	}
+
+	// The breaker closes once the window has passed
+	clock = clock.Add(secondaryRateLimitPause)
+	if _, err := client.GetPullRequest(shortCtx, 1, "owner", "repo", 1); err != nil {
+		t.Errorf("GetPullRequest() after the window error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("requests sent = %d, want 3", calls)
+	}
+}
+
+func TestSecondaryRateLimitDelay(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"Retry-After header", http.StatusForbidden, http.Header{"Retry-After": {"30"}}, "", 30 * time.Second, true},
+		{"429 with Retry-After", http.StatusTooManyRequests, http.Header{"Retry-After": {"5"}}, "", 5 * time.Second, true},
+		{"abuse message without headers", http.StatusForbidden, http.Header{}, "You have triggered an abuse detection mechanism", secondaryRateLimitPause, true},
+		{"plain permission error", http.StatusForbidden, http.Header{}, "Resource not accessible by integration", 0, false},
+		{"server error", http.StatusBadGateway, http.Header{"Retry-After": {"30"}}, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: tt.header, Body: io.NopCloser(strings.NewReader(tt.body))}
+			got, ok := secondaryRateLimitDelay(resp, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("secondaryRateLimitDelay() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tt.body {
+				t.Errorf("body after check = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
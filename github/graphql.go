@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 )
 
@@ -22,10 +21,11 @@ type ReviewThread struct {
 
 // ThreadComment represents a comment within a review thread.
 type ThreadComment struct {
-	ID        string `json:"id"`
-	Body      string `json:"body"`
-	Author    string `json:"author"`
-	CreatedAt string `json:"createdAt"`
+	ID         string `json:"id"`
+	DatabaseID int64  `json:"databaseId"` // REST API comment ID
+	Body       string `json:"body"`
+	Author     string `json:"author"`
+	CreatedAt  string `json:"createdAt"`
 }
 
 // graphQLRequest represents a GraphQL query request.
@@ -79,10 +79,11 @@ type graphQLComments struct {
 }
 
 type graphQLComment struct {
-	ID        string         `json:"id"`
-	Body      string         `json:"body"`
-	Author    *graphQLAuthor `json:"author"`
-	CreatedAt string         `json:"createdAt"`
+	ID         string         `json:"id"`
+	DatabaseID int64          `json:"databaseId"`
+	Body       string         `json:"body"`
+	Author     *graphQLAuthor `json:"author"`
+	CreatedAt  string         `json:"createdAt"`
 }
 
 type graphQLAuthor struct {
@@ -106,6 +107,7 @@ query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
           comments(first: 20) {
             nodes {
               id
+              databaseId
               body
               author {
                 login
@@ -222,8 +224,7 @@ func (c *Client) fetchReviewThreadsPage(ctx context.Context, client *http.Client
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, nil, fmt.Errorf("GraphQL query failed: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("GraphQL query failed: %w", statusError(resp))
 	}
 
 	var result graphQLResponse
@@ -261,10 +262,11 @@ func (c *Client) fetchReviewThreadsPage(ctx context.Context, client *http.Client
 					author = comment.Author.Login
 				}
 				thread.Comments = append(thread.Comments, ThreadComment{
-					ID:        comment.ID,
-					Body:      comment.Body,
-					Author:    author,
-					CreatedAt: comment.CreatedAt,
+					ID:         comment.ID,
+					DatabaseID: comment.DatabaseID,
+					Body:       comment.Body,
+					Author:     author,
+					CreatedAt:  comment.CreatedAt,
 				})
 			}
 		}
@@ -308,8 +310,7 @@ func (c *Client) ResolveReviewThread(ctx context.Context, installationID int64,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("GraphQL mutation failed: status %d, body: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("GraphQL mutation failed: %w", statusError(resp))
 	}
 
 	var result resolveThreadResponse
@@ -323,3 +324,16 @@ func (c *Client) ResolveReviewThread(ctx context.Context, installationID int64,
 
 	return nil
 }
+
+// FindThreadForComment returns the thread containing the review comment with the
+// given REST API ID, or nil if none does.
+func FindThreadForComment(threads []ReviewThread, commentID int64) *ReviewThread {
+	for i := range threads {
+		for _, c := range threads[i].Comments {
+			if c.DatabaseID == commentID {
+				return &threads[i]
+			}
+		}
+	}
+	return nil
+}
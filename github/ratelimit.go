@@ -0,0 +1,125 @@
+package github
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secondaryRateLimitPause is how long to hold off when GitHub reports a secondary
+// rate limit without saying when to retry; GitHub asks for at least a minute.
+const secondaryRateLimitPause = time.Minute
+
+// rateLimitBreaker pauses all requests for an installation once GitHub rate limits
+// it, so concurrent callers wait out the window together instead of each retrying
+// and extending the limit.
+type rateLimitBreaker struct {
+	mu          sync.Mutex
+	pausedUntil map[int64]time.Time
+	now         func() time.Time
+}
+
+func newRateLimitBreaker() *rateLimitBreaker {
+	return &rateLimitBreaker{
+		pausedUntil: make(map[int64]time.Time),
+		now:         time.Now,
+	}
+}
+
+// remaining returns how long requests for the installation must still wait.
+func (b *rateLimitBreaker) remaining(installationID int64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.pausedUntil[installationID]
+	if !ok {
+		return 0
+	}
+	if wait := until.Sub(b.now()); wait > 0 {
+		return wait
+	}
+	delete(b.pausedUntil, installationID)
+	return 0
+}
+
+// open pauses the installation for d, extending any pause already in place.
+func (b *rateLimitBreaker) open(installationID int64, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until := b.now().Add(d)
+	if until.After(b.pausedUntil[installationID]) {
+		b.pausedUntil[installationID] = until
+	}
+}
+
+// breakerTransport sends an installation's requests through its rate-limit breaker.
+type breakerTransport struct {
+	base           http.RoundTripper
+	breaker        *rateLimitBreaker
+	installationID int64
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := t.breaker.remaining(t.installationID); wait > 0 {
+		// Fail fast when the caller can't wait out the pause
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait {
+			return nil, pausedError(req, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := secondaryRateLimitDelay(resp, t.breaker.now()); ok {
+		t.breaker.open(t.installationID, d)
+	}
+	return resp, nil
+}
+
+// pausedError is returned for requests refused while the installation is paused.
+func pausedError(req *http.Request, wait time.Duration) error {
+	return &RateLimitError{
+		APIError: APIError{
+			StatusCode: http.StatusTooManyRequests,
+			Body:       "requests paused after a GitHub rate limit",
+			Endpoint:   req.Method + " " + req.URL.Path,
+		},
+		RetryAfter: wait,
+	}
+}
+
+// secondaryRateLimitDelay reports how long to pause after a rate-limited response:
+// the delay from its headers, or secondaryRateLimitPause when only the body says
+// a secondary limit was hit. The response body is left readable.
+func secondaryRateLimitDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if d, ok := rateLimitDelay(resp.Header, now); ok {
+		return d, d > 0
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0, false
+	}
+	msg := strings.ToLower(string(body))
+	if strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse detection") {
+		return secondaryRateLimitPause, true
+	}
+	return 0, false
+}
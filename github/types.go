@@ -15,18 +15,46 @@ type WebhookEvent struct {
 
 // PullRequest represents a GitHub pull request.
 type PullRequest struct {
-	ID        int64  `json:"id"`
-	Number    int    `json:"number"`
-	State     string `json:"state"`
-	Title     string `json:"title"`
-	Body      string `json:"body"`
-	Head      *Ref   `json:"head"`
-	Base      *Ref   `json:"base"`
-	User      *User  `json:"user"`
-	HTMLURL   string `json:"html_url"`
-	DiffURL   string `json:"diff_url"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID        int64     `json:"id"`
+	Number    int       `json:"number"`
+	State     string    `json:"state"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Head      *Ref      `json:"head"`
+	Base      *Ref      `json:"base"`
+	User      *User     `json:"user"`
+	HTMLURL   string    `json:"html_url"`
+	DiffURL   string    `json:"diff_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Labels    []Label   `json:"labels"`
+	Draft     bool      `json:"draft"`
+	Merged    bool      `json:"merged"`
+
+	// MergeCommitSHA is GitHub's test-merge commit of head into base. Only
+	// meaningful when Mergeable is true; Mergeable is nil while GitHub computes it.
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	Mergeable      *bool  `json:"mergeable"`
+}
+
+// LabelNames returns the names of the pull request's labels.
+func (pr *PullRequest) LabelNames() []string {
+	names := make([]string, 0, len(pr.Labels))
+	for _, l := range pr.Labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// Label represents a GitHub issue or pull request label.
+type Label struct {
+	Name string `json:"name"`
+}
+
+// MergeCommit identifies a pull request's test-merge commit and the base commit it merges onto.
+type MergeCommit struct {
+	SHA     string
+	BaseSHA string
 }
 
 // Ref represents a git reference (branch/commit).
@@ -229,3 +257,44 @@ type IssueComment struct {
 	UpdatedAt string `json:"updated_at"`
 	HTMLURL   string `json:"html_url"`
 }
+
+// CheckRunRequest represents a request to create or update a check run.
+type CheckRunRequest struct {
+	Name       string          `json:"name,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"`
+	Status     string          `json:"status,omitempty"`     // queued, in_progress, completed
+	Conclusion string          `json:"conclusion,omitempty"` // success, failure, neutral, ...
+	Output     *CheckRunOutput `json:"output,omitempty"`
+}
+
+// CheckRunOutput is the output section of a check run.
+type CheckRunOutput struct {
+	Title       string               `json:"title"`
+	Summary     string               `json:"summary"`
+	Annotations []CheckRunAnnotation `json:"annotations,omitempty"`
+}
+
+// CheckRunAnnotation attaches a finding to a line range of a file.
+type CheckRunAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"` // notice, warning, failure
+	Message         string `json:"message"`
+	Title           string `json:"title,omitempty"`
+}
+
+// CheckRun represents a check run response.
+type CheckRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`     // queued, in_progress, completed
+	Conclusion string `json:"conclusion"` // set once completed
+	HTMLURL    string `json:"html_url"`
+}
+
+// CheckRunList is the response from listing a commit's check runs.
+type CheckRunList struct {
+	TotalCount int        `json:"total_count"`
+	CheckRuns  []CheckRun `json:"check_runs"`
+}
@@ -7,9 +7,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 )
 
+// DefaultMaxPayloadSize is the default cap on webhook payload size (5MB).
+// Typical pull request deliveries are well under 1MB.
+const DefaultMaxPayloadSize = 5 << 20
+
 var (
 	// ErrInvalidSignature indicates the webhook signature verification failed.
 	ErrInvalidSignature = errors.New("invalid webhook signature")
@@ -17,23 +23,66 @@ var (
 	ErrMissingSignature = errors.New("missing webhook signature")
 	// ErrUnsupportedEvent indicates the webhook event type is not handled.
 	ErrUnsupportedEvent = errors.New("unsupported event type")
+	// ErrPayloadTooLarge indicates the webhook payload exceeded the configured size limit.
+	ErrPayloadTooLarge = errors.New("webhook payload too large")
 )
 
-// WebhookHandler handles GitHub webhook events.
-type WebhookHandler struct {
+// Signature headers for the supported signature schemes.
+const (
+	// GitHubSignatureHeader carries GitHub's HMAC-SHA256 webhook signature.
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	// BitbucketSignatureHeader carries Bitbucket's HMAC-SHA256 webhook signature.
+	BitbucketSignatureHeader = "X-Hub-Signature"
+)
+
+// SignatureVerifier checks that a webhook payload was signed by a trusted sender.
+type SignatureVerifier interface {
+	// Header returns the name of the request header carrying the signature.
+	Header() string
+	// Verify checks the signature header value against the payload. It returns
+	// ErrMissingSignature for an empty value and ErrInvalidSignature on a mismatch.
+	Verify(payload []byte, signatureHeader string) error
+}
+
+// HMACVerifier verifies "sha256=<hex>" HMAC-SHA256 signatures of the payload,
+// the scheme used by GitHub and Bitbucket, read from a configurable header.
+type HMACVerifier struct {
 	secret []byte
+	header string
 }
 
-// NewWebhookHandler creates a new webhook handler with the given secret.
-func NewWebhookHandler(secret string) *WebhookHandler {
-	return &WebhookHandler{
-		secret: []byte(secret),
+// NewGitHubVerifier returns a verifier for GitHub's X-Hub-Signature-256 header.
+func NewGitHubVerifier(secret string) *HMACVerifier {
+	return &HMACVerifier{secret: []byte(secret), header: GitHubSignatureHeader}
+}
+
+// NewBitbucketVerifier returns a verifier for Bitbucket's X-Hub-Signature header,
+// for deliveries from Bitbucket or from a proxy that re-signs them that way.
+func NewBitbucketVerifier(secret string) *HMACVerifier {
+	return &HMACVerifier{secret: []byte(secret), header: BitbucketSignatureHeader}
+}
+
+// NewSignatureVerifier returns the verifier for a signature scheme: "github"
+// (or "") or "bitbucket".
+func NewSignatureVerifier(scheme, secret string) (SignatureVerifier, error) {
+	switch scheme {
+	case "", "github":
+		return NewGitHubVerifier(secret), nil
+	case "bitbucket":
+		return NewBitbucketVerifier(secret), nil
+	default:
+		return nil, fmt.Errorf("unknown signature scheme %q", scheme)
 	}
 }
 
-// VerifySignature verifies the webhook payload signature.
+// Header returns the name of the header the verifier reads.
+func (v *HMACVerifier) Header() string {
+	return v.header
+}
+
+// Verify verifies the payload signature.
 // The signature header should be in the format "sha256=<hex-encoded-signature>".
-func (h *WebhookHandler) VerifySignature(payload []byte, signatureHeader string) error {
+func (v *HMACVerifier) Verify(payload []byte, signatureHeader string) error {
 	if signatureHeader == "" {
 		return ErrMissingSignature
 	}
@@ -50,7 +99,7 @@ func (h *WebhookHandler) VerifySignature(payload []byte, signatureHeader string)
 	}
 
 	// Compute expected signature
-	mac := hmac.New(sha256.New, h.secret)
+	mac := hmac.New(sha256.New, v.secret)
 	mac.Write(payload)
 	expected := mac.Sum(nil)
 
@@ -62,6 +111,61 @@ func (h *WebhookHandler) VerifySignature(payload []byte, signatureHeader string)
 	return nil
 }
 
+// WebhookHandler handles GitHub webhook events.
+type WebhookHandler struct {
+	verifier       SignatureVerifier
+	maxPayloadSize int64
+}
+
+// NewWebhookHandler creates a new webhook handler that verifies GitHub
+// signatures with the given secret.
+func NewWebhookHandler(secret string) *WebhookHandler {
+	return NewWebhookHandlerWithVerifier(NewGitHubVerifier(secret))
+}
+
+// NewWebhookHandlerWithVerifier creates a new webhook handler that checks
+// deliveries with the given signature verifier.
+func NewWebhookHandlerWithVerifier(verifier SignatureVerifier) *WebhookHandler {
+	return &WebhookHandler{
+		verifier:       verifier,
+		maxPayloadSize: DefaultMaxPayloadSize,
+	}
+}
+
+// SetMaxPayloadSize overrides the maximum accepted payload size in bytes.
+// Values <= 0 are ignored.
+func (h *WebhookHandler) SetMaxPayloadSize(n int64) {
+	if n > 0 {
+		h.maxPayloadSize = n
+	}
+}
+
+// ReadPayload reads the request body, enforcing the maximum payload size.
+// Returns ErrPayloadTooLarge if the body exceeds the limit. This should be called
+// before signature verification so oversized bodies are never buffered in full.
+func (h *WebhookHandler) ReadPayload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxPayloadSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, ErrPayloadTooLarge
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// SignatureHeader returns the request header the handler's verifier reads.
+func (h *WebhookHandler) SignatureHeader() string {
+	return h.verifier.Header()
+}
+
+// VerifySignature verifies the webhook payload signature with the handler's
+// verifier, given the value of its SignatureHeader.
+func (h *WebhookHandler) VerifySignature(payload []byte, signatureHeader string) error {
+	return h.verifier.Verify(payload, signatureHeader)
+}
+
 // ParsePullRequestEvent parses a pull_request webhook payload.
 func (h *WebhookHandler) ParsePullRequestEvent(payload []byte) (*WebhookEvent, error) {
 	var event WebhookEvent
@@ -77,14 +181,15 @@ func (h *WebhookHandler) ParsePullRequestEvent(payload []byte) (*WebhookEvent, e
 }
 
 // ShouldProcess determines if the event should trigger a review.
-// Returns true for pull_request events with actions: opened, synchronize, reopened.
+// Returns true for pull_request events with actions: opened, synchronize, reopened,
+// ready_for_review (a draft marked ready, which skip_drafts may have held back).
 func (h *WebhookHandler) ShouldProcess(eventType string, event *WebhookEvent) bool {
 	if eventType != "pull_request" {
 		return false
 	}
 
 	switch event.Action {
-	case "opened", "synchronize", "reopened":
+	case "opened", "synchronize", "reopened", "ready_for_review":
 		return true
 	default:
 		return false
@@ -106,7 +211,8 @@ func (h *WebhookHandler) ParseReviewCommentEvent(payload []byte) (*ReviewComment
 }
 
 // ShouldProcessComment determines if a review comment should trigger a reply.
-// Returns true if the comment mentions the bot name and action is "created".
+// Returns true if the comment mentions the bot name, action is "created", and the
+// comment wasn't written by a bot.
 func (h *WebhookHandler) ShouldProcessComment(event *ReviewCommentEvent, botName string) bool {
 	if event.Action != "created" {
 		return false
@@ -116,9 +222,23 @@ func (h *WebhookHandler) ShouldProcessComment(event *ReviewCommentEvent, botName
 		return false
 	}
 
+	// The bot's own comments may quote a command; acting on them could loop
+	if IsBotSender(event.Sender, botName) {
+		return false
+	}
+
 	return ContainsMention(event.Comment.Body, botName)
 }
 
+// IsBotSender reports whether a webhook was sent by a bot account: any GitHub
+// App or other bot (type "Bot"), or the "<botName>[bot]" login.
+func IsBotSender(sender *User, botName string) bool {
+	if sender == nil {
+		return false
+	}
+	return sender.Type == "Bot" || strings.EqualFold(sender.Login, botName+"[bot]")
+}
+
 // ContainsMention checks if text contains an @mention of the given username.
 // It ensures the mention is a proper GitHub-style mention (not part of an email address).
 func ContainsMention(text, username string) bool {
@@ -225,6 +345,7 @@ func (h *WebhookHandler) ParseIssueCommentEvent(payload []byte) (*IssueCommentEv
 // - The action is "created"
 // - The issue is a pull request (has a pull_request link)
 // - The comment mentions the bot name
+// - The comment wasn't written by a bot, such as this one
 func (h *WebhookHandler) ShouldProcessIssueComment(event *IssueCommentEvent, botName string) bool {
 	if event.Action != "created" {
 		return false
@@ -238,11 +359,39 @@ func (h *WebhookHandler) ShouldProcessIssueComment(event *IssueCommentEvent, bot
 		return false
 	}
 
+	if IsBotSender(event.Sender, botName) {
+		return false
+	}
+
 	return ContainsMention(event.Comment.Body, botName)
 }
 
+// Comment commands recognized after an @mention.
+const (
+	CommandReview    = "review"
+	CommandRereview  = "rereview"
+	CommandExplain   = "explain"
+	CommandSummarize = "summarize"
+	CommandResolve   = "resolve"
+	CommandRetry     = "retry"
+	CommandIgnore    = "ignore"
+	CommandUnignore  = "unignore"
+)
+
+// knownCommands is the set of commands ExtractCommand recognizes.
+var knownCommands = map[string]bool{
+	CommandReview:    true,
+	CommandRereview:  true,
+	CommandExplain:   true,
+	CommandSummarize: true,
+	CommandResolve:   true,
+	CommandRetry:     true,
+	CommandIgnore:    true,
+	CommandUnignore:  true,
+}
+
 // ExtractCommand extracts a command from a comment body after an @mention.
-// Returns the command (e.g., "review") or empty string if no valid command found.
+// Returns the first known command word (e.g., "review") or empty string if none found.
 // Example: "@shipitai review" -> "review"
 // Example: "@shipitai please review this" -> "review"
 func ExtractCommand(text, botName string) string {
@@ -254,12 +403,15 @@ func ExtractCommand(text, botName string) string {
 		return ""
 	}
 
-	// Get the text after the mention
-	afterMention := strings.TrimSpace(lowerText[idx+len(mention):])
-
-	// Look for "review" command anywhere in the remaining text
-	if strings.Contains(afterMention, "review") {
-		return "review"
+	// Scan the words after the mention; whole-word matching keeps "rereview"
+	// from being read as "review"
+	words := strings.FieldsFunc(lowerText[idx+len(mention):], func(r rune) bool {
+		return r < 'a' || r > 'z'
+	})
+	for _, w := range words {
+		if knownCommands[w] {
+			return w
+		}
 	}
 
 	return ""
@@ -4,6 +4,10 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -80,6 +84,81 @@ func TestVerifySignature(t *testing.T) {
 	})
 }
 
+func TestSignatureVerifiers(t *testing.T) {
+	secret := "test-secret"
+	payload := []byte(`{"action": "opened"}`)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		scheme     string
+		wantHeader string
+	}{
+		{"", GitHubSignatureHeader},
+		{"github", GitHubSignatureHeader},
+		{"bitbucket", BitbucketSignatureHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			verifier, err := NewSignatureVerifier(tt.scheme, secret)
+			if err != nil {
+				t.Fatalf("NewSignatureVerifier() error = %v", err)
+			}
+			handler := NewWebhookHandlerWithVerifier(verifier)
+			if got := handler.SignatureHeader(); got != tt.wantHeader {
+				t.Errorf("SignatureHeader() = %q, want %q", got, tt.wantHeader)
+			}
+			if err := handler.VerifySignature(payload, signature); err != nil {
+				t.Errorf("VerifySignature() unexpected error = %v", err)
+			}
+			if err := handler.VerifySignature([]byte(`{}`), signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("VerifySignature() on another payload = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+
+	if _, err := NewSignatureVerifier("gitlab", secret); err == nil {
+		t.Error("NewSignatureVerifier() expected error for an unknown scheme")
+	}
+}
+
+func TestReadPayload(t *testing.T) {
+	handler := NewWebhookHandler("secret")
+	handler.SetMaxPayloadSize(16)
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"within limit", `{"action":"x"}`, nil},
+		{"at limit", strings.Repeat("a", 16), nil},
+		{"over limit", strings.Repeat("a", 17), ErrPayloadTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			payload, err := handler.ReadPayload(httptest.NewRecorder(), req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadPayload() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && string(payload) != tt.body {
+				t.Errorf("ReadPayload() = %q, want %q", payload, tt.body)
+			}
+		})
+	}
+
+	t.Run("non-positive size ignored", func(t *testing.T) {
+		h := NewWebhookHandler("secret")
+		h.SetMaxPayloadSize(0)
+		if h.maxPayloadSize != DefaultMaxPayloadSize {
+			t.Errorf("maxPayloadSize = %d, want %d", h.maxPayloadSize, DefaultMaxPayloadSize)
+		}
+	})
+}
+
 func TestShouldProcess(t *testing.T) {
 	handler := NewWebhookHandler("secret")
 
@@ -107,6 +186,12 @@ func TestShouldProcess(t *testing.T) {
 			action:    "reopened",
 			want:      true,
 		},
+		{
+			name:      "pull_request ready_for_review",
+			eventType: "pull_request",
+			action:    "ready_for_review",
+			want:      true,
+		},
 		{
 			name:      "pull_request closed",
 			eventType: "pull_request",
@@ -260,6 +345,14 @@ func TestExtractCommand(t *testing.T) {
 		{"no mention here", "shipitai", ""},
 		{"@other-bot review", "shipitai", ""},
 		{"@shipitai REVIEW please", "shipitai", "review"},
+		{"@shipitai rereview", "shipitai", "rereview"},
+		{"@shipitai explain this line", "shipitai", "explain"},
+		{"@shipitai can you summarize?", "shipitai", "summarize"},
+		{"@shipitai resolve", "shipitai", "resolve"},
+		{"@shipitai retry", "shipitai", "retry"},
+		{"@shipitai ignore", "shipitai", "ignore"},
+		{"@shipitai unignore", "shipitai", "unignore"},
+		{"@shipitai reviewing later", "shipitai", ""},
 	}
 
 	for _, tt := range tests {
@@ -387,6 +480,34 @@ func TestShouldProcessIssueComment(t *testing.T) {
 			botName: "shipitai",
 			want:    false,
 		},
+		{
+			name: "comment by the bot itself",
+			event: &IssueCommentEvent{
+				Action: "created",
+				Issue: &Issue{
+					Number:      42,
+					PullRequest: &IssuePRLink{URL: "https://api.github.com/repos/owner/repo/pulls/42"},
+				},
+				Comment: &IssueComment{Body: "Comment `@shipitai review` to try again."},
+				Sender:  &User{Login: "shipitai[bot]"},
+			},
+			botName: "shipitai",
+			want:    false,
+		},
+		{
+			name: "comment by another bot",
+			event: &IssueCommentEvent{
+				Action: "created",
+				Issue: &Issue{
+					Number:      42,
+					PullRequest: &IssuePRLink{URL: "https://api.github.com/repos/owner/repo/pulls/42"},
+				},
+				Comment: &IssueComment{Body: "@shipitai review"},
+				Sender:  &User{Login: "dependabot[bot]", Type: "Bot"},
+			},
+			botName: "shipitai",
+			want:    false,
+		},
 	}
 
 	for _, tt := range tests {
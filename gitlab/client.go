@@ -0,0 +1,787 @@
+// Package gitlab reviews GitLab merge requests through the same interface the
+// reviewer uses for GitHub pull requests, mapping GitLab's API onto the GitHub
+// types: merge request IIDs stand in for PR numbers, "owner/repo" is the project
+// path, and diff discussions stand in for review threads.
+//
+// The package is library-only for now: neither cmd/server nor cmd/local builds a
+// Client, since both only ingest GitHub webhooks. Pass a Client to
+// review.NewReviewer to review merge requests from your own entry point.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+// DefaultBaseURL is the API root of gitlab.com.
+const DefaultBaseURL = "https://gitlab.com/api/v4"
+
+// developerAccess is the lowest access level that can push to a project.
+const developerAccess = 30
+
+// maxStatusDescription is GitLab's limit on a commit status description.
+const maxStatusDescription = 255
+
+// Client provides methods to interact with the GitLab API. Installation IDs are
+// accepted to satisfy the reviewer's interface but ignored: a single access token
+// authenticates every request.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+
+	mu       sync.Mutex
+	statuses map[int64]statusTarget // commit statuses created as check runs, by ID
+}
+
+// statusTarget records where a commit status was set, so it can be updated later.
+type statusTarget struct {
+	project string
+	sha     string
+	name    string
+	state   string
+}
+
+// NewClient creates a GitLab API client. baseURL is the API root, e.g.
+// DefaultBaseURL or "https://gitlab.example.com/api/v4"; token is a personal,
+// project, or group access token with the api scope.
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		statuses:   make(map[int64]statusTarget),
+	}
+}
+
+// projectID returns the URL-encoded project path GitLab accepts in place of a
+// numeric project ID.
+func projectID(owner, repo string) string {
+	return url.PathEscape(owner + "/" + repo)
+}
+
+// mrPath returns the API path of a merge request.
+func mrPath(owner, repo string, iid int) string {
+	return fmt.Sprintf("/projects/%s/merge_requests/%d", projectID(owner, repo), iid)
+}
+
+// do sends a request to the API and decodes the JSON response into out, if set.
+// Unexpected statuses return a *github.APIError (or *github.RateLimitError), so
+// github.IsNotFound works on GitLab errors too.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (http.Header, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, statusError(resp)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// statusError builds the error for an unexpected response, consuming its body.
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	apiErr := github.APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Endpoint:   resp.Request.Method + " " + resp.Request.URL.EscapedPath(),
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return &github.RateLimitError{APIError: apiErr, RetryAfter: time.Duration(secs) * time.Second}
+		}
+	}
+	return &apiErr
+}
+
+// getMergeRequest fetches a merge request.
+func (c *Client) getMergeRequest(ctx context.Context, owner, repo string, iid int) (*mergeRequest, error) {
+	var mr mergeRequest
+	if _, err := c.do(ctx, "GET", mrPath(owner, repo, iid), nil, nil, &mr); err != nil {
+		return nil, fmt.Errorf("failed to get merge request: %w", err)
+	}
+	return &mr, nil
+}
+
+// GetPullRequest fetches a merge request as a pull request.
+func (c *Client) GetPullRequest(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*github.PullRequest, error) {
+	mr, err := c.getMergeRequest(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	return toPullRequest(mr), nil
+}
+
+// toPullRequest maps a merge request onto the GitHub pull request model.
+func toPullRequest(mr *mergeRequest) *github.PullRequest {
+	state := "open"
+	if mr.State != "opened" {
+		state = "closed"
+	}
+
+	pr := &github.PullRequest{
+		ID:        mr.ID,
+		Number:    mr.IID,
+		State:     state,
+		Title:     mr.Title,
+		Body:      mr.Description,
+		Head:      &github.Ref{Ref: mr.SourceBranch, SHA: mr.SHA},
+		Base:      &github.Ref{Ref: mr.TargetBranch},
+		HTMLURL:   mr.WebURL,
+		DiffURL:   mr.WebURL + ".diff",
+		CreatedAt: mr.CreatedAt,
+		UpdatedAt: mr.UpdatedAt,
+		Draft:     mr.Draft,
+		Merged:    mr.State == "merged",
+	}
+	if mr.DiffRefs != nil {
+		pr.Base.SHA = mr.DiffRefs.BaseSHA
+	}
+	if mr.Author != nil {
+		pr.User = &github.User{ID: mr.Author.ID, Login: mr.Author.Username}
+	}
+	for _, name := range mr.Labels {
+		pr.Labels = append(pr.Labels, github.Label{Name: name})
+	}
+	return pr
+}
+
+// GetMergeCommit always returns nil: GitLab doesn't expose a test-merge commit
+// with its base, so reviews use the merge request diff as is.
+func (c *Client) GetMergeCommit(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*github.MergeCommit, error) {
+	return nil, nil
+}
+
+// FetchDiff fetches the diff of a merge request in unified git format.
+func (c *Client) FetchDiff(ctx context.Context, installationID int64, owner, repo string, prNumber int) (string, error) {
+	changes, err := c.listChanges(ctx, owner, repo, prNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch diff: %w", err)
+	}
+	return buildDiff(changes), nil
+}
+
+// listChanges fetches a merge request's changed files with their raw diffs.
+func (c *Client) listChanges(ctx context.Context, owner, repo string, iid int) ([]fileChange, error) {
+	var result struct {
+		Changes []fileChange `json:"changes"`
+	}
+	query := url.Values{"access_raw_diffs": {"true"}}
+	if _, err := c.do(ctx, "GET", mrPath(owner, repo, iid)+"/changes", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Changes, nil
+}
+
+// FetchPullRequestFiles lists a merge request's changed files with their patches.
+func (c *Client) FetchPullRequestFiles(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]github.PullRequestFile, error) {
+	changes, err := c.listChanges(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
+
+	files := make([]github.PullRequestFile, len(changes))
+	for i, ch := range changes {
+		files[i] = github.PullRequestFile{Filename: ch.NewPath, Status: "modified", Patch: ch.Diff}
+		switch {
+		case ch.NewFile:
+			files[i].Status = "added"
+		case ch.DeletedFile:
+			files[i].Status = "removed"
+		case ch.RenamedFile:
+			files[i].Status, files[i].PreviousFilename = "renamed", ch.OldPath
+		}
+	}
+	return files, nil
+}
+
+// CompareDiff fetches the diff between two commits, from their merge base to head.
+func (c *Client) CompareDiff(ctx context.Context, installationID int64, owner, repo, base, head string) (string, error) {
+	var result struct {
+		Diffs []fileChange `json:"diffs"`
+	}
+	query := url.Values{"from": {base}, "to": {head}}
+	path := fmt.Sprintf("/projects/%s/repository/compare", projectID(owner, repo))
+	if _, err := c.do(ctx, "GET", path, query, nil, &result); err != nil {
+		return "", fmt.Errorf("failed to compare commits: %w", err)
+	}
+	return buildDiff(result.Diffs), nil
+}
+
+// buildDiff joins GitLab's per-file hunks into a unified diff with the git
+// headers the diff parser expects.
+func buildDiff(changes []fileChange) string {
+	var b strings.Builder
+	for _, ch := range changes {
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n", ch.OldPath, ch.NewPath)
+		mode := ch.BMode
+		if mode == "" || mode == "0" {
+			mode = "100644"
+		}
+		switch {
+		case ch.NewFile:
+			fmt.Fprintf(&b, "new file mode %s\n", mode)
+		case ch.DeletedFile:
+			fmt.Fprintf(&b, "deleted file mode %s\n", mode)
+		case ch.RenamedFile:
+			fmt.Fprintf(&b, "rename from %s\nrename to %s\n", ch.OldPath, ch.NewPath)
+		}
+		if ch.Diff == "" {
+			continue
+		}
+
+		oldName, newName := "a/"+ch.OldPath, "b/"+ch.NewPath
+		if ch.NewFile {
+			oldName = "/dev/null"
+		}
+		if ch.DeletedFile {
+			newName = "/dev/null"
+		}
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		b.WriteString(ch.Diff)
+		if !strings.HasSuffix(ch.Diff, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// FetchFileContent fetches the raw content of a file at ref.
+// Returns "" with no error if the file doesn't exist.
+func (c *Client) FetchFileContent(ctx context.Context, installationID int64, owner, repo, path, ref string) (string, error) {
+	u := fmt.Sprintf("%s/projects/%s/repository/files/%s/raw?%s", c.baseURL, projectID(owner, repo), url.PathEscape(path), url.Values{"ref": {ref}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch file: %w", statusError(resp))
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return string(content), nil
+}
+
+// ListDirectory returns the paths of the files directly in a repository
+// directory at ref; subdirectories are left out. Returns nil with no error if the
+// directory doesn't exist.
+func (c *Client) ListDirectory(ctx context.Context, installationID int64, owner, repo, dir, ref string) ([]string, error) {
+	var files []string
+	page := "1"
+	for page != "" {
+		var entries []treeEntry
+		query := url.Values{"path": {dir}, "ref": {ref}, "per_page": {"100"}, "page": {page}}
+		header, err := c.do(ctx, "GET", fmt.Sprintf("/projects/%s/repository/tree", projectID(owner, repo)), query, nil, &entries)
+		if github.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list directory: %w", err)
+		}
+		for _, e := range entries {
+			if e.Type == "blob" {
+				files = append(files, e.Path)
+			}
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return files, nil
+}
+
+// FetchMultipleFiles fetches multiple files in parallel.
+// Returns a map of path -> content. Missing files are not included in the map.
+func (c *Client) FetchMultipleFiles(ctx context.Context, installationID int64, owner, repo string, paths []string, ref string) (map[string]string, error) {
+	result := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Limit concurrent requests to avoid rate limiting
+	sem := make(chan struct{}, 10)
+
+	for _, path := range paths {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			content, err := c.FetchFileContent(ctx, installationID, owner, repo, p, ref)
+			if err != nil || content == "" {
+				return
+			}
+			mu.Lock()
+			result[p] = content
+			mu.Unlock()
+		}(path)
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+// FetchFileCommits fetches the most recent commits that touched a file, newest first.
+func (c *Client) FetchFileCommits(ctx context.Context, installationID int64, owner, repo, path, ref string, limit int) ([]github.Commit, error) {
+	query := url.Values{"path": {path}, "per_page": {strconv.Itoa(limit)}}
+	if ref != "" {
+		query.Set("ref_name", ref)
+	}
+	var commits []commit
+	if _, err := c.do(ctx, "GET", fmt.Sprintf("/projects/%s/repository/commits", projectID(owner, repo)), query, nil, &commits); err != nil {
+		return nil, fmt.Errorf("failed to fetch commits: %w", err)
+	}
+
+	result := make([]github.Commit, 0, len(commits))
+	for _, cm := range commits {
+		result = append(result, github.Commit{
+			SHA: cm.ID,
+			Commit: &github.CommitDetail{
+				Message: cm.Message,
+				Author:  &github.CommitAuthor{Name: cm.AuthorName, Email: cm.AuthorEmail, Date: cm.AuthoredDate},
+			},
+		})
+	}
+	return result, nil
+}
+
+// IsContributor checks if a user has at least Developer access to the project.
+// On API error, returns true (fail open) to avoid blocking legitimate MRs.
+func (c *Client) IsContributor(ctx context.Context, installationID int64, owner, repo, username string) (bool, error) {
+	var members []member
+	query := url.Values{"query": {username}}
+	if _, err := c.do(ctx, "GET", fmt.Sprintf("/projects/%s/members/all", projectID(owner, repo)), query, nil, &members); err != nil {
+		return true, fmt.Errorf("failed to get project members: %w", err)
+	}
+	for _, m := range members {
+		if strings.EqualFold(m.Username, username) {
+			return m.AccessLevel >= developerAccess, nil
+		}
+	}
+	return false, nil
+}
+
+// createNote posts a top-level note on a merge request.
+func (c *Client) createNote(ctx context.Context, owner, repo string, iid int, body string) (*note, error) {
+	var n note
+	if _, err := c.do(ctx, "POST", mrPath(owner, repo, iid)+"/notes", nil, map[string]string{"body": body}, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// rejectedCommentsHeading introduces comments GitLab wouldn't open a diff
+// discussion for, listed in the review note instead.
+const rejectedCommentsHeading = "**Comments that couldn't be placed on the diff:**"
+
+// CreateReview posts a review as a diff discussion per comment, then a merge
+// request note carrying the body; the note's ID serves as the review ID. Comments
+// GitLab rejects (a 4xx, e.g. a line outside the MR diff) are listed in the note
+// instead. Posting the note last keeps a retry after a failed discussion from
+// repeating it. An APPROVE event also approves the merge request.
+func (c *Client) CreateReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, review *github.ReviewRequest) (*github.Review, error) {
+	mr, err := c.getMergeRequest(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	if mr.DiffRefs == nil {
+		return nil, errors.New("failed to create review: merge request has no diff refs")
+	}
+
+	var rejected []github.ReviewComment
+	for _, comment := range review.Comments {
+		pos := position{
+			PositionType: "text",
+			BaseSHA:      mr.DiffRefs.BaseSHA,
+			StartSHA:     mr.DiffRefs.StartSHA,
+			HeadSHA:      mr.DiffRefs.HeadSHA,
+			OldPath:      comment.Path,
+			NewPath:      comment.Path,
+		}
+		if comment.Side == "LEFT" {
+			pos.OldLine = comment.Line
+		} else {
+			pos.NewLine = comment.Line
+		}
+		req := map[string]interface{}{"body": comment.Body, "position": pos}
+		if _, err := c.do(ctx, "POST", mrPath(owner, repo, prNumber)+"/discussions", nil, req, nil); err != nil {
+			var apiErr *github.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+				rejected = append(rejected, comment)
+				continue
+			}
+			return nil, fmt.Errorf("failed to comment on %s:%d: %w", comment.Path, comment.Line, err)
+		}
+	}
+
+	body := review.Body
+	if len(rejected) > 0 {
+		var b strings.Builder
+		b.WriteString(rejectedCommentsHeading)
+		for _, comment := range rejected {
+			fmt.Fprintf(&b, "\n- `%s:%d`: %s", comment.Path, comment.Line, strings.ReplaceAll(strings.TrimSpace(comment.Body), "\n", "\n  "))
+		}
+		body = strings.TrimLeft(body+"\n\n"+b.String(), "\n")
+	}
+	n, err := c.createNote(ctx, owner, repo, prNumber, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review: %w", err)
+	}
+
+	if review.Event == "APPROVE" {
+		// Best effort: the token's user may not be an eligible approver
+		_, _ = c.do(ctx, "POST", mrPath(owner, repo, prNumber)+"/approve", nil, nil, nil)
+	}
+
+	return &github.Review{
+		ID:      n.ID,
+		Body:    n.Body,
+		State:   review.Event,
+		HTMLURL: fmt.Sprintf("%s#note_%d", mr.WebURL, n.ID),
+	}, nil
+}
+
+// UpdateReviewBody replaces the body of a review's note.
+func (c *Client) UpdateReviewBody(ctx context.Context, installationID int64, owner, repo string, prNumber int, reviewID int64, body string) error {
+	if err := c.UpdateIssueComment(ctx, installationID, owner, repo, prNumber, reviewID, body); err != nil {
+		return fmt.Errorf("failed to update review: %w", err)
+	}
+	return nil
+}
+
+// listDiscussions fetches every discussion on a merge request.
+func (c *Client) listDiscussions(ctx context.Context, owner, repo string, iid int) ([]discussion, error) {
+	var all []discussion
+	page := "1"
+	for page != "" {
+		var discussions []discussion
+		query := url.Values{"per_page": {"100"}, "page": {page}}
+		header, err := c.do(ctx, "GET", mrPath(owner, repo, iid)+"/discussions", query, nil, &discussions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list discussions: %w", err)
+		}
+		all = append(all, discussions...)
+		page = header.Get("X-Next-Page")
+	}
+	return all, nil
+}
+
+// ListPRReviews lists a merge request's top-level notes as reviews, since
+// CreateReview posts each review's body as one.
+func (c *Client) ListPRReviews(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]github.Review, error) {
+	discussions, err := c.listDiscussions(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews []github.Review
+	for _, d := range discussions {
+		if len(d.Notes) == 0 || d.Notes[0].System || d.Notes[0].Position != nil {
+			continue
+		}
+		n := d.Notes[0]
+		review := github.Review{ID: n.ID, Body: n.Body, SubmittedAt: n.CreatedAt}
+		if n.Author != nil {
+			review.User = &github.User{Login: n.Author.Username}
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+// FetchPRReviewThreads fetches the diff discussions of a merge request as review
+// threads. Thread IDs encode the project and merge request, so
+// ResolveReviewThread can resolve them from the ID alone.
+func (c *Client) FetchPRReviewThreads(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]github.ReviewThread, error) {
+	discussions, err := c.listDiscussions(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var threads []github.ReviewThread
+	for _, d := range discussions {
+		if len(d.Notes) == 0 || d.Notes[0].Position == nil || !d.Notes[0].Resolvable {
+			continue
+		}
+		first := d.Notes[0]
+		thread := github.ReviewThread{
+			ID:         threadID(owner, repo, prNumber, d.ID),
+			IsResolved: first.Resolved,
+			Path:       first.Position.NewPath,
+			Line:       first.Position.NewLine,
+		}
+		if thread.Line == 0 {
+			thread.Path, thread.Line = first.Position.OldPath, first.Position.OldLine
+		}
+		for _, n := range d.Notes {
+			if n.System {
+				continue
+			}
+			tc := github.ThreadComment{
+				ID:         strconv.FormatInt(n.ID, 10),
+				DatabaseID: n.ID,
+				Body:       n.Body,
+				CreatedAt:  n.CreatedAt.Format(time.RFC3339),
+			}
+			if n.Author != nil {
+				tc.Author = n.Author.Username
+			}
+			thread.Comments = append(thread.Comments, tc)
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+// threadID builds the review thread ID of a discussion.
+func threadID(owner, repo string, iid int, discussionID string) string {
+	return fmt.Sprintf("%s:%d:%s", projectID(owner, repo), iid, discussionID)
+}
+
+// ResolveReviewThread resolves a diff discussion by its thread ID.
+func (c *Client) ResolveReviewThread(ctx context.Context, installationID int64, threadID string) error {
+	parts := strings.SplitN(threadID, ":", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid thread ID: %s", threadID)
+	}
+	path := fmt.Sprintf("/projects/%s/merge_requests/%s/discussions/%s", parts[0], parts[1], url.PathEscape(parts[2]))
+	if _, err := c.do(ctx, "PUT", path, url.Values{"resolved": {"true"}}, nil, nil); err != nil {
+		return fmt.Errorf("failed to resolve thread: %w", err)
+	}
+	return nil
+}
+
+// CreateReplyComment replies in the discussion containing the given note.
+func (c *Client) CreateReplyComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64, body string) (*github.PullRequestComment, error) {
+	discussions, err := c.listDiscussions(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range discussions {
+		for _, n := range d.Notes {
+			if n.ID != commentID {
+				continue
+			}
+			var reply note
+			path := fmt.Sprintf("%s/discussions/%s/notes", mrPath(owner, repo, prNumber), url.PathEscape(d.ID))
+			if _, err := c.do(ctx, "POST", path, nil, map[string]string{"body": body}, &reply); err != nil {
+				return nil, fmt.Errorf("failed to create reply: %w", err)
+			}
+			comment := &github.PullRequestComment{
+				ID:          reply.ID,
+				InReplyToID: d.Notes[0].ID,
+				Body:        reply.Body,
+				CreatedAt:   reply.CreatedAt.Format(time.RFC3339),
+				UpdatedAt:   reply.UpdatedAt.Format(time.RFC3339),
+			}
+			if p := d.Notes[0].Position; p != nil {
+				comment.Path, comment.Line = p.NewPath, p.NewLine
+			}
+			if reply.Author != nil {
+				comment.User = &github.User{ID: reply.Author.ID, Login: reply.Author.Username}
+			}
+			return comment, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to create reply: no discussion contains note %d", commentID)
+}
+
+// toIssueComment maps a note onto the GitHub issue comment model.
+func toIssueComment(n *note) *github.IssueCommentResponse {
+	comment := &github.IssueCommentResponse{ID: n.ID, Body: n.Body}
+	if n.Author != nil {
+		comment.User = &github.User{ID: n.Author.ID, Login: n.Author.Username}
+	}
+	return comment
+}
+
+// CreateIssueComment posts a note on a merge request.
+func (c *Client) CreateIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, body string) (*github.IssueCommentResponse, error) {
+	n, err := c.createNote(ctx, owner, repo, prNumber, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	return toIssueComment(n), nil
+}
+
+// GetIssueComment fetches a merge request note.
+func (c *Client) GetIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64) (*github.IssueCommentResponse, error) {
+	var n note
+	if _, err := c.do(ctx, "GET", fmt.Sprintf("%s/notes/%d", mrPath(owner, repo, prNumber), commentID), nil, nil, &n); err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return toIssueComment(&n), nil
+}
+
+// UpdateIssueComment replaces the body of a merge request note.
+func (c *Client) UpdateIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64, body string) error {
+	path := fmt.Sprintf("%s/notes/%d", mrPath(owner, repo, prNumber), commentID)
+	if _, err := c.do(ctx, "PUT", path, nil, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return nil
+}
+
+// CreateCheckRun sets a commit status on the head commit in place of a check run.
+// Commit statuses can't carry annotations, so requests with annotations fail and
+// the reviewer falls back to inline comments.
+func (c *Client) CreateCheckRun(ctx context.Context, installationID int64, owner, repo string, checkRun *github.CheckRunRequest) (*github.CheckRun, error) {
+	target := statusTarget{
+		project: projectID(owner, repo),
+		sha:     checkRun.HeadSHA,
+		name:    checkRun.Name,
+		state:   statusState(checkRun.Status, checkRun.Conclusion),
+	}
+	return c.sendStatus(ctx, target, checkRun)
+}
+
+// UpdateCheckRun updates a commit status set by CreateCheckRun.
+func (c *Client) UpdateCheckRun(ctx context.Context, installationID int64, owner, repo string, checkRunID int64, checkRun *github.CheckRunRequest) (*github.CheckRun, error) {
+	c.mu.Lock()
+	target, ok := c.statuses[checkRunID]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("failed to send check run: unknown commit status %d", checkRunID)
+	}
+	if checkRun.Status != "" || checkRun.Conclusion != "" {
+		target.state = statusState(checkRun.Status, checkRun.Conclusion)
+	}
+	return c.sendStatus(ctx, target, checkRun)
+}
+
+// sendStatus sets the commit status described by target and the check run's output.
+func (c *Client) sendStatus(ctx context.Context, target statusTarget, checkRun *github.CheckRunRequest) (*github.CheckRun, error) {
+	req := map[string]string{"state": target.state, "name": target.name}
+	if out := checkRun.Output; out != nil {
+		if len(out.Annotations) > 0 {
+			return nil, errors.New("failed to send check run: GitLab commit statuses don't support annotations")
+		}
+		desc := out.Title
+		if len(desc) > maxStatusDescription {
+			desc = desc[:maxStatusDescription]
+		}
+		req["description"] = desc
+	}
+
+	var status commitStatus
+	if _, err := c.do(ctx, "POST", fmt.Sprintf("/projects/%s/statuses/%s", target.project, target.sha), nil, req, &status); err != nil {
+		return nil, fmt.Errorf("failed to send check run: %w", err)
+	}
+
+	c.mu.Lock()
+	c.statuses[status.ID] = target
+	c.mu.Unlock()
+
+	return &github.CheckRun{ID: status.ID, HTMLURL: status.TargetURL}, nil
+}
+
+// GetCheckRuns lists a commit's statuses, including pipeline jobs, as check runs.
+func (c *Client) GetCheckRuns(ctx context.Context, installationID int64, owner, repo, ref string) ([]github.CheckRun, error) {
+	var statuses []commitStatus
+	path := fmt.Sprintf("/projects/%s/repository/commits/%s/statuses", projectID(owner, repo), url.PathEscape(ref))
+	if _, err := c.do(ctx, "GET", path, url.Values{"per_page": {"100"}}, nil, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to fetch check runs: %w", err)
+	}
+
+	runs := make([]github.CheckRun, len(statuses))
+	for i, s := range statuses {
+		runs[i] = github.CheckRun{ID: s.ID, Name: s.Name, HTMLURL: s.TargetURL}
+		runs[i].Status, runs[i].Conclusion = checkRunState(s.State)
+	}
+	return runs, nil
+}
+
+// checkRunState maps a commit status state to a check run status and conclusion.
+func checkRunState(state string) (status, conclusion string) {
+	switch state {
+	case "created", "pending":
+		return "queued", ""
+	case "running":
+		return "in_progress", ""
+	case "success":
+		return "completed", "success"
+	case "canceled":
+		return "completed", "cancelled"
+	case "skipped":
+		return "completed", "skipped"
+	default:
+		return "completed", "failure"
+	}
+}
+
+// statusState maps a check run status and conclusion to a commit status state.
+func statusState(status, conclusion string) string {
+	switch status {
+	case "queued":
+		return "pending"
+	case "in_progress":
+		return "running"
+	}
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return "success"
+	case "cancelled":
+		return "canceled"
+	default:
+		return "failed"
+	}
+}
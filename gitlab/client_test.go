@@ -0,0 +1,264 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/review"
+)
+
+var _ review.Platform = (*Client)(nil)
+
+const testMRPath = "/projects/group%2Fproject/merge_requests/7"
+
+// newTestClient serves handler as the GitLab API and returns a client for it.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL, "token")
+}
+
+func TestBuildDiff(t *testing.T) {
+	diff := buildDiff([]fileChange{
+		{OldPath: "main.go", NewPath: "main.go", BMode: "100644", Diff: "@@ -1 +1 @@\n-a\n+b\n"},
+		{OldPath: "new.go", NewPath: "new.go", BMode: "100644", NewFile: true, Diff: "@@ -0,0 +1 @@\n+x"},
+		{OldPath: "gone.go", NewPath: "gone.go", BMode: "0", DeletedFile: true, Diff: "@@ -1 +0,0 @@\n-y\n"},
+		{OldPath: "old.go", NewPath: "moved.go", RenamedFile: true},
+	})
+
+	want := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+x\n" +
+		"diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-y\n" +
+		"diff --git a/old.go b/moved.go\nrename from old.go\nrename to moved.go\n"
+	if diff != want {
+		t.Errorf("buildDiff() =\n%s\nwant:\n%s", diff, want)
+	}
+}
+
+func TestFetchFileContentNotFound(t *testing.T) {
+	var gotPath string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		http.NotFound(w, r)
+	})
+
+	content, err := client.FetchFileContent(context.Background(), 0, "group", "project", "docs/README.md", "main")
+	if err != nil {
+		t.Fatalf("FetchFileContent() error = %v", err)
+	}
+	if content != "" {
+		t.Errorf("content = %q, want empty", content)
+	}
+	if want := "/projects/group%2Fproject/repository/files/docs%2FREADME.md/raw"; gotPath != want {
+		t.Errorf("path = %s, want %s", gotPath, want)
+	}
+}
+
+func TestListDirectory(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/group%2Fproject/repository/tree" || r.URL.Query().Get("path") != "pkg/store" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"name":"store.go","type":"blob","path":"pkg/store/store.go"},{"name":"sql","type":"tree","path":"pkg/store/sql"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"name":"cache.go","type":"blob","path":"pkg/store/cache.go"}]`)
+	})
+
+	files, err := client.ListDirectory(context.Background(), 0, "group", "project", "pkg/store", "main")
+	if err != nil {
+		t.Fatalf("ListDirectory() error = %v", err)
+	}
+	if got := strings.Join(files, " "); got != "pkg/store/store.go pkg/store/cache.go" {
+		t.Errorf("ListDirectory() = %v, want the files on both pages", files)
+	}
+
+	files, err = client.ListDirectory(context.Background(), 0, "group", "project", "missing", "main")
+	if err != nil || files != nil {
+		t.Errorf("ListDirectory() of a missing directory = %v, %v, want nil, nil", files, err)
+	}
+}
+
+func TestGetPullRequestNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	_, err := client.GetPullRequest(context.Background(), 0, "group", "project", 7)
+	if !github.IsNotFound(err) {
+		t.Errorf("IsNotFound(%v) = false, want true", err)
+	}
+}
+
+func TestFetchPRReviewThreadsAndResolve(t *testing.T) {
+	var resolved string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.EscapedPath() == testMRPath+"/discussions":
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprint(w, `[{"id":"general","notes":[{"id":1,"body":"LGTM","author":{"username":"alice"}}]}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"id":"abc123","notes":[
+				{"id":10,"body":"Possible nil dereference","author":{"username":"shipitai"},"resolvable":true,"resolved":false,
+				 "position":{"new_path":"main.go","new_line":12},"created_at":"2024-01-02T03:04:05Z"},
+				{"id":11,"body":"changed the line","author":{"username":"alice"},"system":true},
+				{"id":12,"body":"Fixed","author":{"username":"alice"},"resolvable":true}]}]`)
+		case r.Method == "PUT":
+			resolved = r.URL.EscapedPath() + "?" + r.URL.RawQuery
+			fmt.Fprint(w, `{}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	threads, err := client.FetchPRReviewThreads(context.Background(), 0, "group", "project", 7)
+	if err != nil {
+		t.Fatalf("FetchPRReviewThreads() error = %v", err)
+	}
+	if len(threads) != 1 {
+		t.Fatalf("got %d threads, want 1 (general comments are not review threads)", len(threads))
+	}
+	th := threads[0]
+	if th.Path != "main.go" || th.Line != 12 || th.IsResolved {
+		t.Errorf("thread = %+v, want unresolved main.go:12", th)
+	}
+	if len(th.Comments) != 2 || th.Comments[0].DatabaseID != 10 || th.Comments[0].Author != "shipitai" || th.Comments[1].Body != "Fixed" {
+		t.Errorf("comments = %+v, want notes 10 and 12 without the system note", th.Comments)
+	}
+
+	if err := client.ResolveReviewThread(context.Background(), 0, th.ID); err != nil {
+		t.Fatalf("ResolveReviewThread() error = %v", err)
+	}
+	if want := testMRPath + "/discussions/abc123?resolved=true"; resolved != want {
+		t.Errorf("resolved %s, want %s", resolved, want)
+	}
+}
+
+func TestCreateReview(t *testing.T) {
+	var discussions []map[string]interface{}
+	var note string
+	var requests []string
+	approved := false
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		requests = append(requests, r.Method+" "+strings.TrimPrefix(path, testMRPath))
+		switch {
+		case r.Method == "GET" && path == testMRPath:
+			fmt.Fprint(w, `{"iid":7,"web_url":"https://gitlab.com/group/project/-/merge_requests/7",
+				"diff_refs":{"base_sha":"base","head_sha":"head","start_sha":"start"}}`)
+		case r.Method == "POST" && path == testMRPath+"/notes":
+			body, _ := io.ReadAll(r.Body)
+			note = string(body)
+			fmt.Fprint(w, `{"id":42,"body":"Looks good"}`)
+		case r.Method == "POST" && path == testMRPath+"/discussions":
+			var req map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decode discussion: %v", err)
+			}
+			if req["body"] == "Outside the diff" {
+				http.Error(w, `{"message":"400 Bad request - Note {:line_code=>[\"can't be blank\"]}"}`, http.StatusBadRequest)
+				return
+			}
+			discussions = append(discussions, req)
+			fmt.Fprint(w, `{"id":"d1"}`)
+		case r.Method == "POST" && path == testMRPath+"/approve":
+			approved = true
+			fmt.Fprint(w, `{}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, path)
+			http.NotFound(w, r)
+		}
+	})
+
+	rev, err := client.CreateReview(context.Background(), 0, "group", "project", 7, &github.ReviewRequest{
+		Body:  "Looks good",
+		Event: "APPROVE",
+		Comments: []github.ReviewComment{
+			{Path: "main.go", Line: 12, Body: "Nit"},
+			{Path: "main.go", Line: 99, Body: "Outside the diff"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateReview() error = %v", err)
+	}
+
+	if rev.ID != 42 || rev.HTMLURL != "https://gitlab.com/group/project/-/merge_requests/7#note_42" {
+		t.Errorf("review = %+v, want note 42", rev)
+	}
+	if !strings.Contains(note, "Looks good") {
+		t.Errorf("note = %s, want the review body", note)
+	}
+	if !strings.Contains(note, "`main.go:99`: Outside the diff") {
+		t.Errorf("note = %s, want the rejected comment listed", note)
+	}
+	// The note comes after the discussions, so a retry can't duplicate it
+	if got := strings.Join(requests, ", "); got != "GET , POST /discussions, POST /discussions, POST /notes, POST /approve" {
+		t.Errorf("requests = %s, want the discussions before the note", got)
+	}
+	if len(discussions) != 1 {
+		t.Fatalf("got %d discussions, want 1", len(discussions))
+	}
+	pos, _ := discussions[0]["position"].(map[string]interface{})
+	if pos["new_path"] != "main.go" || pos["new_line"] != float64(12) || pos["head_sha"] != "head" || pos["base_sha"] != "base" {
+		t.Errorf("position = %v, want main.go:12 against the MR diff refs", pos)
+	}
+	if !approved {
+		t.Error("APPROVE review did not approve the merge request")
+	}
+}
+
+func TestCheckRunAsCommitStatus(t *testing.T) {
+	var states []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode status: %v", err)
+		}
+		if want := "/projects/group%2Fproject/statuses/abc"; r.URL.EscapedPath() != want {
+			t.Errorf("path = %s, want %s", r.URL.EscapedPath(), want)
+		}
+		states = append(states, req["state"])
+		fmt.Fprintf(w, `{"id":%d}`, len(states))
+	})
+	ctx := context.Background()
+
+	run, err := client.CreateCheckRun(ctx, 0, "group", "project", &github.CheckRunRequest{
+		Name: "ShipItAI", HeadSHA: "abc", Status: "in_progress",
+	})
+	if err != nil {
+		t.Fatalf("CreateCheckRun() error = %v", err)
+	}
+	if _, err := client.UpdateCheckRun(ctx, 0, "group", "project", run.ID, &github.CheckRunRequest{
+		Status: "completed", Conclusion: "failure", Output: &github.CheckRunOutput{Title: "2 issues"},
+	}); err != nil {
+		t.Fatalf("UpdateCheckRun() error = %v", err)
+	}
+	if len(states) != 2 || states[0] != "running" || states[1] != "failed" {
+		t.Errorf("states = %v, want [running failed]", states)
+	}
+
+	_, err = client.CreateCheckRun(ctx, 0, "group", "project", &github.CheckRunRequest{
+		Name: "ShipItAI", HeadSHA: "abc", Status: "completed", Conclusion: "success",
+		Output: &github.CheckRunOutput{Annotations: []github.CheckRunAnnotation{{Path: "main.go", StartLine: 1}}},
+	})
+	if err == nil {
+		t.Error("CreateCheckRun() with annotations succeeded, want an error so the reviewer falls back to inline comments")
+	}
+	if len(states) != 2 {
+		t.Errorf("annotated check run sent a status")
+	}
+}
@@ -0,0 +1,111 @@
+package gitlab
+
+import "time"
+
+// mergeRequest is a GitLab merge request as returned by the MR API.
+type mergeRequest struct {
+	ID           int64     `json:"id"`
+	IID          int       `json:"iid"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	State        string    `json:"state"` // opened, closed, locked, merged
+	Draft        bool      `json:"draft"`
+	SHA          string    `json:"sha"`
+	SourceBranch string    `json:"source_branch"`
+	TargetBranch string    `json:"target_branch"`
+	WebURL       string    `json:"web_url"`
+	Author       *user     `json:"author"`
+	Labels       []string  `json:"labels"`
+	DiffRefs     *diffRefs `json:"diff_refs"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// diffRefs are the commits a merge request's diff is computed between. Diff
+// note positions must cite them.
+type diffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	HeadSHA  string `json:"head_sha"`
+	StartSHA string `json:"start_sha"`
+}
+
+// user is a GitLab user.
+type user struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// fileChange is one file of a merge request's changes or a compare result.
+// Diff holds only the hunks, without the git headers.
+type fileChange struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	BMode       string `json:"b_mode"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+	Diff        string `json:"diff"`
+}
+
+// note is a comment on a merge request, standalone or within a discussion.
+type note struct {
+	ID         int64     `json:"id"`
+	Body       string    `json:"body"`
+	Author     *user     `json:"author"`
+	System     bool      `json:"system"`
+	Resolvable bool      `json:"resolvable"`
+	Resolved   bool      `json:"resolved"`
+	Position   *position `json:"position,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// discussion is a thread of notes. Discussions started on a diff line are
+// GitLab's equivalent of GitHub review threads.
+type discussion struct {
+	ID    string `json:"id"`
+	Notes []note `json:"notes"`
+}
+
+// position anchors a diff note to a line of the merge request diff.
+type position struct {
+	PositionType string `json:"position_type"`
+	BaseSHA      string `json:"base_sha"`
+	StartSHA     string `json:"start_sha"`
+	HeadSHA      string `json:"head_sha"`
+	OldPath      string `json:"old_path"`
+	NewPath      string `json:"new_path"`
+	OldLine      int    `json:"old_line,omitempty"`
+	NewLine      int    `json:"new_line,omitempty"`
+}
+
+// commit is a repository commit from the commits API.
+type commit struct {
+	ID           string `json:"id"`
+	Message      string `json:"message"`
+	AuthorName   string `json:"author_name"`
+	AuthorEmail  string `json:"author_email"`
+	AuthoredDate string `json:"authored_date"`
+}
+
+// treeEntry is a file ("blob") or directory ("tree") in a repository tree listing.
+type treeEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// member is a project member with their access level (30 is Developer).
+type member struct {
+	Username    string `json:"username"`
+	AccessLevel int    `json:"access_level"`
+}
+
+// commitStatus is a commit status, GitLab's closest match to a check run.
+type commitStatus struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	State       string `json:"state"` // pending, running, success, failed, canceled
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url"`
+}
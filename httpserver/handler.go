@@ -0,0 +1,731 @@
+// Package httpserver handles GitHub webhook deliveries for a reviewer. The
+// production and local servers in cmd/ wrap it with their own routing,
+// storage, and configuration.
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/review"
+	"github.com/shipitai/shipitai/storage"
+)
+
+const (
+	// ReviewTimeout is the default bound on a review started by a webhook.
+	ReviewTimeout = 5 * time.Minute
+
+	// ReplyTimeout is the default bound on a reply to an @mention.
+	ReplyTimeout = 2 * time.Minute
+
+	// bodyPreviewLength is how much of an ignored comment is logged.
+	bodyPreviewLength = 50
+
+	// reactionTimeout bounds each progress reaction request.
+	reactionTimeout = 10 * time.Second
+)
+
+// Handler serves webhook deliveries for one GitHub App. It verifies each
+// delivery, responds right away (Claude can take longer than GitHub's 10s
+// webhook timeout), and runs reviews and replies in the background.
+type Handler struct {
+	reviewer     *review.Reviewer
+	webhooks     *github.WebhookHandler
+	client       *github.Client
+	botName      string
+	storage      storage.Storage // nil skips installation records and event logging
+	logger       *slog.Logger
+	dryRun       bool // Print reviews to stdout instead of posting them
+	eventLogging bool // Log webhook outcomes to storage for analytics
+
+	progressReactions bool // React to PRs while they're reviewed
+
+	queue *ReviewQueue // nil starts every review right away
+
+	reviewTimeout time.Duration // Bound on each background review
+	replyTimeout  time.Duration // Bound on each background reply
+
+	wg sync.WaitGroup // Background reviews and replies
+}
+
+// New creates a webhook handler. store is optional.
+func New(reviewer *review.Reviewer, webhooks *github.WebhookHandler, client *github.Client, botName string, store storage.Storage, logger *slog.Logger) *Handler {
+	return &Handler{
+		reviewer: reviewer,
+		webhooks: webhooks,
+		client:   client,
+		botName:  botName,
+		storage:  store,
+		logger:   logger,
+
+		reviewTimeout: ReviewTimeout,
+		replyTimeout:  ReplyTimeout,
+	}
+}
+
+// SetDryRun makes reviews print to stdout instead of being posted. Off by default.
+func (h *Handler) SetDryRun(enabled bool) {
+	h.dryRun = enabled
+}
+
+// SetEventLogging enables logging webhook outcomes (no payloads) to storage for
+// analytics. Off by default, and a no-op without storage.
+func (h *Handler) SetEventLogging(enabled bool) {
+	h.eventLogging = enabled
+}
+
+// SetProgressReactions makes reviews started by pull request events react to the
+// PR with eyes while they run, replaced by a rocket once a review is posted.
+// Off by default.
+func (h *Handler) SetProgressReactions(enabled bool) {
+	h.progressReactions = enabled
+}
+
+// SetReviewQueue makes reviews started by pull request events wait their turn
+// in q instead of all starting at once. Off by default.
+func (h *Handler) SetReviewQueue(q *ReviewQueue) {
+	h.queue = q
+}
+
+// SetTimeouts sets how long a background review and reply may run (defaults
+// ReviewTimeout and ReplyTimeout). Values of 0 or less keep the current timeout.
+func (h *Handler) SetTimeouts(reviewTimeout, replyTimeout time.Duration) {
+	if reviewTimeout > 0 {
+		h.reviewTimeout = reviewTimeout
+	}
+	if replyTimeout > 0 {
+		h.replyTimeout = replyTimeout
+	}
+}
+
+// Wait blocks until the background reviews and replies started so far finish.
+func (h *Handler) Wait() {
+	h.wg.Wait()
+}
+
+// background runs fn in a goroutine tracked by Wait.
+func (h *Handler) background(fn func()) {
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		fn()
+	}()
+}
+
+// ServeHTTP handles a webhook delivery.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Read body (size-capped, before signature verification)
+	payload, err := h.webhooks.ReadPayload(w, r)
+	if errors.Is(err, github.ErrPayloadTooLarge) {
+		h.logger.Warn("webhook payload too large", "content_length", r.ContentLength)
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to read body", "error", err)
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Get event type
+	eventType := r.Header.Get("X-GitHub-Event")
+	if eventType == "" {
+		http.Error(w, "missing X-GitHub-Event header", http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("received webhook", "event", eventType, "size", len(payload))
+
+	// Verify signature
+	signature := r.Header.Get(h.webhooks.SignatureHeader())
+	if err := h.webhooks.VerifySignature(payload, signature); err != nil {
+		h.logger.Error("signature verification failed", "error", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch eventType {
+	case "ping":
+		h.logger.Info("received ping")
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "pong"})
+	case "pull_request":
+		h.handlePullRequest(w, payload, start)
+	case "pull_request_review_comment":
+		// @mentions in review threads
+		h.handleReviewComment(w, payload, start)
+	case "issue_comment":
+		// PR conversation commands such as "@shipitai review"
+		h.handleIssueComment(w, payload, start)
+	case "installation":
+		// App installed, uninstalled, suspended, or unsuspended
+		h.handleInstallation(w, payload, start)
+	default:
+		h.logger.Info("ignoring event", "type", eventType)
+		h.recordEvent(eventType, "", "", "", "ignored", start)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "event ignored"})
+	}
+}
+
+func (h *Handler) handlePullRequest(w http.ResponseWriter, payload []byte, start time.Time) {
+	const eventType = "pull_request"
+	event, err := h.webhooks.ParsePullRequestEvent(payload)
+	if err != nil {
+		h.logger.Error("failed to parse event", "error", err)
+		http.Error(w, "failed to parse event", http.StatusBadRequest)
+		return
+	}
+
+	// Check if we should process
+	if !h.webhooks.ShouldProcess(eventType, event) {
+		h.logger.Info("skipping event", "action", event.Action)
+		h.recordEvent(eventType, event.Action, event.Repository.Owner.Login, event.Repository.Name, "ignored", start)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "event skipped"})
+		return
+	}
+
+	h.logger.Info("processing PR",
+		"repo", event.Repository.FullName,
+		"pr", event.Number,
+		"action", event.Action,
+	)
+
+	// Respond immediately to GitHub, then review in the background
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "review started"})
+
+	input := &review.ReviewInput{
+		InstallationID: event.Installation.ID,
+		Owner:          event.Repository.Owner.Login,
+		Repo:           event.Repository.Name,
+		PRNumber:       event.Number,
+		PRTitle:        event.PullRequest.Title,
+		PRBody:         event.PullRequest.Body,
+		HeadSHA:        event.PullRequest.Head.SHA,
+		DefaultBranch:  event.Repository.DefaultBranch,
+		PRCreatedAt:    event.PullRequest.CreatedAt,
+		Labels:         event.PullRequest.LabelNames(),
+		Draft:          event.PullRequest.Draft,
+		Merged:         event.PullRequest.Merged,
+		DryRun:         h.dryRun,
+	}
+
+	h.ensureInstallation(context.Background(), event.Installation.ID, event.Repository.Owner.Login)
+
+	run := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), h.reviewTimeout)
+		defer cancel()
+
+		eyesID := h.markReviewStarted(input)
+		result, err := h.reviewer.Review(ctx, input)
+		h.markReviewDone(input, eyesID, result, err)
+		h.recordEvent(eventType, event.Action, input.Owner, input.Repo, review.Outcome(result, err), start)
+		h.logReviewResult(input, result, err)
+	}
+	if h.queue == nil {
+		h.background(run)
+		return
+	}
+	h.enqueueReview(input, run)
+}
+
+// enqueueReview queues a PR's review, tracked by Wait. A review already waiting
+// for the same PR is replaced, so only the latest head gets reviewed.
+func (h *Handler) enqueueReview(input *review.ReviewInput, run func()) {
+	key := fmt.Sprintf("%d/%s/%s#%d", input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+
+	h.wg.Add(1)
+	if !h.queue.enqueue(key, func() {
+		defer h.wg.Done()
+		run()
+	}) {
+		// The replaced review's Add is balanced when this one runs
+		h.wg.Done()
+		h.logger.Info("replaced queued review with newer head",
+			"repo", input.Owner+"/"+input.Repo,
+			"pr", input.PRNumber,
+			"head_sha", input.HeadSHA,
+		)
+		return
+	}
+	if n := h.queue.pending(); n > 0 {
+		h.logger.Info("review queued", "repo", input.Owner+"/"+input.Repo, "pr", input.PRNumber, "pending", n)
+	}
+}
+
+// markReviewStarted reacts to the PR with eyes so its author sees the review was
+// picked up. It returns the reaction's ID, or 0 if progress reactions are off or
+// the reaction couldn't be added; failures are only logged.
+func (h *Handler) markReviewStarted(input *review.ReviewInput) int64 {
+	if !h.progressReactions || input.DryRun {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reactionTimeout)
+	defer cancel()
+
+	reaction, err := h.client.CreateReaction(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, github.ReactionEyes)
+	if err != nil {
+		h.logger.Warn("failed to add progress reaction", "pr", input.PRNumber, "error", err)
+		return 0
+	}
+	return reaction.ID
+}
+
+// markReviewDone removes the eyes reaction added by markReviewStarted and, if a
+// review was posted, reacts with a rocket (GitHub has no checkmark reaction).
+// Failures are only logged.
+func (h *Handler) markReviewDone(input *review.ReviewInput, eyesID int64, result *review.ReviewResult, reviewErr error) {
+	if eyesID == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reactionTimeout)
+	defer cancel()
+
+	if err := h.client.DeleteReaction(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, eyesID); err != nil {
+		h.logger.Warn("failed to remove progress reaction", "pr", input.PRNumber, "error", err)
+	}
+	if review.Outcome(result, reviewErr) != "posted" {
+		return
+	}
+	if _, err := h.client.CreateReaction(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, github.ReactionRocket); err != nil {
+		h.logger.Warn("failed to add done reaction", "pr", input.PRNumber, "error", err)
+	}
+}
+
+// ensureInstallation records an installation the first time it sends a
+// pull request event. Self-hosted installations are always active.
+func (h *Handler) ensureInstallation(ctx context.Context, installationID int64, orgLogin string) {
+	if h.storage == nil {
+		return
+	}
+	if install, _ := h.storage.GetInstallation(ctx, installationID); install != nil {
+		return
+	}
+	install := &storage.Installation{
+		InstallationID: installationID,
+		OrgLogin:       orgLogin,
+		InstalledAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := h.storage.SaveInstallation(ctx, install); err != nil {
+		h.logger.Error("failed to save installation", "error", err)
+	}
+}
+
+// handleInstallation keeps the stored installation in step with the app's
+// lifecycle: created saves it, deleted removes it, and suspend/unsuspend toggle
+// whether it's active, so suspended installations aren't reviewed.
+func (h *Handler) handleInstallation(w http.ResponseWriter, payload []byte, start time.Time) {
+	const eventType = "installation"
+	event, err := h.webhooks.ParseInstallationEvent(payload)
+	if err != nil {
+		h.logger.Error("failed to parse installation event", "error", err)
+		http.Error(w, "failed to parse event", http.StatusBadRequest)
+		return
+	}
+
+	var orgLogin string
+	if event.Installation.Account != nil {
+		orgLogin = event.Installation.Account.Login
+	}
+	if h.storage == nil {
+		h.recordEvent(eventType, event.Action, orgLogin, "", "ignored", start)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "event ignored"})
+		return
+	}
+
+	ctx := context.Background()
+	id := event.Installation.ID
+	switch event.Action {
+	case "created":
+		install := &storage.Installation{
+			InstallationID: id,
+			OrgLogin:       orgLogin,
+			InstalledAt:    time.Now().UTC().Format(time.RFC3339),
+		}
+		if event.Installation.Account != nil {
+			install.AccountID = event.Installation.Account.ID
+		}
+		if event.Sender != nil {
+			install.InstalledBy = event.Sender.Login
+		}
+		err = h.storage.SaveInstallation(ctx, install)
+	case "deleted":
+		err = h.storage.DeleteInstallation(ctx, id)
+	case "suspend", "unsuspend":
+		// Installations from before these events were handled may not be stored yet
+		h.ensureInstallation(ctx, id, orgLogin)
+		err = h.storage.SetInstallationActive(ctx, id, event.Action == "unsuspend")
+	default:
+		h.logger.Info("skipping event", "action", event.Action)
+		h.recordEvent(eventType, event.Action, orgLogin, "", "ignored", start)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "event skipped"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to update installation", "installation_id", id, "action", event.Action, "error", err)
+		h.recordEvent(eventType, event.Action, orgLogin, "", "failed", start)
+		http.Error(w, "failed to update installation", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("updated installation", "installation_id", id, "action", event.Action, "org", orgLogin)
+	h.recordEvent(eventType, event.Action, orgLogin, "", "updated", start)
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "installation updated"})
+}
+
+func (h *Handler) handleReviewComment(w http.ResponseWriter, payload []byte, start time.Time) {
+	const eventType = "pull_request_review_comment"
+	event, err := h.webhooks.ParseReviewCommentEvent(payload)
+	if err != nil {
+		h.logger.Error("failed to parse review comment event", "error", err)
+		http.Error(w, "failed to parse event", http.StatusBadRequest)
+		return
+	}
+
+	// Check if we should process this comment
+	if !h.webhooks.ShouldProcessComment(event, h.botName) {
+		h.logger.Info("ignoring comment (no mention or not created)",
+			"action", event.Action,
+			"body_preview", truncate(event.Comment.Body, bodyPreviewLength),
+		)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "comment ignored"})
+		return
+	}
+
+	h.logger.Info("processing @mention",
+		"repo", event.Repository.FullName,
+		"pr", event.PullRequest.Number,
+		"comment_id", event.Comment.ID,
+		"user", event.Sender.Login,
+	)
+
+	// Respond immediately
+	jsonResponse(w, http.StatusOK, map[string]string{"message": "reply started"})
+
+	owner, repo := event.Repository.Owner.Login, event.Repository.Name
+	h.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), h.replyTimeout)
+		defer cancel()
+
+		// Plain @mention replies are treated as "explain"
+		command := github.ExtractCommand(event.Comment.Body, h.botName)
+		if command == "" {
+			command = github.CommandExplain
+		}
+		cmdReq := &review.CommandRequest{
+			InstallationID: event.Installation.ID,
+			Owner:          owner,
+			Repo:           repo,
+			DefaultBranch:  event.Repository.DefaultBranch,
+			Private:        event.Repository.Private,
+			Username:       event.Sender.Login,
+			Command:        command,
+		}
+		if !h.reviewer.IsCommandAllowed(ctx, cmdReq) {
+			h.logger.Info("command not permitted for user", "command", command, "user", event.Sender.Login)
+			h.recordEvent(eventType, command, owner, repo, "denied", start)
+			return
+		}
+
+		// Threads are identified by their first comment, which replies always point back to
+		threadCommentID := event.Comment.ID
+		if event.Comment.InReplyToID != 0 {
+			threadCommentID = event.Comment.InReplyToID
+		}
+
+		// "@shipitai resolve" closes the thread instead of replying
+		if command == github.CommandResolve {
+			if err := h.reviewer.ResolveCommentThread(ctx, event.Installation.ID, owner, repo, event.PullRequest.Number, threadCommentID); err != nil {
+				h.logger.Error("failed to resolve thread", "comment_id", event.Comment.ID, "error", err)
+				h.recordEvent(eventType, command, owner, repo, "failed", start)
+				return
+			}
+			h.logger.Info("resolved review thread", "comment_id", event.Comment.ID)
+			h.recordEvent(eventType, command, owner, repo, "resolved", start)
+			return
+		}
+
+		// Repos can opt out of conversational replies; mentions are ignored there
+		if !h.reviewer.RepliesEnabled(ctx, cmdReq) {
+			h.logger.Info("replies disabled for repository", "repo", event.Repository.FullName)
+			h.recordEvent(eventType, command, owner, repo, "disabled", start)
+			return
+		}
+
+		// Fetch all comments to build thread context
+		comments, err := h.client.GetReviewComments(ctx, event.Installation.ID, owner, repo, event.PullRequest.Number)
+		if err != nil {
+			h.logger.Error("failed to fetch comments", "error", err)
+			h.recordEvent(eventType, command, owner, repo, "failed", start)
+			return
+		}
+
+		input := &review.ReplyInput{
+			InstallationID: event.Installation.ID,
+			Owner:          owner,
+			Repo:           repo,
+			PRNumber:       event.PullRequest.Number,
+			CommentID:      event.Comment.ID,
+			ThreadID:       threadCommentID,
+			DiffHunk:       event.Comment.DiffHunk,
+			FilePath:       event.Comment.Path,
+			HeadSHA:        event.PullRequest.Head.SHA,
+			DefaultBranch:  event.Repository.DefaultBranch,
+			UserQuestion:   github.ExtractMentionContext(event.Comment.Body, h.botName),
+			ThreadContext:  review.BuildThreadContext(comments, event.Comment.ID),
+			Line:           event.Comment.Line,
+			Kind:           review.ClassifyThread(comments, event.Comment.ID, h.botName),
+		}
+
+		// Rapid mentions in the same thread are answered together by the first one
+		input, ok := h.reviewer.DebounceReply(ctx, threadCommentID, input)
+		if !ok {
+			h.logger.Info("mention folded into a pending reply", "comment_id", event.Comment.ID, "thread_id", threadCommentID)
+			h.recordEvent(eventType, command, owner, repo, "coalesced", start)
+			return
+		}
+
+		result, err := h.reviewer.Reply(ctx, input)
+		if err != nil {
+			h.logger.Error("reply failed", "error", err)
+			h.recordEvent(eventType, command, owner, repo, "failed", start)
+			return
+		}
+		if result.LimitReached {
+			h.recordEvent(eventType, command, owner, repo, "limited", start)
+		} else {
+			h.recordEvent(eventType, command, owner, repo, "replied", start)
+		}
+		if result.CommentID == 0 {
+			h.logger.Info("reply limit message already posted in thread", "thread_id", threadCommentID)
+			return
+		}
+
+		h.logger.Info("reply posted",
+			"comment_id", result.CommentID,
+			"url", result.CommentURL,
+		)
+	})
+}
+
+func (h *Handler) handleIssueComment(w http.ResponseWriter, payload []byte, start time.Time) {
+	const eventType = "issue_comment"
+	event, err := h.webhooks.ParseIssueCommentEvent(payload)
+	if err != nil {
+		h.logger.Error("failed to parse issue comment event", "error", err)
+		http.Error(w, "failed to parse event", http.StatusBadRequest)
+		return
+	}
+
+	if !h.webhooks.ShouldProcessIssueComment(event, h.botName) {
+		h.logger.Info("ignoring issue comment", "action", event.Action)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "comment ignored"})
+		return
+	}
+
+	command := github.ExtractCommand(event.Comment.Body, h.botName)
+	switch command {
+	case github.CommandReview, github.CommandRereview, github.CommandRetry, github.CommandIgnore, github.CommandUnignore, github.CommandSummarize:
+	default:
+		h.logger.Info("ignoring issue comment without review command", "command", command)
+		jsonResponse(w, http.StatusOK, map[string]string{"message": "comment ignored"})
+		return
+	}
+
+	h.logger.Info("processing review request",
+		"repo", event.Repository.FullName,
+		"pr", event.Issue.Number,
+		"user", event.Sender.Login,
+	)
+
+	// Respond immediately
+	started := "review started"
+	if command == github.CommandSummarize {
+		started = "summary started"
+	}
+	jsonResponse(w, http.StatusOK, map[string]string{"message": started})
+
+	h.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), h.reviewTimeout)
+		defer cancel()
+
+		owner := event.Repository.Owner.Login
+		repo := event.Repository.Name
+		prNumber := event.Issue.Number
+
+		allowed := h.reviewer.IsCommandAllowed(ctx, &review.CommandRequest{
+			InstallationID: event.Installation.ID,
+			Owner:          owner,
+			Repo:           repo,
+			DefaultBranch:  event.Repository.DefaultBranch,
+			Private:        event.Repository.Private,
+			Username:       event.Sender.Login,
+			Command:        command,
+		})
+		if !allowed {
+			h.logger.Info("review request denied for non-contributor", "user", event.Sender.Login)
+			h.recordEvent(eventType, command, owner, repo, "denied", start)
+			if _, err := h.client.CreateIssueComment(ctx, event.Installation.ID, owner, repo, prNumber, review.BuildUnauthorizedTriggerMessage()); err != nil {
+				h.logger.Error("failed to post unauthorized message", "error", err)
+			}
+			return
+		}
+
+		if command == github.CommandIgnore || command == github.CommandUnignore {
+			muted := command == github.CommandIgnore
+			if err := h.reviewer.SetPRMuted(ctx, event.Installation.ID, owner, repo, prNumber, muted); err != nil {
+				h.recordEvent(eventType, command, owner, repo, "failed", start)
+				h.logger.Error("failed to set PR muted", "error", err)
+				return
+			}
+			result := "unmuted"
+			if muted {
+				result = "muted"
+			}
+			h.recordEvent(eventType, command, owner, repo, result, start)
+			h.logger.Info("PR "+result, "pr", prNumber)
+			return
+		}
+
+		// The issue payload doesn't include the head SHA
+		pr, err := h.client.GetPullRequest(ctx, event.Installation.ID, owner, repo, prNumber)
+		if err != nil {
+			h.logger.Error("failed to fetch pull request", "error", err)
+			h.recordEvent(eventType, command, owner, repo, "failed", start)
+			return
+		}
+
+		input := &review.ReviewInput{
+			InstallationID: event.Installation.ID,
+			Owner:          owner,
+			Repo:           repo,
+			PRNumber:       prNumber,
+			PRTitle:        pr.Title,
+			PRBody:         pr.Body,
+			HeadSHA:        pr.Head.SHA,
+			DefaultBranch:  event.Repository.DefaultBranch,
+			PRCreatedAt:    pr.CreatedAt,
+			Labels:         pr.LabelNames(),
+			Draft:          pr.Draft,
+			Merged:         pr.Merged,
+			Requested:      true,
+			DryRun:         h.dryRun,
+		}
+
+		if command == github.CommandSummarize {
+			h.summarize(ctx, input, start)
+			return
+		}
+
+		run := h.reviewer.Review
+		if command == github.CommandRetry {
+			// Only re-review the files whose chunks failed last time
+			run = h.reviewer.RetryFailedFiles
+		}
+		result, err := run(ctx, input)
+		h.recordEvent(eventType, command, owner, repo, review.Outcome(result, err), start)
+		h.logReviewResult(input, result, err)
+	})
+}
+
+// summarize posts an overview of the PR for "@shipitai summarize".
+func (h *Handler) summarize(ctx context.Context, input *review.ReviewInput, start time.Time) {
+	const eventType = "issue_comment"
+	result, err := h.reviewer.Summarize(ctx, input)
+	switch {
+	case err != nil:
+		h.logger.Error("summary failed", "error", err)
+		h.recordEvent(eventType, github.CommandSummarize, input.Owner, input.Repo, "failed", start)
+	case result == nil:
+		h.logger.Info("summary skipped (not enabled)")
+		h.recordEvent(eventType, github.CommandSummarize, input.Owner, input.Repo, "disabled", start)
+	case result.SkipReason != "":
+		h.logger.Info("summary skipped", "reason", result.SkipReason)
+		h.recordEvent(eventType, github.CommandSummarize, input.Owner, input.Repo, "skipped_"+result.SkipReason, start)
+	case result.DryRun:
+		fmt.Printf("\n=== Dry run summary: %s/%s#%d ===\n\n%s\n", input.Owner, input.Repo, input.PRNumber, result.Summary)
+		h.recordEvent(eventType, github.CommandSummarize, input.Owner, input.Repo, "summarized", start)
+	default:
+		h.logger.Info("summary posted", "comment_id", result.CommentID, "url", result.CommentURL)
+		h.recordEvent(eventType, github.CommandSummarize, input.Owner, input.Repo, "summarized", start)
+	}
+}
+
+// logReviewResult logs how a background review ended, printing dry runs to stdout.
+func (h *Handler) logReviewResult(input *review.ReviewInput, result *review.ReviewResult, err error) {
+	switch {
+	case err != nil:
+		h.logger.Error("review failed", "error", err)
+	case result == nil:
+		h.logger.Info("review skipped (not enabled)")
+	case result.SkipReason != "":
+		h.logger.Info("review skipped", "reason", result.SkipReason)
+	case result.DryRun:
+		printDryRun(input, result)
+	default:
+		h.logger.Info("review posted",
+			"review_id", result.ReviewID,
+			"comments", result.CommentCount,
+			"url", result.ReviewURL,
+		)
+	}
+}
+
+// recordEvent logs a handled webhook's outcome for analytics when event logging is on.
+// It runs in the background so a slow or failing database never affects webhook handling.
+func (h *Handler) recordEvent(eventType, action, owner, repo, result string, start time.Time) {
+	if !h.eventLogging || h.storage == nil {
+		return
+	}
+	event := &storage.Event{
+		EventType:  eventType,
+		Action:     action,
+		Owner:      owner,
+		Repo:       repo,
+		Result:     result,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.storage.LogEvent(ctx, event); err != nil {
+			h.logger.Warn("failed to log event", "error", err)
+		}
+	}()
+}
+
+// printDryRun writes the review that would have been posted to stdout.
+func printDryRun(input *review.ReviewInput, result *review.ReviewResult) {
+	fmt.Printf("\n=== Dry run: %s/%s#%d (%s) ===\n\n%s\n", input.Owner, input.Repo, input.PRNumber, result.Approval, result.Summary)
+	for _, c := range result.Comments {
+		fmt.Printf("\n--- %s:%d [%s]\n%s\n", c.Path, c.Line, c.Severity, c.Body)
+	}
+	if result.Usage != nil {
+		fmt.Printf("\n=== %d comments, %d input / %d output tokens ===\n", result.CommentCount, result.Usage.InputTokens, result.Usage.OutputTokens)
+	}
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+func jsonResponse(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,393 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/review"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+const testSecret = "secret"
+
+// fakeGitHub serves a repository whose config disables reviews and records the
+// requests made against it.
+type fakeGitHub struct {
+	mu        sync.Mutex
+	requests  []string
+	replies   []string
+	reactions []string
+}
+
+func (f *fakeGitHub) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req.Method+" "+req.URL.Path)
+
+	body, status := `{"message":"Not Found"}`, http.StatusNotFound
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+		content := base64.StdEncoding.EncodeToString([]byte("enabled: false"))
+		body, status = fmt.Sprintf(`{"content":%q,"encoding":"base64"}`, content), http.StatusOK
+	case req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/pulls/7/comments"):
+		body, status = `[{"id":11,"body":"Possible nil dereference","user":{"login":"shipitai[bot]"}},
+			{"id":12,"in_reply_to_id":11,"body":"@shipitai why?","user":{"login":"alice"}}]`, http.StatusOK
+	case req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/replies"):
+		var reply github.CommentReply
+		_ = json.NewDecoder(req.Body).Decode(&reply)
+		f.replies = append(f.replies, reply.Body)
+		body, status = `{"id":13,"html_url":"https://github.com/owner/repo/pull/7#discussion_r13"}`, http.StatusCreated
+	case req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/issues/7/reactions"):
+		var reaction github.Reaction
+		_ = json.NewDecoder(req.Body).Decode(&reaction)
+		f.reactions = append(f.reactions, reaction.Content)
+		body, status = fmt.Sprintf(`{"id":%d,"content":%q}`, 20+len(f.reactions), reaction.Content), http.StatusCreated
+	case req.Method == "DELETE" && strings.Contains(req.URL.Path, "/issues/7/reactions/"):
+		body, status = "", http.StatusNoContent
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), Request: req}, nil
+}
+
+func (f *fakeGitHub) requested(suffix string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests {
+		if strings.HasSuffix(r, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestHandler returns a handler backed by the fake GitHub and in-memory storage.
+func newTestHandler(t *testing.T) (*Handler, *fakeGitHub, *memory.Memory) {
+	t.Helper()
+	gh := &fakeGitHub{}
+	client := github.NewClientWithTransport(gh)
+	store := memory.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reviewer := review.NewReviewer(client, "key", store, logger)
+	reviewer.SetReplyDebounceWindow(0)
+	return New(reviewer, github.NewWebhookHandler(testSecret), client, "shipitai", store, logger), gh, store
+}
+
+// deliver sends a signed webhook delivery to the handler and returns the response.
+func deliver(h *Handler, eventType, payload, secret string) *httptest.ResponseRecorder {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// message decodes the "message" field of a handler response.
+func message(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return resp["message"]
+}
+
+func prPayload(action string) string {
+	return fmt.Sprintf(`{"action":%q,"number":7,"pull_request":{"number":7,"title":"Fix","head":{"sha":"abc123"}},
+		"repository":{"name":"repo","full_name":"owner/repo","default_branch":"main","owner":{"login":"owner"}},
+		"installation":{"id":5}}`, action)
+}
+
+func TestHandlerPullRequestEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		eventType   string
+		payload     string
+		secret      string
+		wantStatus  int
+		wantMessage string
+		wantReview  bool
+	}{
+		{"ping", "ping", `{"zen":"hi"}`, testSecret, http.StatusOK, "pong", false},
+		{"invalid signature", "pull_request", prPayload("opened"), "wrong", http.StatusUnauthorized, "", false},
+		{"unhandled action", "pull_request", prPayload("closed"), testSecret, http.StatusOK, "event skipped", false},
+		{"unhandled event", "star", `{}`, testSecret, http.StatusOK, "event ignored", false},
+		{"opened", "pull_request", prPayload("opened"), testSecret, http.StatusOK, "review started", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, gh, store := newTestHandler(t)
+			rec := deliver(h, tt.eventType, tt.payload, tt.secret)
+			h.Wait()
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantMessage != "" {
+				if got := message(t, rec); got != tt.wantMessage {
+					t.Errorf("message = %q, want %q", got, tt.wantMessage)
+				}
+			}
+
+			reviewed := gh.requested("/repos/owner/repo/contents/.github/shipitai.yml")
+			if reviewed != tt.wantReview {
+				t.Errorf("review ran = %v, want %v", reviewed, tt.wantReview)
+			}
+			install, _ := store.GetInstallation(context.Background(), 5)
+			if (install != nil) != tt.wantReview {
+				t.Errorf("installation recorded = %v, want %v", install != nil, tt.wantReview)
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsNonPost(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/github", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandlerRejectsOversizedPayload(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    string
+		secret     string
+		wantStatus int
+	}{
+		{"within limit reaches signature check", `{"action":"x"}`, "wrong", http.StatusUnauthorized},
+		{"over limit rejected before signature check", strings.Repeat("a", 17), "wrong", http.StatusRequestEntityTooLarge},
+		{"over limit with valid signature", strings.Repeat("a", 17), testSecret, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _, _ := newTestHandler(t)
+			h.webhooks.SetMaxPayloadSize(16)
+			rec := deliver(h, "pull_request", tt.payload, tt.secret)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func reviewCommentPayload(body string) string {
+	return fmt.Sprintf(`{"action":"created","comment":{"id":12,"in_reply_to_id":11,"body":%q,"path":"main.go","line":3,"diff_hunk":"@@ -1 +1 @@"},
+		"pull_request":{"number":7,"head":{"sha":"abc123"}},
+		"repository":{"name":"repo","full_name":"owner/repo","default_branch":"main","private":true,"owner":{"login":"owner"}},
+		"installation":{"id":5},"sender":{"login":"alice"}}`, body)
+}
+
+func TestHandlerReviewComment(t *testing.T) {
+	claude := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":          "msg_1",
+			"type":        "message",
+			"role":        "assistant",
+			"model":       "claude-test",
+			"content":     []map[string]any{{"type": "text", "text": "It can be nil when the cache misses."}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]any{"input_tokens": 10, "output_tokens": 5},
+		})
+	}))
+	defer claude.Close()
+	t.Setenv("ANTHROPIC_BASE_URL", claude.URL)
+
+	t.Run("comment without mention is ignored", func(t *testing.T) {
+		h, gh, _ := newTestHandler(t)
+		rec := deliver(h, "pull_request_review_comment", reviewCommentPayload("Thanks, fixed"), testSecret)
+		h.Wait()
+
+		if got := message(t, rec); got != "comment ignored" {
+			t.Errorf("message = %q, want %q", got, "comment ignored")
+		}
+		if len(gh.replies) != 0 {
+			t.Errorf("posted replies %v, want none", gh.replies)
+		}
+	})
+
+	t.Run("mention gets a reply in the thread", func(t *testing.T) {
+		h, gh, _ := newTestHandler(t)
+		rec := deliver(h, "pull_request_review_comment", reviewCommentPayload("@shipitai why?"), testSecret)
+		h.Wait()
+
+		if got := message(t, rec); got != "reply started" {
+			t.Errorf("message = %q, want %q", got, "reply started")
+		}
+		if !gh.requested("/repos/owner/repo/pulls/7/comments/12/replies") {
+			t.Errorf("requests = %v, want a reply to comment 12", gh.requests)
+		}
+		if len(gh.replies) != 1 || gh.replies[0] != "It can be nil when the cache misses." {
+			t.Errorf("replies = %v, want Claude's answer", gh.replies)
+		}
+	})
+}
+
+func installationPayload(action string) string {
+	return fmt.Sprintf(`{"action":%q,"installation":{"id":5,"account":{"id":9,"login":"owner"}},"sender":{"login":"alice"}}`, action)
+}
+
+func TestHandlerInstallationLifecycle(t *testing.T) {
+	h, _, store := newTestHandler(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		action     string
+		wantStored bool
+		wantActive bool
+	}{
+		{"created", true, true},
+		{"suspend", true, false},
+		{"unsuspend", true, true},
+		{"deleted", false, false},
+	}
+	for _, step := range steps {
+		rec := deliver(h, "installation", installationPayload(step.action), testSecret)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d; body: %s", step.action, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if got := message(t, rec); got != "installation updated" {
+			t.Errorf("%s: message = %q, want %q", step.action, got, "installation updated")
+		}
+
+		install, err := store.GetInstallation(ctx, 5)
+		if err != nil {
+			t.Fatalf("%s: GetInstallation: %v", step.action, err)
+		}
+		if (install != nil) != step.wantStored {
+			t.Fatalf("%s: installation stored = %v, want %v", step.action, install != nil, step.wantStored)
+		}
+		if install == nil {
+			continue
+		}
+		if install.Active != step.wantActive {
+			t.Errorf("%s: active = %v, want %v", step.action, install.Active, step.wantActive)
+		}
+		if install.OrgLogin != "owner" || install.InstalledBy != "alice" {
+			t.Errorf("%s: installation = %+v, want org owner installed by alice", step.action, install)
+		}
+	}
+}
+
+func TestHandlerInstallationSuspendUnknown(t *testing.T) {
+	h, _, store := newTestHandler(t)
+	rec := deliver(h, "installation", installationPayload("suspend"), testSecret)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	install, _ := store.GetInstallation(context.Background(), 5)
+	if install == nil || install.Active {
+		t.Errorf("installation = %+v, want it recorded as suspended", install)
+	}
+}
+
+func TestHandlerProgressReactions(t *testing.T) {
+	h, gh, _ := newTestHandler(t)
+	h.SetProgressReactions(true)
+
+	// Reviews are disabled by the repository's config, so eyes are removed without a rocket
+	rec := deliver(h, "pull_request", prPayload("opened"), testSecret)
+	h.Wait()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if len(gh.reactions) != 1 || gh.reactions[0] != github.ReactionEyes {
+		t.Errorf("reactions = %v, want [eyes]", gh.reactions)
+	}
+	if !gh.requested("DELETE /repos/owner/repo/issues/7/reactions/21") {
+		t.Error("eyes reaction was not removed")
+	}
+
+	// A posted review gets a rocket
+	input := &review.ReviewInput{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: 7}
+	h.markReviewDone(input, 21, &review.ReviewResult{ReviewID: 1}, nil)
+	if len(gh.reactions) != 2 || gh.reactions[1] != github.ReactionRocket {
+		t.Errorf("reactions = %v, want a rocket after eyes", gh.reactions)
+	}
+
+	// Off by default
+	h, gh, _ = newTestHandler(t)
+	deliver(h, "pull_request", prPayload("opened"), testSecret)
+	h.Wait()
+	if len(gh.reactions) != 0 {
+		t.Errorf("reactions = %v, want none", gh.reactions)
+	}
+}
+
+func TestHandlerQueuedReviewsCoalesce(t *testing.T) {
+	h, gh, _ := newTestHandler(t)
+	q := NewReviewQueue(1)
+	h.SetReviewQueue(q)
+
+	// Occupy the only worker so deliveries queue up behind it
+	started, release := make(chan struct{}), make(chan struct{})
+	q.enqueue("busy", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	for i := 0; i < 3; i++ {
+		if rec := deliver(h, "pull_request", prPayload("synchronize"), testSecret); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	}
+	if got := q.pending(); got != 1 {
+		t.Errorf("pending() = %d, want the syncs collapsed to 1", got)
+	}
+	close(release)
+	h.Wait()
+
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	configFetches := 0
+	for _, r := range gh.requests {
+		if strings.HasSuffix(r, "/contents/.github/shipitai.yml") {
+			configFetches++
+		}
+	}
+	if configFetches != 1 {
+		t.Errorf("ran %d reviews, want 1", configFetches)
+	}
+}
+
+func TestHandlerIgnoresBotComments(t *testing.T) {
+	h, gh, _ := newTestHandler(t)
+
+	// A private repository allows every command, so only the sender check stops this
+	payload := `{"action":"created","issue":{"number":7,"pull_request":{"url":"https://api.github.com/repos/owner/repo/pulls/7"}},
+		"comment":{"id":1,"body":"Comment ` + "`@shipitai review`" + ` to try again."},
+		"repository":{"name":"repo","full_name":"owner/repo","private":true,"owner":{"login":"owner"}},
+		"installation":{"id":5},"sender":{"login":"shipitai[bot]","type":"Bot"}}`
+	rec := deliver(h, "issue_comment", payload, testSecret)
+	h.Wait()
+
+	if got := message(t, rec); got != "comment ignored" {
+		t.Errorf("message = %q, want %q", got, "comment ignored")
+	}
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	if len(gh.requests) != 0 {
+		t.Errorf("bot comment made GitHub requests %v, want none", gh.requests)
+	}
+}
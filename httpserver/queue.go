@@ -0,0 +1,76 @@
+package httpserver
+
+import "sync"
+
+// ReviewQueue runs queued reviews on a bounded number of workers, so a burst of
+// events (e.g. rebasing a stack of PRs) doesn't start every review at once.
+// Queuing a review for a PR that already has one waiting replaces the waiting
+// review, so a burst of pushes to one PR collapses to a review of its latest
+// head. A queue may be shared by several handlers to cap reviews process-wide.
+type ReviewQueue struct {
+	maxConcurrent int
+
+	mu      sync.Mutex
+	running int               // Workers currently draining the queue
+	order   []string          // Keys of waiting reviews, oldest first
+	waiting map[string]func() // Waiting review per key
+}
+
+// NewReviewQueue creates a queue running at most maxConcurrent reviews at once.
+// Values below 1 allow one review at a time.
+func NewReviewQueue(maxConcurrent int) *ReviewQueue {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &ReviewQueue{
+		maxConcurrent: maxConcurrent,
+		waiting:       make(map[string]func()),
+	}
+}
+
+// enqueue queues run under key, starting a worker if one is free. If a review
+// is already waiting under key, run replaces it, keeping its place in the
+// queue, and enqueue returns false; the replaced review never runs.
+func (q *ReviewQueue) enqueue(key string, run func()) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.waiting[key]; ok {
+		q.waiting[key] = run
+		return false
+	}
+	q.waiting[key] = run
+	q.order = append(q.order, key)
+
+	if q.running < q.maxConcurrent {
+		q.running++
+		go q.work()
+	}
+	return true
+}
+
+// pending returns how many reviews are waiting for a worker.
+func (q *ReviewQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.order)
+}
+
+// work runs waiting reviews oldest first until none are left.
+func (q *ReviewQueue) work() {
+	for {
+		q.mu.Lock()
+		if len(q.order) == 0 {
+			q.running--
+			q.mu.Unlock()
+			return
+		}
+		key := q.order[0]
+		q.order = q.order[1:]
+		run := q.waiting[key]
+		delete(q.waiting, key)
+		q.mu.Unlock()
+
+		run()
+	}
+}
@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReviewQueueCapsConcurrency(t *testing.T) {
+	q := NewReviewQueue(2)
+
+	var (
+		mu                 sync.Mutex
+		running, maxActive int
+		wg                 sync.WaitGroup
+	)
+	started, release := make(chan struct{}, 6), make(chan struct{})
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		q.enqueue(string(rune('a'+i)), func() {
+			defer wg.Done()
+			mu.Lock()
+			running++
+			maxActive = max(maxActive, running)
+			mu.Unlock()
+			started <- struct{}{}
+
+			<-release
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+	}
+
+	// Wait for both workers to pick up a review before releasing them all
+	<-started
+	<-started
+	if got := q.pending(); got != 4 {
+		t.Errorf("pending() = %d, want 4 waiting behind 2 workers", got)
+	}
+	close(release)
+	wg.Wait()
+
+	if maxActive != 2 {
+		t.Errorf("ran %d reviews at once, want 2", maxActive)
+	}
+}
+
+func TestReviewQueueCoalescesSamePR(t *testing.T) {
+	q := NewReviewQueue(1)
+
+	var (
+		mu  sync.Mutex
+		ran []string
+	)
+	record := func(name string, done chan<- struct{}) func() {
+		return func() {
+			mu.Lock()
+			ran = append(ran, name)
+			mu.Unlock()
+			if done != nil {
+				close(done)
+			}
+		}
+	}
+
+	// Occupy the only worker so the following reviews wait
+	started, release := make(chan struct{}), make(chan struct{})
+	q.enqueue("1/owner/repo#1", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	if !q.enqueue("1/owner/repo#7", record("pr7-first", nil)) {
+		t.Fatal("enqueue() of a new PR = false, want true")
+	}
+	done := make(chan struct{})
+	q.enqueue("1/owner/repo#8", record("pr8", done))
+	if q.enqueue("1/owner/repo#7", record("pr7-latest", nil)) {
+		t.Fatal("enqueue() of a waiting PR = true, want false")
+	}
+	if got := q.pending(); got != 2 {
+		t.Errorf("pending() = %d, want 2", got)
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued reviews didn't run")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The replacement keeps the replaced review's place in line
+	if len(ran) != 2 || ran[0] != "pr7-latest" || ran[1] != "pr8" {
+		t.Errorf("ran %v, want [pr7-latest pr8]", ran)
+	}
+}
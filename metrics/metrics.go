@@ -0,0 +1,129 @@
+// Package metrics defines the Prometheus metrics exported by ShipItAI.
+//
+// Metrics are always recorded but only exposed when a server mounts Handler,
+// so instrumented code doesn't need to know whether metrics are enabled.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shipitai"
+
+var (
+	reviewsStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_started_total",
+		Help:      "Reviews started, by installation.",
+	}, []string{"installation"})
+
+	reviewsCompleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_completed_total",
+		Help:      "Reviews finished without error, by installation and result (posted, disabled, skipped_<reason>).",
+	}, []string{"installation", "result"})
+
+	reviewsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_failed_total",
+		Help:      "Reviews that returned an error, by installation.",
+	}, []string{"installation"})
+
+	commentsPosted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comments_posted_total",
+		Help:      "Review comments posted, by installation.",
+	}, []string{"installation"})
+
+	tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "claude_tokens_total",
+		Help:      "Claude tokens used by reviews, by installation and type (input, output).",
+	}, []string{"installation", "type"})
+
+	claudeLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "claude_request_duration_seconds",
+		Help:      "Claude API review call latency, including retries, by result (success, error).",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"result"})
+
+	chunks = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "review_chunks",
+		Help:      "Number of chunks large diffs are split into.",
+		Buckets:   []float64{2, 3, 4, 6, 8, 12, 16, 24, 32},
+	})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		reviewsStarted,
+		reviewsCompleted,
+		reviewsFailed,
+		commentsPosted,
+		tokens,
+		claudeLatency,
+		chunks,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns an HTTP handler serving all metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ReviewStarted records the start of a review.
+func ReviewStarted(installationID int64) {
+	reviewsStarted.WithLabelValues(installationLabel(installationID)).Inc()
+}
+
+// ReviewCompleted records a review that finished without error with the given
+// result, and the comments it posted.
+func ReviewCompleted(installationID int64, result string, comments int) {
+	installation := installationLabel(installationID)
+	reviewsCompleted.WithLabelValues(installation, result).Inc()
+	if comments > 0 {
+		commentsPosted.WithLabelValues(installation).Add(float64(comments))
+	}
+}
+
+// ReviewFailed records a review that returned an error.
+func ReviewFailed(installationID int64) {
+	reviewsFailed.WithLabelValues(installationLabel(installationID)).Inc()
+}
+
+// TokensUsed records Claude token usage for an installation.
+func TokensUsed(installationID int64, input, output int64) {
+	installation := installationLabel(installationID)
+	tokens.WithLabelValues(installation, "input").Add(float64(input))
+	tokens.WithLabelValues(installation, "output").Add(float64(output))
+}
+
+// ClaudeRequest records the latency of a Claude API call.
+func ClaudeRequest(d time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	claudeLatency.WithLabelValues(result).Observe(d.Seconds())
+}
+
+// ReviewChunked records how many chunks a large diff was split into.
+func ReviewChunked(count int) {
+	chunks.Observe(float64(count))
+}
+
+func installationLabel(installationID int64) string {
+	return strconv.FormatInt(installationID, 10)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReviewCompleted(t *testing.T) {
+	ReviewCompleted(42, "posted", 3)
+	ReviewCompleted(42, "posted", 2)
+	ReviewCompleted(42, "skipped_revert", 0)
+
+	if got := testutil.ToFloat64(reviewsCompleted.WithLabelValues("42", "posted")); got != 2 {
+		t.Errorf("reviews_completed_total{posted} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(reviewsCompleted.WithLabelValues("42", "skipped_revert")); got != 1 {
+		t.Errorf("reviews_completed_total{skipped_revert} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(commentsPosted.WithLabelValues("42")); got != 5 {
+		t.Errorf("comments_posted_total = %v, want 5", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ReviewStarted(7)
+	TokensUsed(7, 1200, 300)
+	ClaudeRequest(2*time.Second, nil)
+	ClaudeRequest(time.Second, errors.New("boom"))
+	ReviewChunked(3)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`shipitai_reviews_started_total{installation="7"} 1`,
+		`shipitai_claude_tokens_total{installation="7",type="input"} 1200`,
+		`shipitai_claude_tokens_total{installation="7",type="output"} 300`,
+		`shipitai_claude_request_duration_seconds_count{result="error"} 1`,
+		`shipitai_review_chunks_count 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/storage"
+	"golang.org/x/sync/errgroup"
+)
+
+// analyzer is an independent analysis of a pull request (the Claude review,
+// deterministic scanners, description checks). Analyzers run concurrently and
+// their findings are merged into a single review.
+type analyzer interface {
+	name() string
+	analyze(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error)
+}
+
+// analyzerFunc adapts a function to the analyzer interface.
+type analyzerFunc struct {
+	label string
+	fn    func(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error)
+}
+
+func (a analyzerFunc) name() string { return a.label }
+
+func (a analyzerFunc) analyze(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error) {
+	return a.fn(ctx)
+}
+
+// runAnalyzers runs all analyzers concurrently and merges their findings.
+// Results are merged in analyzer order, regardless of completion order, so the
+// primary analyzer's summary always comes first. Any analyzer error fails the run.
+func runAnalyzers(ctx context.Context, analyzers []analyzer) (*ClaudeResponse, *storage.TokenUsage, error) {
+	results := make([]*ClaudeResponse, len(analyzers))
+	usages := make([]*storage.TokenUsage, len(analyzers))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, a := range analyzers {
+		g.Go(func() error {
+			resp, usage, err := a.analyze(gctx)
+			if err != nil {
+				return fmt.Errorf("%s: %w", a.name(), err)
+			}
+			results[i] = resp
+			usages[i] = usage
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return mergeAnalyzerResults(results), aggregateUsage(usages), nil
+}
+
+// mergeAnalyzerResults combines analyzer responses: summaries are joined,
+// comments concatenated, and the strictest approval wins. Analyzers that
+// express no approval opinion (empty string) don't affect the result.
+func mergeAnalyzerResults(results []*ClaudeResponse) *ClaudeResponse {
+	merged := &ClaudeResponse{Comments: []ClaudeComment{}}
+	var summaries []string
+
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		if r.Summary != "" {
+			summaries = append(summaries, r.Summary)
+		}
+		merged.Comments = append(merged.Comments, r.Comments...)
+		merged.Extras = mergeExtras(merged.Extras, r.Extras)
+		merged.FailedFiles = append(merged.FailedFiles, r.FailedFiles...)
+		merged.Unplaced = append(merged.Unplaced, r.Unplaced...)
+		if r.Approval == "" {
+			continue
+		}
+		if merged.Approval == "" {
+			merged.Approval = r.Approval
+		} else {
+			merged.Approval = mergeApproval(merged.Approval, r.Approval)
+		}
+	}
+
+	merged.Summary = strings.Join(summaries, "\n\n")
+	if merged.Approval == "" {
+		merged.Approval = "comment"
+	}
+	return merged
+}
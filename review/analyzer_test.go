@@ -0,0 +1,134 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shipitai/shipitai/storage"
+)
+
+// delayedAnalyzer returns a fixed response after a delay.
+func delayedAnalyzer(name string, delay time.Duration, resp *ClaudeResponse, inputTokens int64) analyzer {
+	return analyzerFunc{label: name, fn: func(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		return resp, &storage.TokenUsage{InputTokens: inputTokens}, nil
+	}}
+}
+
+func TestRunAnalyzers(t *testing.T) {
+	review := &ClaudeResponse{
+		Summary:  "Code looks reasonable.",
+		Comments: []ClaudeComment{{Path: "main.go", Line: 10, Body: "Handle this error", Severity: "medium"}},
+		Approval: "comment",
+	}
+	secrets := &ClaudeResponse{
+		Comments: []ClaudeComment{{Path: "config.go", Line: 3, Body: "Possible hardcoded token", Severity: "critical"}},
+		Approval: "request_changes",
+	}
+	description := &ClaudeResponse{
+		Summary: "PR description is empty.",
+	}
+
+	tests := []struct {
+		name   string
+		delays [3]time.Duration // claude review, secret scan, description check
+	}{
+		{"primary finishes last", [3]time.Duration{30 * time.Millisecond, 0, 10 * time.Millisecond}},
+		{"primary finishes first", [3]time.Duration{0, 30 * time.Millisecond, 10 * time.Millisecond}},
+		{"all together", [3]time.Duration{0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzers := []analyzer{
+				delayedAnalyzer("claude_review", tt.delays[0], review, 100),
+				delayedAnalyzer("secret_scan", tt.delays[1], secrets, 0),
+				delayedAnalyzer("description_check", tt.delays[2], description, 0),
+			}
+
+			merged, usage, err := runAnalyzers(context.Background(), analyzers)
+			if err != nil {
+				t.Fatalf("runAnalyzers() error = %v", err)
+			}
+
+			if len(merged.Comments) != 2 {
+				t.Fatalf("Comments length = %d, want 2", len(merged.Comments))
+			}
+			if merged.Comments[0].Path != "main.go" || merged.Comments[1].Path != "config.go" {
+				t.Errorf("Comments not in analyzer order: %+v", merged.Comments)
+			}
+			wantSummary := "Code looks reasonable.\n\nPR description is empty."
+			if merged.Summary != wantSummary {
+				t.Errorf("Summary = %q, want %q", merged.Summary, wantSummary)
+			}
+			if merged.Approval != "request_changes" {
+				t.Errorf("Approval = %q, want request_changes", merged.Approval)
+			}
+			if usage.InputTokens != 100 {
+				t.Errorf("InputTokens = %d, want 100", usage.InputTokens)
+			}
+		})
+	}
+}
+
+func TestRunAnalyzersError(t *testing.T) {
+	failing := analyzerFunc{label: "claude_review", fn: func(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error) {
+		return nil, nil, errors.New("api unavailable")
+	}}
+	analyzers := []analyzer{failing, delayedAnalyzer("secret_scan", time.Second, &ClaudeResponse{}, 0)}
+
+	_, _, err := runAnalyzers(context.Background(), analyzers)
+	if err == nil {
+		t.Fatal("runAnalyzers() expected error")
+	}
+	if err.Error() != "claude_review: api unavailable" {
+		t.Errorf("error = %q, want analyzer name prefix", err.Error())
+	}
+}
+
+func TestMergeAnalyzerResultsApproval(t *testing.T) {
+	tests := []struct {
+		name      string
+		approvals []string
+		want      string
+	}{
+		{"approve with no-opinion analyzer", []string{"approve", ""}, "approve"},
+		{"strictest wins", []string{"approve", "comment"}, "comment"},
+		{"no opinions", []string{"", ""}, "comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []*ClaudeResponse
+			for _, a := range tt.approvals {
+				results = append(results, &ClaudeResponse{Approval: a})
+			}
+			if got := mergeAnalyzerResults(results).Approval; got != tt.want {
+				t.Errorf("Approval = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAnalyzerResultsExtras(t *testing.T) {
+	results := []*ClaudeResponse{
+		{Extras: map[string]json.RawMessage{"estimated_risk": json.RawMessage(`"low"`)}},
+		{Extras: map[string]json.RawMessage{"estimated_risk": json.RawMessage(`"high"`), "owner": json.RawMessage(`"db-team"`)}},
+		{},
+	}
+
+	merged := mergeAnalyzerResults(results)
+	if string(merged.Extras["estimated_risk"]) != `"low"` {
+		t.Errorf("estimated_risk = %s, want first analyzer's value", merged.Extras["estimated_risk"])
+	}
+	if string(merged.Extras["owner"]) != `"db-team"` {
+		t.Errorf("owner = %s, want \"db-team\"", merged.Extras["owner"])
+	}
+}
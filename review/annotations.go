@@ -0,0 +1,179 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+const (
+	// MaxAnnotationsPerRequest is the Checks API limit on annotations per request.
+	MaxAnnotationsPerRequest = 50
+
+	// CheckRunName is the name of the check run ShipItAI reports findings on.
+	CheckRunName = "ShipItAI Review"
+
+	// annotationsNote is the review body line pointing authors at the check run.
+	annotationsNote = "Findings are posted as annotations on the **" + CheckRunName + "** check."
+)
+
+// checkRunConclusion maps the review's approval state to a check run conclusion,
+// so teams can gate merges on the check.
+func checkRunConclusion(approval string) string {
+	switch approval {
+	case "approve":
+		return "success"
+	case "request_changes":
+		return "failure"
+	default:
+		return "neutral"
+	}
+}
+
+// checkRunSummary builds the check run summary: the review summary followed by a
+// count of findings by severity.
+func checkRunSummary(summary string, comments []ClaudeComment, scale config.SeverityScale) string {
+	counts := make(map[string]int)
+	for _, c := range comments {
+		counts[c.Severity]++
+	}
+
+	var parts []string
+	for _, severity := range scale.Names() {
+		if n := counts[severity]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, severity))
+		}
+	}
+
+	findings := "**Findings:** none"
+	if len(parts) > 0 {
+		findings = "**Findings:** " + strings.Join(parts, ", ")
+	}
+	if strings.TrimSpace(summary) == "" {
+		return findings
+	}
+	return summary + "\n\n" + findings
+}
+
+// annotationLevel maps a comment severity to a Check Run annotation level by the
+// approval its level leads to on the scale.
+func annotationLevel(scale config.SeverityScale, severity string) string {
+	switch scale.Approval(severity) {
+	case config.ApprovalRequestChanges:
+		return "failure"
+	case config.ApprovalApprove:
+		return "notice"
+	default:
+		return "warning"
+	}
+}
+
+// toAnnotations converts review comments into Check Run annotations.
+func toAnnotations(comments []ClaudeComment, scale config.SeverityScale) []github.CheckRunAnnotation {
+	annotations := make([]github.CheckRunAnnotation, len(comments))
+	for i, c := range comments {
+		severity := scale.Level(c.Severity).Name
+		annotations[i] = github.CheckRunAnnotation{
+			Path:            c.Path,
+			StartLine:       c.Line,
+			EndLine:         c.Line,
+			AnnotationLevel: annotationLevel(scale, severity),
+			Message:         c.Body,
+			Title:           fmt.Sprintf("%s severity", severity),
+		}
+	}
+	return annotations
+}
+
+// batchAnnotations splits annotations into batches of at most size.
+func batchAnnotations(annotations []github.CheckRunAnnotation, size int) [][]github.CheckRunAnnotation {
+	var batches [][]github.CheckRunAnnotation
+	for start := 0; start < len(annotations); start += size {
+		end := min(start+size, len(annotations))
+		batches = append(batches, annotations[start:end])
+	}
+	return batches
+}
+
+// postAnnotations creates a completed check run on the head commit carrying the
+// findings as annotations. The first batch is sent with the create request and the
+// rest are appended with updates, since the Checks API caps annotations per request.
+func (r *Reviewer) postAnnotations(ctx context.Context, input *ReviewInput, scale config.SeverityScale, summary, approval string, comments []ClaudeComment) (*github.CheckRun, error) {
+	batches := batchAnnotations(toAnnotations(comments, scale), MaxAnnotationsPerRequest)
+	output := func(batch []github.CheckRunAnnotation) *github.CheckRunOutput {
+		return &github.CheckRunOutput{
+			Title:       CheckRunName,
+			Summary:     checkRunSummary(summary, comments, scale),
+			Annotations: batch,
+		}
+	}
+
+	var first []github.CheckRunAnnotation
+	if len(batches) > 0 {
+		first = batches[0]
+	}
+	checkRun, err := r.githubClient.CreateCheckRun(ctx, input.InstallationID, input.Owner, input.Repo, &github.CheckRunRequest{
+		Name:       CheckRunName,
+		HeadSHA:    input.HeadSHA,
+		Status:     "completed",
+		Conclusion: checkRunConclusion(approval),
+		Output:     output(first),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(batches); i++ {
+		if _, err := r.githubClient.UpdateCheckRun(ctx, input.InstallationID, input.Owner, input.Repo, checkRun.ID, &github.CheckRunRequest{
+			Output: output(batches[i]),
+		}); err != nil {
+			return checkRun, fmt.Errorf("failed to add annotation batch %d: %w", i+1, err)
+		}
+	}
+
+	return checkRun, nil
+}
+
+// publishAnnotations posts findings as Check Run annotations when the config asks for it.
+// Returns true if the findings were published there and shouldn't also be posted inline.
+// Failures are non-fatal: the caller falls back to inline comments.
+func (r *Reviewer) publishAnnotations(ctx context.Context, input *ReviewInput, cfg *config.Config, summary, approval string, comments []ClaudeComment) bool {
+	if !cfg.UsesAnnotations() || len(comments) == 0 {
+		return false
+	}
+
+	checkRun, err := r.postAnnotations(ctx, input, cfg.SeverityScale, summary, approval, comments)
+	if checkRun == nil {
+		r.logger.Warn("failed to create check run, posting inline comments instead", "error", err)
+		return false
+	}
+	if err != nil {
+		r.logger.Warn("check run created but some annotations failed", "check_run_id", checkRun.ID, "error", err)
+	}
+	r.logger.Info("posted findings as check run annotations", "check_run_id", checkRun.ID, "annotations", len(comments))
+	return true
+}
+
+// publishCheckRun reports the review outcome as a completed check run on the head
+// commit. Failures are logged and don't fail the review.
+func (r *Reviewer) publishCheckRun(ctx context.Context, input *ReviewInput, cfg *config.Config, summary, approval string, comments []ClaudeComment) {
+	conclusion := checkRunConclusion(approval)
+	checkRun, err := r.githubClient.CreateCheckRun(ctx, input.InstallationID, input.Owner, input.Repo, &github.CheckRunRequest{
+		Name:       CheckRunName,
+		HeadSHA:    input.HeadSHA,
+		Status:     "completed",
+		Conclusion: conclusion,
+		Output: &github.CheckRunOutput{
+			Title:   CheckRunName,
+			Summary: checkRunSummary(summary, comments, cfg.SeverityScale),
+		},
+	})
+	if err != nil {
+		r.logger.Warn("failed to create check run", "error", err)
+		return
+	}
+	r.logger.Info("created check run", "check_run_id", checkRun.ID, "conclusion", conclusion)
+}
@@ -0,0 +1,147 @@
+package review
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnnotationLevel(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     string
+	}{
+		{"critical", "failure"},
+		{"high", "failure"},
+		{"medium", "warning"},
+		{"low", "notice"},
+		{"", "warning"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			if got := annotationLevel(nil, tt.severity); got != tt.want {
+				t.Errorf("annotationLevel(%q) = %q, want %q", tt.severity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToAnnotations(t *testing.T) {
+	comments := []ClaudeComment{
+		{Path: "main.go", Line: 10, Body: "SQL injection", Severity: "critical"},
+		{Path: "util.go", Line: 3, Body: "Typo in name", Severity: "low"},
+	}
+
+	got := toAnnotations(comments, nil)
+	if len(got) != 2 {
+		t.Fatalf("toAnnotations() returned %d annotations, want 2", len(got))
+	}
+	first := got[0]
+	if first.Path != "main.go" || first.StartLine != 10 || first.EndLine != 10 {
+		t.Errorf("annotation location = %s:%d-%d, want main.go:10-10", first.Path, first.StartLine, first.EndLine)
+	}
+	if first.AnnotationLevel != "failure" || first.Message != "SQL injection" {
+		t.Errorf("annotation = %+v, want failure level with comment body", first)
+	}
+	if got[1].AnnotationLevel != "notice" {
+		t.Errorf("AnnotationLevel = %q, want notice", got[1].AnnotationLevel)
+	}
+}
+
+func TestBatchAnnotations(t *testing.T) {
+	tests := []struct {
+		count       int
+		wantBatches []int
+	}{
+		{0, nil},
+		{1, []int{1}},
+		{50, []int{50}},
+		{51, []int{50, 1}},
+		{120, []int{50, 50, 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d annotations", tt.count), func(t *testing.T) {
+			comments := make([]ClaudeComment, tt.count)
+			for i := range comments {
+				comments[i] = ClaudeComment{Path: "main.go", Line: i + 1, Body: "finding"}
+			}
+
+			batches := batchAnnotations(toAnnotations(comments, nil), MaxAnnotationsPerRequest)
+			if len(batches) != len(tt.wantBatches) {
+				t.Fatalf("got %d batches, want %d", len(batches), len(tt.wantBatches))
+			}
+			next := 1
+			for i, b := range batches {
+				if len(b) != tt.wantBatches[i] {
+					t.Errorf("batch %d size = %d, want %d", i, len(b), tt.wantBatches[i])
+				}
+				for _, a := range b {
+					if a.StartLine != next {
+						t.Fatalf("annotation out of order: line %d, want %d", a.StartLine, next)
+					}
+					next++
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRunConclusion(t *testing.T) {
+	tests := []struct {
+		approval string
+		want     string
+	}{
+		{"approve", "success"},
+		{"request_changes", "failure"},
+		{"comment", "neutral"},
+		{"", "neutral"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.approval, func(t *testing.T) {
+			if got := checkRunConclusion(tt.approval); got != tt.want {
+				t.Errorf("checkRunConclusion(%q) = %q, want %q", tt.approval, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRunSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  string
+		comments []ClaudeComment
+		want     string
+	}{
+		{
+			name:    "counts by severity, most severe first",
+			summary: "Found some issues.",
+			comments: []ClaudeComment{
+				{Severity: "low"},
+				{Severity: "critical"},
+				{Severity: "low"},
+				{Severity: "medium"},
+			},
+			want: "Found some issues.\n\n**Findings:** 1 critical, 1 medium, 2 low",
+		},
+		{
+			name:    "no findings",
+			summary: "Looks good.",
+			want:    "Looks good.\n\n**Findings:** none",
+		},
+		{
+			name:     "empty summary",
+			comments: []ClaudeComment{{Severity: "high"}},
+			want:     "**Findings:** 1 high",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkRunSummary(tt.summary, tt.comments, nil); got != tt.want {
+				t.Errorf("checkRunSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
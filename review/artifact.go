@@ -0,0 +1,179 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shipitai/shipitai/config"
+)
+
+// artifactWriteTimeout bounds writing a review's artifacts to the sink.
+const artifactWriteTimeout = 30 * time.Second
+
+// ArtifactSink stores review artifacts for archival, e.g. in an S3-compatible
+// object store. Implementations must be safe for concurrent use.
+type ArtifactSink interface {
+	// URL returns the link at which the artifact stored under key can be
+	// downloaded. The link is posted before the artifact is written, so it must
+	// not depend on the write.
+	URL(key string) string
+	// Put stores data under key, replacing any existing artifact.
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// FileArtifactSink is an ArtifactSink that writes artifacts under a directory,
+// for self-hosters who serve that directory at baseURL themselves.
+type FileArtifactSink struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileArtifactSink creates a sink writing to dir, linking artifacts under baseURL.
+func NewFileArtifactSink(dir, baseURL string) *FileArtifactSink {
+	return &FileArtifactSink{dir: dir, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// URL returns baseURL followed by the escaped key.
+func (s *FileArtifactSink) URL(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return s.baseURL + "/" + strings.Join(parts, "/")
+}
+
+// Put writes data to the key's path under the directory, creating parent
+// directories as needed. Keys that would escape the directory are rejected.
+func (s *FileArtifactSink) Put(ctx context.Context, key string, data []byte) error {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return fmt.Errorf("invalid artifact key: %q", key)
+	}
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial artifact
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".artifact-*")
+	if err != nil {
+		return fmt.Errorf("failed to create artifact: %w", err)
+	}
+	_, writeErr := tmp.Write(data)
+	if err := errors.Join(writeErr, tmp.Close()); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return nil
+}
+
+// reviewArtifact is the JSON form of an archived review.
+type reviewArtifact struct {
+	Owner     string          `json:"owner"`
+	Repo      string          `json:"repo"`
+	PRNumber  int             `json:"pr_number"`
+	HeadSHA   string          `json:"head_sha"`
+	CreatedAt time.Time       `json:"created_at"`
+	Approval  string          `json:"approval"`
+	Summary   string          `json:"summary"`
+	Comments  []ClaudeComment `json:"comments"`
+}
+
+// artifactKey returns the key, without extension, under which a review of the
+// PR's head commit made at the given time is archived.
+func artifactKey(input *ReviewInput, at time.Time) string {
+	sha := input.HeadSHA
+	if len(sha) > 12 {
+		sha = sha[:12]
+	}
+	return fmt.Sprintf("%s/%s/pr-%d/%s-%s", input.Owner, input.Repo, input.PRNumber, at.UTC().Format("20060102T150405Z"), sha)
+}
+
+// artifactLinks returns the key a review will be archived under and the links
+// to add to its body, or "" for both if no sink is set, the repository hasn't
+// enabled review_artifacts, or this is a dry run.
+func (r *Reviewer) artifactLinks(input *ReviewInput, cfg *config.Config) (key, links string) {
+	if r.artifacts == nil || !cfg.ReviewArtifacts || input.DryRun {
+		return "", ""
+	}
+	key = artifactKey(input, time.Now())
+	return key, buildArtifactLinks(r.artifacts.URL(key+".md"), r.artifacts.URL(key+".json"))
+}
+
+// archiveReview writes the posted review's Markdown and JSON artifacts under key
+// in the background, tracked by Wait. Sink errors are only logged, so they never
+// fail a review.
+func (r *Reviewer) archiveReview(key string, input *ReviewInput, parsed *ClaudeResponse) {
+	if key == "" {
+		return
+	}
+
+	artifact := &reviewArtifact{
+		Owner:     input.Owner,
+		Repo:      input.Repo,
+		PRNumber:  input.PRNumber,
+		HeadSHA:   input.HeadSHA,
+		CreatedAt: time.Now().UTC(),
+		Approval:  parsed.Approval,
+		Summary:   parsed.Summary,
+		Comments:  parsed.Comments,
+	}
+	if artifact.Comments == nil {
+		artifact.Comments = []ClaudeComment{}
+	}
+	jsonData, err := json.MarshalIndent(artifact, "", "  ")
+	if err != nil {
+		r.logger.Error("failed to encode review artifact", "error", err)
+		return
+	}
+	files := map[string][]byte{
+		key + ".md":   []byte(buildArtifactMarkdown(artifact)),
+		key + ".json": jsonData,
+	}
+
+	r.archives.Add(1)
+	go func() {
+		defer r.archives.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), artifactWriteTimeout)
+		defer cancel()
+		for k, data := range files {
+			if err := r.artifacts.Put(ctx, k, data); err != nil {
+				r.logger.Error("failed to store review artifact", "key", k, "error", err)
+			}
+		}
+	}()
+}
+
+// buildArtifactLinks formats the links to a review's archived artifacts.
+func buildArtifactLinks(markdownURL, jsonURL string) string {
+	return fmt.Sprintf("This review is archived as [Markdown](%s) and [JSON](%s).", markdownURL, jsonURL)
+}
+
+// buildArtifactMarkdown renders an archived review as a Markdown document.
+func buildArtifactMarkdown(a *reviewArtifact) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Review of %s/%s#%d\n\n", a.Owner, a.Repo, a.PRNumber)
+	fmt.Fprintf(&b, "- **Commit:** %s\n", a.HeadSHA)
+	fmt.Fprintf(&b, "- **Reviewed:** %s\n", a.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- **Approval:** %s\n\n", a.Approval)
+	fmt.Fprintf(&b, "## Summary\n\n%s\n\n", a.Summary)
+	fmt.Fprintf(&b, "## Findings (%d)\n", len(a.Comments))
+	for _, c := range a.Comments {
+		fmt.Fprintf(&b, "\n### `%s:%d`", c.Path, c.Line)
+		if c.Severity != "" {
+			fmt.Fprintf(&b, " (%s)", c.Severity)
+		}
+		fmt.Fprintf(&b, "\n\n%s\n", c.Body)
+	}
+	return b.String()
+}
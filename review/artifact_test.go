@@ -0,0 +1,138 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+func TestFileArtifactSink(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileArtifactSink(dir, "https://archive.example.com/reviews/")
+
+	if got, want := sink.URL("owner/repo/pr-7/a b.md"), "https://archive.example.com/reviews/owner/repo/pr-7/a%20b.md"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	if err := sink.Put(context.Background(), "owner/repo/pr-7/review.md", []byte("first")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := sink.Put(context.Background(), "owner/repo/pr-7/review.md", []byte("second")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "owner", "repo", "pr-7", "review.md"))
+	if err != nil || string(data) != "second" {
+		t.Errorf("stored artifact = %q, %v, want the latest write", data, err)
+	}
+
+	for _, key := range []string{"../escape.md", "/etc/escape.md", ""} {
+		if err := sink.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Errorf("Put(%q) error = nil, want the key rejected", key)
+		}
+	}
+}
+
+// recordingSink collects the artifacts put to it, failing every write if err is set.
+type recordingSink struct {
+	err error
+
+	mu   sync.Mutex
+	puts map[string][]byte
+}
+
+func newRecordingSink(err error) *recordingSink {
+	return &recordingSink{err: err, puts: make(map[string][]byte)}
+}
+
+func (s *recordingSink) URL(key string) string {
+	return "https://archive.example.com/" + key
+}
+
+func (s *recordingSink) Put(ctx context.Context, key string, data []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.puts[key] = data
+	return nil
+}
+
+func TestReviewArchivesArtifacts(t *testing.T) {
+	newFakeClaude(t, `{"summary":"One issue.","approval":"comment","comments":[{"path":"a.go","line":2,"body":"Check this.","severity":"high"}]}`)
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n package a\n-var x = 1\n+var x = 2\n+var y = 3\n"
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}
+
+	tests := []struct {
+		name     string
+		config   string
+		sinkErr  error
+		wantLink bool
+	}{
+		{name: "archived", config: "review_artifacts: true", wantLink: true},
+		{name: "sink errors don't fail the review", config: "review_artifacts: true", sinkErr: errors.New("bucket unavailable"), wantLink: true},
+		{name: "disabled for the repository", config: "enabled: true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &reviewsTransport{diff: diff, config: tt.config}
+			sink := newRecordingSink(tt.sinkErr)
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			reviewer.SetArtifactSink(sink)
+
+			if _, err := reviewer.Review(context.Background(), input); err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			reviewer.Wait()
+			if len(transport.posted) != 1 {
+				t.Fatalf("posted %d reviews, want 1", len(transport.posted))
+			}
+			body := transport.posted[0].Body
+			if hasLink := strings.Contains(body, "This review is archived as [Markdown](https://archive.example.com/owner/repo/pr-7/"); hasLink != tt.wantLink {
+				t.Fatalf("review body = %q, want archive link %v", body, tt.wantLink)
+			}
+			if !tt.wantLink || tt.sinkErr != nil {
+				return
+			}
+
+			sink.mu.Lock()
+			defer sink.mu.Unlock()
+			if len(sink.puts) != 2 {
+				t.Fatalf("wrote %d artifacts, want 2", len(sink.puts))
+			}
+			var markdown, jsonData []byte
+			for key, data := range sink.puts {
+				if !strings.Contains(body, sink.URL(key)) {
+					t.Errorf("review body doesn't link artifact %q", key)
+				}
+				switch filepath.Ext(key) {
+				case ".md":
+					markdown = data
+				case ".json":
+					jsonData = data
+				}
+			}
+			if !strings.Contains(string(markdown), "One issue.") || !strings.Contains(string(markdown), "### `a.go:2` (high)") {
+				t.Errorf("markdown artifact = %q, want the summary and finding", markdown)
+			}
+			var artifact reviewArtifact
+			if err := json.Unmarshal(jsonData, &artifact); err != nil {
+				t.Fatalf("json artifact: %v", err)
+			}
+			if artifact.HeadSHA != "abc123" || artifact.Approval != "comment" || len(artifact.Comments) != 1 {
+				t.Errorf("json artifact = %+v, want the posted review", artifact)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+// pendingChecksNote starts the review body line explaining an approval held back for CI.
+const pendingChecksNote = "Holding off on approving until CI finishes"
+
+// holdApprovalForChecks downgrades an approving review to a comment when
+// wait_for_checks is set and other check runs on the head commit haven't
+// completed, so a PR can't merge on the bot's approval while tests are running.
+// If the checks can't be listed, the approval is held too.
+func (r *Reviewer) holdApprovalForChecks(ctx context.Context, input *ReviewInput, cfg *config.Config, req *github.ReviewRequest) {
+	if !cfg.WaitForChecks || req.Event != "APPROVE" {
+		return
+	}
+
+	runs, err := r.githubClient.GetCheckRuns(ctx, input.InstallationID, input.Owner, input.Repo, input.HeadSHA)
+	if err != nil {
+		r.logger.Warn("failed to fetch check runs, holding approval", "error", err)
+		holdApproval(req, pendingChecksNote+"; the status of its checks couldn't be read.")
+		return
+	}
+
+	pending := pendingCheckRuns(runs)
+	if len(pending) == 0 {
+		return
+	}
+	r.logger.Info("checks still running, downgrading approval to comment", "pending", pending)
+	holdApproval(req, fmt.Sprintf("%s: %s still running.", pendingChecksNote, formatCheckNames(pending)))
+}
+
+// holdApproval turns an approving review into a comment carrying note.
+func holdApproval(req *github.ReviewRequest, note string) {
+	req.Event = "COMMENT"
+	req.Body = strings.TrimLeft(req.Body+"\n\n"+note, "\n")
+}
+
+// pendingCheckRuns returns the names of check runs that haven't completed,
+// ignoring ShipItAI's own.
+func pendingCheckRuns(runs []github.CheckRun) []string {
+	var pending []string
+	for _, run := range runs {
+		if run.Status != "completed" && run.Name != CheckRunName {
+			pending = append(pending, run.Name)
+		}
+	}
+	return pending
+}
+
+// formatCheckNames renders check names for the pending checks note.
+func formatCheckNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "**" + name + "**"
+	}
+	if len(quoted) == 1 {
+		return quoted[0] + " is"
+	}
+	return strings.Join(quoted, ", ") + " are"
+}
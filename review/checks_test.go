@@ -0,0 +1,96 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+func TestHoldApprovalForChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.Config
+		event     string
+		status    int
+		checkRuns string
+		wantEvent string
+		wantBody  string
+		wantFetch bool
+	}{
+		{
+			name:      "pending checks downgrade approval",
+			cfg:       &config.Config{WaitForChecks: true},
+			event:     "APPROVE",
+			status:    http.StatusOK,
+			checkRuns: `{"total_count":3,"check_runs":[{"name":"test","status":"in_progress"},{"name":"lint","status":"queued"},{"name":"build","status":"completed","conclusion":"success"}]}`,
+			wantEvent: "COMMENT",
+			wantBody:  "Looks good.\n\n" + pendingChecksNote + ": **test**, **lint** are still running.",
+			wantFetch: true,
+		},
+		{
+			name:      "completed checks keep approval",
+			cfg:       &config.Config{WaitForChecks: true},
+			event:     "APPROVE",
+			status:    http.StatusOK,
+			checkRuns: `{"total_count":2,"check_runs":[{"name":"test","status":"completed","conclusion":"success"},{"name":"` + CheckRunName + `","status":"in_progress"}]}`,
+			wantEvent: "APPROVE",
+			wantBody:  "Looks good.",
+			wantFetch: true,
+		},
+		{
+			name:      "unreadable checks downgrade approval",
+			cfg:       &config.Config{WaitForChecks: true},
+			event:     "APPROVE",
+			status:    http.StatusForbidden,
+			checkRuns: `{"message":"Resource not accessible by integration"}`,
+			wantEvent: "COMMENT",
+			wantBody:  "Looks good.\n\n" + pendingChecksNote + "; the status of its checks couldn't be read.",
+			wantFetch: true,
+		},
+		{
+			name:      "non-approving review not checked",
+			cfg:       &config.Config{WaitForChecks: true},
+			event:     "REQUEST_CHANGES",
+			wantEvent: "REQUEST_CHANGES",
+			wantBody:  "Looks good.",
+		},
+		{
+			name:      "disabled",
+			cfg:       &config.Config{},
+			event:     "APPROVE",
+			wantEvent: "APPROVE",
+			wantBody:  "Looks good.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := false
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if !strings.HasSuffix(req.URL.Path, "/commits/abc123/check-runs") {
+					t.Errorf("unexpected request %s", req.URL.Path)
+				}
+				fetched = true
+				return newResponse(req, tt.status, tt.checkRuns), nil
+			})
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}
+			req := &github.ReviewRequest{Body: "Looks good.", Event: tt.event}
+
+			reviewer.holdApprovalForChecks(context.Background(), input, tt.cfg, req)
+
+			if fetched != tt.wantFetch {
+				t.Errorf("fetched check runs = %v, want %v", fetched, tt.wantFetch)
+			}
+			if req.Event != tt.wantEvent || req.Body != tt.wantBody {
+				t.Errorf("review = %s %q, want %s %q", req.Event, req.Body, tt.wantEvent, tt.wantBody)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package review
 
 import (
+	"encoding/json"
 	"strings"
 )
 
@@ -12,6 +13,11 @@ const ChunkThreshold = 100 * 1024
 // ~80KB corresponds to roughly 20K tokens.
 const MaxChunkSize = 80 * 1024
 
+// MaxChunkPromptSize bounds everything sent for one chunk (in bytes): system
+// prompt, rich context, and the chunk's diff. ~400KB is roughly 100K tokens,
+// half of Claude's context window, leaving room for the response.
+const MaxChunkPromptSize = 400 * 1024
+
 // FileDiff represents a single file's diff content.
 type FileDiff struct {
 	Path    string
@@ -37,6 +43,8 @@ type MergedReview struct {
 	Summary  string
 	Comments []ClaudeComment
 	Approval string
+	Extras   map[string]json.RawMessage
+	Unplaced []ClaudeComment // Comments filtered out for lines outside their chunk's diff
 }
 
 // SplitDiffByFile splits a unified diff into individual file diffs.
@@ -86,8 +94,13 @@ func SplitDiffByFile(diff string) []FileDiff {
 
 // ChunkDiff splits a diff into chunks that fit within the size limit.
 // Uses greedy bin-packing: adds files to current chunk until limit reached.
+// A file larger than the limit is split between hunks first; a single hunk
+// larger than the limit still gets a chunk of its own.
 func ChunkDiff(diff string, maxChunkSize int) []Chunk {
-	files := SplitDiffByFile(diff)
+	var files []FileDiff
+	for _, file := range SplitDiffByFile(diff) {
+		files = append(files, splitFileDiff(file, maxChunkSize)...)
+	}
 	if len(files) == 0 {
 		return nil
 	}
@@ -141,6 +154,50 @@ func ChunkDiff(diff string, maxChunkSize int) []Chunk {
 	return chunks
 }
 
+// splitFileDiff splits a file's diff larger than maxSize into pieces at hunk
+// boundaries, each repeating the file's header so it stands alone as a diff.
+// Diffs within the limit, or with a single hunk, are returned whole.
+func splitFileDiff(file FileDiff, maxSize int) []FileDiff {
+	if len(file.Content) <= maxSize {
+		return []FileDiff{file}
+	}
+
+	lines := strings.Split(file.Content, "\n")
+	var starts []int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "@@") {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) < 2 {
+		return []FileDiff{file}
+	}
+
+	header := strings.Join(lines[:starts[0]], "\n")
+	var pieces []FileDiff
+	var piece strings.Builder
+	piece.WriteString(header)
+	hunks := 0
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		hunk := strings.Join(lines[start:end], "\n")
+
+		if hunks > 0 && piece.Len()+1+len(hunk) > maxSize {
+			pieces = append(pieces, FileDiff{Path: file.Path, Content: piece.String()})
+			piece.Reset()
+			piece.WriteString(header)
+			hunks = 0
+		}
+		piece.WriteString("\n")
+		piece.WriteString(hunk)
+		hunks++
+	}
+	return append(pieces, FileDiff{Path: file.Path, Content: piece.String()})
+}
+
 // ChunkToDiff converts a Chunk back to a unified diff string.
 func ChunkToDiff(chunk *Chunk) string {
 	var builder strings.Builder
@@ -187,6 +244,8 @@ func MergeChunkResponses(results []*ChunkResult) (*MergedReview, error) {
 
 		// Collect all comments
 		merged.Comments = append(merged.Comments, resp.Comments...)
+		merged.Unplaced = append(merged.Unplaced, resp.Unplaced...)
+		merged.Extras = mergeExtras(merged.Extras, resp.Extras)
 
 		// Merge approval (strictest wins)
 		merged.Approval = mergeApproval(merged.Approval, resp.Approval)
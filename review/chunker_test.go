@@ -317,3 +317,34 @@ func TestPluralize(t *testing.T) {
 		}
 	}
 }
+
+func TestChunkDiff_SplitsOversizedFileAtHunks(t *testing.T) {
+	header := "diff --git a/big.go b/big.go\n--- a/big.go\n+++ b/big.go\n"
+	diff := header
+	for i := 0; i < 4; i++ {
+		diff += "@@ -" + itoa(i*10+1) + ",1 +" + itoa(i*10+1) + ",1 @@\n+" + strings.Repeat("x", 200) + "\n"
+	}
+
+	chunks := ChunkDiff(diff, 500)
+
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+	hunks := 0
+	for i, chunk := range chunks {
+		if len(chunk.Files) != 1 || chunk.Files[0].Path != "big.go" {
+			t.Fatalf("chunk %d: files = %+v, want one piece of big.go", i, chunk.Files)
+		}
+		content := chunk.Files[0].Content
+		if len(content) > 500 {
+			t.Errorf("chunk %d: size = %d, want at most 500", i, len(content))
+		}
+		if !strings.HasPrefix(content, header) {
+			t.Errorf("chunk %d: missing the file header", i)
+		}
+		hunks += strings.Count(content, "\n@@ ")
+	}
+	if hunks != 4 {
+		t.Errorf("chunks hold %d hunks, want 4", hunks)
+	}
+}
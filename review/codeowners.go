@@ -0,0 +1,127 @@
+package review
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// codeOwnersPaths are where GitHub looks for a CODEOWNERS file, in its order.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwnersRule maps a CODEOWNERS path pattern to its owners.
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+	re      *regexp.Regexp
+}
+
+// ParseCodeOwners parses a CODEOWNERS file into rules, in file order.
+// Comments, blank lines, and invalid patterns are skipped.
+func ParseCodeOwners(content string) []CodeOwnersRule {
+	var rules []CodeOwnersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		re, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+		rules = append(rules, CodeOwnersRule{Pattern: fields[0], Owners: fields[1:], re: re})
+	}
+	return rules
+}
+
+// OwnersOf returns the owners of path. As in Git, the last matching rule wins; a
+// matching rule with no owners leaves the path unowned.
+func OwnersOf(rules []CodeOwnersRule, path string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].re.MatchString(path) {
+			return rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// codeOwnersPattern compiles a CODEOWNERS pattern, which follows gitignore rules:
+// a leading or inner slash anchors it to the repository root, a trailing slash
+// matches only directories, * and ? stay within a path segment, ** spans
+// segments, and a pattern naming a directory matches everything under it.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
+
+// fetchCodeOwners loads the repository's CODEOWNERS rules at ref.
+// It returns nil when the repository has no CODEOWNERS file.
+func (r *Reviewer) fetchCodeOwners(ctx context.Context, input *ReviewInput, ref string) ([]CodeOwnersRule, error) {
+	for _, path := range codeOwnersPaths {
+		content, err := r.githubClient.FetchFileContent(ctx, input.InstallationID, input.Owner, input.Repo, path, ref)
+		if err != nil {
+			return nil, err
+		}
+		if content != "" {
+			return ParseCodeOwners(content), nil
+		}
+	}
+	return nil, nil
+}
+
+// filterDiffToOwners keeps only the diffs of files owned by one of owners.
+// Handles match case-insensitively, with or without a leading "@".
+func filterDiffToOwners(diff string, rules []CodeOwnersRule, owners []string) string {
+	wanted := make(map[string]bool, len(owners))
+	for _, o := range owners {
+		wanted[normalizeOwner(o)] = true
+	}
+
+	var owned []string
+	for _, fd := range SplitDiffByFile(diff) {
+		for _, o := range OwnersOf(rules, fd.Path) {
+			if wanted[normalizeOwner(o)] {
+				owned = append(owned, fd.Path)
+				break
+			}
+		}
+	}
+	return filterDiffToFiles(diff, owned)
+}
+
+func normalizeOwner(owner string) string {
+	return strings.ToLower(strings.TrimPrefix(owner, "@"))
+}
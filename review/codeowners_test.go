@@ -0,0 +1,86 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+func TestOwnersOf(t *testing.T) {
+	rules := ParseCodeOwners(`
+# Default owners
+*                 @org/everyone
+*.go              @org/go-team   # inline comment
+/build/           @org/infra
+docs/             @org/docs
+apps/**/api/*.ts  @org/api
+/payments/        @org/payments @alice
+/payments/legacy/
+config            @bob
+`)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"README.md", "@org/everyone"},
+		{"cmd/main.go", "@org/go-team"},
+		{"build/ci.sh", "@org/infra"},
+		{"tools/build/ci.sh", "@org/everyone"}, // leading slash anchors to the root
+		{"docs/guide.md", "@org/docs"},
+		{"site/docs/index.md", "@org/docs"}, // trailing-slash pattern matches at any depth
+		{"apps/web/api/users.ts", "@org/api"},
+		{"apps/api/users.ts", "@org/api"}, // ** matches zero directories
+		{"apps/web/api/v1/users.ts", "@org/everyone"},
+		{"payments/charge.go", "@org/payments @alice"}, // later rule overrides *.go
+		{"payments/legacy/old.go", ""},                 // a rule without owners unowns
+		{"config/app.yml", "@bob"},                     // a name matches directories too
+	}
+	for _, tt := range tests {
+		if got := strings.Join(OwnersOf(rules, tt.path), " "); got != tt.want {
+			t.Errorf("OwnersOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilterDiffToOwners(t *testing.T) {
+	rules := ParseCodeOwners("/payments/ @org/Payments\n/web/ @org/web\n")
+	diff := "diff --git a/payments/a.go b/payments/a.go\n+a\n" +
+		"diff --git a/web/b.ts b/web/b.ts\n+b\n" +
+		"diff --git a/other/c.go b/other/c.go\n+c"
+
+	got := filterDiffToOwners(diff, rules, []string{"org/payments"})
+	if want := "diff --git a/payments/a.go b/payments/a.go\n+a"; strings.TrimSpace(got) != want {
+		t.Errorf("filterDiffToOwners() = %q, want only the payments file", got)
+	}
+
+	if got := filterDiffToOwners(diff, nil, []string{"@org/payments"}); got != "" {
+		t.Errorf("filterDiffToOwners() without CODEOWNERS = %q, want no files", got)
+	}
+}
+
+func TestReviewSkipsWithoutOwnedFiles(t *testing.T) {
+	diff := "diff --git a/web/a.ts b/web/a.ts\n--- a/web/a.ts\n+++ b/web/a.ts\n@@ -1 +1 @@\n-a\n+b\n"
+	transport := &writeRecorder{next: routeTransport{
+		"/pulls/7":                       newResponse(nil, http.StatusOK, diff),
+		"/contents/.github/shipitai.yml": configResponse("only_owned_by: ['@org/payments']"),
+		"/contents/.github/CODEOWNERS":   configResponse("/payments/ @org/payments\n/web/ @org/web\n"),
+	}}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"})
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if result.SkipReason != SkipReasonNoOwnedFiles {
+		t.Errorf("SkipReason = %q, want %q", result.SkipReason, SkipReasonNoOwnedFiles)
+	}
+	if len(transport.writes) != 0 {
+		t.Errorf("skipped review wrote to GitHub: %v", transport.writes)
+	}
+}
@@ -0,0 +1,75 @@
+package review
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+// CommandRequest identifies a comment command and who is running it.
+type CommandRequest struct {
+	InstallationID int64
+	Owner          string
+	Repo           string
+	DefaultBranch  string // Config is always read from the default branch
+	Private        bool   // Private repos skip the contributor check
+	Username       string
+	Command        string
+}
+
+// IsCommandAllowed checks the repository's command permissions for the requesting user.
+// The contributor lookup is only made when the command isn't open to everyone.
+func (r *Reviewer) IsCommandAllowed(ctx context.Context, req *CommandRequest) bool {
+	// Only users with repository access can comment on private repos
+	if req.Private {
+		return true
+	}
+
+	cfg, err := r.configLoader.Load(ctx, req.InstallationID, req.Owner, req.Repo, req.DefaultBranch)
+	if err != nil {
+		r.logger.Warn("failed to load config for command check, using defaults", "error", err)
+		cfg = config.DefaultConfig()
+	}
+
+	if cfg.IsCommandAllowed(req.Command, false) {
+		return true
+	}
+
+	isContributor, err := r.githubClient.IsContributor(ctx, req.InstallationID, req.Owner, req.Repo, req.Username)
+	if err != nil {
+		r.logger.Warn("failed to check contributor status", "user", req.Username, "error", err)
+	}
+	return cfg.IsCommandAllowed(req.Command, isContributor)
+}
+
+// RepliesEnabled reports whether the repository allows conversational replies
+// to @mentions (replies_enabled). Config load failures fall back to the default.
+func (r *Reviewer) RepliesEnabled(ctx context.Context, req *CommandRequest) bool {
+	cfg, err := r.configLoader.Load(ctx, req.InstallationID, req.Owner, req.Repo, req.DefaultBranch)
+	if err != nil {
+		r.logger.Warn("failed to load config for reply check, using defaults", "error", err)
+		cfg = config.DefaultConfig()
+	}
+	return cfg.AreRepliesEnabled()
+}
+
+// ResolveCommentThread resolves the review thread containing the given review comment
+// (for "@shipitai resolve"). Already-resolved threads are left as they are.
+func (r *Reviewer) ResolveCommentThread(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64) error {
+	threads, err := r.githubClient.FetchPRReviewThreads(ctx, installationID, owner, repo, prNumber)
+	if err != nil {
+		return fmt.Errorf("failed to fetch review threads: %w", err)
+	}
+
+	thread := github.FindThreadForComment(threads, commentID)
+	if thread == nil {
+		return fmt.Errorf("no review thread found for comment %d", commentID)
+	}
+	if thread.IsResolved {
+		return nil
+	}
+
+	return r.githubClient.ResolveReviewThread(ctx, installationID, thread.ID)
+}
@@ -11,6 +11,9 @@ type FileContext struct {
 	Language string
 	// Truncated indicates if the content was truncated due to size limits.
 	Truncated bool
+	// DeclarationsOnly indicates the content holds only the declarations the diff
+	// touches rather than the whole file (context.smart_go_context).
+	DeclarationsOnly bool
 }
 
 // RelatedFile represents a file related to a modified file.
@@ -24,6 +27,8 @@ type RelatedFile struct {
 	Content string
 	// SourceFile is the modified file this is related to.
 	SourceFile string
+	// Truncated indicates if the content was truncated due to size limits.
+	Truncated bool
 }
 
 // CommitInfo contains information about a single commit.
@@ -3,17 +3,19 @@ package review
 import (
 	"context"
 	"log/slog"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/shipitai/shipitai/config"
-	"github.com/shipitai/shipitai/github"
 )
 
 const (
-	// MaxFileSize is the maximum size for a single file (50KB).
+	// MaxFileSize is the default maximum size for a single file (50KB).
+	// Repos can override it with context.max_file_size.
 	MaxFileSize = 50 * 1024
 
 	// TotalContextBudget is the total budget for all context (100KB).
@@ -28,48 +30,112 @@ const (
 	// ImportsBudgetRatio is the portion of budget for imported files (15%).
 	ImportsBudgetRatio = 0.15
 
+	// MaxImportFiles is the default cap on candidate paths fetched for local imports.
+	// Repos can override it with context.max_import_files.
+	MaxImportFiles = 20
+
 	// HistoryBudgetRatio is the portion of budget for commit history (10%).
 	// Note: History is text-only so this is more of a placeholder.
 	HistoryBudgetRatio = 0.10
 
+	// maxGoModFiles caps the go.mod files fetched to find the Go modules a PR touches.
+	maxGoModFiles = 10
+
+	// MaxHistoryFiles is the default cap on files whose commit history is fetched.
+	// Repos can override it with context.max_history_files.
+	MaxHistoryFiles = 20
+
 	// CommitsPerFile is the maximum number of commits to fetch per file.
 	CommitsPerFile = 5
 
-	// ContextFetchTimeout is the timeout for fetching all context.
+	// ContextFetchTimeout is the timeout for fetching all context when the
+	// caller's context has no deadline.
 	ContextFetchTimeout = 30 * time.Second
+
+	// DefaultContextFetchFraction is the share of the remaining review deadline
+	// given to context fetching (25%).
+	DefaultContextFetchFraction = 0.25
+
+	// MaxContextFetchTimeout caps the context fetch timeout on generous deadlines.
+	MaxContextFetchTimeout = 90 * time.Second
 )
 
 // ContextFetcher fetches enriched context for code reviews.
 type ContextFetcher struct {
-	client     *github.Client
-	logger     *slog.Logger
-	modulePath string // Go module path for import resolution
+	client          Platform
+	logger          *slog.Logger
+	modulePath      string        // Go module path of the repo root, overriding its go.mod
+	timeoutFraction float64       // Share of the remaining deadline for fetching
+	timeout         time.Duration // Fixed fetch timeout overriding the fraction (0 = scale with the deadline)
 }
 
 // NewContextFetcher creates a new context fetcher.
-func NewContextFetcher(client *github.Client, logger *slog.Logger) *ContextFetcher {
+func NewContextFetcher(client Platform, logger *slog.Logger) *ContextFetcher {
 	return &ContextFetcher{
-		client: client,
-		logger: logger,
+		client:          client,
+		logger:          logger,
+		timeoutFraction: DefaultContextFetchFraction,
 	}
 }
 
+// SetTimeoutFraction sets the share of the caller's remaining deadline that
+// context fetching may use. Values outside (0, 1] are ignored.
+func (f *ContextFetcher) SetTimeoutFraction(fraction float64) {
+	if fraction > 0 && fraction <= 1 {
+		f.timeoutFraction = fraction
+	}
+}
+
+// SetTimeout sets a fixed timeout for context fetching, replacing the share of
+// the remaining deadline and the MaxContextFetchTimeout cap. Useful for huge PRs
+// whose context legitimately takes longer to fetch. Values of 0 or less are ignored.
+func (f *ContextFetcher) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		f.timeout = timeout
+	}
+}
+
+// fetchTimeout returns how long context fetching may take: the timeout set with
+// SetTimeout, or else a fraction of the remaining deadline, capped at
+// MaxContextFetchTimeout. Without either it falls back to ContextFetchTimeout.
+func (f *ContextFetcher) fetchTimeout(ctx context.Context, now time.Time) time.Duration {
+	if f.timeout > 0 {
+		return f.timeout
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ContextFetchTimeout
+	}
+	timeout := time.Duration(float64(deadline.Sub(now)) * f.timeoutFraction)
+	if timeout > MaxContextFetchTimeout {
+		return MaxContextFetchTimeout
+	}
+	if timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
 // ContextInput contains the parameters for fetching context.
 type ContextInput struct {
 	InstallationID int64
 	Owner          string
 	Repo           string
-	HeadRef        string           // The branch/SHA to fetch files from
-	ChangedFiles   []string         // List of file paths from the diff
-	Config         *config.Config   // Repository config (for context settings)
-	Budget         int              // Total size budget in bytes (0 = default)
+	HeadRef        string         // The branch/SHA to fetch files from
+	ChangedFiles   []string       // List of file paths from the diff
+	ChangedLines   DiffLineMap    // New-file lines in the diff's hunks, per path
+	Config         *config.Config // Repository config (for context settings)
+	Budget         int            // Total size budget in bytes (0 = default)
+	MaxBudget      int            // Cap on the budget, e.g. to fit a chunk's prompt (0 = none)
+	Files          *FileCache     // Contents already fetched in this review (nil = no caching)
 }
 
 // FetchContext fetches all available context within the size budget.
 // Failures are non-fatal - partial context is returned rather than failing.
 func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput) *ReviewContext {
-	// Apply timeout
-	ctx, cancel := context.WithTimeout(ctx, ContextFetchTimeout)
+	// Apply timeout, scaled to what's left of the review deadline
+	timeout := f.fetchTimeout(ctx, time.Now())
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	result := &ReviewContext{
@@ -90,9 +156,17 @@ func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput)
 		return result
 	}
 
+	maxSize := maxFileSize(input.Config)
 	budget := input.Budget
 	if budget <= 0 {
 		budget = TotalContextBudget
+		// Make room for at least one full file at a raised per-file cap
+		if minBudget := int(float64(maxSize) / FullFilesBudgetRatio); minBudget > budget {
+			budget = minBudget
+		}
+	}
+	if input.MaxBudget > 0 && budget > input.MaxBudget {
+		budget = input.MaxBudget
 	}
 
 	// Calculate budget allocations
@@ -121,7 +195,7 @@ func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput)
 
 	// Priority 1: Fetch full files for changed files
 	if fetchFullFiles {
-		files, used := f.fetchFullFiles(ctx, input, fullFilesBudget)
+		files, used := f.fetchFullFiles(ctx, input, fullFilesBudget, maxSize)
 		result.FullFiles = files
 		budgetUsed += used
 		f.logger.Info("fetched full files",
@@ -133,7 +207,7 @@ func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput)
 
 	// Priority 2: Fetch test files
 	if fetchRelatedFiles && budgetUsed < budget {
-		testFiles, used := f.fetchTestFiles(ctx, input, result.FullFiles, testFilesBudget)
+		testFiles, used := f.fetchTestFiles(ctx, input, result.FullFiles, testFilesBudget, maxSize)
 		result.RelatedFiles = append(result.RelatedFiles, testFiles...)
 		budgetUsed += used
 		f.logger.Info("fetched test files",
@@ -144,7 +218,7 @@ func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput)
 		// Priority 3: Fetch imported files (if budget allows)
 		remainingBudget := importsBudget
 		if budgetUsed < budget {
-			importedFiles, used := f.fetchImportedFiles(ctx, input, result.FullFiles, remainingBudget)
+			importedFiles, used := f.fetchImportedFiles(ctx, input, result.FullFiles, remainingBudget, maxSize)
 			result.RelatedFiles = append(result.RelatedFiles, importedFiles...)
 			f.logger.Info("fetched imported files",
 				"count", len(importedFiles),
@@ -153,9 +227,10 @@ func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput)
 		}
 	}
 
-	// Priority 4: Fetch commit history
+	// Priority 4: Fetch commit history, only for files whose content was included
 	if fetchHistory {
-		histories := f.fetchFileHistories(ctx, input)
+		paths := historyPaths(input, result.FullFiles, fetchFullFiles)
+		histories := f.fetchFileHistories(ctx, input, paths)
 		result.FileHistories = histories
 		f.logger.Info("fetched file histories",
 			"count", len(histories),
@@ -172,32 +247,132 @@ func (f *ContextFetcher) FetchContext(ctx context.Context, input *ContextInput)
 	return result
 }
 
+// maxFileSize returns the per-file size cap, honoring the context.max_file_size override.
+func maxFileSize(cfg *config.Config) int {
+	if cfg != nil && cfg.Context != nil && cfg.Context.MaxFileSize > 0 {
+		return cfg.Context.MaxFileSize
+	}
+	return MaxFileSize
+}
+
+// maxImportFiles returns the import candidate cap, honoring the context.max_import_files override.
+func maxImportFiles(cfg *config.Config) int {
+	if cfg != nil && cfg.Context != nil && cfg.Context.MaxImportFiles > 0 {
+		return cfg.Context.MaxImportFiles
+	}
+	return MaxImportFiles
+}
+
+// javaBasePackage returns the package prefix of project imports in Java and Kotlin
+// files (context.java_base_package), or "" to infer it from each file's package.
+func javaBasePackage(cfg *config.Config) string {
+	if cfg != nil && cfg.Context != nil {
+		return cfg.Context.JavaBasePackage
+	}
+	return ""
+}
+
+// maxHistoryFiles returns the history file cap, honoring the context.max_history_files override.
+func maxHistoryFiles(cfg *config.Config) int {
+	if cfg != nil && cfg.Context != nil && cfg.Context.MaxHistoryFiles > 0 {
+		return cfg.Context.MaxHistoryFiles
+	}
+	return MaxHistoryFiles
+}
+
+// historyPaths returns the files to fetch commit history for: those that got
+// full-file context, or every changed file when full files are disabled, capped
+// at the configured limit.
+func historyPaths(input *ContextInput, fullFiles []FileContext, fetchFullFiles bool) []string {
+	var paths []string
+	if fetchFullFiles {
+		for _, fc := range fullFiles {
+			paths = append(paths, fc.Path)
+		}
+	} else {
+		paths = input.ChangedFiles
+	}
+	if limit := maxHistoryFiles(input.Config); len(paths) > limit {
+		paths = paths[:limit]
+	}
+	return paths
+}
+
+// truncateContent cuts content to limit bytes, reporting whether it was cut.
+func truncateContent(content string, limit int) (string, bool) {
+	if len(content) > limit {
+		return content[:limit], true
+	}
+	return content, false
+}
+
+// fetchFiles fetches paths at the input's HeadRef, reusing contents already in
+// input.Files and caching the newly fetched ones there.
+func (f *ContextFetcher) fetchFiles(ctx context.Context, input *ContextInput, paths []string) (map[string]string, error) {
+	if input.Files == nil {
+		return f.client.FetchMultipleFiles(ctx, input.InstallationID, input.Owner, input.Repo, paths, input.HeadRef)
+	}
+
+	contents, missing := input.Files.lookup(paths, input.HeadRef)
+	if len(missing) == 0 {
+		return contents, nil
+	}
+	fetched, err := f.client.FetchMultipleFiles(ctx, input.InstallationID, input.Owner, input.Repo, missing, input.HeadRef)
+	if err != nil {
+		return nil, err
+	}
+	input.Files.store(fetched, input.HeadRef)
+	for p, content := range fetched {
+		contents[p] = content
+	}
+	return contents, nil
+}
+
 // fetchFullFiles fetches the complete content of modified files.
-func (f *ContextFetcher) fetchFullFiles(ctx context.Context, input *ContextInput, budget int) ([]FileContext, int) {
+// Files in unrecognized languages (data, assets, binaries) are skipped before fetching.
+func (f *ContextFetcher) fetchFullFiles(ctx context.Context, input *ContextInput, budget, maxSize int) ([]FileContext, int) {
 	var result []FileContext
 	var totalSize int
 
+	var paths []string
+	for _, path := range input.ChangedFiles {
+		if DetectLanguage(path) == "" {
+			f.logger.Debug("skipping file with unknown language", "path", path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return result, 0
+	}
+
 	// Fetch files in parallel (FetchMultipleFiles handles concurrency internally)
-	contents, err := f.client.FetchMultipleFiles(ctx, input.InstallationID, input.Owner, input.Repo, input.ChangedFiles, input.HeadRef)
+	contents, err := f.fetchFiles(ctx, input, paths)
 	if err != nil {
 		f.logger.Warn("failed to fetch files", "error", err)
 		return result, 0
 	}
 
+	smartGo := input.Config != nil && input.Config.Context != nil && input.Config.Context.SmartGoContext
+
 	// Process fetched files sequentially to respect budget ordering
-	for _, path := range input.ChangedFiles {
+	for _, path := range paths {
 		content, ok := contents[path]
 		if !ok || content == "" {
 			continue
 		}
 
-		// Check per-file limit
-		truncated := false
-		if len(content) > MaxFileSize {
-			content = content[:MaxFileSize]
-			truncated = true
+		// Keep only the Go declarations the diff touches; whole file if that fails
+		declarationsOnly := false
+		if smartGo && strings.HasSuffix(path, ".go") {
+			if decls, ok := changedGoDeclarations(path, content, input.ChangedLines[path]); ok {
+				content, declarationsOnly = decls, true
+			}
 		}
 
+		// Check per-file limit
+		content, truncated := truncateContent(content, maxSize)
+
 		// Check budget
 		if totalSize+len(content) > budget {
 			f.logger.Debug("budget exhausted for full files", "path", path)
@@ -206,10 +381,11 @@ func (f *ContextFetcher) fetchFullFiles(ctx context.Context, input *ContextInput
 		totalSize += len(content)
 
 		result = append(result, FileContext{
-			Path:      path,
-			Content:   content,
-			Language:  DetectLanguage(path),
-			Truncated: truncated,
+			Path:             path,
+			Content:          content,
+			Language:         DetectLanguage(path),
+			Truncated:        truncated,
+			DeclarationsOnly: declarationsOnly,
 		})
 	}
 
@@ -217,7 +393,7 @@ func (f *ContextFetcher) fetchFullFiles(ctx context.Context, input *ContextInput
 }
 
 // fetchTestFiles finds and fetches test files for the modified files.
-func (f *ContextFetcher) fetchTestFiles(ctx context.Context, input *ContextInput, fullFiles []FileContext, budget int) ([]RelatedFile, int) {
+func (f *ContextFetcher) fetchTestFiles(ctx context.Context, input *ContextInput, fullFiles []FileContext, budget, maxSize int) ([]RelatedFile, int) {
 	var result []RelatedFile
 	var totalSize int
 
@@ -248,7 +424,7 @@ func (f *ContextFetcher) fetchTestFiles(ctx context.Context, input *ContextInput
 	}
 
 	// Fetch test files
-	contents, err := f.client.FetchMultipleFiles(ctx, input.InstallationID, input.Owner, input.Repo, uniquePaths, input.HeadRef)
+	contents, err := f.fetchFiles(ctx, input, uniquePaths)
 	if err != nil {
 		f.logger.Warn("failed to fetch test files", "error", err)
 		return result, 0
@@ -261,9 +437,7 @@ func (f *ContextFetcher) fetchTestFiles(ctx context.Context, input *ContextInput
 		}
 
 		// Check per-file limit
-		if len(content) > MaxFileSize {
-			content = content[:MaxFileSize]
-		}
+		content, truncated := truncateContent(content, maxSize)
 
 		// Check budget
 		if totalSize+len(content) > budget {
@@ -277,6 +451,7 @@ func (f *ContextFetcher) fetchTestFiles(ctx context.Context, input *ContextInput
 			Relationship: "test",
 			Content:      content,
 			SourceFile:   pathToSource[path],
+			Truncated:    truncated,
 		})
 	}
 
@@ -284,7 +459,7 @@ func (f *ContextFetcher) fetchTestFiles(ctx context.Context, input *ContextInput
 }
 
 // fetchImportedFiles finds and fetches locally imported files.
-func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextInput, fullFiles []FileContext, budget int) ([]RelatedFile, int) {
+func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextInput, fullFiles []FileContext, budget, maxSize int) ([]RelatedFile, int) {
 	var result []RelatedFile
 	var totalSize int
 
@@ -292,8 +467,21 @@ func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextI
 	var importPaths []string
 	pathToSource := make(map[string]string)
 
+	var goModules map[string]string
+	goPackages := make(map[string][]string)
 	for _, file := range fullFiles {
-		imports := ParseLocalImports(file.Path, file.Content, f.modulePath)
+		var imports []string
+		switch DetectLanguage(file.Path) {
+		case "go":
+			if goModules == nil {
+				goModules = f.goModules(ctx, input, fullFiles)
+			}
+			imports = resolveGoImports(file.Content, goModules)
+		case "java", "kotlin":
+			imports = ParseLocalImports(file.Path, file.Content, javaBasePackage(input.Config))
+		default:
+			imports = ParseLocalImports(file.Path, file.Content, "")
+		}
 		for _, imp := range imports {
 			// Skip if it's one of the changed files (already have full content)
 			isChanged := false
@@ -303,10 +491,24 @@ func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextI
 					break
 				}
 			}
-			if !isChanged {
-				importPaths = append(importPaths, imp)
-				pathToSource[imp] = file.Path
+			if isChanged {
+				continue
+			}
+			if DetectLanguage(file.Path) == "go" {
+				// Go imports name a package directory, which can't be fetched as a file
+				files, ok := goPackages[imp]
+				if !ok {
+					files = f.goPackageFiles(ctx, input, imp)
+					goPackages[imp] = files
+				}
+				for _, p := range files {
+					importPaths = append(importPaths, p)
+					pathToSource[p] = file.Path
+				}
+				continue
 			}
+			importPaths = append(importPaths, imp)
+			pathToSource[imp] = file.Path
 		}
 	}
 
@@ -324,19 +526,15 @@ func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextI
 		}
 	}
 
-	// For imports, we might need to try with different extensions
-	var pathsToTry []string
-	for _, p := range uniquePaths {
-		if filepath.Ext(p) == "" {
-			// Try common extensions based on other files in the PR
-			pathsToTry = append(pathsToTry, p+".ts", p+".tsx", p+".js", p+".jsx", p+"/index.ts", p+"/index.js")
-		} else {
-			pathsToTry = append(pathsToTry, p)
-		}
+	// Imports without an extension may resolve to several files; try the likeliest first
+	prExts := make(map[string]bool)
+	for _, changed := range input.ChangedFiles {
+		prExts[filepath.Ext(changed)] = true
 	}
+	pathsToTry := importCandidates(uniquePaths, prExts, maxImportFiles(input.Config))
 
 	// Fetch imported files
-	contents, err := f.client.FetchMultipleFiles(ctx, input.InstallationID, input.Owner, input.Repo, pathsToTry, input.HeadRef)
+	contents, err := f.fetchFiles(ctx, input, pathsToTry)
 	if err != nil {
 		f.logger.Warn("failed to fetch imported files", "error", err)
 		return result, 0
@@ -349,9 +547,7 @@ func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextI
 		}
 
 		// Check per-file limit
-		if len(content) > MaxFileSize {
-			content = content[:MaxFileSize]
-		}
+		content, truncated := truncateContent(content, maxSize)
 
 		// Check budget
 		if totalSize+len(content) > budget {
@@ -374,14 +570,54 @@ func (f *ContextFetcher) fetchImportedFiles(ctx context.Context, input *ContextI
 			Relationship: "import",
 			Content:      content,
 			SourceFile:   sourceFile,
+			Truncated:    truncated,
 		})
 	}
 
 	return result, totalSize
 }
 
-// fetchFileHistories fetches recent commit history for modified files.
-func (f *ContextFetcher) fetchFileHistories(ctx context.Context, input *ContextInput) []FileHistory {
+// importSuffixes are the files an extensionless import may resolve to, most likely first.
+var importSuffixes = []string{".ts", ".tsx", ".js", ".jsx", "/index.ts", "/index.js"}
+
+// importCandidates lists the paths to fetch for the given imports, at most limit.
+// Extensionless imports expand to importSuffixes, preferring extensions already in
+// the PR. Candidates are taken round-robin, so every import gets its likeliest
+// path before any import gets a second guess.
+func importCandidates(imports []string, prExts map[string]bool, limit int) []string {
+	suffixes := append([]string(nil), importSuffixes...)
+	sort.SliceStable(suffixes, func(i, j int) bool {
+		return prExts[filepath.Ext(suffixes[i])] && !prExts[filepath.Ext(suffixes[j])]
+	})
+
+	perImport := make([][]string, len(imports))
+	for i, p := range imports {
+		if filepath.Ext(p) != "" {
+			perImport[i] = []string{p}
+			continue
+		}
+		for _, s := range suffixes {
+			perImport[i] = append(perImport[i], p+s)
+		}
+	}
+
+	var result []string
+	for rank := 0; rank < len(suffixes); rank++ {
+		for _, candidates := range perImport {
+			if rank >= len(candidates) {
+				continue
+			}
+			if len(result) == limit {
+				return result
+			}
+			result = append(result, candidates[rank])
+		}
+	}
+	return result
+}
+
+// fetchFileHistories fetches recent commit history for the given files.
+func (f *ContextFetcher) fetchFileHistories(ctx context.Context, input *ContextInput, paths []string) []FileHistory {
 	var result []FileHistory
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -389,7 +625,7 @@ func (f *ContextFetcher) fetchFileHistories(ctx context.Context, input *ContextI
 	// Limit concurrent requests
 	sem := make(chan struct{}, 5)
 
-	for _, path := range input.ChangedFiles {
+	for _, path := range paths {
 		wg.Add(1)
 		go func(p string) {
 			defer wg.Done()
@@ -446,11 +682,59 @@ func (f *ContextFetcher) fetchFileHistories(ctx context.Context, input *ContextI
 	return result
 }
 
-// SetModulePath sets the Go module path for import resolution.
+// SetModulePath sets the Go module path of the repository root for import
+// resolution, in place of the one read from its go.mod.
 func (f *ContextFetcher) SetModulePath(modulePath string) {
 	f.modulePath = modulePath
 }
 
+// goModules finds the Go modules the changed Go files belong to by reading the
+// go.mod files in their directories and the directories above them, so imports
+// resolve in monorepos holding several modules. Returns module paths keyed by the
+// directory holding their go.mod ("" for the repo root).
+func (f *ContextFetcher) goModules(ctx context.Context, input *ContextInput, fullFiles []FileContext) map[string]string {
+	var goFiles []string
+	for _, file := range fullFiles {
+		if DetectLanguage(file.Path) == "go" {
+			goFiles = append(goFiles, file.Path)
+		}
+	}
+	candidates := goModCandidates(goFiles)
+	if len(candidates) > maxGoModFiles {
+		candidates = candidates[:maxGoModFiles]
+	}
+
+	modules := make(map[string]string)
+	contents, err := f.fetchFiles(ctx, input, candidates)
+	if err != nil {
+		f.logger.Debug("failed to fetch go.mod files", "error", err)
+	}
+	for gomod, content := range contents {
+		if modPath := parseGoModulePath(content); modPath != "" {
+			dir := path.Dir(gomod)
+			if dir == "." {
+				dir = ""
+			}
+			modules[dir] = modPath
+		}
+	}
+	if f.modulePath != "" {
+		modules[""] = f.modulePath
+	}
+	f.logger.Debug("resolved Go modules", "modules", modules)
+	return modules
+}
+
+// goPackageFiles lists the non-test Go files of the package in dir.
+func (f *ContextFetcher) goPackageFiles(ctx context.Context, input *ContextInput, dir string) []string {
+	files, err := f.client.ListDirectory(ctx, input.InstallationID, input.Owner, input.Repo, dir, input.HeadRef)
+	if err != nil {
+		f.logger.Debug("failed to list Go package", "dir", dir, "error", err)
+		return nil
+	}
+	return goSourceFiles(files)
+}
+
 // FetchContextForChunk fetches context for a specific chunk of files.
 // The budget is divided by the number of chunks.
 func (f *ContextFetcher) FetchContextForChunk(ctx context.Context, input *ContextInput, chunkFiles []string, chunkIndex, totalChunks int) *ReviewContext {
@@ -461,8 +745,11 @@ func (f *ContextFetcher) FetchContextForChunk(ctx context.Context, input *Contex
 		Repo:           input.Repo,
 		HeadRef:        input.HeadRef,
 		ChangedFiles:   chunkFiles,
+		ChangedLines:   input.ChangedLines,
 		Config:         input.Config,
 		Budget:         input.Budget / totalChunks, // Divide budget among chunks
+		MaxBudget:      input.MaxBudget,
+		Files:          input.Files,
 	}
 
 	return f.FetchContext(ctx, chunkInput)
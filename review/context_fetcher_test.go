@@ -0,0 +1,447 @@
+package review
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+// flakyContentsTransport serves the contents API, failing the first request for
+// each path in failOnce with a 502. Paths holding files are listed as directories.
+type flakyContentsTransport struct {
+	mu       sync.Mutex
+	files    map[string]string
+	failOnce map[string]bool
+	calls    map[string]int
+}
+
+func (t *flakyContentsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := req.URL.Path[strings.Index(req.URL.Path, "/contents/")+len("/contents/"):]
+	t.calls[path]++
+
+	if t.failOnce[path] && t.calls[path] == 1 {
+		return newResponse(req, http.StatusBadGateway, "bad gateway"), nil
+	}
+	content, ok := t.files[path]
+	if !ok {
+		return t.listDirectory(req, path), nil
+	}
+	body := `{"encoding":"base64","content":"` + base64.StdEncoding.EncodeToString([]byte(content)) + `"}`
+	return newResponse(req, http.StatusOK, body), nil
+}
+
+// listDirectory serves the contents API's listing of dir, or a 404 if no file is in it.
+func (t *flakyContentsTransport) listDirectory(req *http.Request, dir string) *http.Response {
+	var entries []github.FileContent
+	seen := make(map[string]bool)
+	for p := range t.files {
+		rest, ok := strings.CutPrefix(p, dir+"/")
+		if !ok {
+			continue
+		}
+		entry := github.FileContent{Type: "file", Path: p}
+		if sub, _, nested := strings.Cut(rest, "/"); nested {
+			entry = github.FileContent{Type: "dir", Path: dir + "/" + sub}
+		}
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`)
+	}
+	body, _ := json.Marshal(entries)
+	return newResponse(req, http.StatusOK, string(body))
+}
+
+func newResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}
+}
+
+func TestFetchContextRetriesTransientFileFailure(t *testing.T) {
+	transport := &flakyContentsTransport{
+		files:    map[string]string{"main.go": "package main\n", "util.go": "package main\n"},
+		failOnce: map[string]bool{"main.go": true},
+		calls:    make(map[string]int),
+	}
+	fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	disabled := false
+	result := fetcher.FetchContext(context.Background(), &ContextInput{
+		Owner:        "owner",
+		Repo:         "repo",
+		HeadRef:      "abc123",
+		ChangedFiles: []string{"main.go", "util.go"},
+		Config: &config.Config{Context: &config.ContextConfig{
+			RelatedFiles: &disabled,
+			History:      &disabled,
+		}},
+	})
+
+	got := make(map[string]bool)
+	for _, f := range result.FullFiles {
+		got[f.Path] = true
+	}
+	if !got["main.go"] || !got["util.go"] {
+		t.Errorf("FullFiles = %v, want main.go and util.go", got)
+	}
+	if transport.calls["main.go"] != 2 {
+		t.Errorf("main.go fetched %d times, want 2", transport.calls["main.go"])
+	}
+	if transport.calls["util.go"] != 1 {
+		t.Errorf("util.go fetched %d times, want 1", transport.calls["util.go"])
+	}
+}
+
+func TestFetchContextForChunkSharesFileCache(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{Context: &config.ContextConfig{History: &disabled}}
+
+	tests := []struct {
+		name      string
+		files     *FileCache
+		wantFetch int
+	}{
+		{"shared cache fetches once", NewFileCache(), 1},
+		{"no cache fetches per chunk", nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &flakyContentsTransport{
+				files: map[string]string{"main.go": "package main\n", "main_test.go": "package main\n"},
+				calls: make(map[string]int),
+			}
+			fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+			input := &ContextInput{Owner: "owner", Repo: "repo", HeadRef: "abc123", Config: cfg, Files: tt.files}
+
+			// A large file split across two chunks
+			for i := 0; i < 2; i++ {
+				result := fetcher.FetchContextForChunk(context.Background(), input, []string{"main.go"}, i, 2)
+				if len(result.FullFiles) != 1 || len(result.RelatedFiles) != 1 {
+					t.Fatalf("chunk %d: FullFiles = %d, RelatedFiles = %d, want 1 each", i, len(result.FullFiles), len(result.RelatedFiles))
+				}
+			}
+
+			for _, path := range []string{"main.go", "main_test.go"} {
+				if got := transport.calls[path]; got != tt.wantFetch {
+					t.Errorf("%s fetched %d times, want %d", path, got, tt.wantFetch)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchContextMaxFileSize(t *testing.T) {
+	large := "package big\n" + strings.Repeat("// filler\n", 6*1024) // ~60KB
+
+	tests := []struct {
+		name          string
+		maxFileSize   int
+		wantSize      int
+		wantTruncated bool
+	}{
+		{"default cap truncates", 0, MaxFileSize, true},
+		{"raised cap keeps whole file", 200 * 1024, len(large), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &flakyContentsTransport{
+				files: map[string]string{"big.go": large, "assets/app.min.css": "body{}", "data.bin": "\x00\x01"},
+				calls: make(map[string]int),
+			}
+			fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			disabled := false
+			result := fetcher.FetchContext(context.Background(), &ContextInput{
+				Owner:        "owner",
+				Repo:         "repo",
+				HeadRef:      "abc123",
+				ChangedFiles: []string{"big.go", "assets/app.min.css", "data.bin"},
+				Config: &config.Config{Context: &config.ContextConfig{
+					RelatedFiles: &disabled,
+					History:      &disabled,
+					MaxFileSize:  tt.maxFileSize,
+				}},
+			})
+
+			if len(result.FullFiles) != 1 || result.FullFiles[0].Path != "big.go" {
+				t.Fatalf("FullFiles = %+v, want only big.go", result.FullFiles)
+			}
+			got := result.FullFiles[0]
+			if len(got.Content) != tt.wantSize || got.Truncated != tt.wantTruncated {
+				t.Errorf("big.go size = %d, truncated = %v, want %d, %v", len(got.Content), got.Truncated, tt.wantSize, tt.wantTruncated)
+			}
+			if transport.calls["assets/app.min.css"] != 0 || transport.calls["data.bin"] != 0 {
+				t.Errorf("unknown-language files were fetched: %v", transport.calls)
+			}
+		})
+	}
+}
+
+func TestContextFetchTimeoutScalesWithDeadline(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		fraction  float64
+		timeout   time.Duration // fixed timeout, 0 means unset
+		remaining time.Duration // 0 means no deadline
+		want      time.Duration
+	}{
+		{"tight local deadline", 0, 0, 2 * time.Minute, 30 * time.Second},
+		{"generous server deadline", 0, 0, 5 * time.Minute, 75 * time.Second},
+		{"capped on long deadlines", 0, 0, 20 * time.Minute, MaxContextFetchTimeout},
+		{"custom fraction", 0.5, 0, 2 * time.Minute, time.Minute},
+		{"no deadline uses fixed timeout", 0, 0, 0, ContextFetchTimeout},
+		{"expired deadline", 0, 0, -time.Second, 0},
+		{"configured timeout overrides fraction", 0, 2 * time.Minute, 5 * time.Minute, 2 * time.Minute},
+		{"configured timeout beyond cap", 0, 3 * time.Minute, 20 * time.Minute, 3 * time.Minute},
+		{"configured timeout without deadline", 0, 45 * time.Second, 0, 45 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := NewContextFetcher(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if tt.fraction != 0 {
+				fetcher.SetTimeoutFraction(tt.fraction)
+			}
+			fetcher.SetTimeout(tt.timeout)
+
+			ctx := context.Background()
+			if tt.remaining != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tt.remaining))
+				defer cancel()
+			}
+
+			if got := fetcher.fetchTimeout(ctx, now); got != tt.want {
+				t.Errorf("fetchTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportCandidates(t *testing.T) {
+	imports := []string{"src/a", "src/b", "src/c.css"}
+
+	got := importCandidates(imports, map[string]bool{".tsx": true, ".js": true}, 100)
+	want := []string{
+		"src/a.tsx", "src/b.tsx", "src/c.css",
+		"src/a.js", "src/b.js",
+		"src/a/index.js", "src/b/index.js",
+		"src/a.ts", "src/b.ts",
+		"src/a.jsx", "src/b.jsx",
+		"src/a/index.ts", "src/b/index.ts",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("importCandidates() =\n%v\nwant\n%v", got, want)
+	}
+
+	if got := importCandidates(imports, nil, 4); strings.Join(got, " ") != "src/a.ts src/b.ts src/c.css src/a.tsx" {
+		t.Errorf("importCandidates() with limit 4 = %v, want each import's first guess first", got)
+	}
+}
+
+func TestFetchContextBoundsImportCandidates(t *testing.T) {
+	var source strings.Builder
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&source, "import m%d from './mod%d'\n", i, i)
+		files[fmt.Sprintf("src/mod%d.tsx", i)] = "export default 1\n"
+	}
+	files["src/app.tsx"] = source.String()
+
+	tests := []struct {
+		name           string
+		maxImportFiles int
+		wantFetched    int
+	}{
+		{"default cap", 0, MaxImportFiles},
+		{"configured cap", 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &flakyContentsTransport{files: files, calls: make(map[string]int)}
+			fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			disabled := false
+			result := fetcher.FetchContext(context.Background(), &ContextInput{
+				Owner:        "owner",
+				Repo:         "repo",
+				HeadRef:      "abc123",
+				ChangedFiles: []string{"src/app.tsx"},
+				Config: &config.Config{Context: &config.ContextConfig{
+					History:        &disabled,
+					MaxImportFiles: tt.maxImportFiles,
+				}},
+			})
+
+			// 20 imports would be 120 candidates; only the PR's own extension is tried
+			fetched := 0
+			for path := range transport.calls {
+				if strings.HasPrefix(path, "src/mod") {
+					fetched++
+					if !strings.HasSuffix(path, ".tsx") {
+						t.Errorf("fetched %s before every import's .tsx candidate", path)
+					}
+				}
+			}
+			if fetched != tt.wantFetched {
+				t.Errorf("fetched %d import candidates, want %d", fetched, tt.wantFetched)
+			}
+
+			imported := 0
+			for _, f := range result.RelatedFiles {
+				if f.Relationship == "import" {
+					imported++
+				}
+			}
+			if imported != tt.wantFetched {
+				t.Errorf("imported files = %d, want %d", imported, tt.wantFetched)
+			}
+		})
+	}
+}
+
+// historyTransport serves the contents API and records the paths
+// whose commit history is requested.
+type historyTransport struct {
+	mu       sync.Mutex
+	contents *flakyContentsTransport
+	history  []string
+}
+
+func (t *historyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/commits") {
+		return t.contents.RoundTrip(req)
+	}
+	t.mu.Lock()
+	t.history = append(t.history, req.URL.Query().Get("path"))
+	t.mu.Unlock()
+	return newResponse(req, http.StatusOK, `[{"sha":"abcdef1234567","commit":{"message":"Initial commit"}}]`), nil
+}
+
+func TestFetchContextHistoryOnlyForIncludedFiles(t *testing.T) {
+	files := map[string]string{}
+	var changed []string
+	for i := 0; i < 8; i++ {
+		path := fmt.Sprintf("pkg/file%d.go", i)
+		files[path] = "package pkg\n"
+		changed = append(changed, path)
+	}
+	// Not fetched as full files: an unknown language and a missing file
+	changed = append(changed, "assets/logo.png", "pkg/deleted.go")
+
+	tests := []struct {
+		name            string
+		maxHistoryFiles int
+		wantHistory     int
+	}{
+		{"default cap", 0, 8},
+		{"configured cap", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &historyTransport{contents: &flakyContentsTransport{files: files, calls: make(map[string]int)}}
+			fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			disabled := false
+			result := fetcher.FetchContext(context.Background(), &ContextInput{
+				Owner:        "owner",
+				Repo:         "repo",
+				HeadRef:      "abc123",
+				ChangedFiles: changed,
+				Config: &config.Config{Context: &config.ContextConfig{
+					RelatedFiles:    &disabled,
+					MaxHistoryFiles: tt.maxHistoryFiles,
+				}},
+			})
+
+			if len(transport.history) != tt.wantHistory {
+				t.Errorf("fetched history for %d files, want %d: %v", len(transport.history), tt.wantHistory, transport.history)
+			}
+			included := make(map[string]bool)
+			for _, fc := range result.FullFiles {
+				included[fc.Path] = true
+			}
+			for _, path := range transport.history {
+				if !included[path] {
+					t.Errorf("fetched history for %s, which has no full-file context", path)
+				}
+			}
+			if len(result.FileHistories) != tt.wantHistory {
+				t.Errorf("FileHistories = %d, want %d", len(result.FileHistories), tt.wantHistory)
+			}
+		})
+	}
+}
+
+func TestFetchContextResolvesGoModuleImports(t *testing.T) {
+	files := map[string]string{
+		"go.mod":              "module example.com/mono\n",
+		"services/api/go.mod": "module example.com/api\n",
+		"services/api/handler.go": `package api
+
+import (
+	"example.com/api/store"
+	"example.com/mono/lib/log"
+)
+`,
+		"services/api/store/store.go":      "package store\n",
+		"services/api/store/cache.go":      "package store\n",
+		"services/api/store/store_test.go": "package store\n",
+		"services/api/store/sql/sql.go":    "package sql\n",
+		"lib/log/logger.go":                "package log\n",
+	}
+	transport := &flakyContentsTransport{files: files, calls: make(map[string]int)}
+	fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	disabled := false
+	result := fetcher.FetchContext(context.Background(), &ContextInput{
+		Owner:        "owner",
+		Repo:         "repo",
+		HeadRef:      "abc123",
+		ChangedFiles: []string{"services/api/handler.go"},
+		Config:       &config.Config{Context: &config.ContextConfig{History: &disabled}},
+	})
+
+	imported := make(map[string]string)
+	for _, rf := range result.RelatedFiles {
+		if rf.Relationship == "import" {
+			imported[rf.Path] = rf.SourceFile
+		}
+	}
+	// Every non-test file of each imported package, whatever it's named
+	for _, path := range []string{"services/api/store/cache.go", "services/api/store/store.go", "lib/log/logger.go"} {
+		if imported[path] != "services/api/handler.go" {
+			t.Errorf("imported files = %v, want %s imported by services/api/handler.go", imported, path)
+		}
+	}
+	if len(imported) != 3 {
+		t.Errorf("imported files = %v, want only the packages' non-test files", imported)
+	}
+}
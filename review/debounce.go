@@ -0,0 +1,112 @@
+package review
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReplyDebounceWindow is how long a reply waits for further @mentions in
+// the same thread before answering them together.
+const DefaultReplyDebounceWindow = 3 * time.Second
+
+// ReplyDebouncer coalesces rapid @mentions in the same review thread so they are
+// answered with a single reply.
+type ReplyDebouncer interface {
+	// Debounce queues a mention for the thread. The first mention in a window
+	// waits for the window to close and gets every mention queued meanwhile, in
+	// order, with ok true. Later mentions in the window return ok false at once.
+	Debounce(ctx context.Context, threadID int64, input *ReplyInput) (batch []*ReplyInput, ok bool)
+}
+
+// memoryDebouncer is a ReplyDebouncer for a single process.
+type memoryDebouncer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[int64][]*ReplyInput
+}
+
+// NewMemoryReplyDebouncer creates an in-memory debouncer with the given window.
+func NewMemoryReplyDebouncer(window time.Duration) ReplyDebouncer {
+	return &memoryDebouncer{
+		window:  window,
+		pending: make(map[int64][]*ReplyInput),
+	}
+}
+
+func (d *memoryDebouncer) Debounce(ctx context.Context, threadID int64, input *ReplyInput) ([]*ReplyInput, bool) {
+	d.mu.Lock()
+	if batch, ok := d.pending[threadID]; ok {
+		d.pending[threadID] = append(batch, input)
+		d.mu.Unlock()
+		return nil, false
+	}
+	d.pending[threadID] = []*ReplyInput{input}
+	d.mu.Unlock()
+
+	// Answer what has arrived so far if the request is cancelled while waiting
+	timer := time.NewTimer(d.window)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	batch := d.pending[threadID]
+	delete(d.pending, threadID)
+	return batch, true
+}
+
+// SetReplyDebounceWindow sets how long a reply waits to coalesce further @mentions
+// in the same thread (default 3s). A window of 0 or less disables debouncing.
+func (r *Reviewer) SetReplyDebounceWindow(window time.Duration) {
+	if window <= 0 {
+		r.replyDebouncer = nil
+		return
+	}
+	r.replyDebouncer = NewMemoryReplyDebouncer(window)
+}
+
+// SetReplyDebouncer replaces the reply debouncer, e.g. with one shared across processes.
+func (r *Reviewer) SetReplyDebouncer(d ReplyDebouncer) {
+	r.replyDebouncer = d
+}
+
+// DebounceReply waits for further @mentions in the thread and returns a single
+// input answering all of them. It returns false when the mention was folded into
+// a reply another caller is already waiting to send.
+func (r *Reviewer) DebounceReply(ctx context.Context, threadID int64, input *ReplyInput) (*ReplyInput, bool) {
+	if r.replyDebouncer == nil {
+		return input, true
+	}
+	batch, ok := r.replyDebouncer.Debounce(ctx, threadID, input)
+	if !ok {
+		return nil, false
+	}
+	if len(batch) > 1 {
+		r.logger.Info("coalesced rapid mentions into one reply", "thread_id", threadID, "mentions", len(batch))
+	}
+	return coalesceReplies(batch), true
+}
+
+// coalesceReplies merges queued mentions into one reply to the latest, which
+// carries the most complete thread context, with every question included.
+func coalesceReplies(batch []*ReplyInput) *ReplyInput {
+	latest := *batch[len(batch)-1]
+	if len(batch) == 1 {
+		return &latest
+	}
+
+	questions := make([]string, 0, len(batch))
+	for _, in := range batch {
+		if q := strings.TrimSpace(in.UserQuestion); q != "" {
+			questions = append(questions, q)
+		}
+	}
+	latest.UserQuestion = strings.Join(questions, "\n\n")
+	return &latest
+}
@@ -0,0 +1,83 @@
+package review
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryReplyDebouncerCoalescesWithinWindow(t *testing.T) {
+	d := NewMemoryReplyDebouncer(100 * time.Millisecond)
+	ctx := context.Background()
+
+	type outcome struct {
+		batch []*ReplyInput
+		ok    bool
+	}
+	first := make(chan outcome)
+	go func() {
+		batch, ok := d.Debounce(ctx, 1, &ReplyInput{CommentID: 10, UserQuestion: "why?"})
+		first <- outcome{batch, ok}
+	}()
+
+	// Wait until the first mention is pending, then send two more
+	time.Sleep(20 * time.Millisecond)
+	for _, id := range []int64{11, 12} {
+		if _, ok := d.Debounce(ctx, 1, &ReplyInput{CommentID: id}); ok {
+			t.Errorf("mention %d should be folded into the pending reply", id)
+		}
+	}
+
+	// A different thread is debounced separately
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if batch, ok := d.Debounce(ctx, 2, &ReplyInput{CommentID: 20}); !ok || len(batch) != 1 {
+			t.Errorf("other thread Debounce() = %d inputs, %v, want 1, true", len(batch), ok)
+		}
+	}()
+
+	got := <-first
+	wg.Wait()
+	if !got.ok || len(got.batch) != 3 {
+		t.Fatalf("Debounce() = %d inputs, %v, want 3, true", len(got.batch), got.ok)
+	}
+	for i, want := range []int64{10, 11, 12} {
+		if got.batch[i].CommentID != want {
+			t.Errorf("batch[%d].CommentID = %d, want %d", i, got.batch[i].CommentID, want)
+		}
+	}
+}
+
+func TestMemoryReplyDebouncerSeparateOutsideWindow(t *testing.T) {
+	d := NewMemoryReplyDebouncer(10 * time.Millisecond)
+	ctx := context.Background()
+
+	for _, id := range []int64{10, 11} {
+		batch, ok := d.Debounce(ctx, 1, &ReplyInput{CommentID: id})
+		if !ok || len(batch) != 1 || batch[0].CommentID != id {
+			t.Errorf("Debounce(%d) = %d inputs, %v, want its own reply", id, len(batch), ok)
+		}
+	}
+}
+
+func TestCoalesceReplies(t *testing.T) {
+	batch := []*ReplyInput{
+		{CommentID: 10, UserQuestion: "Why is this needed?", ThreadContext: "first"},
+		{CommentID: 11, UserQuestion: "  ", ThreadContext: "second"},
+		{CommentID: 12, UserQuestion: "Could it be simpler?", ThreadContext: "third"},
+	}
+
+	got := coalesceReplies(batch)
+	if got.CommentID != 12 || got.ThreadContext != "third" {
+		t.Errorf("coalesceReplies() replies to %d with context %q, want the latest mention", got.CommentID, got.ThreadContext)
+	}
+	if want := "Why is this needed?\n\nCould it be simpler?"; got.UserQuestion != want {
+		t.Errorf("UserQuestion = %q, want %q", got.UserQuestion, want)
+	}
+	if batch[2].UserQuestion != "Could it be simpler?" {
+		t.Error("coalesceReplies() modified its input")
+	}
+}
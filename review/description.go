@@ -0,0 +1,103 @@
+package review
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/shipitai/shipitai/storage"
+)
+
+// missingDescriptionNote is added to the review summary when the description check
+// finds nothing but template text.
+const missingDescriptionNote = "**Description:** This PR has no description. A few sentences on what it changes and why help reviewers."
+
+// htmlCommentPattern matches HTML comments, which PR templates use for instructions,
+// along with the line break after a comment that fills its own lines.
+var htmlCommentPattern = regexp.MustCompile(`(?m)^[ \t]*<!--(?s:.*?)-->[ \t]*(?:\n|$)|(?s)<!--.*?-->`)
+
+// listMarkerPattern matches a leading list item or checkbox marker.
+var listMarkerPattern = regexp.MustCompile(`^(?:[-*+]|\d+\.)\s+(?:\[[ xX]\]\s*)?`)
+
+// placeholderPatterns match lines of template text left for the author to replace.
+var placeholderPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\[[^\]]*\]$`), // [Describe your change]
+	regexp.MustCompile(`^<[^>]*>$`),    // <describe here>
+	regexp.MustCompile(`(?i)^_?no response_?$`),
+	regexp.MustCompile(`(?i)^(?:todo|tbd|\.\.\.|…)$`),
+	regexp.MustCompile(`(?i)^(?:please )?(?:describe|explain|summarize|list|add|provide|include|write|link)\b.*\b(?:here|below)[.:]?$`),
+}
+
+// headingPattern matches a Markdown heading, capturing its level.
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s`)
+
+// stripTemplatePlaceholders removes unfilled PR template text from a description:
+// HTML comments, placeholder lines, and headings whose sections hold nothing else.
+// A description that is only an unfilled template comes back empty.
+func stripTemplatePlaceholders(body string) string {
+	lines := strings.Split(htmlCommentPattern.ReplaceAllString(body, ""), "\n")
+
+	// Mark the lines that carry the author's own text
+	content := make([]bool, len(lines))
+	levels := make([]int, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+			levels[i] = len(m[1])
+			continue
+		}
+		content[i] = !isPlaceholderLine(trimmed)
+	}
+
+	var kept []string
+	blank := false
+	for i, line := range lines {
+		keep := content[i]
+		if levels[i] > 0 {
+			// Keep a heading only if its section, subsections included, has content
+			for j := i + 1; j < len(lines) && (levels[j] == 0 || levels[j] > levels[i]); j++ {
+				if content[j] {
+					keep = true
+					break
+				}
+			}
+		}
+		if !keep {
+			if strings.TrimSpace(line) == "" && len(kept) > 0 && !blank {
+				kept = append(kept, "")
+				blank = true
+			}
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t\r"))
+		blank = false
+	}
+
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// isPlaceholderLine reports whether a trimmed line is blank or template text:
+// an empty list item, an unticked checkbox with no text, or a known placeholder.
+func isPlaceholderLine(line string) bool {
+	text := strings.TrimSpace(listMarkerPattern.ReplaceAllString(line, ""))
+	if text == "" || text == "-" || text == "*" {
+		return true
+	}
+	for _, p := range placeholderPatterns {
+		if p.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// descriptionCheck returns an analyzer that flags a PR whose description is empty
+// once unfilled template text is stripped. It has no say in the approval.
+func descriptionCheck(body string) analyzer {
+	return analyzerFunc{label: "description_check", fn: func(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error) {
+		if stripTemplatePlaceholders(body) != "" {
+			return nil, nil, nil
+		}
+		return &ClaudeResponse{Summary: missingDescriptionNote}, nil, nil
+	}}
+}
@@ -0,0 +1,118 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestStripTemplatePlaceholders(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "filled section kept",
+			body: "## Description\nAdds retry logic to the file fetcher.\n\n## Testing\nUnit tests.",
+			want: "## Description\nAdds retry logic to the file fetcher.\n\n## Testing\nUnit tests.",
+		},
+		{
+			name: "unfilled placeholder section dropped",
+			body: "## Description\nFixes the flaky webhook test.\n\n## Screenshots\n[Add screenshots here]\n\n## Notes\nDescribe anything reviewers should know here.",
+			want: "## Description\nFixes the flaky webhook test.",
+		},
+		{
+			name: "HTML comment only section dropped",
+			body: "## Description\n<!-- describe here -->\n\n## Motivation\nUsers hit timeouts on large repos.",
+			want: "## Motivation\nUsers hit timeouts on large repos.",
+		},
+		{
+			name: "multiline HTML comment removed from filled section",
+			body: "## Description\n<!--\nWhat does this PR do?\nWhy?\n-->\nBumps the Go version.",
+			want: "## Description\nBumps the Go version.",
+		},
+		{
+			name: "unfilled template is empty",
+			body: "## Description\n<!-- describe here -->\n\n## Checklist\n- [ ]\n- \n\n## Related issues\n_No response_\nTBD",
+			want: "",
+		},
+		{
+			name: "parent heading kept for filled subsection",
+			body: "## Changes\n### Backend\nNew endpoint.\n### Frontend\n<describe here>",
+			want: "## Changes\n### Backend\nNew endpoint.",
+		},
+		{
+			name: "ticked checklist kept",
+			body: "## Checklist\n- [x] Tests added\n- [ ] Docs updated",
+			want: "## Checklist\n- [x] Tests added\n- [ ] Docs updated",
+		},
+		{
+			name: "body without headings",
+			body: "Quick typo fix.\n<!-- template hint -->",
+			want: "Quick typo fix.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripTemplatePlaceholders(tt.body); got != tt.want {
+				t.Errorf("stripTemplatePlaceholders() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPRDescriptionUnfilledTemplate(t *testing.T) {
+	input := &ReviewInput{PRBody: "## Description\n<!-- describe here -->\n\n## Testing\n[How was this tested?]"}
+	if got := prDescription(input, &config.Config{}); got != "" {
+		t.Errorf("prDescription() = %q, want empty so the prompt says no description was provided", got)
+	}
+}
+
+func TestReviewDescriptionCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		body     string
+		wantNote bool
+	}{
+		{"unfilled template", "description_check: true", "## Description\n<!-- describe here -->\n\n## Testing\n[How was this tested?]", true},
+		{"filled description", "description_check: true", "## Description\nAdds retry logic to the file fetcher.", false},
+		{"check disabled", "enabled: true", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claude := newFakeClaude(t, `{"summary":"Looks fine.","approval":"approve","comments":[]}`)
+			transport := &reviewsTransport{diff: largeFileDiff("main.go", 3), config: tt.config}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", memory.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, PRBody: tt.body}); err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			if len(transport.posted) != 1 {
+				t.Fatalf("posted reviews = %d, want 1", len(transport.posted))
+			}
+			posted := transport.posted[0]
+			if got := strings.Contains(posted.Body, missingDescriptionNote); got != tt.wantNote {
+				t.Errorf("review body has description note = %v, want %v; body:\n%s", got, tt.wantNote, posted.Body)
+			}
+			if !strings.Contains(posted.Body, "Looks fine.") {
+				t.Errorf("review body = %q, want Claude's summary kept", posted.Body)
+			}
+			if posted.Event != "APPROVE" {
+				t.Errorf("Event = %q, want APPROVE, the check has no say in the verdict", posted.Event)
+			}
+			if claude.calls() != 1 {
+				t.Errorf("Claude calls = %d, want 1", claude.calls())
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package review
+
+import "sync"
+
+// FileCache holds file contents fetched during a single review, so chunks that
+// share files, or context lookups that overlap, don't download the same blob
+// twice. Create one per review and drop it afterward: entries are keyed by
+// path@ref and never expire. Safe for concurrent use.
+type FileCache struct {
+	mu       sync.Mutex
+	contents map[string]string
+}
+
+// NewFileCache creates an empty file cache.
+func NewFileCache() *FileCache {
+	return &FileCache{contents: make(map[string]string)}
+}
+
+// lookup returns the cached contents of paths at ref, and the paths not cached.
+func (c *FileCache) lookup(paths []string, ref string) (map[string]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	found := make(map[string]string)
+	var missing []string
+	for _, p := range paths {
+		if content, ok := c.contents[fileCacheKey(p, ref)]; ok {
+			found[p] = content
+		} else {
+			missing = append(missing, p)
+		}
+	}
+	return found, missing
+}
+
+// store caches fetched contents at ref. Files that weren't found aren't cached,
+// since the fetch may have failed transiently.
+func (c *FileCache) store(contents map[string]string, ref string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for p, content := range contents {
+		c.contents[fileCacheKey(p, ref)] = content
+	}
+}
+
+// fileCacheKey identifies a file at a ref.
+func fileCacheKey(path, ref string) string {
+	return path + "@" + ref
+}
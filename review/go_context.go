@@ -0,0 +1,66 @@
+package review
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// changedGoDeclarations returns the package clause and the top-level declarations
+// (with their doc comments) of a Go file that overlap the changed lines, each
+// headed by its line range. It reports false when the file doesn't parse or no
+// declaration overlaps, so callers can fall back to the whole file.
+func changedGoDeclarations(path, content string, changed map[int]bool) (string, bool) {
+	if len(changed) == 0 {
+		return "", false
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n", file.Name.Name)
+	found := false
+	for _, decl := range file.Decls {
+		start := declStart(decl)
+		startLine, endLine := fset.Position(start).Line, fset.Position(decl.End()).Line
+		if !overlapsLines(changed, startLine, endLine) {
+			continue
+		}
+		found = true
+		fmt.Fprintf(&b, "\n// lines %d-%d\n", startLine, endLine)
+		b.WriteString(content[fset.Position(start).Offset:fset.Position(decl.End()).Offset])
+		b.WriteString("\n")
+	}
+	return b.String(), found
+}
+
+// declStart returns where a declaration begins, including its doc comment.
+func declStart(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
+
+// overlapsLines reports whether any changed line falls within [start, end].
+func overlapsLines(changed map[int]bool, start, end int) bool {
+	for line := range changed {
+		if line >= start && line <= end {
+			return true
+		}
+	}
+	return false
+}
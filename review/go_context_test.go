@@ -0,0 +1,96 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+const goContextSource = `package shapes
+
+import "math"
+
+// Circle is a round shape.
+type Circle struct {
+	R float64
+}
+
+// Area returns the circle's area.
+func (c Circle) Area() float64 {
+	return math.Pi * c.R * c.R
+}
+
+// Perimeter returns the circle's circumference.
+func (c Circle) Perimeter() float64 {
+	return 2 * math.Pi * c.R
+}
+`
+
+func TestChangedGoDeclarations(t *testing.T) {
+	got, ok := changedGoDeclarations("shapes.go", goContextSource, map[int]bool{12: true})
+	if !ok {
+		t.Fatal("changedGoDeclarations() reported no declarations")
+	}
+	want := "package shapes\n\n// lines 10-13\n// Area returns the circle's area.\nfunc (c Circle) Area() float64 {\n\treturn math.Pi * c.R * c.R\n}\n"
+	if got != want {
+		t.Errorf("changedGoDeclarations() =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, ok := changedGoDeclarations("shapes.go", goContextSource, map[int]bool{14: true}); ok {
+		t.Error("changedGoDeclarations() found a declaration for a line between declarations")
+	}
+	if _, ok := changedGoDeclarations("shapes.go", "package shapes\nfunc {", map[int]bool{2: true}); ok {
+		t.Error("changedGoDeclarations() succeeded on a file that doesn't parse")
+	}
+}
+
+func TestFetchContextSmartGoContext(t *testing.T) {
+	files := map[string]string{"shapes.go": goContextSource, "broken.go": "package shapes\nfunc {"}
+	changed := DiffLineMap{"shapes.go": {17: true}, "broken.go": {2: true}}
+
+	tests := []struct {
+		name      string
+		smart     bool
+		wantDecls bool
+	}{
+		{"off sends whole file", false, false},
+		{"on sends changed declarations", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &flakyContentsTransport{files: files, calls: make(map[string]int)}
+			fetcher := NewContextFetcher(github.NewClientWithTransport(transport), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			disabled := false
+			result := fetcher.FetchContext(context.Background(), &ContextInput{
+				Owner:        "owner",
+				Repo:         "repo",
+				HeadRef:      "abc123",
+				ChangedFiles: []string{"shapes.go", "broken.go"},
+				ChangedLines: changed,
+				Config: &config.Config{Context: &config.ContextConfig{
+					RelatedFiles:   &disabled,
+					History:        &disabled,
+					SmartGoContext: tt.smart,
+				}},
+			})
+
+			if len(result.FullFiles) != 2 {
+				t.Fatalf("got %d full files, want 2", len(result.FullFiles))
+			}
+			shapes, broken := result.FullFiles[0], result.FullFiles[1]
+			if shapes.DeclarationsOnly != tt.wantDecls || strings.Contains(shapes.Content, "Area") == tt.wantDecls {
+				t.Errorf("shapes.go DeclarationsOnly = %v with content:\n%s", shapes.DeclarationsOnly, shapes.Content)
+			}
+			if broken.DeclarationsOnly || broken.Content != files["broken.go"] {
+				t.Errorf("broken.go = %+v, want the whole file", broken)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package review
 
 import (
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -97,9 +99,37 @@ func GetTestFilePath(path string) string {
 		return filepath.Join(testDir, name+"Test"+ext)
 
 	case "rust":
-		// Rust typically uses inline #[cfg(test)] modules
-		// but also has tests/integration_test.rs
-		return ""
+		// src/foo.rs -> tests/foo.rs (integration tests beside the crate's src/)
+		// Unit tests live in inline #[cfg(test)] modules, which are already
+		// part of the file itself
+		p := "/" + filepath.ToSlash(path)
+		i := strings.LastIndex(p, "/src/")
+		if i < 0 || strings.Contains(p, "/tests/") {
+			return ""
+		}
+		return filepath.Join(strings.TrimPrefix(p[:i], "/"), "tests", base)
+
+	case "csharp":
+		// Foo.cs -> FooTests.cs (xUnit/NUnit convention)
+		if strings.HasSuffix(name, "Tests") || strings.HasSuffix(name, "Test") {
+			return ""
+		}
+		return filepath.Join(dir, name+"Tests"+ext)
+
+	case "php":
+		// Foo.php -> FooTest.php (PHPUnit)
+		if strings.HasSuffix(name, "Test") {
+			return ""
+		}
+		return filepath.Join(dir, name+"Test"+ext)
+
+	case "scala":
+		// src/main/scala/Foo.scala -> src/test/scala/FooSpec.scala (ScalaTest)
+		if strings.HasSuffix(name, "Spec") || strings.HasSuffix(name, "Test") {
+			return ""
+		}
+		testDir := filepath.Dir(strings.Replace(path, "/main/", "/test/", 1))
+		return filepath.Join(testDir, name+"Spec"+ext)
 
 	default:
 		return ""
@@ -107,7 +137,8 @@ func GetTestFilePath(path string) string {
 }
 
 // ParseLocalImports extracts local import paths from file content.
-// modulePath is the Go module path (e.g., "github.com/user/repo") for Go files.
+// modulePath is the Go module path (e.g., "github.com/user/repo") for Go files,
+// or the base package (e.g., "com.company") for Java and Kotlin files.
 // Returns paths relative to repository root where possible.
 func ParseLocalImports(path, content, modulePath string) []string {
 	lang := DetectLanguage(path)
@@ -120,6 +151,8 @@ func ParseLocalImports(path, content, modulePath string) []string {
 		imports = parseTSImports(path, content)
 	case "python":
 		imports = parsePythonImports(path, content)
+	case "java", "kotlin":
+		imports = parseJavaImports(path, content, modulePath)
 	}
 
 	return imports
@@ -130,38 +163,109 @@ func parseGoImports(content, modulePath string) []string {
 	if modulePath == "" {
 		return nil
 	}
+	return resolveGoImports(content, map[string]string{"": modulePath})
+}
 
+// resolveGoImports extracts the Go source's imports of packages in the given
+// modules, keyed by the directory holding each module's go.mod ("" for the repo
+// root), and returns their directories relative to the repository root. An import
+// belongs to the module with the longest matching path, so nested modules win
+// over the module enclosing them.
+func resolveGoImports(content string, modules map[string]string) []string {
 	var imports []string
+	for _, imp := range goImportPaths(content) {
+		dir, rel, ok := "", "", false
+		best := ""
+		for modDir, modPath := range modules {
+			if modPath == "" || len(modPath) <= len(best) {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(imp, modPath+"/"):
+				dir, rel, ok, best = modDir, strings.TrimPrefix(imp, modPath+"/"), true, modPath
+			case imp == modPath && modDir != "":
+				// The module's root package, outside the repo root
+				dir, rel, ok, best = modDir, "", true, modPath
+			}
+		}
+		if ok {
+			imports = append(imports, path.Join(dir, rel))
+		}
+	}
+	return imports
+}
+
+// goImportPaths returns every import path in Go source.
+func goImportPaths(content string) []string {
+	var paths []string
 
 	// Match import statements: import "path" or import ( "path" )
 	// Single import
-	singleRe := regexp.MustCompile(`import\s+"([^"]+)"`)
+	singleRe := regexp.MustCompile(`import\s+(?:\w+\s+)?"([^"]+)"`)
 	for _, match := range singleRe.FindAllStringSubmatch(content, -1) {
-		if strings.HasPrefix(match[1], modulePath+"/") {
-			// Convert module path to relative path
-			relPath := strings.TrimPrefix(match[1], modulePath+"/")
-			imports = append(imports, relPath)
-		}
+		paths = append(paths, match[1])
 	}
 
 	// Grouped imports
 	groupRe := regexp.MustCompile(`import\s*\(([^)]+)\)`)
+	pathRe := regexp.MustCompile(`^(?:[\w.]+\s+)?"([^"]+)"`)
 	for _, group := range groupRe.FindAllStringSubmatch(content, -1) {
-		lines := strings.Split(group[1], "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
+		for _, line := range strings.Split(group[1], "\n") {
 			// Handle: "path" or alias "path"
-			pathRe := regexp.MustCompile(`(?:\w+\s+)?"([^"]+)"`)
-			if m := pathRe.FindStringSubmatch(line); m != nil {
-				if strings.HasPrefix(m[1], modulePath+"/") {
-					relPath := strings.TrimPrefix(m[1], modulePath+"/")
-					imports = append(imports, relPath)
-				}
+			if m := pathRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				paths = append(paths, m[1])
 			}
 		}
 	}
 
-	return imports
+	return paths
+}
+
+// parseGoModulePath returns the module path declared by a go.mod file, or "" if
+// it has no module directive.
+func parseGoModulePath(gomod string) string {
+	for _, line := range strings.Split(gomod, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		rest, ok := strings.CutPrefix(line, "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t' && rest[0] != '"') {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(rest), `"`)
+	}
+	return ""
+}
+
+// goModCandidates returns the go.mod paths that could declare the modules of the
+// given Go files: the repository root's first, then one in each file's directory
+// and every directory above it, nearest first.
+func goModCandidates(files []string) []string {
+	candidates := []string{"go.mod"}
+	seen := map[string]bool{"go.mod": true}
+	for _, file := range files {
+		for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if gomod := path.Join(dir, "go.mod"); !seen[gomod] {
+				seen[gomod] = true
+				candidates = append(candidates, gomod)
+			}
+		}
+	}
+	return candidates
+}
+
+// goSourceFiles returns the Go files in a package directory listing, leaving out
+// tests, in sorted order.
+func goSourceFiles(files []string) []string {
+	var result []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") && !strings.HasSuffix(f, "_test.go") {
+			result = append(result, f)
+		}
+	}
+	sort.Strings(result)
+	return result
 }
 
 // parseTSImports extracts relative imports from TypeScript/JavaScript source.
@@ -226,6 +330,77 @@ func parsePythonImports(filePath, content string) []string {
 	return imports
 }
 
+var (
+	javaImportRe  = regexp.MustCompile(`(?m)^\s*import\s+(?:static\s+)?(\w+(?:\.\w+)*)`)
+	javaPackageRe = regexp.MustCompile(`(?m)^\s*package\s+(\w+(?:\.\w+)*)`)
+)
+
+// parseJavaImports extracts project imports from Java or Kotlin source.
+// Imports under basePackage are mapped to files under the importing file's source
+// root (e.g. src/main/java). Wildcard imports of a package are skipped, since
+// they name a directory rather than a file. If basePackage is empty, the first
+// two segments of the file's own package are used.
+func parseJavaImports(filePath, content, basePackage string) []string {
+	if basePackage == "" {
+		m := javaPackageRe.FindStringSubmatch(content)
+		if m == nil {
+			return nil
+		}
+		segments := strings.Split(m[1], ".")
+		basePackage = strings.Join(segments[:min(2, len(segments))], ".")
+	}
+
+	root := javaSourceRoot(filePath)
+	ext := filepath.Ext(filePath)
+	var imports []string
+	seen := make(map[string]bool)
+	for _, match := range javaImportRe.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		if name != basePackage && !strings.HasPrefix(name, basePackage+".") {
+			continue
+		}
+
+		// The first capitalized segment is the class, and names its file; anything
+		// after it is a nested class or a static member
+		segments := strings.Split(name, ".")
+		class := -1
+		for i, s := range segments {
+			if s[0] >= 'A' && s[0] <= 'Z' {
+				class = i
+				break
+			}
+		}
+
+		if class < 0 {
+			// A package wildcard, or a Kotlin top-level function, whose file can't be known
+			continue
+		}
+		imp := filepath.Join(root, filepath.Join(segments[:class+1]...)+ext)
+		if !seen[imp] {
+			seen[imp] = true
+			imports = append(imports, imp)
+		}
+	}
+
+	return imports
+}
+
+// javaSourceRoot returns the source root of a Java or Kotlin file: the path up to
+// its src/main/java or src/main/kotlin directory, so multi-module builds resolve
+// imports within the same module. Defaults to the conventional root at the repository root.
+func javaSourceRoot(filePath string) string {
+	p := "/" + filepath.ToSlash(filePath)
+	for _, dir := range []string{"/src/main/java/", "/src/main/kotlin/"} {
+		if i := strings.Index(p, dir); i >= 0 {
+			return p[1 : i+len(dir)-1]
+		}
+	}
+	if DetectLanguage(filePath) == "kotlin" {
+		return "src/main/kotlin"
+	}
+	return "src/main/java"
+}
+
 // resolveRelativeImport converts a relative import path to a repository-relative path.
 func resolveRelativeImport(fileDir, importPath string) string {
 	// Join the file's directory with the import path
@@ -274,6 +449,24 @@ func GetRelatedTestPaths(path string) []string {
 		paths = append(paths, filepath.Join(dir, "tests", "test_"+name+ext))
 		// conftest.py for fixtures
 		paths = append(paths, filepath.Join(dir, "conftest.py"))
+
+	case "csharp":
+		// Also try Foo.Tests.cs
+		if primary != "" {
+			paths = append(paths, filepath.Join(dir, name+".Tests"+ext))
+		}
+
+	case "php":
+		// Also try tests/FooTest.php
+		if primary != "" {
+			paths = append(paths, filepath.Join("tests", name+"Test"+ext))
+		}
+
+	case "scala":
+		// Also try FooTest.scala
+		if primary != "" {
+			paths = append(paths, filepath.Join(filepath.Dir(primary), name+"Test"+ext))
+		}
 	}
 
 	return paths
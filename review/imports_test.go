@@ -1,6 +1,7 @@
 package review
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -83,8 +84,24 @@ func TestGetTestFilePath(t *testing.T) {
 		{"src/main/java/com/example/Service.java", "src/test/java/com/example/ServiceTest.java"},
 		{"ServiceTest.java", ""}, // Already a test file
 
-		// Rust (no convention)
-		{"lib.rs", ""},
+		// Rust (integration tests)
+		{"src/parser.rs", "tests/parser.rs"},
+		{"crates/core/src/net/client.rs", "crates/core/tests/client.rs"},
+		{"tests/parser.rs", ""}, // Already a test file
+		{"lib.rs", ""},          // No src/ directory
+
+		// C#
+		{"Services/OrderService.cs", "Services/OrderServiceTests.cs"},
+		{"OrderServiceTests.cs", ""}, // Already a test file
+
+		// PHP
+		{"src/Invoice.php", "src/InvoiceTest.php"},
+		{"InvoiceTest.php", ""}, // Already a test file
+
+		// Scala
+		{"src/main/scala/Parser.scala", "src/test/scala/ParserSpec.scala"},
+		{"ParserSpec.scala", ""}, // Already a test file
+		{"ParserTest.scala", ""}, // Already a test file
 
 		// Unknown extension
 		{"README.md", ""},
@@ -113,6 +130,18 @@ func TestGetRelatedTestPaths(t *testing.T) {
 
 		// Python - test_foo.py, tests/test_foo.py, conftest.py
 		{"handler.py", 3},
+
+		// C# - FooTests.cs, Foo.Tests.cs
+		{"OrderService.cs", 2},
+
+		// PHP - FooTest.php, tests/FooTest.php
+		{"src/Invoice.php", 2},
+
+		// Rust - tests/foo.rs
+		{"src/parser.rs", 1},
+
+		// Scala - FooSpec.scala, FooTest.scala
+		{"Parser.scala", 2},
 	}
 
 	for _, tt := range tests {
@@ -232,6 +261,81 @@ from ..shared import helpers
 	}
 }
 
+func TestParseLocalImports_Java(t *testing.T) {
+	content := `package com.example.orders.service;
+
+import java.util.List;
+import com.example.orders.model.Order;
+import com.example.orders.model.Order.Status;
+import com.example.shared.util.*;
+import static com.example.shared.Money.ZERO;
+import org.springframework.stereotype.Service;
+`
+
+	tests := []struct {
+		name        string
+		path        string
+		basePackage string
+		expected    []string
+	}{
+		{
+			name:        "configured base package",
+			path:        "src/main/java/com/example/orders/service/OrderService.java",
+			basePackage: "com.example",
+			expected: []string{
+				"src/main/java/com/example/orders/model/Order.java",
+				"src/main/java/com/example/shared/Money.java",
+			},
+		},
+		{
+			name:        "narrower base package",
+			path:        "src/main/java/com/example/orders/service/OrderService.java",
+			basePackage: "com.example.orders",
+			expected: []string{
+				"src/main/java/com/example/orders/model/Order.java",
+			},
+		},
+		{
+			name: "inferred base package in a submodule",
+			path: "orders/src/main/java/com/example/orders/service/OrderService.java",
+			expected: []string{
+				"orders/src/main/java/com/example/orders/model/Order.java",
+				"orders/src/main/java/com/example/shared/Money.java",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := ParseLocalImports(tt.path, content, tt.basePackage)
+			if strings.Join(imports, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("ParseLocalImports() =\n%v\nwant\n%v", imports, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseLocalImports_Kotlin(t *testing.T) {
+	content := `package com.example.app
+
+import com.example.app.data.UserRepository
+import com.example.app.ui.theme.*
+import com.example.app.util.formatDate
+import com.example.app.data.User as DataUser
+import kotlinx.coroutines.flow.Flow
+`
+
+	imports := ParseLocalImports("app/src/main/kotlin/com/example/app/MainViewModel.kt", content, "")
+
+	expected := []string{
+		"app/src/main/kotlin/com/example/app/data/UserRepository.kt",
+		"app/src/main/kotlin/com/example/app/data/User.kt",
+	}
+	if strings.Join(imports, " ") != strings.Join(expected, " ") {
+		t.Errorf("ParseLocalImports() =\n%v\nwant\n%v", imports, expected)
+	}
+}
+
 func TestParseLocalImports_EmptyModulePath(t *testing.T) {
 	content := `package main
 
@@ -255,3 +359,57 @@ func TestParseLocalImports_UnknownLanguage(t *testing.T) {
 		t.Errorf("got %d imports for unknown language, want 0", len(imports))
 	}
 }
+
+func TestParseGoModulePath(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string
+		want  string
+	}{
+		{"plain", "module github.com/example/myapp\n\ngo 1.23\n", "github.com/example/myapp"},
+		{"quoted with comment", "// Service module\nmodule \"example.com/api\" // api\n", "example.com/api"},
+		{"no module directive", "go 1.23\n\nrequire example.com/lib v1.0.0\n", ""},
+		{"modulename is not a directive", "modulex example.com/x\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGoModulePath(tt.gomod); got != tt.want {
+				t.Errorf("parseGoModulePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveGoImports(t *testing.T) {
+	content := `package handler
+
+import (
+	"context"
+
+	"example.com/mono/lib/log"
+	"example.com/mono/tools/gen"
+	store "example.com/api/store"
+	"example.com/api"
+	"github.com/other/library"
+)
+`
+	modules := map[string]string{
+		"":             "example.com/mono",
+		"tools":        "example.com/mono/tools",
+		"services/api": "example.com/api",
+	}
+
+	got := resolveGoImports(content, modules)
+	want := []string{"lib/log", "tools/gen", "services/api/store", "services/api"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("resolveGoImports() = %v, want %v", got, want)
+	}
+}
+
+func TestGoModCandidates(t *testing.T) {
+	got := goModCandidates([]string{"services/api/store/store.go", "services/api/handler.go", "main.go"})
+	want := []string{"go.mod", "services/api/store/go.mod", "services/api/go.mod", "services/go.mod"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("goModCandidates() = %v, want %v", got, want)
+	}
+}
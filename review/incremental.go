@@ -0,0 +1,81 @@
+package review
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shipitai/shipitai/config"
+)
+
+// lastReviewedSHA returns the head commit covered by the PR's latest stored
+// review, or "" if none of its reviews recorded one.
+func (r *Reviewer) lastReviewedSHA(ctx context.Context, input *ReviewInput) string {
+	if r.storage == nil {
+		return ""
+	}
+	reviews, err := r.storage.ListReviewsForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to list earlier reviews", "error", err)
+		return ""
+	}
+	for i := len(reviews) - 1; i >= 0; i-- {
+		if reviews[i].HeadSHA != "" {
+			return reviews[i].HeadSHA
+		}
+	}
+	return ""
+}
+
+// incrementalDiff returns the diff of the commits pushed since the PR's last
+// review, filtered like the PR diff, and whether it's narrower than the PR diff.
+// It falls back to the full PR diff when the last reviewed head is unknown or
+// unchanged, the compare fails, or the compare touches files outside the PR
+// diff, as when the branch was force-pushed onto a newer base and the old head
+// is no longer an ancestor.
+func (r *Reviewer) incrementalDiff(ctx context.Context, input *ReviewInput, cfg *config.Config, diff string) (string, bool) {
+	base := r.lastReviewedSHA(ctx, input)
+	if base == "" || base == input.HeadSHA {
+		return diff, false
+	}
+
+	incremental, err := r.githubClient.CompareDiff(ctx, input.InstallationID, input.Owner, input.Repo, base, input.HeadSHA)
+	if err != nil {
+		r.logger.Warn("failed to fetch diff since last review, reviewing full diff", "base", base, "error", err)
+		return diff, false
+	}
+	incremental = filterDiff(incremental, cfg)
+	if strings.TrimSpace(incremental) == "" {
+		return diff, false
+	}
+
+	prFiles := make(map[string]bool)
+	for _, f := range ParseDiffInfo(diff).Files {
+		prFiles[f] = true
+	}
+	for _, f := range ParseDiffInfo(incremental).Files {
+		if !prFiles[f] {
+			r.logger.Info("diff since last review touches files outside the PR, reviewing full diff", "base", base, "file", f)
+			return diff, false
+		}
+	}
+
+	r.logger.Info("reviewing changes since last review", "base", base, "head", input.HeadSHA)
+	return incremental, true
+}
+
+// intersectDiffLines returns the lines commentable in both a and b.
+func intersectDiffLines(a, b DiffLineMap) DiffLineMap {
+	result := make(DiffLineMap)
+	for path, lines := range a {
+		for line := range lines {
+			if !b[path][line] {
+				continue
+			}
+			if result[path] == nil {
+				result[path] = make(map[int]bool)
+			}
+			result[path][line] = true
+		}
+	}
+	return result
+}
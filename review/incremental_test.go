@@ -0,0 +1,124 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+// compareTransport serves commit compares from compares, keyed by "base...head",
+// failing any it doesn't know, and passes everything else to next.
+type compareTransport struct {
+	next     http.RoundTripper
+	compares map[string]string
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func (t *compareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, spec, ok := strings.Cut(req.URL.Path, "/compare/")
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	t.mu.Lock()
+	t.requested = append(t.requested, spec)
+	t.mu.Unlock()
+	if diff, ok := t.compares[spec]; ok {
+		return newResponse(req, http.StatusOK, diff), nil
+	}
+	return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+}
+
+func TestReviewSubsequentUsesIncrementalDiff(t *testing.T) {
+	claude := newFakeClaude(t, `{"summary":"Looks good.","approval":"approve","comments":[]}`)
+
+	fileDiff := func(path, line string) string {
+		return "diff --git a/" + path + " b/" + path + "\n--- a/" + path + "\n+++ b/" + path + "\n@@ -0,0 +1 @@\n+" + line + "\n"
+	}
+	prDiff := fileDiff("a.go", "alpha") + fileDiff("b.go", "beta")
+
+	tests := []struct {
+		name        string
+		reviewedSHA string
+		config      string
+		compares    map[string]string
+		wantCompare bool
+		wantFull    bool
+	}{
+		{name: "reviewed head unknown", wantFull: true},
+		{name: "same head reviewed again", reviewedSHA: "abc123", wantFull: true},
+		{name: "changes since last review", reviewedSHA: "old111", compares: map[string]string{"old111...abc123": fileDiff("b.go", "beta")}, wantCompare: true},
+		{name: "compare fails", reviewedSHA: "old111", wantCompare: true, wantFull: true},
+		{name: "excluded files filtered from the compare", reviewedSHA: "old111", config: "exclude: [\"gen/**\"]", compares: map[string]string{"old111...abc123": fileDiff("b.go", "beta") + fileDiff("gen/x.go", "gamma")}, wantCompare: true},
+		{name: "compare outside the PR diff", reviewedSHA: "old111", compares: map[string]string{"old111...abc123": fileDiff("c.go", "gamma")}, wantCompare: true, wantFull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claude.reset()
+			store := memory.New()
+			if err := store.StoreReview(context.Background(), &storage.ReviewContext{
+				Owner:      "owner",
+				Repo:       "repo",
+				PRNumber:   7,
+				ReviewID:   100,
+				ReviewBody: "First pass.",
+				HeadSHA:    tt.reviewedSHA,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := tt.config
+			if cfg == "" {
+				cfg = "enabled: true"
+			}
+			transport := &compareTransport{next: &reviewsTransport{diff: prDiff, config: cfg}, compares: tt.compares}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}); err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+
+			if compared := len(transport.requested) > 0; compared != tt.wantCompare {
+				t.Errorf("compared commits = %v (%v), want %v", compared, transport.requested, tt.wantCompare)
+			}
+			prompts := claude.prompts()
+			if len(prompts) != 1 {
+				t.Fatalf("Claude calls = %d, want 1", len(prompts))
+			}
+			if full := strings.Contains(prompts[0], "alpha"); full != tt.wantFull {
+				t.Errorf("prompt has full PR diff = %v, want %v", full, tt.wantFull)
+			}
+			if strings.Contains(prompts[0], "gamma") {
+				t.Error("prompt includes a file outside the reviewed diff")
+			}
+			if !strings.Contains(prompts[0], "beta") {
+				t.Error("prompt is missing the latest changes")
+			}
+
+			reviews, _ := store.ListReviewsForPR(context.Background(), 0, "owner", "repo", 7)
+			if last := reviews[len(reviews)-1]; last.ReviewID == 100 || last.HeadSHA != "abc123" {
+				t.Errorf("stored review = %+v, want the new review recorded at head abc123", last)
+			}
+		})
+	}
+}
+
+func TestIntersectDiffLines(t *testing.T) {
+	a := DiffLineMap{"a.go": {1: true, 2: true}, "b.go": {5: true}}
+	b := DiffLineMap{"a.go": {2: true, 3: true}, "c.go": {1: true}}
+
+	got := intersectDiffLines(a, b)
+	if len(got) != 1 || len(got["a.go"]) != 1 || !got["a.go"][2] {
+		t.Errorf("intersectDiffLines() = %v, want only a.go:2", got)
+	}
+}
@@ -0,0 +1,65 @@
+package review
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// installationLimiter caps concurrent reviews per installation so a single tenant
+// submitting many PRs at once can't take every available review slot.
+type installationLimiter struct {
+	limit int64
+
+	mu      sync.Mutex
+	entries map[int64]*limiterEntry
+}
+
+// limiterEntry is an installation's semaphore and the number of reviews holding
+// or waiting on it, so idle installations can be dropped from the map.
+type limiterEntry struct {
+	sem  *semaphore.Weighted
+	refs int
+}
+
+// newInstallationLimiter creates a limiter allowing limit concurrent reviews per installation.
+func newInstallationLimiter(limit int) *installationLimiter {
+	return &installationLimiter{
+		limit:   int64(limit),
+		entries: make(map[int64]*limiterEntry),
+	}
+}
+
+// acquire blocks until a review slot is free for the installation or ctx is done.
+// On success, the returned function must be called to release the slot.
+func (l *installationLimiter) acquire(ctx context.Context, installationID int64) (func(), error) {
+	l.mu.Lock()
+	entry, ok := l.entries[installationID]
+	if !ok {
+		entry = &limiterEntry{sem: semaphore.NewWeighted(l.limit)}
+		l.entries[installationID] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	if err := entry.sem.Acquire(ctx, 1); err != nil {
+		l.done(installationID, entry)
+		return nil, err
+	}
+
+	return func() {
+		entry.sem.Release(1)
+		l.done(installationID, entry)
+	}, nil
+}
+
+// done drops a reference to the installation's entry, removing it once unused.
+func (l *installationLimiter) done(installationID int64, entry *limiterEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 {
+		delete(l.entries, installationID)
+	}
+}
@@ -0,0 +1,70 @@
+package review
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInstallationLimiter(t *testing.T) {
+	l := newInstallationLimiter(1)
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, 1)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// A second review for the same installation waits for the slot
+	acquired := make(chan func())
+	go func() {
+		r, err := l.acquire(ctx, 1)
+		if err != nil {
+			t.Errorf("acquire() error = %v", err)
+			return
+		}
+		acquired <- r
+	}()
+
+	// A different installation proceeds immediately
+	other, err := l.acquire(ctx, 2)
+	if err != nil {
+		t.Fatalf("acquire() for other installation error = %v", err)
+	}
+	other()
+
+	select {
+	case <-acquired:
+		t.Fatal("second review for the same installation should wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case r := <-acquired:
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("second review did not proceed after the slot was released")
+	}
+
+	if len(l.entries) != 0 {
+		t.Errorf("entries = %d, want 0 after all slots released", len(l.entries))
+	}
+}
+
+func TestInstallationLimiterContextCanceled(t *testing.T) {
+	l := newInstallationLimiter(1)
+
+	release, err := l.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 1); err == nil {
+		t.Fatal("acquire() expected error when context is done")
+	}
+}
@@ -0,0 +1,99 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+// fetchReviewDiff returns the diff to review. With review_merge_result enabled it
+// diffs the PR's test-merge commit against base, so the review covers what will
+// actually land; otherwise, or when there's no merge commit, it's the branch diff.
+func (r *Reviewer) fetchReviewDiff(ctx context.Context, input *ReviewInput, cfg *config.Config) (string, error) {
+	if cfg.ReviewMergeResult {
+		merge, err := r.githubClient.GetMergeCommit(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+		switch {
+		case err != nil:
+			r.logger.Warn("failed to get merge commit, reviewing branch diff", "error", err)
+		case merge == nil:
+			r.logger.Info("no merge commit available, reviewing branch diff")
+		default:
+			diff, err := r.githubClient.CompareDiff(ctx, input.InstallationID, input.Owner, input.Repo, merge.BaseSHA, merge.SHA)
+			if err == nil {
+				r.logger.Info("reviewing merge result", "merge_commit", merge.SHA, "base", merge.BaseSHA)
+				return diff, nil
+			}
+			r.logger.Warn("failed to fetch merge diff, reviewing branch diff", "error", err)
+		}
+	}
+
+	diff, err := r.githubClient.FetchDiff(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if !github.IsDiffTooLarge(err) {
+		return diff, err
+	}
+
+	// GitHub won't render diffs past 3000 files or 20000 lines, but still lists
+	// each file's patch
+	r.logger.Warn("diff too large to fetch, rebuilding it from the PR's files", "error", err)
+	files, err := r.githubClient.FetchPullRequestFiles(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch files for too-large diff: %w", err)
+	}
+	return diffFromFiles(files), nil
+}
+
+// headDiffLines narrows diffLines to the lines in the PR's branch diff when
+// review_merge_result is enabled. Comments are posted at the head commit, where
+// GitHub only accepts lines of the branch diff, so lines only the merge result
+// changes can't carry one. If the branch diff can't be fetched, diffLines is
+// returned unchanged.
+func (r *Reviewer) headDiffLines(ctx context.Context, input *ReviewInput, cfg *config.Config, diffLines DiffLineMap) DiffLineMap {
+	if !cfg.ReviewMergeResult {
+		return diffLines
+	}
+	branchDiff, err := r.githubClient.FetchDiff(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to fetch branch diff, placing comments on the merge diff", "error", err)
+		return diffLines
+	}
+	return intersectDiffLines(diffLines, ParseDiffLines(branchDiff))
+}
+
+// diffFromFiles rebuilds a unified diff from the files API's per-file patches,
+// with the same headers git uses. Files without a patch (binaries, or patches
+// GitHub omits as too large) are skipped.
+func diffFromFiles(files []github.PullRequestFile) string {
+	var b strings.Builder
+	for _, f := range files {
+		if f.Patch == "" {
+			continue
+		}
+		oldPath, newPath := f.Filename, f.Filename
+		if f.PreviousFilename != "" {
+			oldPath = f.PreviousFilename
+		}
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n", oldPath, newPath)
+
+		from, to := "a/"+oldPath, "b/"+newPath
+		switch f.Status {
+		case "added":
+			b.WriteString("new file mode 100644\n")
+			from = "/dev/null"
+		case "removed":
+			b.WriteString("deleted file mode 100644\n")
+			to = "/dev/null"
+		case "renamed":
+			fmt.Fprintf(&b, "rename from %s\nrename to %s\n", oldPath, newPath)
+		}
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+
+		b.WriteString(f.Patch)
+		if !strings.HasSuffix(f.Patch, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+)
+
+// prTransport serves a pull request's JSON, its branch diff, and a compare diff.
+type prTransport struct {
+	prJSON string
+}
+
+func (t prTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.URL.Path == "/repos/owner/repo/pulls/7" && req.Header.Get("Accept") == "application/vnd.github.diff":
+		return newResponse(req, http.StatusOK, "branch diff"), nil
+	case req.URL.Path == "/repos/owner/repo/pulls/7":
+		return newResponse(req, http.StatusOK, t.prJSON), nil
+	case req.URL.Path == "/repos/owner/repo/compare/base1...merge1":
+		return newResponse(req, http.StatusOK, "merge diff"), nil
+	}
+	return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+}
+
+func TestFetchReviewDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		mergeResult bool
+		prJSON      string
+		want        string
+	}{
+		{
+			name:   "option disabled",
+			prJSON: `{"merge_commit_sha":"merge1","mergeable":true,"base":{"sha":"base1"}}`,
+			want:   "branch diff",
+		},
+		{
+			name:        "mergeable uses merge diff",
+			mergeResult: true,
+			prJSON:      `{"merge_commit_sha":"merge1","mergeable":true,"base":{"sha":"base1"}}`,
+			want:        "merge diff",
+		},
+		{
+			name:        "conflicts fall back",
+			mergeResult: true,
+			prJSON:      `{"merge_commit_sha":"merge1","mergeable":false,"base":{"sha":"base1"}}`,
+			want:        "branch diff",
+		},
+		{
+			name:        "mergeability not computed falls back",
+			mergeResult: true,
+			prJSON:      `{"merge_commit_sha":null,"mergeable":null,"base":{"sha":"base1"}}`,
+			want:        "branch diff",
+		},
+		{
+			name:        "compare failure falls back",
+			mergeResult: true,
+			prJSON:      `{"merge_commit_sha":"other","mergeable":true,"base":{"sha":"base1"}}`,
+			want:        "branch diff",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := github.NewClientWithTransport(prTransport{prJSON: tt.prJSON})
+			reviewer := NewReviewer(client, "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			cfg := config.DefaultConfig()
+			cfg.ReviewMergeResult = tt.mergeResult
+
+			got, err := reviewer.fetchReviewDiff(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}, cfg)
+			if err != nil {
+				t.Fatalf("fetchReviewDiff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("fetchReviewDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchReviewDiffTooLarge(t *testing.T) {
+	var pages []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/repos/owner/repo/pulls/7":
+			return newResponse(req, http.StatusNotAcceptable, `{"message":"Sorry, the diff exceeded the maximum number of files (3000).","errors":[{"resource":"PullRequest","field":"diff","code":"too_large"}]}`), nil
+		case "/repos/owner/repo/pulls/7/files":
+			page := req.URL.Query().Get("page")
+			pages = append(pages, page)
+			if page == "1" {
+				// A full page, so the client asks for the next one
+				files := make([]string, 100)
+				for i := range files {
+					files[i] = fmt.Sprintf(`{"filename":"gen/f%d.bin","status":"added"}`, i)
+				}
+				files[0] = `{"filename":"main.go","status":"modified","patch":"@@ -1,2 +1,2 @@\n package main\n-var x = 1\n+var x = 2"}`
+				return newResponse(req, http.StatusOK, "["+strings.Join(files, ",")+"]"), nil
+			}
+			return newResponse(req, http.StatusOK, `[
+				{"filename":"new.go","status":"added","patch":"@@ -0,0 +1 @@\n+package main\n"},
+				{"filename":"gone.go","status":"removed","patch":"@@ -1 +0,0 @@\n-package main"},
+				{"filename":"moved.go","previous_filename":"old.go","status":"renamed","patch":"@@ -1 +1 @@\n-package old\n+package moved"}]`), nil
+		}
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+	})
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	diff, err := reviewer.fetchReviewDiff(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("fetchReviewDiff() error = %v", err)
+	}
+
+	want := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n package main\n-var x = 1\n+var x = 2\n" +
+		"diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+package main\n" +
+		"diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package main\n" +
+		"diff --git a/old.go b/moved.go\nrename from old.go\nrename to moved.go\n--- a/old.go\n+++ b/moved.go\n@@ -1 +1 @@\n-package old\n+package moved\n"
+	if diff != want {
+		t.Errorf("fetchReviewDiff() =\n%s\nwant:\n%s", diff, want)
+	}
+	if len(pages) != 2 {
+		t.Errorf("fetched file pages %v, want 2", pages)
+	}
+
+	// The rebuilt diff works with the line map and chunker
+	if !ParseDiffLines(diff).IsValidCommentLine("main.go", 2) {
+		t.Error("ParseDiffLines() doesn't map main.go:2")
+	}
+	if chunks := ChunkDiff(diff, 100000); len(chunks) != 1 || len(chunks[0].Files) != 4 {
+		t.Errorf("ChunkDiff() = %+v, want one chunk of 4 files", chunks)
+	}
+}
+
+func TestHeadDiffLines(t *testing.T) {
+	branchDiff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	fetched := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/repos/owner/repo/pulls/7" {
+			fetched++
+			return newResponse(req, http.StatusOK, branchDiff), nil
+		}
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+	})
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}
+
+	// The merge result also changes a.go:10 and b.go, which came from base
+	mergeLines := DiffLineMap{"a.go": {1: true, 2: true, 10: true}, "b.go": {1: true}}
+
+	cfg := config.DefaultConfig()
+	if got := reviewer.headDiffLines(context.Background(), input, cfg, mergeLines); len(got) != 2 || fetched != 0 {
+		t.Errorf("headDiffLines() without review_merge_result = %v after %d fetches, want the lines unchanged", got, fetched)
+	}
+
+	cfg.ReviewMergeResult = true
+	got := reviewer.headDiffLines(context.Background(), input, cfg, mergeLines)
+	if len(got) != 1 || len(got["a.go"]) != 2 || !got["a.go"][1] || !got["a.go"][2] {
+		t.Errorf("headDiffLines() = %v, want only a.go:1-2 from the branch diff", got)
+	}
+}
@@ -0,0 +1,44 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetPRMuted mutes (or unmutes) reviews on a single pull request and confirms
+// it with a comment. While muted, Review and RetryFailedFiles skip the PR, even
+// when requested.
+func (r *Reviewer) SetPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int, muted bool) error {
+	if r.storage == nil {
+		return errors.New("muting a PR requires storage")
+	}
+
+	if err := r.storage.SetPRMuted(ctx, installationID, owner, repo, prNumber, muted); err != nil {
+		return fmt.Errorf("failed to store muted flag: %w", err)
+	}
+	r.logger.Info("set PR muted", "owner", owner, "repo", repo, "pr", prNumber, "muted", muted)
+
+	message := BuildUnmutedMessage()
+	if muted {
+		message = BuildMutedMessage()
+	}
+	if _, err := r.githubClient.CreateIssueComment(ctx, installationID, owner, repo, prNumber, message); err != nil {
+		r.logger.Warn("failed to post mute confirmation", "error", err)
+	}
+	return nil
+}
+
+// isPRMuted reports whether the PR was muted with the ignore command. Lookup
+// failures count as unmuted.
+func (r *Reviewer) isPRMuted(ctx context.Context, input *ReviewInput) bool {
+	if r.storage == nil {
+		return false
+	}
+	muted, err := r.storage.IsPRMuted(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to check whether PR is muted", "error", err)
+		return false
+	}
+	return muted
+}
@@ -0,0 +1,68 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestReviewSkipsMutedPR(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	recorder := &writeRecorder{next: routeTransport{
+		"/issues/7/comments": newResponse(nil, http.StatusCreated, `{"id":1}`),
+		// Reaching the diff fetch would mean the muted PR was reviewed
+		"/pulls/7": newResponse(nil, http.StatusInternalServerError, "boom"),
+	}}
+	reviewer := NewReviewer(github.NewClientWithTransport(recorder), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := reviewer.SetPRMuted(ctx, 1, "owner", "repo", 7, true); err != nil {
+		t.Fatalf("SetPRMuted() error = %v", err)
+	}
+	if len(recorder.writes) != 1 || recorder.writes[0] != "POST /repos/owner/repo/issues/7/comments" {
+		t.Errorf("writes = %v, want one confirmation comment", recorder.writes)
+	}
+
+	input := &ReviewInput{InstallationID: 1, Owner: "owner", Repo: "repo", PRNumber: 7, Requested: true}
+	result, err := reviewer.Review(ctx, input)
+	if result != nil || err != nil {
+		t.Errorf("Review() = %+v, %v, want nil, nil for a muted PR", result, err)
+	}
+
+	// A retry is skipped too, even with failed files to review
+	if err := store.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 1, FailedFiles: []string{"a.go"}}); err != nil {
+		t.Fatal(err)
+	}
+	result, err = reviewer.RetryFailedFiles(ctx, input)
+	if result != nil || err != nil {
+		t.Errorf("RetryFailedFiles() = %+v, %v, want nil, nil for a muted PR", result, err)
+	}
+
+	if err := reviewer.SetPRMuted(ctx, 1, "owner", "repo", 7, false); err != nil {
+		t.Fatalf("SetPRMuted() error = %v", err)
+	}
+	if muted, _ := store.IsPRMuted(ctx, 1, "owner", "repo", 7); muted {
+		t.Error("PR still muted after unignore")
+	}
+}
+
+func TestMuteMessagesAreNotCommands(t *testing.T) {
+	for _, body := range []string{BuildMutedMessage(), BuildUnmutedMessage()} {
+		if cmd := github.ExtractCommand(body, "shipitai"); cmd != "" {
+			t.Errorf("ExtractCommand(%q) = %q, want none", body, cmd)
+		}
+	}
+}
+
+func TestSetPRMutedRequiresStorage(t *testing.T) {
+	reviewer := NewReviewer(github.NewClientWithTransport(routeTransport{}), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := reviewer.SetPRMuted(context.Background(), 1, "owner", "repo", 7, true); err == nil {
+		t.Error("SetPRMuted() error = nil, want an error without storage")
+	}
+}
@@ -0,0 +1,36 @@
+package review
+
+import (
+	"context"
+)
+
+// postNoticeOnce posts body as a PR comment unless the notice keyed by notice
+// was already posted on the PR, so notices re-triggered by every push aren't
+// repeated. Without storage the notice is posted every time. Failures are
+// logged and never block the review.
+func (r *Reviewer) postNoticeOnce(ctx context.Context, input *ReviewInput, notice, body string) {
+	if !r.markNoticePosted(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, notice) {
+		return
+	}
+	if _, err := r.githubClient.CreateIssueComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, body); err != nil {
+		r.logger.Warn("failed to post notice", "notice", notice, "error", err)
+	}
+}
+
+// markNoticePosted records the notice keyed by notice as posted on the PR and
+// reports whether the caller should post it: true the first time, or always
+// without storage, and false if it was already posted or storage failed.
+func (r *Reviewer) markNoticePosted(ctx context.Context, installationID int64, owner, repo string, prNumber int, notice string) bool {
+	if r.storage == nil {
+		return true
+	}
+	first, err := r.storage.MarkPRNoticePosted(ctx, installationID, owner, repo, prNumber, notice)
+	if err != nil {
+		r.logger.Warn("failed to check whether notice was posted", "notice", notice, "error", err)
+		return false
+	}
+	if !first {
+		r.logger.Info("notice already posted", "notice", notice)
+	}
+	return first
+}
@@ -0,0 +1,97 @@
+package review
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+)
+
+// SetTrackCommentOutcomes enables recording, on each subsequent review, whether the
+// authors resolved the threads of earlier bot comments. Off by default.
+func (r *Reviewer) SetTrackCommentOutcomes(enabled bool) {
+	r.trackCommentOutcomes = enabled
+}
+
+// reconcileCommentOutcomes records the outcome of every stored comment on the PR
+// from the current review threads. Failures are logged and never block the review.
+func (r *Reviewer) reconcileCommentOutcomes(ctx context.Context, input *ReviewInput, threads []github.ReviewThread) {
+	if !r.trackCommentOutcomes || r.storage == nil || input.DryRun {
+		return
+	}
+
+	reviews, err := r.storage.ListReviewsForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to load reviews for comment outcomes", "error", err)
+		return
+	}
+
+	botLogin := r.botNameOrDefault() + "[bot]"
+	var botThreads []github.ReviewThread
+	for _, t := range threads {
+		if len(t.Comments) > 0 && t.Comments[0].Author == botLogin {
+			botThreads = append(botThreads, t)
+		}
+	}
+
+	updated := 0
+	for _, rev := range reviews {
+		if !matchCommentOutcomes(rev.Comments, botThreads) {
+			continue
+		}
+		if err := r.storage.StoreReview(ctx, rev); err != nil {
+			r.logger.Warn("failed to store comment outcomes", "review_id", rev.ReviewID, "error", err)
+			continue
+		}
+		updated++
+	}
+	r.logger.Info("reconciled comment outcomes", "reviews", len(reviews), "updated", updated)
+}
+
+// matchCommentOutcomes sets each comment's outcome from the bot thread it started,
+// matched by path and body; a comment with no thread left was dismissed. Each
+// thread matches at most one comment, preferring one on the same line, since
+// outdated threads lose their line. Reports whether any outcome changed.
+func matchCommentOutcomes(comments []storage.Comment, threads []github.ReviewThread) bool {
+	used := make([]bool, len(threads))
+	matched := make([]int, len(comments))
+	for i := range matched {
+		matched[i] = -1
+	}
+
+	for _, sameLine := range []bool{true, false} {
+		for i, c := range comments {
+			if matched[i] >= 0 {
+				continue
+			}
+			for j, t := range threads {
+				if used[j] || t.Path != c.Path || (sameLine && t.Line != c.Line) {
+					continue
+				}
+				if body := strings.TrimSpace(c.Body); body == "" || !strings.Contains(t.Comments[0].Body, body) {
+					continue
+				}
+				used[j] = true
+				matched[i] = j
+				break
+			}
+		}
+	}
+
+	changed := false
+	for i := range comments {
+		outcome := storage.CommentOutcomeDismissed
+		if j := matched[i]; j >= 0 {
+			outcome = storage.CommentOutcomeUnresolved
+			if threads[j].IsResolved {
+				outcome = storage.CommentOutcomeResolved
+			}
+		}
+		if comments[i].Outcome != outcome {
+			comments[i].Outcome = outcome
+			changed = true
+		}
+	}
+	return changed
+}
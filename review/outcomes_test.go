@@ -0,0 +1,90 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func botThread(path string, line int, body string, resolved bool) github.ReviewThread {
+	return github.ReviewThread{
+		Path:       path,
+		Line:       line,
+		IsResolved: resolved,
+		Comments:   []github.ThreadComment{{Author: "shipitai[bot]", Body: body}},
+	}
+}
+
+func TestMatchCommentOutcomes(t *testing.T) {
+	comments := []storage.Comment{
+		{Path: "a.go", Line: 10, Body: "Check the error."},
+		{Path: "a.go", Line: 20, Body: "Check the error."},
+		{Path: "b.go", Line: 5, Body: "Unused variable."},
+		{Path: "c.go", Line: 1, Body: "Deleted comment."},
+	}
+	threads := []github.ReviewThread{
+		// Posted bodies carry a severity badge ahead of the stored text
+		botThread("a.go", 20, "**Warning** Check the error.", true),
+		botThread("a.go", 10, "**Warning** Check the error.", false),
+		// Outdated threads lose their line but still match by path and body
+		botThread("b.go", 0, "Unused variable.", true),
+	}
+
+	if !matchCommentOutcomes(comments, threads) {
+		t.Fatal("matchCommentOutcomes() = false, want outcomes recorded")
+	}
+	want := []string{
+		storage.CommentOutcomeUnresolved,
+		storage.CommentOutcomeResolved,
+		storage.CommentOutcomeResolved,
+		storage.CommentOutcomeDismissed,
+	}
+	for i, c := range comments {
+		if c.Outcome != want[i] {
+			t.Errorf("comments[%d].Outcome = %q, want %q", i, c.Outcome, want[i])
+		}
+	}
+
+	if matchCommentOutcomes(comments, threads) {
+		t.Error("matchCommentOutcomes() = true on a second pass, want no change")
+	}
+}
+
+func TestReconcileCommentOutcomes(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	_ = store.StoreReview(ctx, &storage.ReviewContext{Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 1, Comments: []storage.Comment{
+		{Path: "a.go", Line: 3, Body: "Nil map write."},
+		{Path: "a.go", Line: 9, Body: "Typo in name."},
+	}})
+
+	reviewer := NewReviewer(github.NewClientWithTransport(routeTransport{}), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}
+	threads := []github.ReviewThread{
+		botThread("a.go", 3, "Nil map write.", true),
+		// A human thread with the same text is not the bot's comment
+		{Path: "a.go", Line: 9, Comments: []github.ThreadComment{{Author: "alice", Body: "Typo in name."}}},
+	}
+
+	// Disabled by default
+	reviewer.reconcileCommentOutcomes(ctx, input, threads)
+	if got, _ := store.GetCommentOutcomes(ctx, 0, time.Time{}); got.Pending != 2 {
+		t.Errorf("outcomes with tracking off = %+v, want all pending", got)
+	}
+
+	reviewer.SetTrackCommentOutcomes(true)
+	reviewer.reconcileCommentOutcomes(ctx, input, threads)
+	got, err := store.GetCommentOutcomes(ctx, 0, time.Time{})
+	if err != nil {
+		t.Fatalf("GetCommentOutcomes() error = %v", err)
+	}
+	if want := (storage.CommentOutcomes{Resolved: 1, Dismissed: 1}); *got != want {
+		t.Errorf("GetCommentOutcomes() = %+v, want %+v", *got, want)
+	}
+}
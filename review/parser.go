@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/shipitai/shipitai/config"
 	"github.com/shipitai/shipitai/github"
 )
 
@@ -156,21 +157,81 @@ func (m DiffLineMap) IsValidCommentLine(path string, line int) bool {
 	return fileLines[line]
 }
 
+// CanonicalPath returns the diff's path for a comment's path: the path itself if
+// the diff has it, or else the one diff path equal to it ignoring case, for when
+// Claude writes "Main.go" for "main.go". Returns false if no path matches, or if
+// several differ only in case and the right one can't be told apart.
+func (m DiffLineMap) CanonicalPath(path string) (string, bool) {
+	if _, ok := m[path]; ok {
+		return path, true
+	}
+	match := ""
+	for candidate := range m {
+		if !strings.EqualFold(candidate, path) {
+			continue
+		}
+		if match != "" {
+			return "", false
+		}
+		match = candidate
+	}
+	return match, match != ""
+}
+
 // FilterValidComments filters out comments with invalid line numbers.
 // Returns the valid comments and a count of how many were filtered out.
 func FilterValidComments(comments []ClaudeComment, diffLines DiffLineMap, logger *slog.Logger) ([]ClaudeComment, int) {
+	valid, filtered, _ := SnapValidComments(comments, diffLines, 0, false, logger)
+	return valid, filtered
+}
+
+// SnapValidComments filters out comments with invalid line numbers, like
+// FilterValidComments, but first moves a comment on an invalid line to the
+// nearest valid line at most window lines away, preferring the line above on a
+// tie. Comments with a suggestion block stay put, since the suggestion replaces
+// the line it's attached to. With foldPathCase, a comment whose path matches a
+// diff path only ignoring case is moved to that path first (see CanonicalPath).
+// Returns the kept comments, the count filtered out, and the count moved.
+func SnapValidComments(comments []ClaudeComment, diffLines DiffLineMap, window int, foldPathCase bool, logger *slog.Logger) ([]ClaudeComment, int, int) {
+	valid, filtered, snapped := snapComments(comments, diffLines, window, foldPathCase, logger)
+	return valid, len(filtered), snapped
+}
+
+// snapComments is SnapValidComments, returning the filtered comments themselves.
+func snapComments(comments []ClaudeComment, diffLines DiffLineMap, window int, foldPathCase bool, logger *slog.Logger) ([]ClaudeComment, []ClaudeComment, int) {
 	if len(comments) == 0 {
-		return comments, 0
+		return comments, nil, 0
 	}
 
 	valid := make([]ClaudeComment, 0, len(comments))
-	filtered := 0
+	var filtered []ClaudeComment
+	snapped := 0
 
 	for _, c := range comments {
+		if foldPathCase {
+			if path, ok := diffLines.CanonicalPath(c.Path); ok && path != c.Path {
+				if logger != nil {
+					logger.Info("matched comment path to the diff ignoring case", "path", c.Path, "diff_path", path)
+				}
+				c.Path = path
+			}
+		}
+
 		if diffLines.IsValidCommentLine(c.Path, c.Line) {
 			valid = append(valid, c)
+		} else if line, ok := nearestValidLine(diffLines, c, window); ok {
+			snapped++
+			if logger != nil {
+				logger.Info("moved comment to nearest line in the diff",
+					"path", c.Path,
+					"line", c.Line,
+					"snapped_line", line,
+				)
+			}
+			c.Line = line
+			valid = append(valid, c)
 		} else {
-			filtered++
+			filtered = append(filtered, c)
 			if logger != nil {
 				logger.Warn("filtered comment with invalid line number",
 					"path", c.Path,
@@ -181,7 +242,92 @@ func FilterValidComments(comments []ClaudeComment, diffLines DiffLineMap, logger
 		}
 	}
 
-	return valid, filtered
+	return valid, filtered, snapped
+}
+
+// nearestValidLine returns the valid diff line closest to the comment's line,
+// at most window lines away, checking the line above before the one below.
+func nearestValidLine(diffLines DiffLineMap, c ClaudeComment, window int) (int, bool) {
+	if strings.Contains(c.Body, suggestionFence) {
+		return 0, false
+	}
+	for d := 1; d <= window; d++ {
+		for _, line := range []int{c.Line - d, c.Line + d} {
+			if line > 0 && diffLines.IsValidCommentLine(c.Path, line) {
+				return line, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// FilterBySeverity drops comments less severe than minSeverity on the given scale.
+// An empty minSeverity keeps every comment. Returns the kept comments and the
+// count of dropped ones.
+func FilterBySeverity(comments []ClaudeComment, scale config.SeverityScale, minSeverity string, logger *slog.Logger) ([]ClaudeComment, int) {
+	if minSeverity == "" || len(comments) == 0 {
+		return comments, 0
+	}
+
+	minRank := scale.Rank(minSeverity)
+	kept := make([]ClaudeComment, 0, len(comments))
+	for _, c := range comments {
+		if scale.Rank(c.Severity) >= minRank {
+			kept = append(kept, c)
+		}
+	}
+
+	dropped := len(comments) - len(kept)
+	if dropped > 0 && logger != nil {
+		logger.Info("dropped comments below min_severity", "min_severity", minSeverity, "dropped", dropped)
+	}
+	return kept, dropped
+}
+
+// confidenceRank orders confidence levels from least (1) to most (3) sure.
+// A missing confidence ranks as medium.
+func confidenceRank(confidence string) int {
+	switch confidence {
+	case "low":
+		return 1
+	case "high":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// FilterByConfidence drops comments Claude is less confident in than minConfidence.
+// An empty minConfidence keeps every comment. Returns the kept comments and the
+// count of dropped ones.
+func FilterByConfidence(comments []ClaudeComment, minConfidence string, logger *slog.Logger) ([]ClaudeComment, int) {
+	if minConfidence == "" || len(comments) == 0 {
+		return comments, 0
+	}
+
+	minRank := confidenceRank(minConfidence)
+	kept := make([]ClaudeComment, 0, len(comments))
+	for _, c := range comments {
+		if confidenceRank(c.Confidence) >= minRank {
+			kept = append(kept, c)
+		}
+	}
+
+	dropped := len(comments) - len(kept)
+	if dropped > 0 && logger != nil {
+		logger.Info("dropped comments below min_confidence", "min_confidence", minConfidence, "dropped", dropped)
+	}
+	return kept, dropped
+}
+
+// appendConfidence adds Claude's stated confidence to each comment body.
+func appendConfidence(comments []ClaudeComment) []ClaudeComment {
+	for i, c := range comments {
+		if c.Confidence != "" {
+			comments[i].Body = c.Body + "\n\n_Confidence: " + c.Confidence + "_"
+		}
+	}
+	return comments
 }
 
 // truncateString truncates a string to maxLen and adds "..." if truncated.
@@ -198,18 +344,71 @@ type ClaudeResponse struct {
 	Comments        []ClaudeComment `json:"comments"`
 	Approval        string          `json:"approval"`
 	ResolvedThreads []string        `json:"resolved_threads,omitempty"`
+
+	// Extras holds any additional top-level fields in the response (e.g. an
+	// "estimated_risk" requested through custom instructions), keyed by field name.
+	Extras map[string]json.RawMessage `json:"-"`
+
+	// FailedFiles lists files whose chunk could not be reviewed when
+	// partial_chunk_reviews is enabled. Never part of Claude's response.
+	FailedFiles []string `json:"-"`
+
+	// Unplaced holds comments filtered out because their line isn't in the diff.
+	// Never part of Claude's response.
+	Unplaced []ClaudeComment `json:"-"`
+}
+
+// claudeResponseFields are the top-level fields ClaudeResponse decodes itself.
+var claudeResponseFields = []string{"summary", "comments", "approval", "resolved_threads"}
+
+// UnmarshalJSON decodes the known fields and collects any others into Extras.
+func (r *ClaudeResponse) UnmarshalJSON(data []byte) error {
+	type plain ClaudeResponse // no methods, so no recursion
+	var known plain
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, name := range claudeResponseFields {
+		delete(fields, name)
+	}
+
+	*r = ClaudeResponse(known)
+	if len(fields) > 0 {
+		r.Extras = fields
+	}
+	return nil
+}
+
+// mergeExtras combines extra response fields; the first response to set a field wins.
+func mergeExtras(dst, src map[string]json.RawMessage) map[string]json.RawMessage {
+	for name, value := range src {
+		if dst == nil {
+			dst = make(map[string]json.RawMessage)
+		}
+		if _, ok := dst[name]; !ok {
+			dst[name] = value
+		}
+	}
+	return dst
 }
 
 // ClaudeComment represents a single comment from Claude's review.
 type ClaudeComment struct {
-	Path     string `json:"path"`
-	Line     int    `json:"line"`
-	Body     string `json:"body"`
-	Severity string `json:"severity,omitempty"` // "critical", "high", "medium", "low"
+	Path       string `json:"path"`
+	Line       int    `json:"line"`
+	Body       string `json:"body"`
+	Severity   string `json:"severity,omitempty"`   // a level of the severity scale ("critical", "high", "medium", "low" by default)
+	Confidence string `json:"confidence,omitempty"` // "high", "medium", "low"; only requested when configured
 }
 
-// ParseResponse parses Claude's JSON response into a structured review.
-func ParseResponse(response string) (*ClaudeResponse, error) {
+// ParseResponse parses Claude's JSON response into a structured review, checking
+// comment severities against the given scale (nil for the default one).
+func ParseResponse(response string, scale config.SeverityScale) (*ClaudeResponse, error) {
 	// Clean up the response - remove markdown code blocks if present
 	cleaned := cleanResponse(response)
 
@@ -218,7 +417,7 @@ func ParseResponse(response string) (*ClaudeResponse, error) {
 		return nil, fmt.Errorf("failed to parse Claude response as JSON: %w\nResponse: %s", err, cleaned)
 	}
 
-	if err := validateResponse(&result); err != nil {
+	if err := validateResponse(&result, scale); err != nil {
 		return nil, err
 	}
 
@@ -274,7 +473,7 @@ func cleanResponse(response string) string {
 }
 
 // validateResponse validates the parsed response.
-func validateResponse(resp *ClaudeResponse) error {
+func validateResponse(resp *ClaudeResponse, scale config.SeverityScale) error {
 	switch resp.Approval {
 	case "approve", "request_changes", "comment":
 		// Valid
@@ -295,13 +494,18 @@ func validateResponse(resp *ClaudeResponse) error {
 			return fmt.Errorf("comment %d has empty body", i)
 		}
 		// Validate and normalize severity
-		switch comment.Severity {
-		case "critical", "high", "medium", "low":
+		switch {
+		case comment.Severity == "":
+			resp.Comments[i].Severity = scale.DefaultLevel()
+		case !scale.Has(comment.Severity):
+			return fmt.Errorf("comment %d has invalid severity: %s (must be one of: %s)", i, comment.Severity, strings.Join(scale.Names(), ", "))
+		}
+		// Confidence is optional; it's only requested when configured
+		switch comment.Confidence {
+		case "high", "medium", "low", "":
 			// Valid
-		case "":
-			resp.Comments[i].Severity = "medium" // Default to medium
 		default:
-			return fmt.Errorf("comment %d has invalid severity: %s (must be critical, high, medium, or low)", i, comment.Severity)
+			return fmt.Errorf("comment %d has invalid confidence: %s (must be high, medium, or low)", i, comment.Confidence)
 		}
 	}
 
@@ -328,12 +532,30 @@ func ToGitHubReview(resp *ClaudeResponse, commitSHA string) (*github.ReviewReque
 
 	return &github.ReviewRequest{
 		CommitID: commitSHA,
-		Body:     resp.Summary,
+		Body:     reviewBody(resp),
 		Event:    event,
 		Comments: comments,
 	}, nil
 }
 
+// defaultApprovalBody is the review body used for approvals with no summary.
+const defaultApprovalBody = "Looks good to me! No issues found."
+
+// reviewBody returns the review summary, synthesizing one when Claude left it empty
+// so GitHub never renders a blank review.
+func reviewBody(resp *ClaudeResponse) string {
+	if strings.TrimSpace(resp.Summary) != "" {
+		return resp.Summary
+	}
+	if len(resp.Comments) > 0 {
+		return "Reviewed " + pluralize(len(resp.Comments), "change") + "; see inline comments."
+	}
+	if resp.Approval == "approve" {
+		return defaultApprovalBody
+	}
+	return "Reviewed; no inline comments."
+}
+
 // mapApprovalToEvent maps Claude's approval value to GitHub's event type.
 func mapApprovalToEvent(approval string) string {
 	switch approval {
@@ -357,56 +579,231 @@ A contributor can trigger a review by commenting: `+"`@%s review`"+`
 *[ShipItAI](https://shipitai.dev) - AI Code Reviews*`, botName)
 }
 
+// BuildWelcomeMessage returns the one-time comment posted on a new installation's
+// first pull request. The mention comes after every command word, so the comment
+// can't be read as a command itself.
+func BuildWelcomeMessage(botName string) string {
+	return fmt.Sprintf(`ShipItAI is now reviewing pull requests here. Here's how it works:
+
+- New and updated PRs get an automatic review with inline comments
+- Ask about a comment in its review thread, or comment `+"`review`"+` to request a review
+- Comment `+"`ignore`"+` to stop reviews on a PR
+
+Questions and commands go in a comment mentioning `+"`@%s`"+`.
+
+Configure reviews with a `+"`.github/shipitai.yml`"+` file in the repository; set `+"`welcome_message: false`"+` there to skip this message.
+
+---
+*[ShipItAI](https://shipitai.dev) - AI Code Reviews*`, botName)
+}
+
 // BuildUnauthorizedTriggerMessage returns the message when a non-contributor tries to trigger a review.
 func BuildUnauthorizedTriggerMessage() string {
 	return "Only repository contributors can trigger reviews. If you believe you should have access, please contact a repository maintainer."
 }
 
-// DetermineApprovalFromSeverity determines approval based on comment severities.
+// BuildMutedMessage returns the message posted when reviews are muted on a PR.
+// It names the command without the mention, so it can't be read as one.
+func BuildMutedMessage() string {
+	return "Reviews are now muted on this PR. Mention me with `unignore` to turn them back on."
+}
+
+// BuildUnmutedMessage returns the message posted when reviews are unmuted on a PR.
+func BuildUnmutedMessage() string {
+	return "Reviews are back on for this PR. Mention me with `review` to request one now."
+}
+
+// BuildRevertNoticeMessage returns the message posted when a revert PR is skipped.
+func BuildRevertNoticeMessage(botName string) string {
+	return fmt.Sprintf("This appears to be a revert, so a detailed review was skipped. Comment `@%s review` to request a full review.", botName)
+}
+
+// BuildDeletionNoticeMessage returns the message posted when a mostly-deletion PR is skipped.
+func BuildDeletionNoticeMessage(botName string, deletions int) string {
+	return fmt.Sprintf("This PR mostly removes code (%d lines deleted), so a detailed review was skipped. Comment `@%s review` to request a full review.", deletions, botName)
+}
+
+// BuildReplyLimitMessage returns the reply posted to a mention once a PR has had
+// max_replies_per_pr replies.
+func BuildReplyLimitMessage(limit int) string {
+	return fmt.Sprintf("Reply limit reached for this PR (%d replies), so I won't answer further mentions here. A maintainer can raise `max_replies_per_pr` in `.github/shipitai.yml`.", limit)
+}
+
+// BuildEmptyResponseMessage returns the message posted when Claude answered a
+// review request without a review, e.g. because it refused.
+func BuildEmptyResponseMessage(stopReason string) string {
+	if stopReason == "" {
+		stopReason = "unknown"
+	}
+	return fmt.Sprintf("ShipItAI couldn't generate a review for this PR: Claude responded without one (stop reason: `%s`). Push a new commit to try again.", stopReason)
+}
+
+// BuildFailedFilesNote returns the note added to a review summary when some files
+// could not be reviewed.
+func BuildFailedFilesNote(botName string, files []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s could not be reviewed:**", pluralize(len(files), "file"))
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n- `%s`", f)
+	}
+	fmt.Fprintf(&b, "\n\nComment `@%s retry` to review them again.", botName)
+	return b.String()
+}
+
+// DetermineApprovalFromSeverity determines approval based on comment severities,
+// using the approval each level maps to on the scale. With the default scale:
 // Returns "request_changes" if there are critical or high severity comments.
 // Returns "comment" if there are medium severity comments.
 // Returns "approve" if there are only low severity comments or no comments.
-func DetermineApprovalFromSeverity(comments []ClaudeComment) string {
-	if len(comments) == 0 {
-		return "approve"
+// A requestChangesAt threshold (request_changes_at) moves where comments start
+// requesting changes; empty keeps the scale's mapping.
+// Severities not on the scale are ignored.
+func DetermineApprovalFromSeverity(comments []ClaudeComment, scale config.SeverityScale, requestChangesAt string) string {
+	approval := config.ApprovalApprove
+	for _, c := range comments {
+		if !scale.Has(c.Severity) {
+			continue
+		}
+		switch scale.ApprovalWithThreshold(c.Severity, requestChangesAt) {
+		case config.ApprovalRequestChanges:
+			return config.ApprovalRequestChanges
+		case config.ApprovalComment:
+			approval = config.ApprovalComment
+		}
 	}
+	return approval
+}
 
-	for _, c := range comments {
-		if c.Severity == "critical" || c.Severity == "high" {
-			return "request_changes"
+// Summary verdicts used to tell authors how blocking a set of findings is.
+const (
+	verdictBlocking    = "Changes requested: blocking issues found."
+	verdictSuggestions = "Suggestions to consider; nothing blocking."
+	verdictNitpicks    = "Approved with minor optional suggestions."
+)
+
+// summaryVerdict classifies findings by severity into a one-line verdict.
+// Returns an empty string when there are no findings (the summary speaks for itself).
+func summaryVerdict(comments []ClaudeComment, scale config.SeverityScale, requestChangesAt string) string {
+	switch DetermineApprovalFromSeverity(comments, scale, requestChangesAt) {
+	case "request_changes":
+		return verdictBlocking
+	case "comment":
+		return verdictSuggestions
+	default:
+		if len(comments) == 0 {
+			return ""
 		}
+		return verdictNitpicks
 	}
+}
 
+// suggestionFence opens a GitHub suggestion block in a comment body.
+const suggestionFence = "```suggestion"
+
+// countApplicableSuggestions counts comments containing a suggestion block and how
+// many of those are directly applicable (the block is closed, so GitHub renders the
+// one-click "Commit suggestion" button).
+func countApplicableSuggestions(comments []ClaudeComment) (suggestions, applicable int) {
 	for _, c := range comments {
-		if c.Severity == "medium" {
-			return "comment"
+		idx := strings.Index(c.Body, suggestionFence)
+		if idx < 0 {
+			continue
 		}
+		suggestions++
+		if strings.Contains(c.Body[idx+len(suggestionFence):], "\n```") {
+			applicable++
+		}
+	}
+	return suggestions, applicable
+}
+
+// suggestionSummaryLine builds the summary note for inline suggestions.
+// Returns an empty string when there are no suggestions.
+func suggestionSummaryLine(comments []ClaudeComment) string {
+	suggestions, applicable := countApplicableSuggestions(comments)
+	if suggestions == 0 {
+		return ""
 	}
+	noun := "fixes"
+	if applicable == 1 {
+		noun = "fix"
+	}
+	return fmt.Sprintf("**%d applicable %s available** (%d suggested); see inline.", applicable, noun, suggestions)
+}
 
-	return "approve"
+// diffStatsLine builds the summary header describing the size of a diff, e.g.
+// "**+120/−45** across 8 files".
+func diffStatsLine(info *DiffInfo) string {
+	noun := "files"
+	if len(info.Files) == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("**+%d/−%d** across %d %s", info.Additions, info.Deletions, len(info.Files), noun)
 }
 
-// HasUnresolvedBlockers checks if there are any unresolved critical/high severity comments.
+// HasUnresolvedBlockers checks if there are any unresolved comments at a blocking
+// (request_changes) level of the scale: critical/high on the default scale.
 // Used when deciding whether to approve after subsequent reviews.
-func HasUnresolvedBlockers(comments []ClaudeComment) bool {
+func HasUnresolvedBlockers(comments []ClaudeComment, scale config.SeverityScale) bool {
 	for _, c := range comments {
-		if c.Severity == "critical" || c.Severity == "high" {
+		if scale.Approval(c.Severity) == config.ApprovalRequestChanges {
 			return true
 		}
 	}
 	return false
 }
 
-// FormatCommentWithSeverity adds a severity badge to the comment body.
-func FormatCommentWithSeverity(body, severity string) string {
-	switch severity {
-	case "critical":
-		return "**[critical]** " + body
-	case "high":
-		return "**[high]** " + body
-	case "low":
-		return "*[low]* " + body
+// FormatCommentWithSeverity adds a severity badge to the start of the comment body.
+func FormatCommentWithSeverity(body, severity string, scale config.SeverityScale) string {
+	return FormatSeverityBadge(body, severity, scale, config.SeverityBadgePrefix, false)
+}
+
+// FormatSeverityBadge adds a severity badge to the comment body at the given
+// placement (see config.SeverityBadge*), optionally led by a colored emoji.
+// Blocking levels are bold and approving ones italic; the scale's default level
+// (medium on the default scale) has no badge.
+func FormatSeverityBadge(body, severity string, scale config.SeverityScale, placement string, emoji bool) string {
+	level := scale.Level(severity)
+	if level.Name == scale.DefaultLevel() {
+		return body
+	}
+
+	var badge string
+	switch level.Approval {
+	case config.ApprovalRequestChanges:
+		badge = "**[" + level.Name + "]**"
+	case config.ApprovalApprove:
+		badge = "*[" + level.Name + "]*"
 	default:
+		badge = "[" + level.Name + "]"
+	}
+	if emoji {
+		badge = severityEmoji(scale, level) + " " + badge
+	}
+
+	switch placement {
+	case config.SeverityBadgeNone:
 		return body
+	case config.SeverityBadgeSuffix:
+		// On its own line so it doesn't run into trailing suggestion blocks
+		return body + "\n\n" + badge
+	default:
+		return badge + " " + body
+	}
+}
+
+// severityEmoji returns the emoji shown before a severity badge when enabled:
+// red for the scale's most severe level, orange for other blocking levels, yellow
+// for commenting ones, and blue for approving ones.
+func severityEmoji(scale config.SeverityScale, level config.SeverityLevel) string {
+	switch {
+	case scale.Rank(level.Name) == len(scale.Names()):
+		return "🔴"
+	case level.Approval == config.ApprovalRequestChanges:
+		return "🟠"
+	case level.Approval == config.ApprovalComment:
+		return "🟡"
+	default:
+		return "🔵"
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/shipitai/shipitai/config"
 )
 
 func TestAnnotateDiffWithLineNumbers(t *testing.T) {
@@ -421,6 +423,143 @@ func TestFilterValidComments(t *testing.T) {
 	}
 }
 
+func TestSnapValidComments(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -10,3 +10,4 @@
+ 	existing()
++	newLine()
+ 	alsoExisting()
+ }`
+	diffLines := ParseDiffLines(diff)
+
+	tests := []struct {
+		name         string
+		comment      ClaudeComment
+		window       int
+		wantLine     int // 0 when the comment should be filtered
+		wantSnapped  int
+		wantFiltered int
+	}{
+		{"valid line stays", ClaudeComment{Path: "main.go", Line: 11, Body: "ok"}, 2, 11, 0, 0},
+		{"line after block snaps up", ClaudeComment{Path: "main.go", Line: 14, Body: "off by one"}, 2, 13, 1, 0},
+		{"two lines before snaps down", ClaudeComment{Path: "main.go", Line: 8, Body: "off by two"}, 2, 10, 1, 0},
+		{"outside window is filtered", ClaudeComment{Path: "main.go", Line: 16, Body: "too far"}, 2, 0, 0, 1},
+		{"strict without window", ClaudeComment{Path: "main.go", Line: 14, Body: "off by one"}, 0, 0, 0, 1},
+		{"suggestion is never moved", ClaudeComment{Path: "main.go", Line: 14, Body: "Fix:\n```suggestion\nx()\n```"}, 2, 0, 0, 1},
+		{"other file is filtered", ClaudeComment{Path: "other.go", Line: 11, Body: "wrong file"}, 2, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, filtered, snapped := SnapValidComments([]ClaudeComment{tt.comment}, diffLines, tt.window, false, nil)
+
+			if filtered != tt.wantFiltered || snapped != tt.wantSnapped {
+				t.Errorf("filtered, snapped = %d, %d, want %d, %d", filtered, snapped, tt.wantFiltered, tt.wantSnapped)
+			}
+			if tt.wantLine == 0 {
+				if len(valid) != 0 {
+					t.Errorf("kept %+v, want it filtered", valid)
+				}
+				return
+			}
+			if len(valid) != 1 || valid[0].Line != tt.wantLine {
+				t.Errorf("kept %+v, want the comment on line %d", valid, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestSnapValidCommentsFoldsPathCase(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,2 @@
+ package main
++func main() {}
+diff --git a/pkg/Util.go b/pkg/Util.go
+--- a/pkg/Util.go
++++ b/pkg/Util.go
+@@ -1,1 +1,2 @@
+ package pkg
++func Util() {}
+diff --git a/pkg/util.go b/pkg/util.go
+--- a/pkg/util.go
++++ b/pkg/util.go
+@@ -1,1 +1,2 @@
+ package pkg
++func util() {}`
+	diffLines := ParseDiffLines(diff)
+
+	tests := []struct {
+		name     string
+		path     string
+		fold     bool
+		wantPath string // "" when the comment should be filtered
+	}{
+		{"exact match", "main.go", true, "main.go"},
+		{"case mismatch remapped", "Main.go", true, "main.go"},
+		{"exact match wins over case variants", "pkg/Util.go", true, "pkg/Util.go"},
+		{"ambiguous case mismatch is filtered", "PKG/UTIL.GO", true, ""},
+		{"case mismatch filtered without folding", "Main.go", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := ClaudeComment{Path: tt.path, Line: 2, Body: "note"}
+			valid, filtered, _ := SnapValidComments([]ClaudeComment{comment}, diffLines, 0, tt.fold, nil)
+
+			if tt.wantPath == "" {
+				if len(valid) != 0 || filtered != 1 {
+					t.Errorf("kept %+v, want it filtered", valid)
+				}
+				return
+			}
+			if len(valid) != 1 || valid[0].Path != tt.wantPath {
+				t.Errorf("kept %+v, want the comment on %s", valid, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	comments := []ClaudeComment{
+		{Path: "a.go", Line: 1, Severity: "low"},
+		{Path: "a.go", Line: 2, Severity: "medium"},
+		{Path: "a.go", Line: 3, Severity: "high"},
+		{Path: "a.go", Line: 4, Severity: "critical"},
+	}
+
+	tests := []struct {
+		minSeverity string
+		wantKept    []int // lines of kept comments
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"low", []int{1, 2, 3, 4}},
+		{"medium", []int{2, 3, 4}},
+		{"high", []int{3, 4}},
+		{"critical", []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run("min "+tt.minSeverity, func(t *testing.T) {
+			kept, dropped := FilterBySeverity(comments, nil, tt.minSeverity, nil)
+			if len(kept) != len(tt.wantKept) {
+				t.Fatalf("got %d comments, want %d", len(kept), len(tt.wantKept))
+			}
+			for i, c := range kept {
+				if c.Line != tt.wantKept[i] {
+					t.Errorf("kept[%d].Line = %d, want %d", i, c.Line, tt.wantKept[i])
+				}
+			}
+			if dropped != len(comments)-len(tt.wantKept) {
+				t.Errorf("dropped = %d, want %d", dropped, len(comments)-len(tt.wantKept))
+			}
+		})
+	}
+}
+
 func TestParseResponse(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -527,7 +666,7 @@ func TestParseResponse(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ParseResponse(tt.response)
+			result, err := ParseResponse(tt.response, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseResponse() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -618,6 +757,142 @@ func TestCleanResponse(t *testing.T) {
 	}
 }
 
+func TestParseResponseWithExtras(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		wantExtras map[string]string // field name -> raw JSON
+	}{
+		{
+			name: "extra fields collected",
+			response: `{
+				"summary": "Risky migration",
+				"comments": [{"path": "db.go", "line": 4, "body": "Add an index", "severity": "high"}],
+				"approval": "comment",
+				"estimated_risk": "high",
+				"test_suggestions": ["run the migration twice"]
+			}`,
+			wantExtras: map[string]string{
+				"estimated_risk":   `"high"`,
+				"test_suggestions": `["run the migration twice"]`,
+			},
+		},
+		{
+			name:     "no extra fields",
+			response: `{"summary": "Fine", "comments": [], "approval": "approve", "resolved_threads": []}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResponse(tt.response, nil)
+			if err != nil {
+				t.Fatalf("ParseResponse() error = %v", err)
+			}
+			if got.Summary == "" || got.Approval == "" {
+				t.Errorf("known fields not parsed: %+v", got)
+			}
+			if len(got.Extras) != len(tt.wantExtras) {
+				t.Fatalf("Extras = %v, want %d fields", got.Extras, len(tt.wantExtras))
+			}
+			if tt.wantExtras == nil && got.Extras != nil {
+				t.Errorf("Extras = %v, want nil", got.Extras)
+			}
+			for name, want := range tt.wantExtras {
+				if raw := string(got.Extras[name]); raw != want {
+					t.Errorf("Extras[%q] = %s, want %s", name, raw, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseResponseWithConfidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "confidence parsed",
+			response: `{"summary": "s", "comments": [{"path": "a.go", "line": 1, "body": "b", "severity": "high", "confidence": "low"}], "approval": "comment"}`,
+			want:     "low",
+		},
+		{
+			name:     "confidence optional",
+			response: `{"summary": "s", "comments": [{"path": "a.go", "line": 1, "body": "b", "severity": "high"}], "approval": "comment"}`,
+			want:     "",
+		},
+		{
+			name:     "invalid confidence",
+			response: `{"summary": "s", "comments": [{"path": "a.go", "line": 1, "body": "b", "severity": "high", "confidence": "certain"}], "approval": "comment"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResponse(tt.response, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Comments[0].Confidence != tt.want {
+				t.Errorf("Confidence = %q, want %q", got.Comments[0].Confidence, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterByConfidence(t *testing.T) {
+	comments := []ClaudeComment{
+		{Path: "a.go", Line: 1, Confidence: "low"},
+		{Path: "a.go", Line: 2, Confidence: "medium"},
+		{Path: "a.go", Line: 3, Confidence: "high"},
+		{Path: "a.go", Line: 4}, // unrated ranks as medium
+	}
+
+	tests := []struct {
+		minConfidence string
+		wantKept      []int // lines of kept comments
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"low", []int{1, 2, 3, 4}},
+		{"medium", []int{2, 3, 4}},
+		{"high", []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run("min "+tt.minConfidence, func(t *testing.T) {
+			kept, dropped := FilterByConfidence(comments, tt.minConfidence, nil)
+			if len(kept) != len(tt.wantKept) {
+				t.Fatalf("got %d comments, want %d", len(kept), len(tt.wantKept))
+			}
+			for i, c := range kept {
+				if c.Line != tt.wantKept[i] {
+					t.Errorf("kept[%d].Line = %d, want %d", i, c.Line, tt.wantKept[i])
+				}
+			}
+			if dropped != len(comments)-len(tt.wantKept) {
+				t.Errorf("dropped = %d, want %d", dropped, len(comments)-len(tt.wantKept))
+			}
+		})
+	}
+}
+
+func TestAppendConfidence(t *testing.T) {
+	comments := appendConfidence([]ClaudeComment{
+		{Body: "Check this", Confidence: "low"},
+		{Body: "Unrated"},
+	})
+	if comments[0].Body != "Check this\n\n_Confidence: low_" {
+		t.Errorf("Body = %q, want confidence appended", comments[0].Body)
+	}
+	if comments[1].Body != "Unrated" {
+		t.Errorf("Body = %q, want unchanged", comments[1].Body)
+	}
+}
+
 func TestParseResponseWithResolvedThreads(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -682,7 +957,7 @@ func TestParseResponseWithResolvedThreads(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ParseResponse(tt.response)
+			result, err := ParseResponse(tt.response, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseResponse() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -763,7 +1038,7 @@ func TestParseResponseWithSeverity(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ParseResponse(tt.response)
+			result, err := ParseResponse(tt.response, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseResponse() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -837,7 +1112,7 @@ func TestDetermineApprovalFromSeverity(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := DetermineApprovalFromSeverity(tt.comments)
+			got := DetermineApprovalFromSeverity(tt.comments, nil, "")
 			if got != tt.want {
 				t.Errorf("DetermineApprovalFromSeverity() = %v, want %v", got, tt.want)
 			}
@@ -845,6 +1120,211 @@ func TestDetermineApprovalFromSeverity(t *testing.T) {
 	}
 }
 
+func TestDetermineApprovalWithRequestChangesAt(t *testing.T) {
+	tests := []struct {
+		name       string
+		scale      config.SeverityScale
+		threshold  string
+		severities []string
+		want       string
+	}{
+		{"never downgrades critical", nil, "never", []string{"critical", "low"}, "comment"},
+		{"never keeps low approving", nil, "never", []string{"low"}, "approve"},
+		{"medium blocks", nil, "medium", []string{"medium", "low"}, "request_changes"},
+		{"medium leaves low approving", nil, "medium", []string{"low"}, "approve"},
+		{"critical only", nil, "critical", []string{"high"}, "comment"},
+		{"critical blocks", nil, "critical", []string{"high", "critical"}, "request_changes"},
+		{"high matches the default", nil, "high", []string{"high"}, "request_changes"},
+		{"custom scale", priorityScale, "P2", []string{"P2"}, "request_changes"},
+		{"custom scale below threshold", priorityScale, "P0", []string{"P1"}, "comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comments []ClaudeComment
+			for _, s := range tt.severities {
+				comments = append(comments, ClaudeComment{Severity: s})
+			}
+			if got := DetermineApprovalFromSeverity(comments, tt.scale, tt.threshold); got != tt.want {
+				t.Errorf("DetermineApprovalFromSeverity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// priorityScale is a custom P0-P3 severity scale for tests.
+var priorityScale = config.SeverityScale{
+	{Name: "P0", Approval: config.ApprovalRequestChanges, Description: "Outage or data loss"},
+	{Name: "P1", Approval: config.ApprovalRequestChanges},
+	{Name: "P2", Approval: config.ApprovalComment},
+	{Name: "P3", Approval: config.ApprovalApprove},
+}
+
+func TestCustomSeverityScaleApproval(t *testing.T) {
+	tests := []struct {
+		name       string
+		severities []string
+		want       string
+	}{
+		{"no comments", nil, "approve"},
+		{"P0 blocks", []string{"P3", "P0"}, "request_changes"},
+		{"P1 blocks", []string{"P1"}, "request_changes"},
+		{"P2 comments", []string{"P2", "P3"}, "comment"},
+		{"P3 approves", []string{"P3", "P3"}, "approve"},
+		{"built-in levels are ignored", []string{"critical"}, "approve"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comments []ClaudeComment
+			for _, s := range tt.severities {
+				comments = append(comments, ClaudeComment{Severity: s})
+			}
+			if got := DetermineApprovalFromSeverity(comments, priorityScale, ""); got != tt.want {
+				t.Errorf("DetermineApprovalFromSeverity() = %v, want %v", got, tt.want)
+			}
+			if got := HasUnresolvedBlockers(comments, priorityScale); got != (tt.want == "request_changes") {
+				t.Errorf("HasUnresolvedBlockers() = %v, want %v", got, tt.want == "request_changes")
+			}
+		})
+	}
+}
+
+func TestParseResponseCustomSeverityScale(t *testing.T) {
+	resp, err := ParseResponse(`{"summary":"s","approval":"comment","comments":[{"path":"a.go","line":1,"body":"b","severity":"P1"},{"path":"a.go","line":2,"body":"c"}]}`, priorityScale)
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+	if resp.Comments[0].Severity != "P1" || resp.Comments[1].Severity != "P2" {
+		t.Errorf("severities = %q, %q, want P1 and the default level P2", resp.Comments[0].Severity, resp.Comments[1].Severity)
+	}
+
+	_, err = ParseResponse(`{"summary":"s","approval":"comment","comments":[{"path":"a.go","line":1,"body":"b","severity":"high"}]}`, priorityScale)
+	if err == nil || !strings.Contains(err.Error(), "P0, P1, P2, P3") {
+		t.Errorf("ParseResponse() error = %v, want an invalid severity error listing the scale", err)
+	}
+}
+
+func TestFormatSeverityBadgeCustomScale(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     string
+	}{
+		{"P0", "🔴 **[P0]** Fix this"},
+		{"P1", "🟠 **[P1]** Fix this"},
+		{"P2", "Fix this"},
+		{"P3", "🔵 *[P3]* Fix this"},
+	}
+	for _, tt := range tests {
+		if got := FormatSeverityBadge("Fix this", tt.severity, priorityScale, config.SeverityBadgePrefix, true); got != tt.want {
+			t.Errorf("FormatSeverityBadge(%q) = %q, want %q", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestSummaryVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []ClaudeComment
+		want     string
+	}{
+		{
+			name:     "no findings",
+			comments: nil,
+			want:     "",
+		},
+		{
+			name: "all nitpicks",
+			comments: []ClaudeComment{
+				{Path: "a.go", Line: 1, Body: "nit", Severity: "low"},
+				{Path: "b.go", Line: 2, Body: "nit", Severity: "low"},
+			},
+			want: verdictNitpicks,
+		},
+		{
+			name: "has suggestion",
+			comments: []ClaudeComment{
+				{Path: "a.go", Line: 1, Body: "nit", Severity: "low"},
+				{Path: "b.go", Line: 2, Body: "consider", Severity: "medium"},
+			},
+			want: verdictSuggestions,
+		},
+		{
+			name: "has blocker",
+			comments: []ClaudeComment{
+				{Path: "a.go", Line: 1, Body: "consider", Severity: "medium"},
+				{Path: "b.go", Line: 2, Body: "bug", Severity: "high"},
+			},
+			want: verdictBlocking,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summaryVerdict(tt.comments, nil, ""); got != tt.want {
+				t.Errorf("summaryVerdict() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountApplicableSuggestions(t *testing.T) {
+	tests := []struct {
+		name           string
+		comments       []ClaudeComment
+		wantTotal      int
+		wantApplicable int
+		wantLine       string
+	}{
+		{
+			name:     "no comments",
+			comments: nil,
+			wantLine: "",
+		},
+		{
+			name: "no suggestion blocks",
+			comments: []ClaudeComment{
+				{Body: "Consider renaming this."},
+				{Body: "```go\nfoo()\n```"},
+			},
+			wantLine: "",
+		},
+		{
+			name: "mixed comment set",
+			comments: []ClaudeComment{
+				{Body: "Fix:\n```suggestion\nreturn nil\n```"},
+				{Body: "Typo.\n```suggestion\nconst x = 1\n```"},
+				{Body: "Just a note, no fix."},
+				{Body: "Truncated:\n```suggestion\nreturn err"},
+			},
+			wantTotal:      3,
+			wantApplicable: 2,
+			wantLine:       "**2 applicable fixes available** (3 suggested); see inline.",
+		},
+		{
+			name: "single applicable",
+			comments: []ClaudeComment{
+				{Body: "```suggestion\nx := 1\n```"},
+			},
+			wantTotal:      1,
+			wantApplicable: 1,
+			wantLine:       "**1 applicable fix available** (1 suggested); see inline.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, applicable := countApplicableSuggestions(tt.comments)
+			if total != tt.wantTotal || applicable != tt.wantApplicable {
+				t.Errorf("countApplicableSuggestions() = (%d, %d), want (%d, %d)", total, applicable, tt.wantTotal, tt.wantApplicable)
+			}
+			if got := suggestionSummaryLine(tt.comments); got != tt.wantLine {
+				t.Errorf("suggestionSummaryLine() = %q, want %q", got, tt.wantLine)
+			}
+		})
+	}
+}
+
 func TestFormatCommentWithSeverity(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -886,7 +1366,7 @@ func TestFormatCommentWithSeverity(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := FormatCommentWithSeverity(tt.body, tt.severity)
+			got := FormatCommentWithSeverity(tt.body, tt.severity, nil)
 			if got != tt.want {
 				t.Errorf("FormatCommentWithSeverity() = %v, want %v", got, tt.want)
 			}
@@ -894,6 +1374,33 @@ func TestFormatCommentWithSeverity(t *testing.T) {
 	}
 }
 
+func TestFormatSeverityBadge(t *testing.T) {
+	tests := []struct {
+		name      string
+		severity  string
+		placement string
+		emoji     bool
+		want      string
+	}{
+		{"prefix", "critical", "prefix", false, "**[critical]** Fix this"},
+		{"empty placement is prefix", "high", "", false, "**[high]** Fix this"},
+		{"suffix", "high", "suffix", false, "Fix this\n\n**[high]**"},
+		{"none", "critical", "none", false, "Fix this"},
+		{"prefix with emoji", "critical", "prefix", true, "🔴 **[critical]** Fix this"},
+		{"suffix with emoji", "low", "suffix", true, "Fix this\n\n🔵 *[low]*"},
+		{"medium has no badge", "medium", "suffix", true, "Fix this"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatSeverityBadge("Fix this", tt.severity, nil, tt.placement, tt.emoji)
+			if got != tt.want {
+				t.Errorf("FormatSeverityBadge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestToGitHubReview(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -961,3 +1468,45 @@ func TestToGitHubReview(t *testing.T) {
 		})
 	}
 }
+
+func TestToGitHubReviewEmptySummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *ClaudeResponse
+		wantBody string
+	}{
+		{
+			name: "empty summary with comments",
+			response: &ClaudeResponse{
+				Comments: []ClaudeComment{
+					{Path: "main.go", Line: 10, Body: "Bug here"},
+					{Path: "util.go", Line: 20, Body: "Consider this"},
+				},
+				Approval: "comment",
+			},
+			wantBody: "Reviewed 2 changes; see inline comments.",
+		},
+		{
+			name:     "empty summary approve",
+			response: &ClaudeResponse{Summary: "  ", Approval: "approve"},
+			wantBody: defaultApprovalBody,
+		},
+		{
+			name:     "summary preserved",
+			response: &ClaudeResponse{Summary: "LGTM", Approval: "approve"},
+			wantBody: "LGTM",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review, err := ToGitHubReview(tt.response, "abc123")
+			if err != nil {
+				t.Fatalf("ToGitHubReview() error = %v", err)
+			}
+			if review.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", review.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package review
+
+import (
+	"context"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+// Platform is the code host API the Reviewer works against. github.Client
+// implements it for GitHub and gitlab.Client for GitLab merge requests; the GitHub
+// types serve as the shared model, so prNumber is a merge request IID on GitLab
+// and installationID is ignored there.
+type Platform interface {
+	// Pull requests and diffs
+	GetPullRequest(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*github.PullRequest, error)
+	GetMergeCommit(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*github.MergeCommit, error)
+	FetchDiff(ctx context.Context, installationID int64, owner, repo string, prNumber int) (string, error)
+	FetchPullRequestFiles(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]github.PullRequestFile, error)
+	CompareDiff(ctx context.Context, installationID int64, owner, repo, base, head string) (string, error)
+	IsContributor(ctx context.Context, installationID int64, owner, repo, username string) (bool, error)
+
+	// Repository contents
+	FetchFileContent(ctx context.Context, installationID int64, owner, repo, path, ref string) (string, error)
+	ListDirectory(ctx context.Context, installationID int64, owner, repo, dir, ref string) ([]string, error)
+	FetchMultipleFiles(ctx context.Context, installationID int64, owner, repo string, paths []string, ref string) (map[string]string, error)
+	FetchFileCommits(ctx context.Context, installationID int64, owner, repo, path, ref string, limit int) ([]github.Commit, error)
+
+	// Reviews and review threads
+	ListPRReviews(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]github.Review, error)
+	CreateReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, review *github.ReviewRequest) (*github.Review, error)
+	UpdateReviewBody(ctx context.Context, installationID int64, owner, repo string, prNumber int, reviewID int64, body string) error
+	CreateReplyComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64, body string) (*github.PullRequestComment, error)
+	FetchPRReviewThreads(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]github.ReviewThread, error)
+	ResolveReviewThread(ctx context.Context, installationID int64, threadID string) error
+
+	// PR comments
+	CreateIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, body string) (*github.IssueCommentResponse, error)
+	GetIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64) (*github.IssueCommentResponse, error)
+	UpdateIssueComment(ctx context.Context, installationID int64, owner, repo string, prNumber int, commentID int64, body string) error
+
+	// Check runs
+	GetCheckRuns(ctx context.Context, installationID int64, owner, repo, ref string) ([]github.CheckRun, error)
+	CreateCheckRun(ctx context.Context, installationID int64, owner, repo string, checkRun *github.CheckRunRequest) (*github.CheckRun, error)
+	UpdateCheckRun(ctx context.Context, installationID int64, owner, repo string, checkRunID int64, checkRun *github.CheckRunRequest) (*github.CheckRun, error)
+}
+
+var _ Platform = (*github.Client)(nil)
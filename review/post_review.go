@@ -0,0 +1,51 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+// unplacedCommentsHeading introduces inline comments GitHub wouldn't anchor to the diff.
+const unplacedCommentsHeading = "**Comments that couldn't be placed on the diff:**"
+
+// postReview creates a review, recovering when GitHub rejects its inline comments as
+// unprocessable (a 422, e.g. a line outside the diff GitHub has for the PR). GitHub
+// doesn't say which comment it rejected, so the review is retried once with the
+// inline comments moved into its body rather than losing the findings.
+func (r *Reviewer) postReview(ctx context.Context, input *ReviewInput, req *github.ReviewRequest) (*github.Review, error) {
+	review, err := r.githubClient.CreateReview(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, req)
+	if err == nil || !github.IsUnprocessable(err) || len(req.Comments) == 0 {
+		return review, err
+	}
+
+	r.logger.Warn("review rejected as unprocessable, retrying with inline comments in the body",
+		"comment_count", len(req.Comments), "error", err)
+
+	retry := *req
+	retry.Comments = nil
+	retry.Body = strings.TrimLeft(req.Body+"\n\n"+formatUnplacedComments(req.Comments), "\n")
+	return r.githubClient.CreateReview(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, &retry)
+}
+
+// formatUnplacedFindings renders Claude's comments as a list for the review body.
+func formatUnplacedFindings(comments []ClaudeComment) string {
+	reviewComments := make([]github.ReviewComment, len(comments))
+	for i, c := range comments {
+		reviewComments[i] = github.ReviewComment{Path: c.Path, Line: c.Line, Body: c.Body}
+	}
+	return formatUnplacedComments(reviewComments)
+}
+
+// formatUnplacedComments renders inline comments as a list for the review body.
+func formatUnplacedComments(comments []github.ReviewComment) string {
+	var b strings.Builder
+	b.WriteString(unplacedCommentsHeading)
+	for _, c := range comments {
+		body := strings.ReplaceAll(strings.TrimSpace(c.Body), "\n", "\n  ")
+		fmt.Fprintf(&b, "\n- `%s:%d`: %s", c.Path, c.Line, body)
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+func TestPostReviewUnprocessable(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		comments   []github.ReviewComment
+		wantPosts  int
+		wantErr    bool
+		wantInBody string
+	}{
+		{
+			name:       "rejected comments move into the body",
+			status:     http.StatusUnprocessableEntity,
+			comments:   []github.ReviewComment{{Path: "main.go", Line: 12, Body: "Possible nil dereference\nCheck the cache."}},
+			wantPosts:  2,
+			wantInBody: "Summary.\n\n" + unplacedCommentsHeading + "\n- `main.go:12`: Possible nil dereference\n  Check the cache.",
+		},
+		{
+			name:      "review without comments is not retried",
+			status:    http.StatusUnprocessableEntity,
+			wantPosts: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "other errors are not retried",
+			status:    http.StatusInternalServerError,
+			comments:  []github.ReviewComment{{Path: "main.go", Line: 12, Body: "Nit"}},
+			wantPosts: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posted []github.ReviewRequest
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				var review github.ReviewRequest
+				_ = json.NewDecoder(req.Body).Decode(&review)
+				posted = append(posted, review)
+				if len(posted) == 1 {
+					return newResponse(req, tt.status, `{"message":"Unprocessable Entity"}`), nil
+				}
+				return newResponse(req, http.StatusOK, `{"id":1}`), nil
+			})
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}
+			_, err := reviewer.postReview(context.Background(), input, &github.ReviewRequest{Body: "Summary.", Event: "COMMENT", Comments: tt.comments})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("postReview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(posted) != tt.wantPosts {
+				t.Fatalf("posted %d reviews, want %d", len(posted), tt.wantPosts)
+			}
+			if tt.wantInBody == "" {
+				return
+			}
+			retry := posted[len(posted)-1]
+			if len(retry.Comments) != 0 || !strings.Contains(retry.Body, tt.wantInBody) {
+				t.Errorf("retried review = %+v, want no inline comments and a body containing %q", retry, tt.wantInBody)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ package review
 import (
 	"fmt"
 	"strings"
+
+	"github.com/shipitai/shipitai/config"
 )
 
 const systemPrompt = `You are an expert code reviewer. Your job is to review pull request diffs and provide actionable, helpful feedback.
@@ -104,6 +106,107 @@ func BuildPrompt(title, description, diff string) string {
 	return fmt.Sprintf(reviewPromptTemplate, title, description, AnnotateDiffWithLineNumbers(diff))
 }
 
+// summarySystemPrompt is the system prompt for "@shipitai summarize".
+const summarySystemPrompt = `You are an expert software engineer summarizing a pull request for its reviewers. Your job is to explain what the change does, not to review it.
+
+Write the summary in GitHub-flavored Markdown:
+- Start with one or two sentences on what the PR does and why, in plain language
+- Follow with a short bulleted list of the main changes, grouped by area or component
+- Call out anything reviewers should look at closely: behavior changes, migrations, new dependencies, or changes to public APIs
+- Do NOT list every file, restate the diff line by line, or include code review feedback
+
+Keep it brief: a reviewer should understand the PR in under a minute.`
+
+const summaryPromptTemplate = `Summarize the following pull request.
+
+**Pull Request Title:** %s
+
+**Pull Request Description:**
+%s
+%s
+<diff>
+%s
+</diff>`
+
+// maxSummaryDiffSize caps the diff sent for a summary; larger diffs are cut at a
+// line boundary.
+const maxSummaryDiffSize = 2 * MaxChunkSize
+
+// BuildSummaryPrompt constructs the Claude prompt for summarizing a PR.
+func BuildSummaryPrompt(title, description, diff string) string {
+	if description == "" {
+		description = "(No description provided)"
+	}
+
+	note := ""
+	if len(diff) > maxSummaryDiffSize {
+		diff = diff[:maxSummaryDiffSize]
+		if i := strings.LastIndex(diff, "\n"); i > 0 {
+			diff = diff[:i+1]
+		}
+		note = "\nThe diff is too large to include in full; it is truncated below. Summarize what you can see and say that the summary covers part of the PR.\n"
+	}
+
+	return fmt.Sprintf(summaryPromptTemplate, title, description, note, diff)
+}
+
+// confidenceInstructions asks Claude to rate its confidence in each finding.
+const confidenceInstructions = `For each comment, also set "confidence" to "high", "medium", or "low": how sure you are the issue is real, given what you can see. Use "low" when it depends on code or context outside the diff.`
+
+// severityOutcomes describes what each severity level approval means for the PR.
+var severityOutcomes = map[string]string{
+	config.ApprovalRequestChanges: "blocks merging (request_changes)",
+	config.ApprovalComment:        "worth raising, not blocking (comment)",
+	config.ApprovalApprove:        "minor; the PR can still be approved (approve)",
+}
+
+// severityScaleInstructions tells Claude to use the repository's own severity
+// levels in place of the built-in ones described in the system prompt.
+func severityScaleInstructions(scale config.SeverityScale) string {
+	var b strings.Builder
+	b.WriteString(`This repository uses its own severity scale. Set "severity" to one of these levels instead of "critical", "high", "medium", or "low", most severe first:`)
+	for _, level := range scale {
+		fmt.Fprintf(&b, "\n- %q: ", level.Name)
+		if level.Description != "" {
+			b.WriteString(level.Description + "; ")
+		}
+		b.WriteString(severityOutcomes[level.Approval])
+	}
+	b.WriteString("\n\nSet \"approval\" from your most severe finding's level as shown in parentheses, or \"approve\" if there are no findings.")
+	return b.String()
+}
+
+// reviewInstructions returns the repository's custom instructions plus any
+// prompt additions its config turns on.
+func reviewInstructions(cfg *config.Config) string {
+	var parts []string
+	if cfg.Instructions != "" {
+		parts = append(parts, cfg.Instructions)
+	}
+	if cfg.SeverityScale.IsCustom() {
+		parts = append(parts, severityScaleInstructions(cfg.SeverityScale))
+	}
+	if cfg.RequestsConfidence() {
+		parts = append(parts, confidenceInstructions)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// prDescription returns the PR description for the prompt, without unfilled PR
+// template text, noting the PR's labels when label_context is on so Claude can
+// weigh what they signal.
+func prDescription(input *ReviewInput, cfg *config.Config) string {
+	body := stripTemplatePlaceholders(input.PRBody)
+	if len(input.Labels) == 0 || !cfg.IsLabelContextEnabled() {
+		return body
+	}
+	labels := "This PR is labeled: " + strings.Join(input.Labels, ", ")
+	if body == "" {
+		return labels
+	}
+	return body + "\n\n" + labels
+}
+
 // GetSystemPrompt returns the system prompt for Claude, optionally with project context and custom instructions.
 func GetSystemPrompt(claudeMD, instructions string) string {
 	result := systemPrompt
@@ -120,13 +223,16 @@ func GetSystemPrompt(claudeMD, instructions string) string {
 }
 
 // GetSystemPromptWithContext returns the system prompt with context instructions included.
-func GetSystemPromptWithContext(claudeMD, instructions string, hasContext bool) string {
+// The prefix and suffix wrap the built-in prompt, ahead of CLAUDE.md and instructions.
+func GetSystemPromptWithContext(claudeMD, instructions, prefix, suffix string, hasContext bool) string {
 	result := systemPrompt
 
 	if hasContext {
 		result += contextInstructions
 	}
 
+	result = wrapSystemPrompt(prefix, result, suffix)
+
 	if claudeMD != "" {
 		result += "\n\n## Project Context (from CLAUDE.md)\n\n" + claudeMD
 	}
@@ -138,6 +244,17 @@ func GetSystemPromptWithContext(claudeMD, instructions string, hasContext bool)
 	return result
 }
 
+// wrapSystemPrompt places the configured prefix and suffix around a built-in system prompt.
+func wrapSystemPrompt(prefix, prompt, suffix string) string {
+	if prefix != "" {
+		prompt = prefix + "\n\n" + prompt
+	}
+	if suffix != "" {
+		prompt += "\n\n" + suffix
+	}
+	return prompt
+}
+
 // DiffInfo contains parsed information about a diff for context.
 type DiffInfo struct {
 	Files       []string
@@ -236,22 +353,6 @@ func ParseDiffInfo(diff string) *DiffInfo {
 	return info
 }
 
-// BuildPromptWithContext constructs the Claude prompt with rich context included.
-func BuildPromptWithContext(title, description, diff string, ctx *ReviewContext) string {
-	var builder strings.Builder
-
-	// Add rich context first if available
-	if ctx != nil && !ctx.IsEmpty() {
-		builder.WriteString(formatContext(ctx))
-		builder.WriteString("\n\n---\n\n")
-	}
-
-	// Add the standard prompt
-	builder.WriteString(BuildPrompt(title, description, diff))
-
-	return builder.String()
-}
-
 // BuildChunkedPromptWithContext constructs a chunked prompt with context.
 func BuildChunkedPromptWithContext(title, description, diff string, chunkIndex, totalChunks int, filePaths []string, ctx *ReviewContext) string {
 	var builder strings.Builder
@@ -373,9 +474,10 @@ func BuildSubsequentReviewPrompt(title, description, diff string, existingCommen
 	return fmt.Sprintf(subsequentReviewPromptTemplate, title, description, commentContext, AnnotateDiffWithLineNumbers(diff))
 }
 
-// GetSubsequentReviewSystemPrompt returns the system prompt for subsequent reviews.
-func GetSubsequentReviewSystemPrompt(claudeMD, instructions string) string {
-	result := subsequentReviewSystemPrompt
+// GetSubsequentReviewSystemPrompt returns the system prompt for subsequent reviews,
+// wrapped in the prefix and suffix like GetSystemPromptWithContext.
+func GetSubsequentReviewSystemPrompt(claudeMD, instructions, prefix, suffix string) string {
+	result := wrapSystemPrompt(prefix, subsequentReviewSystemPrompt, suffix)
 
 	if claudeMD != "" {
 		result += "\n\n## Project Context (from CLAUDE.md)\n\n" + claudeMD
@@ -419,6 +521,14 @@ func formatExistingComments(comments []ExistingComment) string {
 	return builder.String()
 }
 
+// truncatedMarker returns the header suffix for content cut at the per-file limit.
+func truncatedMarker(truncated bool) string {
+	if truncated {
+		return " (truncated)"
+	}
+	return ""
+}
+
 // formatContext formats the review context for inclusion in the prompt.
 func formatContext(ctx *ReviewContext) string {
 	var builder strings.Builder
@@ -430,15 +540,18 @@ func formatContext(ctx *ReviewContext) string {
 
 		for _, f := range ctx.FullFiles {
 			builder.WriteString(fmt.Sprintf("### %s", f.Path))
+			if f.DeclarationsOnly {
+				builder.WriteString(" (changed declarations only)")
+			}
 			if f.Truncated {
 				builder.WriteString(" (truncated)")
 			}
 			builder.WriteString("\n\n")
 
-			// Use appropriate language for syntax highlighting
+			// Use appropriate language for syntax highlighting, detecting it when unset
 			lang := f.Language
 			if lang == "" {
-				lang = ""
+				lang = DetectLanguage(f.Path)
 			}
 			builder.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", lang, f.Content))
 		}
@@ -464,7 +577,7 @@ func formatContext(ctx *ReviewContext) string {
 		if len(testFiles) > 0 {
 			builder.WriteString("### Test Files\n\n")
 			for _, f := range testFiles {
-				builder.WriteString(fmt.Sprintf("**%s** (tests for %s):\n\n", f.Path, f.SourceFile))
+				builder.WriteString(fmt.Sprintf("**%s** (tests for %s)%s:\n\n", f.Path, f.SourceFile, truncatedMarker(f.Truncated)))
 				lang := DetectLanguage(f.Path)
 				builder.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", lang, f.Content))
 			}
@@ -473,7 +586,7 @@ func formatContext(ctx *ReviewContext) string {
 		if len(importFiles) > 0 {
 			builder.WriteString("### Imported Files\n\n")
 			for _, f := range importFiles {
-				builder.WriteString(fmt.Sprintf("**%s** (imported by %s):\n\n", f.Path, f.SourceFile))
+				builder.WriteString(fmt.Sprintf("**%s** (imported by %s)%s:\n\n", f.Path, f.SourceFile, truncatedMarker(f.Truncated)))
 				lang := DetectLanguage(f.Path)
 				builder.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", lang, f.Content))
 			}
@@ -500,3 +613,12 @@ func formatContext(ctx *ReviewContext) string {
 
 	return builder.String()
 }
+
+const jsonRepairSystemPrompt = `You fix malformed JSON. You will be given a code review response that failed to parse and the parser's error.
+
+Return only the corrected JSON. Keep the content of every field exactly as it was; change only what is needed to make the JSON valid and match the schema. If the response was cut off, close it after the last complete item.`
+
+// BuildJSONRepairPrompt asks Claude to correct a response that failed to parse.
+func BuildJSONRepairPrompt(invalid string, parseErr error) string {
+	return fmt.Sprintf("This response failed to parse: %v\n\nResponse:\n%s", parseErr, invalid)
+}
@@ -3,6 +3,8 @@ package review
 import (
 	"strings"
 	"testing"
+
+	"github.com/shipitai/shipitai/config"
 )
 
 func TestBuildSubsequentReviewPrompt(t *testing.T) {
@@ -156,7 +158,7 @@ func TestGetSubsequentReviewSystemPrompt(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetSubsequentReviewSystemPrompt(tt.claudeMD, tt.instructions)
+			got := GetSubsequentReviewSystemPrompt(tt.claudeMD, tt.instructions, "", "")
 			for _, want := range tt.wantContains {
 				if !strings.Contains(got, want) {
 					t.Errorf("GetSubsequentReviewSystemPrompt() missing %q", want)
@@ -231,3 +233,95 @@ func TestFormatExistingComments(t *testing.T) {
 		})
 	}
 }
+
+func TestReviewInstructions(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		wantConfidence bool
+	}{
+		{"off by default", &config.Config{Instructions: "Focus on security"}, false},
+		{"explain_confidence", &config.Config{Instructions: "Focus on security", ExplainConfidence: true}, true},
+		{"min_confidence", &config.Config{MinConfidence: "high"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reviewInstructions(tt.cfg)
+			if !strings.HasPrefix(got, tt.cfg.Instructions) {
+				t.Errorf("reviewInstructions() = %q, want it to start with the repo instructions", got)
+			}
+			if strings.Contains(got, confidenceInstructions) != tt.wantConfidence {
+				t.Errorf("confidence instructions included = %v, want %v", !tt.wantConfidence, tt.wantConfidence)
+			}
+			if strings.Contains(got, "severity scale") {
+				t.Errorf("reviewInstructions() = %q, want no severity scale with the default levels", got)
+			}
+		})
+	}
+
+	got := reviewInstructions(&config.Config{SeverityScale: config.SeverityScale{
+		{Name: "P0", Approval: config.ApprovalRequestChanges, Description: "Outage or data loss"},
+		{Name: "P1", Approval: config.ApprovalApprove},
+	}})
+	for _, want := range []string{`- "P0": Outage or data loss; blocks merging (request_changes)`, `- "P1": minor; the PR can still be approved (approve)`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("reviewInstructions() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPRDescriptionLabels(t *testing.T) {
+	off := false
+	tests := []struct {
+		name  string
+		input *ReviewInput
+		cfg   *config.Config
+		want  string
+	}{
+		{"labels follow the body", &ReviewInput{PRBody: "Adds auth.", Labels: []string{"security", "breaking-change"}}, &config.Config{}, "Adds auth.\n\nThis PR is labeled: security, breaking-change"},
+		{"labels without a body", &ReviewInput{Labels: []string{"performance"}}, &config.Config{}, "This PR is labeled: performance"},
+		{"no labels", &ReviewInput{PRBody: "Adds auth."}, &config.Config{}, "Adds auth."},
+		{"label_context off", &ReviewInput{PRBody: "Adds auth.", Labels: []string{"security"}}, &config.Config{LabelContext: &off}, "Adds auth."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prDescription(tt.input, tt.cfg); got != tt.want {
+				t.Errorf("prDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	input := &ReviewInput{PRTitle: "Add auth", Labels: []string{"security"}}
+	if prompt := BuildPrompt(input.PRTitle, prDescription(input, &config.Config{}), "+x"); !strings.Contains(prompt, "This PR is labeled: security") {
+		t.Errorf("BuildPrompt() missing labels:\n%s", prompt)
+	}
+}
+
+func TestFormatContextDetectsFullFileLanguage(t *testing.T) {
+	got := formatContext(&ReviewContext{FullFiles: []FileContext{{Path: "pkg/server.go", Content: "package pkg"}}})
+	if !strings.Contains(got, "```go\npackage pkg\n```") {
+		t.Errorf("formatContext() missing the go hint for a file without Language:\n%s", got)
+	}
+}
+
+func TestSystemPromptPrefixAndSuffix(t *testing.T) {
+	prompts := map[string]string{
+		"GetSystemPromptWithContext":      GetSystemPromptWithContext("Project uses Go", "Focus on security", "PREFIX", "SUFFIX", true),
+		"GetSubsequentReviewSystemPrompt": GetSubsequentReviewSystemPrompt("Project uses Go", "Focus on security", "PREFIX", "SUFFIX"),
+	}
+	for name, got := range prompts {
+		if !strings.HasPrefix(got, "PREFIX\n\n") {
+			t.Errorf("%s() should start with the prefix", name)
+		}
+		suffix := strings.Index(got, "SUFFIX")
+		if suffix < 0 || suffix > strings.Index(got, "Project Context") || suffix < strings.Index(got, "JSON") {
+			t.Errorf("%s() should place the suffix after the built-in prompt and before CLAUDE.md", name)
+		}
+	}
+
+	if got := GetSystemPromptWithContext("", "", "", "", false); got != systemPrompt {
+		t.Error("GetSystemPromptWithContext() without a prefix or suffix should be the built-in prompt")
+	}
+}
@@ -30,10 +30,9 @@ Keep responses short and focused - this is a code review conversation, not an es
 
 const replyPromptTemplate = `The developer is asking about code in this file: %s
 
-Here's the relevant code context (diff hunk):
 %s
 
-Here's the conversation thread:
+%sHere's the conversation thread:
 %s
 
 The developer's latest message:
@@ -41,6 +40,45 @@ The developer's latest message:
 
 Respond helpfully and concisely.`
 
+const pushbackSystemPrompt = `You are a code review assistant. You previously left a review comment on a pull request, and a developer is now pushing back on it.
+
+Weigh their counterargument on its merits, using the code and your original finding. Do not defer just because they disagree, and do not dig in just because you raised the issue. Then clearly do one of:
+1. Concede: say plainly that their point holds and the concern can be dropped
+2. Defend: explain concisely, with specifics from the code, why the issue still matters
+
+If a smaller change would address both your concern and theirs, suggest it. When you have a specific single-line fix, use GitHub's suggestion syntax:
+
+` + "```suggestion" + `
+fixed code here
+` + "```" + `
+
+Keep responses short and focused - this is a code review conversation, not an essay.`
+
+const pushbackPromptTemplate = `The developer is responding to your review comment in this file: %s
+
+%s
+
+Your original finding on this line:
+%s
+
+%sHere's the conversation thread:
+%s
+
+The developer's latest message:
+%s
+
+Decide whether to concede or defend your finding, and respond.`
+
+// ThreadKind classifies a reply thread to choose how to respond.
+type ThreadKind int
+
+const (
+	// ThreadGeneral is a question that isn't responding to one of the bot's comments.
+	ThreadGeneral ThreadKind = iota
+	// ThreadPushback is a human responding to a comment the bot left on the line.
+	ThreadPushback
+)
+
 // ReplyInput contains the information needed to reply to a comment.
 type ReplyInput struct {
 	InstallationID int64
@@ -48,10 +86,15 @@ type ReplyInput struct {
 	Repo           string
 	PRNumber       int
 	CommentID      int64
+	ThreadID       int64 // First comment of the thread; 0 means CommentID starts it
 	DiffHunk       string
 	FilePath       string
+	HeadSHA        string // Commit to read FilePath at when there's no DiffHunk
+	DefaultBranch  string // Config is always read from the default branch
 	UserQuestion   string
-	ThreadContext  string // Previous comments in the thread
+	ThreadContext  string     // Previous comments in the thread
+	Line           int        // Line the thread is attached to (for finding the stored review comment)
+	Kind           ThreadKind // Selects the general or defend-or-concede prompt
 }
 
 // ReplyResult contains the result of a reply.
@@ -60,6 +103,11 @@ type ReplyResult struct {
 	CommentURL string
 	Body       string
 	Usage      *storage.TokenUsage
+
+	// LimitReached is set when the PR is past max_replies_per_pr and the canned
+	// limit message was posted instead of a reply from Claude. Nothing is posted
+	// (CommentID is 0) if the thread already got the message.
+	LimitReached bool
 }
 
 // Reply responds to a user's comment that mentioned the bot.
@@ -71,6 +119,25 @@ func (r *Reviewer) Reply(ctx context.Context, input *ReplyInput) (*ReplyResult,
 		"comment_id", input.CommentID,
 	)
 
+	// Past the PR's reply cap, answer with a canned message instead of calling
+	// Claude, once per thread so repeated mentions don't each get a copy
+	if limit := r.replyLimitReached(ctx, input); limit > 0 {
+		r.logger.Info("reply limit reached for pull request", "max_replies_per_pr", limit)
+		threadID := input.ThreadID
+		if threadID == 0 {
+			threadID = input.CommentID
+		}
+		if !r.markNoticePosted(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, fmt.Sprintf("reply_limit:%d", threadID)) {
+			return &ReplyResult{LimitReached: true}, nil
+		}
+		body := BuildReplyLimitMessage(limit)
+		comment, err := r.githubClient.CreateReplyComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, input.CommentID, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to post reply limit message: %w", err)
+		}
+		return &ReplyResult{CommentID: comment.ID, CommentURL: comment.HTMLURL, Body: body, LimitReached: true}, nil
+	}
+
 	// Get the appropriate API key
 	apiKey, isCustomKey, err := r.getAPIKey(ctx, input.InstallationID)
 	if err != nil {
@@ -91,6 +158,7 @@ func (r *Reviewer) Reply(ctx context.Context, input *ReplyInput) (*ReplyResult,
 	}
 
 	r.logger.Info("generated reply", "length", len(claudeResp.Text))
+	body := sanitizeBody(claudeResp.Text)
 
 	// Post the reply
 	comment, err := r.githubClient.CreateReplyComment(
@@ -100,33 +168,86 @@ func (r *Reviewer) Reply(ctx context.Context, input *ReplyInput) (*ReplyResult,
 		input.Repo,
 		input.PRNumber,
 		input.CommentID,
-		claudeResp.Text,
+		body,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post reply: %w", err)
 	}
 
+	if r.storage != nil {
+		if err := r.storage.IncrementReplyCount(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber); err != nil {
+			r.logger.Warn("failed to record reply count", "error", err)
+		}
+	}
+
 	return &ReplyResult{
 		CommentID:  comment.ID,
 		CommentURL: comment.HTMLURL,
-		Body:       claudeResp.Text,
+		Body:       body,
 		Usage:      claudeResp.Usage,
 	}, nil
 }
 
+// replyLimitReached returns the repository's max_replies_per_pr if the PR has
+// already had that many replies, or 0 if another reply may be sent. Without
+// storage, or if the config or count can't be read, replies aren't limited.
+func (r *Reviewer) replyLimitReached(ctx context.Context, input *ReplyInput) int {
+	if r.storage == nil {
+		return 0
+	}
+	cfg, err := r.configLoader.Load(ctx, input.InstallationID, input.Owner, input.Repo, input.DefaultBranch)
+	if err != nil {
+		r.logger.Warn("failed to load config for reply limit, using defaults", "error", err)
+		return 0
+	}
+	if cfg.MaxRepliesPerPR == 0 {
+		return 0
+	}
+
+	count, err := r.storage.GetReplyCount(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to get reply count", "error", err)
+		return 0
+	}
+	if count < cfg.MaxRepliesPerPR {
+		return 0
+	}
+	return cfg.MaxRepliesPerPR
+}
+
 // generateReply calls Claude to generate a reply and returns usage info.
 func (r *Reviewer) generateReply(ctx context.Context, apiKey, model string, input *ReplyInput) (*ClaudeAPIResponse, error) {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+	client := anthropic.NewClient(append([]option.RequestOption{option.WithAPIKey(apiKey)}, r.claudeOptions...)...)
+
+	filePath := input.FilePath
+	if filePath == "" {
+		filePath = "(no specific file)"
+	}
+	codeContext := r.replyCodeContext(ctx, input)
 
+	systemPrompt := replySystemPrompt
+	history := r.reviewHistory(ctx, input)
 	prompt := fmt.Sprintf(replyPromptTemplate,
-		input.FilePath,
-		input.DiffHunk,
+		filePath,
+		codeContext,
+		history,
 		input.ThreadContext,
 		input.UserQuestion,
 	)
+	if input.Kind == ThreadPushback {
+		systemPrompt = pushbackSystemPrompt
+		prompt = fmt.Sprintf(pushbackPromptTemplate,
+			filePath,
+			codeContext,
+			r.originalFinding(ctx, input),
+			history,
+			input.ThreadContext,
+			input.UserQuestion,
+		)
+	}
 
 	// Add timeout to prevent hanging indefinitely
-	timeoutCtx, cancel := context.WithTimeout(ctx, ClaudeAPITimeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.claudeTimeout)
 	defer cancel()
 
 	// Retry on transient failures
@@ -135,7 +256,7 @@ func (r *Reviewer) generateReply(ctx context.Context, apiKey, model string, inpu
 			Model:     anthropic.Model(model),
 			MaxTokens: 1024,
 			System: []anthropic.TextBlockParam{
-				{Text: replySystemPrompt},
+				{Text: systemPrompt},
 			},
 			Messages: []anthropic.MessageParam{
 				anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
@@ -160,16 +281,150 @@ func (r *Reviewer) generateReply(ctx context.Context, apiKey, model string, inpu
 	)
 
 	// Extract text from response
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			return &ClaudeAPIResponse{
-				Text:  block.Text,
-				Usage: usage,
-			}, nil
+	text, err := messageText(message)
+	if err != nil {
+		return nil, err
+	}
+	return &ClaudeAPIResponse{
+		Text:  text,
+		Usage: usage,
+	}, nil
+}
+
+// replyContextLines is how many lines either side of the thread's line are shown
+// when a reply has no diff hunk.
+const replyContextLines = 20
+
+// replyCodeContext returns the code section of a reply prompt: the diff hunk when
+// the event carried one, otherwise the lines around the thread's line read from
+// the file at the head commit.
+func (r *Reviewer) replyCodeContext(ctx context.Context, input *ReplyInput) string {
+	if input.DiffHunk != "" {
+		return "Here's the relevant code context (diff hunk):\n" + input.DiffHunk
+	}
+	if input.FilePath == "" {
+		return "There's no specific code attached to this question; answer it about the pull request as a whole."
+	}
+
+	if input.HeadSHA != "" {
+		content, err := r.githubClient.FetchFileContent(ctx, input.InstallationID, input.Owner, input.Repo, input.FilePath, input.HeadSHA)
+		if err != nil {
+			r.logger.Warn("failed to fetch file for reply context", "path", input.FilePath, "error", err)
+		} else if window := lineWindow(content, input.Line, replyContextLines); window != "" {
+			return "Here's the relevant code context (from the file, thread line marked with >):\n" + window
 		}
 	}
+	return "The code for this file couldn't be loaded; say so if your answer depends on it."
+}
+
+// lineWindow returns the lines within radius of line (1-based), numbered, with the
+// line itself marked. Without a line it returns the start of the file.
+func lineWindow(content string, line, radius int) string {
+	if content == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	start, end := 1, min(len(lines), 2*radius+1)
+	if line > 0 {
+		start = max(1, line-radius)
+		end = min(len(lines), line+radius)
+	}
+	if start > end {
+		return ""
+	}
+
+	var b strings.Builder
+	for n := start; n <= end; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s%5d | %s\n", marker, n, lines[n-1])
+	}
+	return b.String()
+}
+
+// originalFinding returns the stored review comment for the thread's line, falling
+// back to a pointer at the thread when storage is unavailable or has no match.
+func (r *Reviewer) originalFinding(ctx context.Context, input *ReplyInput) string {
+	const fallback = "(see the first comment in the thread)"
+	if r.storage == nil {
+		return fallback
+	}
+
+	reviews, err := r.storage.ListReviewsForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to load stored review for reply grounding", "error", err)
+		return fallback
+	}
 
-	return nil, fmt.Errorf("no text content in Claude response")
+	// Reviews are oldest first; prefer the most recent finding on the line
+	finding := ""
+	for _, rv := range reviews {
+		for _, c := range rv.Comments {
+			if c.Path == input.FilePath && c.Line == input.Line {
+				finding = c.Body
+			}
+		}
+	}
+	if finding == "" {
+		return fallback
+	}
+	return finding
+}
+
+// reviewHistory returns the reply prompt section recalling the PR's first review:
+// its summary and, for general questions, the comment it left on the thread's
+// line (pushback prompts already carry the original finding). It is empty without
+// storage (local mode) or a stored review.
+func (r *Reviewer) reviewHistory(ctx context.Context, input *ReplyInput) string {
+	if r.storage == nil {
+		return ""
+	}
+	first, err := r.storage.GetFirstReviewForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to load review history for reply", "error", err)
+		return ""
+	}
+	if first == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	if summary := strings.TrimSpace(first.ReviewBody); summary != "" {
+		b.WriteString("Your original review of this pull request:\n" + summary + "\n\n")
+	}
+	if input.Kind != ThreadPushback && input.FilePath != "" {
+		for _, c := range first.Comments {
+			if c.Path == input.FilePath && c.Line == input.Line {
+				b.WriteString("The comment you left on this line in that review:\n" + c.Body + "\n\n")
+				break
+			}
+		}
+	}
+	return b.String()
+}
+
+// ClassifyThread returns ThreadPushback when the thread containing targetID has an
+// earlier comment by the bot (a human responding to the bot's finding), and
+// ThreadGeneral otherwise.
+func ClassifyThread(comments []github.PullRequestComment, targetID int64, botName string) ThreadKind {
+	for _, c := range findThreadComments(comments, targetID) {
+		if c.ID < targetID && isBotLogin(c.User, botName) {
+			return ThreadPushback
+		}
+	}
+	return ThreadGeneral
+}
+
+// isBotLogin reports whether a comment author is the bot (the GitHub App's
+// "<name>[bot]" login, or a plain user account with the bot's name).
+func isBotLogin(user *github.User, botName string) bool {
+	if user == nil || botName == "" {
+		return false
+	}
+	return strings.EqualFold(user.Login, botName+"[bot]") || strings.EqualFold(user.Login, botName)
 }
 
 // BuildThreadContext builds the conversation context from a list of comments.
@@ -0,0 +1,339 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestClassifyThread(t *testing.T) {
+	bot := &github.User{Login: "shipitai[bot]"}
+	alice := &github.User{Login: "alice"}
+	bob := &github.User{Login: "bob"}
+
+	tests := []struct {
+		name     string
+		comments []github.PullRequestComment
+		targetID int64
+		want     ThreadKind
+	}{
+		{
+			name: "pushback on bot comment",
+			comments: []github.PullRequestComment{
+				{ID: 1, User: bot, Body: "This error is ignored."},
+				{ID: 2, InReplyToID: 1, User: alice, Body: "@shipitai it can't fail here, the file is embedded."},
+			},
+			targetID: 2,
+			want:     ThreadPushback,
+		},
+		{
+			name: "pushback deeper in thread",
+			comments: []github.PullRequestComment{
+				{ID: 1, User: bot, Body: "Possible nil dereference."},
+				{ID: 2, InReplyToID: 1, User: bob, Body: "Agreed?"},
+				{ID: 3, InReplyToID: 1, User: alice, Body: "@shipitai no, it's checked by the caller."},
+			},
+			targetID: 3,
+			want:     ThreadPushback,
+		},
+		{
+			name: "general question on human thread",
+			comments: []github.PullRequestComment{
+				{ID: 1, User: bob, Body: "Why a map here?"},
+				{ID: 2, InReplyToID: 1, User: alice, Body: "@shipitai what do you think about this data structure?"},
+			},
+			targetID: 2,
+			want:     ThreadGeneral,
+		},
+		{
+			name: "new top-level question",
+			comments: []github.PullRequestComment{
+				{ID: 5, User: alice, Body: "@shipitai can you explain this function?"},
+			},
+			targetID: 5,
+			want:     ThreadGeneral,
+		},
+		{
+			name: "bot comment in a different thread",
+			comments: []github.PullRequestComment{
+				{ID: 1, User: bot, Body: "Unrelated finding."},
+				{ID: 2, User: bob, Body: "Is this safe?"},
+				{ID: 3, InReplyToID: 2, User: alice, Body: "@shipitai thoughts?"},
+			},
+			targetID: 3,
+			want:     ThreadGeneral,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyThread(tt.comments, tt.targetID, "shipitai"); got != tt.want {
+				t.Errorf("ClassifyThread() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplyCodeContext(t *testing.T) {
+	var file strings.Builder
+	for i := 1; i <= 100; i++ {
+		file.WriteString("line " + itoa(i) + "\n")
+	}
+
+	tests := []struct {
+		name      string
+		input     *ReplyInput
+		want      []string
+		wantNot   []string
+		wantFetch bool
+	}{
+		{
+			name:    "hunk present",
+			input:   &ReplyInput{FilePath: "main.go", Line: 50, HeadSHA: "abc123", DiffHunk: "@@ -1 +1 @@\n+changed"},
+			want:    []string{"(diff hunk)", "+changed"},
+			wantNot: []string{"line 50"},
+		},
+		{
+			name:      "file known without hunk",
+			input:     &ReplyInput{FilePath: "main.go", Line: 50, HeadSHA: "abc123"},
+			want:      []string{">   50 | line 50", "    30 | line 30", "    70 | line 70"},
+			wantNot:   []string{"line 29\n", "line 71\n"},
+			wantFetch: true,
+		},
+		{
+			name:      "file missing at head",
+			input:     &ReplyInput{FilePath: "gone.go", Line: 5, HeadSHA: "abc123"},
+			want:      []string{"couldn't be loaded"},
+			wantFetch: true,
+		},
+		{
+			name:  "no file",
+			input: &ReplyInput{UserQuestion: "What does this PR change?"},
+			want:  []string{"pull request as a whole"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched bool
+			transport := routeTransport{"/contents/main.go": configResponse(file.String())}
+			recorder := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				fetched = true
+				return transport.RoundTrip(req)
+			})
+			reviewer := NewReviewer(github.NewClientWithTransport(recorder), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			got := reviewer.replyCodeContext(context.Background(), tt.input)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("replyCodeContext() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tt.wantNot {
+				if strings.Contains(got, notWant) {
+					t.Errorf("replyCodeContext() = %q, want it not to contain %q", got, notWant)
+				}
+			}
+			if fetched != tt.wantFetch {
+				t.Errorf("fetched file = %v, want %v", fetched, tt.wantFetch)
+			}
+		})
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRepliesEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   bool
+	}{
+		{"default", "enabled: true", true},
+		{"opted out", "replies_enabled: false", false},
+		{"opted in", "replies_enabled: true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := routeTransport{"/contents/.github/shipitai.yml": configResponse(tt.config)}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			got := reviewer.RepliesEnabled(context.Background(), &CommandRequest{Owner: "owner", Repo: "repo", Command: github.CommandExplain})
+			if got != tt.want {
+				t.Errorf("RepliesEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplyLimit(t *testing.T) {
+	claude := newFakeClaude(t, "Good question.")
+
+	tests := []struct {
+		name        string
+		prior       int
+		wantLimited bool
+		wantCount   int
+	}{
+		{"under the limit", 1, false, 2},
+		{"at the limit", 2, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claude.reset()
+			var posted []string
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				switch {
+				case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+					return configResponse("max_replies_per_pr: 2"), nil
+				case strings.HasSuffix(req.URL.Path, "/pulls/7/comments/3/replies"):
+					var comment struct{ Body string }
+					_ = json.NewDecoder(req.Body).Decode(&comment)
+					posted = append(posted, comment.Body)
+					return newResponse(req, http.StatusCreated, `{"id":9}`), nil
+				}
+				return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+			})
+			store := memory.New()
+			ctx := context.Background()
+			for range tt.prior {
+				_ = store.IncrementReplyCount(ctx, 5, "owner", "repo", 7)
+			}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := reviewer.Reply(ctx, &ReplyInput{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: 7, CommentID: 3, UserQuestion: "Why?"})
+			if err != nil {
+				t.Fatalf("Reply() error = %v", err)
+			}
+
+			if result.LimitReached != tt.wantLimited {
+				t.Errorf("LimitReached = %v, want %v", result.LimitReached, tt.wantLimited)
+			}
+			if called := claude.calls() > 0; called == tt.wantLimited {
+				t.Errorf("called Claude = %v, want %v", called, !tt.wantLimited)
+			}
+			if len(posted) != 1 || strings.Contains(posted[0], "Reply limit reached") != tt.wantLimited {
+				t.Errorf("posted %q, want one reply (limit message: %v)", posted, tt.wantLimited)
+			}
+			if n, _ := store.GetReplyCount(ctx, 5, "owner", "repo", 7); n != tt.wantCount {
+				t.Errorf("GetReplyCount() = %d, want %d", n, tt.wantCount)
+			}
+		})
+	}
+
+	t.Run("limit message once per thread", func(t *testing.T) {
+		var posted []string
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+				return configResponse("max_replies_per_pr: 1"), nil
+			case strings.HasSuffix(req.URL.Path, "/replies"):
+				posted = append(posted, req.URL.Path)
+				return newResponse(req, http.StatusCreated, `{"id":9}`), nil
+			}
+			return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+		})
+		store := memory.New()
+		ctx := context.Background()
+		_ = store.IncrementReplyCount(ctx, 5, "owner", "repo", 7)
+		reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		// Two more mentions in thread 3, then one starting thread 20
+		for _, in := range []struct{ comment, thread int64 }{{3, 0}, {4, 3}, {20, 0}} {
+			result, err := reviewer.Reply(ctx, &ReplyInput{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: 7, CommentID: in.comment, ThreadID: in.thread, UserQuestion: "Why?"})
+			if err != nil || !result.LimitReached {
+				t.Fatalf("Reply(%d) = %+v, %v, want the limit reached", in.comment, result, err)
+			}
+		}
+		want := "/repos/owner/repo/pulls/7/comments/3/replies /repos/owner/repo/pulls/7/comments/20/replies"
+		if got := strings.Join(posted, " "); got != want {
+			t.Errorf("posted limit messages to %s, want %s", got, want)
+		}
+	})
+}
+
+func TestReviewHistory(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	if err := store.StoreReview(ctx, &storage.ReviewContext{
+		Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 1,
+		ReviewBody: "Adds a cache in front of the user lookup.",
+		Comments:   []storage.Comment{{Path: "cache.go", Line: 12, Body: "Lookup can return nil on a cache miss."}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name    string
+		store   storage.Storage
+		input   *ReplyInput
+		want    []string
+		wantNot []string
+	}{
+		{
+			name:  "question on a flagged line",
+			store: store,
+			input: &ReplyInput{Owner: "owner", Repo: "repo", PRNumber: 7, FilePath: "cache.go", Line: 12},
+			want:  []string{"Adds a cache in front of the user lookup.", "Lookup can return nil on a cache miss."},
+		},
+		{
+			name:    "pushback leaves the finding to its own section",
+			store:   store,
+			input:   &ReplyInput{Owner: "owner", Repo: "repo", PRNumber: 7, FilePath: "cache.go", Line: 12, Kind: ThreadPushback},
+			want:    []string{"Adds a cache in front of the user lookup."},
+			wantNot: []string{"Lookup can return nil"},
+		},
+		{
+			name:    "other line gets only the summary",
+			store:   store,
+			input:   &ReplyInput{Owner: "owner", Repo: "repo", PRNumber: 7, FilePath: "cache.go", Line: 40},
+			want:    []string{"Adds a cache in front of the user lookup."},
+			wantNot: []string{"Lookup can return nil"},
+		},
+		{
+			name:  "PR without a stored review",
+			store: store,
+			input: &ReplyInput{Owner: "owner", Repo: "repo", PRNumber: 8, FilePath: "cache.go", Line: 12},
+		},
+		{
+			name:  "no storage",
+			input: &ReplyInput{Owner: "owner", Repo: "repo", PRNumber: 7, FilePath: "cache.go", Line: 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviewer := NewReviewer(github.NewClientWithTransport(routeTransport{}), "key", tt.store, logger)
+			got := reviewer.reviewHistory(ctx, tt.input)
+			if len(tt.want) == 0 && got != "" {
+				t.Errorf("reviewHistory() = %q, want empty", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reviewHistory() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tt.wantNot {
+				if strings.Contains(got, notWant) {
+					t.Errorf("reviewHistory() = %q, want it not to contain %q", got, notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package review
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/shipitai/shipitai/storage"
+)
+
+// diffHash identifies the diff a review was run on, for matching reviews to logs.
+func diffHash(diff string) string {
+	sum := sha256.Sum256([]byte(diff))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// buildReproMarker returns a hidden HTML comment recording the model, diff hash,
+// and token usage behind a review, so a posted review can be traced to its logs
+// and reproduced. It doesn't render on GitHub.
+func buildReproMarker(model, diffHash string, usage *storage.TokenUsage) string {
+	var input, output int64
+	if usage != nil {
+		input, output = usage.InputTokens, usage.OutputTokens
+	}
+	return fmt.Sprintf("\n\n<!-- shipitai: model=%s diff=%s input_tokens=%d output_tokens=%d -->", model, diffHash, input, output)
+}
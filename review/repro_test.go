@@ -0,0 +1,63 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestBuildReproMarker(t *testing.T) {
+	marker := strings.TrimSpace(buildReproMarker("claude-sonnet-4-5", "0123456789abcdef", &storage.TokenUsage{InputTokens: 1200, OutputTokens: 340}))
+
+	if !strings.HasPrefix(marker, "<!--") || !strings.HasSuffix(marker, "-->") || strings.Count(marker, "-->") != 1 {
+		t.Fatalf("marker %q is not a single HTML comment", marker)
+	}
+	for _, want := range []string{"model=claude-sonnet-4-5", "diff=0123456789abcdef", "input_tokens=1200", "output_tokens=340"} {
+		if !strings.Contains(marker, want) {
+			t.Errorf("marker %q missing %q", marker, want)
+		}
+	}
+
+	if marker := buildReproMarker("m", "h", nil); !strings.Contains(marker, "input_tokens=0 output_tokens=0") {
+		t.Errorf("marker without usage = %q, want zero token counts", marker)
+	}
+}
+
+func TestDiffHash(t *testing.T) {
+	a, b := diffHash("+x\n"), diffHash("+y\n")
+	if len(a) != 16 || a == b || a != diffHash("+x\n") {
+		t.Errorf("diffHash() = %q, %q, want stable 16-character hashes that differ by diff", a, b)
+	}
+}
+
+func TestReviewBodyCarriesReproMarker(t *testing.T) {
+	newFakeClaude(t, `{"summary":"Looks fine.","approval":"approve","comments":[]}`)
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n"
+	transport := &reviewsTransport{diff: diff}
+	store := memory.New()
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	if _, err := reviewer.Review(ctx, &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if len(transport.posted) != 1 {
+		t.Fatalf("posted %d reviews, want 1", len(transport.posted))
+	}
+	body := transport.posted[0].Body
+	if !strings.HasSuffix(body, "diff="+diffHash(diff)+" input_tokens=100 output_tokens=10 -->") {
+		t.Errorf("posted body = %q, want it to end with the repro marker", body)
+	}
+
+	first, _ := store.GetFirstReviewForPR(ctx, 0, "owner", "repo", 7)
+	if first == nil || strings.Contains(first.ReviewBody, "<!--") {
+		t.Errorf("stored review = %+v, want its body without the marker", first)
+	}
+}
@@ -0,0 +1,216 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+)
+
+// RetryFailedFiles re-reviews only the files whose chunks failed in the PR's first
+// review (for "@shipitai retry"). New findings are posted as a separate review and
+// the original review body is updated in place: the failure note is replaced with
+// the retry summary, listing any files that failed again.
+func (r *Reviewer) RetryFailedFiles(ctx context.Context, input *ReviewInput) (*ReviewResult, error) {
+	if r.storage == nil {
+		return nil, errors.New("retry requires storage")
+	}
+
+	// Like Review, a muted PR is left alone
+	if r.isPRMuted(ctx, input) {
+		r.logger.Info("retry skipped: PR is muted")
+		return nil, nil
+	}
+
+	firstReview, err := r.storage.GetFirstReviewForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load first review: %w", err)
+	}
+	if firstReview == nil || len(firstReview.FailedFiles) == 0 {
+		r.logger.Info("retry skipped: no failed files recorded", "pr", input.PRNumber)
+		return &ReviewResult{SkipReason: SkipReasonNothingToRetry}, nil
+	}
+
+	r.logger.Info("retrying failed files",
+		"first_review_id", firstReview.ReviewID,
+		"files", firstReview.FailedFiles,
+	)
+
+	if r.limiter != nil {
+		release, err := r.limiter.acquire(ctx, input.InstallationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting for review slot: %w", err)
+		}
+		defer release()
+	}
+
+	if r.isInstallationSuspended(ctx, input.InstallationID) {
+		r.logger.Info("retry skipped: installation is suspended", "installation_id", input.InstallationID)
+		return &ReviewResult{SkipReason: SkipReasonInstallationSuspended}, nil
+	}
+
+	cfg, err := r.loadConfig(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := retryWithBackoff(ctx, r.logger, "fetchDiff", func() (string, error) {
+		return r.fetchReviewDiff(ctx, input, cfg)
+	})
+	if github.IsNotFound(err) {
+		r.logger.Info("PR is no longer accessible, skipping retry", "error", err)
+		return &ReviewResult{SkipReason: SkipReasonPRInaccessible}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch diff: %w", err)
+	}
+
+	// Files that have since been removed from the PR no longer need a review
+	diff = filterDiffToFiles(diff, firstReview.FailedFiles)
+	changedFiles := ParseDiffInfo(diff).Files
+
+	apiKey, _, err := r.getAPIKey(ctx, input.InstallationID)
+	if err != nil {
+		apiKey = r.claudeAPIKey
+	}
+	model := r.getModel(ctx, input.InstallationID)
+
+	var reviewCtx *ReviewContext
+	parsed := &ClaudeResponse{Approval: "approve"}
+	var usage *storage.TokenUsage
+	if len(changedFiles) > 0 {
+		reviewCtx = r.contextFetcher.FetchContext(ctx, &ContextInput{
+			InstallationID: input.InstallationID,
+			Owner:          input.Owner,
+			Repo:           input.Repo,
+			HeadRef:        input.HeadSHA,
+			ChangedFiles:   changedFiles,
+			Config:         cfg,
+		})
+		parsed, usage, err = r.claudeReview(ctx, input, cfg, diff, apiKey, model, reviewCtx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	diffLines := r.headDiffLines(ctx, input, cfg, ParseDiffLines(diff))
+	parsed.Comments, _, _ = SnapValidComments(parsed.Comments, diffLines, cfg.CommentSnapWindow(), cfg.CaseInsensitivePaths, r.logger)
+	parsed.Comments, _ = FilterBySeverity(parsed.Comments, cfg.SeverityScale, cfg.MinSeverity, r.logger)
+	parsed.Comments, _ = FilterByConfidence(parsed.Comments, cfg.MinConfidence, r.logger)
+	if cfg.RespectTODOs {
+		parsed.Comments = filterAcknowledgedComments(parsed.Comments, ParseAddedLines(diff), r.logger)
+	}
+	parsed.Comments = sanitizeComments(parsed.Comments)
+	if cfg.ExplainConfidence {
+		parsed.Comments = appendConfidence(parsed.Comments)
+	}
+	parsed.Approval = DetermineApprovalFromSeverity(parsed.Comments, cfg.SeverityScale, cfg.RequestChangesAt)
+
+	// Replace the failure note in the original body with the retry outcome
+	botName := r.botNameOrDefault()
+	retrySummary := fmt.Sprintf("**Retried %s:**", pluralize(len(firstReview.FailedFiles), "file"))
+	if parsed.Summary != "" {
+		retrySummary += " " + parsed.Summary
+	}
+	if len(parsed.FailedFiles) > 0 {
+		retrySummary += "\n\n" + BuildFailedFilesNote(botName, parsed.FailedFiles)
+	}
+	oldNote := BuildFailedFilesNote(botName, firstReview.FailedFiles)
+	newBody := firstReview.ReviewBody
+	if strings.Contains(newBody, oldNote) {
+		newBody = strings.Replace(newBody, oldNote, retrySummary, 1)
+	} else {
+		newBody += "\n\n" + retrySummary
+	}
+
+	if input.DryRun {
+		r.logger.Info("dry run, not posting retry review", "comments", len(parsed.Comments))
+		return &ReviewResult{
+			Summary:      parsed.Summary,
+			CommentCount: len(parsed.Comments),
+			Approval:     parsed.Approval,
+			Extras:       parsed.Extras,
+			Usage:        usage,
+			DryRun:       true,
+			Comments:     parsed.Comments,
+			FailedFiles:  parsed.FailedFiles,
+		}, nil
+	}
+
+	if err := r.githubClient.UpdateReviewBody(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, firstReview.ReviewID, newBody); err != nil {
+		r.logger.Error("failed to update original review body", "error", err)
+	}
+
+	reviewComments := make([]github.ReviewComment, len(parsed.Comments))
+	for i, c := range parsed.Comments {
+		reviewComments[i] = github.ReviewComment{
+			Path: c.Path,
+			Line: c.Line,
+			Side: "RIGHT",
+			Body: FormatSeverityBadge(c.Body, c.Severity, cfg.SeverityScale, cfg.SeverityBadge, cfg.SeverityEmoji),
+		}
+	}
+	reviewReq := &github.ReviewRequest{
+		CommitID: input.HeadSHA,
+		Body:     "", // Empty body since we updated the original
+		Event:    mapApprovalToEvent(parsed.Approval),
+		Comments: reviewComments,
+	}
+	r.holdApprovalForChecks(ctx, input, cfg, reviewReq)
+	retryReview, err := r.postReview(ctx, input, reviewReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post retry review: %w", err)
+	}
+	r.logger.Info("posted retry review", "review_id", retryReview.ID, "event", reviewReq.Event, "comment_count", len(parsed.Comments))
+
+	// The first review now only tracks files that failed again
+	firstReview.ReviewBody = newBody
+	firstReview.FailedFiles = parsed.FailedFiles
+	if err := r.storage.StoreReview(ctx, firstReview); err != nil {
+		r.logger.Error("failed to update first review context", "error", err)
+	}
+	if err := r.storage.StoreReview(ctx, &storage.ReviewContext{
+		InstallationID: input.InstallationID,
+		Owner:          input.Owner,
+		Repo:           input.Repo,
+		PRNumber:       input.PRNumber,
+		ReviewID:       retryReview.ID,
+		ReviewBody:     parsed.Summary,
+		Comments:       toStorageComments(parsed.Comments),
+		Usage:          usage,
+		UsageType:      "review",
+	}); err != nil {
+		r.logger.Error("failed to store retry review context", "error", err)
+	}
+
+	return &ReviewResult{
+		ReviewID:     firstReview.ReviewID,
+		ReviewURL:    retryReview.HTMLURL,
+		Summary:      parsed.Summary,
+		CommentCount: len(parsed.Comments),
+		Approval:     parsed.Approval,
+		Extras:       parsed.Extras,
+		Usage:        usage,
+		Comments:     parsed.Comments,
+		FailedFiles:  parsed.FailedFiles,
+	}, nil
+}
+
+// filterDiffToFiles keeps only the diffs of the given files.
+func filterDiffToFiles(diff string, files []string) string {
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[f] = true
+	}
+
+	var kept Chunk
+	for _, fd := range SplitDiffByFile(diff) {
+		if keep[fd.Path] {
+			kept.Files = append(kept.Files, fd)
+		}
+	}
+	return ChunkToDiff(&kept)
+}
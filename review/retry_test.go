@@ -0,0 +1,176 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+// reviewsTransport fakes the GitHub endpoints a review writes to: posted reviews
+// get sequential IDs and review body updates are recorded.
+type reviewsTransport struct {
+	diff   string
+	config string
+
+	mu      sync.Mutex
+	nextID  int64
+	posted  []github.ReviewRequest
+	updates []string
+}
+
+func (t *reviewsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/contents/.github/shipitai.yml"):
+		return configResponse(t.config), nil
+	case req.Method == http.MethodGet && strings.HasSuffix(path, "/pulls/7"):
+		return newResponse(req, http.StatusOK, t.diff), nil
+	case req.Method == http.MethodPost && strings.HasSuffix(path, "/pulls/7/reviews"):
+		var review github.ReviewRequest
+		_ = json.NewDecoder(req.Body).Decode(&review)
+		t.posted = append(t.posted, review)
+		t.nextID++
+		return newResponse(req, http.StatusOK, fmt.Sprintf(`{"id":%d}`, t.nextID)), nil
+	case req.Method == http.MethodPut && strings.Contains(path, "/pulls/7/reviews/"):
+		var update struct {
+			Body string `json:"body"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&update)
+		t.updates = append(t.updates, update.Body)
+		return newResponse(req, http.StatusOK, `{}`), nil
+	}
+	return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+}
+
+// largeFileDiff returns a diff adding n lines to path.
+func largeFileDiff(path string, n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -0,0 +1,%d @@\n", path, path, path, path, n)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "+var v%d = \"padding to make the diff large\"\n", i)
+	}
+	return b.String()
+}
+
+func TestRetryFailedFiles(t *testing.T) {
+	// Two files too large to share a chunk, so each is reviewed separately
+	diff := largeFileDiff("ok.go", 1500) + largeFileDiff("fail.go", 1500)
+
+	claude := newFakeClaude(t, `{"summary":"Looks fine.","approval":"approve","comments":[]}`)
+	claude.failRequestsWith("b/fail.go")
+
+	transport := &reviewsTransport{diff: diff, config: "partial_chunk_reviews: true"}
+	store := memory.New()
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}
+	ctx := context.Background()
+
+	// The first review posts what succeeded and records the failed chunk's files
+	result, err := reviewer.Review(ctx, input)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if len(result.FailedFiles) != 1 || result.FailedFiles[0] != "fail.go" {
+		t.Fatalf("FailedFiles = %v, want [fail.go]", result.FailedFiles)
+	}
+	if len(transport.posted) != 1 || !strings.Contains(transport.posted[0].Body, "`@shipitai retry`") {
+		t.Fatalf("posted reviews = %+v, want one review with the retry note", transport.posted)
+	}
+	first, err := store.GetFirstReviewForPR(ctx, 0, "owner", "repo", 7)
+	if err != nil || first == nil {
+		t.Fatalf("GetFirstReviewForPR() = %v, %v", first, err)
+	}
+	if len(first.FailedFiles) != 1 || first.FailedFiles[0] != "fail.go" {
+		t.Errorf("stored FailedFiles = %v, want [fail.go]", first.FailedFiles)
+	}
+
+	// The retry only sends the failed file to Claude and clears the note
+	claude.failRequestsWith("")
+	claude.reset()
+
+	result, err = reviewer.RetryFailedFiles(ctx, input)
+	if err != nil {
+		t.Fatalf("RetryFailedFiles() error = %v", err)
+	}
+	if result.SkipReason != "" || len(result.FailedFiles) != 0 {
+		t.Errorf("retry result = %+v, want a full retry", result)
+	}
+	if prompts := claude.prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], "b/fail.go") || strings.Contains(prompts[0], "b/ok.go") {
+		t.Errorf("retry sent %d prompts, want one covering only fail.go", len(prompts))
+	}
+	if len(transport.updates) != 1 {
+		t.Fatalf("review body updates = %d, want 1", len(transport.updates))
+	}
+	if body := transport.updates[0]; strings.Contains(body, "could not be reviewed") || !strings.Contains(body, "**Retried 1 file:** Looks fine.") {
+		t.Errorf("updated body = %q, want the note replaced by the retry summary", body)
+	}
+	if len(transport.posted) != 2 {
+		t.Errorf("posted reviews = %d, want the retry review too", len(transport.posted))
+	}
+
+	first, _ = store.GetFirstReviewForPR(ctx, 0, "owner", "repo", 7)
+	if len(first.FailedFiles) != 0 || first.ReviewBody != transport.updates[0] {
+		t.Errorf("stored first review = %+v, want no failed files and the updated body", first)
+	}
+
+	// Nothing is left to retry
+	result, err = reviewer.RetryFailedFiles(ctx, input)
+	if err != nil || result.SkipReason != SkipReasonNothingToRetry {
+		t.Errorf("second RetryFailedFiles() = %+v, %v, want %s skip", result, err, SkipReasonNothingToRetry)
+	}
+}
+
+func TestReviewChunkFailureWithoutPartialReviews(t *testing.T) {
+	diff := largeFileDiff("ok.go", 1500) + largeFileDiff("fail.go", 1500)
+	newFakeClaudeReplies(t, claudeReply{Status: http.StatusBadRequest})
+
+	transport := &reviewsTransport{diff: diff, config: "enabled: true"}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", memory.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}); err == nil {
+		t.Error("Review() error = nil, want the chunk failure")
+	}
+	if len(transport.posted) != 0 {
+		t.Errorf("posted reviews = %d, want none", len(transport.posted))
+	}
+}
+
+func TestRetryFailedFilesSkipsSuspendedInstallation(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	if err := store.SaveInstallation(ctx, &storage.Installation{InstallationID: 5, OrgLogin: "owner"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetInstallationActive(ctx, 5, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreReview(ctx, &storage.ReviewContext{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 1, FailedFiles: []string{"fail.go"}}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := &writeRecorder{next: routeTransport{}}
+	reviewer := NewReviewer(github.NewClientWithTransport(recorder), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.RetryFailedFiles(ctx, &ReviewInput{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: 7})
+	if err != nil {
+		t.Fatalf("RetryFailedFiles() error = %v", err)
+	}
+	if result.SkipReason != SkipReasonInstallationSuspended {
+		t.Errorf("SkipReason = %q, want %q", result.SkipReason, SkipReasonInstallationSuspended)
+	}
+	if len(recorder.writes) != 0 {
+		t.Errorf("writes = %v, want none", recorder.writes)
+	}
+}
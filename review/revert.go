@@ -0,0 +1,20 @@
+package review
+
+import (
+	"regexp"
+	"strings"
+)
+
+// revertTitleRegex matches titles GitHub and git generate for reverts,
+// e.g. `Revert "Add feature"` or `revert: add feature`.
+var revertTitleRegex = regexp.MustCompile(`(?i)^revert\b[\s:"(]`)
+
+// revertBodyRegex matches the trailer git adds to revert commit messages,
+// which GitHub copies into the description of single-commit PRs.
+var revertBodyRegex = regexp.MustCompile(`This reverts commit [0-9a-f]{7,40}`)
+
+// isRevertPR reports whether a pull request looks like a pure revert,
+// based on its title or a "This reverts commit <sha>" line in its description.
+func isRevertPR(title, body string) bool {
+	return revertTitleRegex.MatchString(strings.TrimSpace(title)) || revertBodyRegex.MatchString(body)
+}
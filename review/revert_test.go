@@ -0,0 +1,67 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestIsRevertPR(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		want  bool
+	}{
+		{"GitHub revert title", `Revert "Add retry to webhook handler"`, "Reverts owner/repo#41", true},
+		{"conventional revert title", "revert: drop legacy parser", "", true},
+		{"revert trailer in body", "Undo parser change", "This reverts commit 1a2b3c4d5e6f.", true},
+		{"normal PR", "Add retry to webhook handler", "Retries transient failures.", false},
+		{"revert as a later word", "Fix revert of config loader", "", false},
+		{"word starting with revert", "Reverted state handling", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRevertPR(tt.title, tt.body); got != tt.want {
+				t.Errorf("isRevertPR(%q, %q) = %v, want %v", tt.title, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewPostsRevertNoticeOnce(t *testing.T) {
+	var mu sync.Mutex
+	notices := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+			return configResponse("light_review_reverts: true"), nil
+		case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/issues/7/comments"):
+			mu.Lock()
+			notices++
+			mu.Unlock()
+			return newResponse(req, http.StatusCreated, `{"id":1}`), nil
+		}
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+	})
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", memory.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Each push to the revert PR skips the review again
+	for _, head := range []string{"abc123", "def456"} {
+		result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: head, PRTitle: `Revert "Add cache"`})
+		if err != nil || result == nil || result.SkipReason != SkipReasonRevert {
+			t.Fatalf("Review() = %+v, %v, want SkipReason %q", result, err, SkipReasonRevert)
+		}
+	}
+	if notices != 1 {
+		t.Errorf("posted %d revert notices, want 1", notices)
+	}
+}
@@ -2,6 +2,7 @@ package review
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -13,63 +14,81 @@ import (
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/shipitai/shipitai/config"
 	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/metrics"
 	"github.com/shipitai/shipitai/storage"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 )
 
-// reviewResponseSchema is the JSON schema for structured outputs, matching ClaudeResponse.
-var reviewResponseSchema = map[string]any{
-	"type":                 "object",
-	"additionalProperties": false,
-	"properties": map[string]any{
-		"summary": map[string]any{"type": "string"},
-		"comments": map[string]any{
-			"type": "array",
-			"items": map[string]any{
-				"type":                 "object",
-				"additionalProperties": false,
-				"properties": map[string]any{
-					"path":     map[string]any{"type": "string"},
-					"line":     map[string]any{"type": "integer"},
-					"body":     map[string]any{"type": "string"},
-					"severity": map[string]any{"type": "string", "enum": []any{"low", "medium", "high", "critical"}},
+// reviewResponseSchema returns the JSON schema for structured outputs, matching
+// ClaudeResponse, with severity limited to the levels of the scale.
+func reviewResponseSchema(scale config.SeverityScale) map[string]any {
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"summary": map[string]any{"type": "string"},
+			"comments": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties": map[string]any{
+						"path":       map[string]any{"type": "string"},
+						"line":       map[string]any{"type": "integer"},
+						"body":       map[string]any{"type": "string"},
+						"severity":   map[string]any{"type": "string", "enum": severityEnum(scale)},
+						"confidence": map[string]any{"type": "string", "enum": []any{"low", "medium", "high"}},
+					},
+					"required": []string{"path", "line", "body", "severity"},
 				},
-				"required": []string{"path", "line", "body", "severity"},
 			},
+			"approval": map[string]any{"type": "string"},
 		},
-		"approval": map[string]any{"type": "string"},
-	},
-	"required": []string{"summary", "comments", "approval"},
+		"required": []string{"summary", "comments", "approval"},
+	}
 }
 
 // subsequentReviewResponseSchema extends the review schema with resolved_threads for subsequent reviews.
-var subsequentReviewResponseSchema = map[string]any{
-	"type":                 "object",
-	"additionalProperties": false,
-	"properties": map[string]any{
-		"summary": map[string]any{"type": "string"},
-		"comments": map[string]any{
-			"type": "array",
-			"items": map[string]any{
-				"type":                 "object",
-				"additionalProperties": false,
-				"properties": map[string]any{
-					"path":     map[string]any{"type": "string"},
-					"line":     map[string]any{"type": "integer"},
-					"body":     map[string]any{"type": "string"},
-					"severity": map[string]any{"type": "string", "enum": []any{"low", "medium", "high", "critical"}},
+func subsequentReviewResponseSchema(scale config.SeverityScale) map[string]any {
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"summary": map[string]any{"type": "string"},
+			"comments": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties": map[string]any{
+						"path":       map[string]any{"type": "string"},
+						"line":       map[string]any{"type": "integer"},
+						"body":       map[string]any{"type": "string"},
+						"severity":   map[string]any{"type": "string", "enum": severityEnum(scale)},
+						"confidence": map[string]any{"type": "string", "enum": []any{"low", "medium", "high"}},
+					},
+					"required": []string{"path", "line", "body", "severity"},
 				},
-				"required": []string{"path", "line", "body", "severity"},
 			},
+			"resolved_threads": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "string"},
+			},
+			"approval": map[string]any{"type": "string"},
 		},
-		"resolved_threads": map[string]any{
-			"type":  "array",
-			"items": map[string]any{"type": "string"},
-		},
-		"approval": map[string]any{"type": "string"},
-	},
-	"required": []string{"summary", "comments", "resolved_threads", "approval"},
+		"required": []string{"summary", "comments", "resolved_threads", "approval"},
+	}
+}
+
+// severityEnum lists the scale's level names for a schema enum, least severe first.
+func severityEnum(scale config.SeverityScale) []any {
+	names := scale.Names()
+	enum := make([]any, len(names))
+	for i, name := range names {
+		enum[len(names)-1-i] = name
+	}
+	return enum
 }
 
 // APIKeyFunc is a function that resolves the API key for a given installation.
@@ -85,14 +104,39 @@ type ModelFunc func(ctx context.Context, installationID int64) (string, error)
 type ModelOption struct {
 	ID    string
 	Label string
+
+	// MaxOutputTokens is the most output tokens the model accepts per request.
+	MaxOutputTokens int
 }
 
 // SupportedModels lists the Claude models available for per-installation selection.
 // The first entry is the default selection.
 var SupportedModels = []ModelOption{
-	{ID: "claude-sonnet-4-5-20250929", Label: "Claude Sonnet 4.5"},
-	{ID: "claude-opus-4-6", Label: "Claude Opus 4.6"},
-	{ID: "claude-haiku-4-5-20251001", Label: "Claude Haiku 4.5"},
+	{ID: "claude-sonnet-4-5-20250929", Label: "Claude Sonnet 4.5", MaxOutputTokens: 64000},
+	{ID: "claude-opus-4-6", Label: "Claude Opus 4.6", MaxOutputTokens: 128000},
+	{ID: "claude-haiku-4-5-20251001", Label: "Claude Haiku 4.5", MaxOutputTokens: 64000},
+}
+
+// defaultModelMaxOutputTokens is the output token ceiling of DefaultModel.
+const defaultModelMaxOutputTokens = 64000
+
+// maxOutputTokens returns the repo's max_output_tokens clamped to the model's
+// ceiling. Models not in SupportedModels (other than DefaultModel) aren't clamped.
+func maxOutputTokens(cfg *config.Config, model string) int64 {
+	limit := cfg.OutputTokens()
+	ceiling := 0
+	if model == DefaultModel {
+		ceiling = defaultModelMaxOutputTokens
+	}
+	for _, m := range SupportedModels {
+		if m.ID == model {
+			ceiling = m.MaxOutputTokens
+		}
+	}
+	if ceiling > 0 && limit > ceiling {
+		return int64(ceiling)
+	}
+	return int64(limit)
 }
 
 // IsValidModel checks whether a model ID is in the supported models list.
@@ -109,24 +153,105 @@ const (
 	// DefaultModel is the Claude model used for code reviews.
 	DefaultModel = "claude-sonnet-4-20250514"
 
-	// ClaudeAPITimeout is the maximum time to wait for a Claude API response.
+	// ClaudeAPITimeout is the default maximum time to wait for a Claude API response.
 	ClaudeAPITimeout = 3 * time.Minute
 
-	// MaxConcurrentChunks limits how many chunks can be reviewed in parallel.
-	MaxConcurrentChunks = 5
+	// DefaultMaxConcurrentChunks limits how many chunks of a review are sent to
+	// Claude in parallel.
+	DefaultMaxConcurrentChunks = 5
+
+	// DefaultMaxConcurrentReviewsPerInstallation limits how many reviews a single
+	// installation can run at once, so one tenant can't starve the others.
+	DefaultMaxConcurrentReviewsPerInstallation = 3
 
 	// MaxRetries is the number of times to retry transient API failures.
 	MaxRetries = 3
 
 	// RetryBaseDelay is the initial delay between retries (doubles each attempt).
 	RetryBaseDelay = 1 * time.Second
+
+	// MaxRetryAfter caps how long we'll wait when GitHub asks us to back off;
+	// longer waits fail the operation instead of holding the review open.
+	MaxRetryAfter = 2 * time.Minute
 )
 
+// Outcome summarizes a review's result for logging and metrics: "failed",
+// "disabled" (nil result), "skipped_<reason>", "dry_run", or "posted".
+func Outcome(result *ReviewResult, err error) string {
+	switch {
+	case err != nil:
+		return "failed"
+	case result == nil:
+		return "disabled"
+	case result.SkipReason != "":
+		return "skipped_" + result.SkipReason
+	case result.DryRun:
+		return "dry_run"
+	default:
+		return "posted"
+	}
+}
+
+// Skip reasons reported in ReviewResult.SkipReason when a review is intentionally not performed.
+const (
+	// SkipReasonDraft indicates the PR is a draft and skip_drafts is enabled.
+	SkipReasonDraft = "draft"
+
+	// SkipReasonStalePR indicates the PR is older than the configured max_pr_age.
+	SkipReasonStalePR = "stale_pr"
+
+	// SkipReasonRevert indicates the PR is a revert and light_review_reverts is enabled.
+	SkipReasonRevert = "revert"
+
+	// SkipReasonPRInaccessible indicates GitHub returned 404 for the PR's diff,
+	// because the PR was deleted or the app lost access after the webhook arrived.
+	SkipReasonPRInaccessible = "pr_inaccessible"
+
+	// SkipReasonMostlyDeletions indicates the diff is at least skip_deletion_ratio deletions.
+	SkipReasonMostlyDeletions = "mostly_deletions"
+
+	// SkipReasonNothingToRetry indicates "@shipitai retry" found no failed files to review.
+	SkipReasonNothingToRetry = "nothing_to_retry"
+
+	// SkipReasonNoOwnedFiles indicates only_owned_by is set and CODEOWNERS assigns
+	// none of the changed files to those owners.
+	SkipReasonNoOwnedFiles = "no_owned_files"
+
+	// SkipReasonStorageUnavailable indicates stored review history couldn't be read
+	// and the review was skipped rather than risk posting a duplicate first review.
+	SkipReasonStorageUnavailable = "storage_unavailable"
+
+	// SkipReasonTooSmall indicates the diff changes fewer lines than min_changed_lines.
+	SkipReasonTooSmall = "too_small"
+
+	// SkipReasonOnlySkippedFiles indicates every changed file matches skip_if_only_files.
+	SkipReasonOnlySkippedFiles = "only_skipped_files"
+
+	// SkipReasonInstallationSuspended indicates the GitHub App installation is suspended.
+	SkipReasonInstallationSuspended = "installation_suspended"
+
+	// SkipReasonAlreadyMerged indicates the PR was already merged, so its changes are
+	// in the base branch and there's nothing left to review.
+	SkipReasonAlreadyMerged = "already_merged"
+
+	// SkipReasonEmptyDiff indicates the PR's diff is empty once excluded, binary,
+	// and oversized files are dropped, e.g. a PR reopened after its base branch
+	// picked up the same changes, or one that only updates images.
+	SkipReasonEmptyDiff = "empty_diff"
+)
+
+// retryBaseDelay is the first backoff delay used by retryWithBackoff; tests shorten it.
+var retryBaseDelay = RetryBaseDelay
+
 // isRetryableError checks if an error is transient and worth retrying.
 func isRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter <= MaxRetryAfter
+	}
 	errStr := err.Error()
 	// Retry on rate limits, server errors, and network issues
 	return strings.Contains(errStr, "429") ||
@@ -155,7 +280,12 @@ func retryWithBackoff[T any](ctx context.Context, logger *slog.Logger, operation
 		}
 
 		if attempt < MaxRetries {
-			delay := RetryBaseDelay * time.Duration(1<<attempt) // exponential backoff
+			delay := retryBaseDelay * time.Duration(1<<attempt) // exponential backoff
+			// Honor GitHub's requested wait when it tells us how long to back off
+			var rateLimitErr *github.RateLimitError
+			if errors.As(lastErr, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+				delay = rateLimitErr.RetryAfter
+			}
 			logger.Warn("retrying after transient error",
 				"operation", operation,
 				"attempt", attempt+1,
@@ -175,42 +305,168 @@ func retryWithBackoff[T any](ctx context.Context, logger *slog.Logger, operation
 	return result, fmt.Errorf("max retries exceeded for %s: %w", operation, lastErr)
 }
 
-// callAndParse calls Claude and parses the response, retrying once on parse failure.
-// The callFn should make the Claude API call and return the response.
-// On parse failure, it re-calls Claude (fresh API call) and retries parsing.
-func callAndParse(logger *slog.Logger, operation string, callFn func() (*ClaudeAPIResponse, error)) (*ClaudeResponse, *ClaudeAPIResponse, error) {
-	const maxParseRetries = 1
+// logTruncation warns when a response that failed to parse was cut off at the
+// output token limit, which retrying with the same limit rarely fixes.
+func logTruncation(logger *slog.Logger, operation, stopReason string, maxTokens int64) {
+	if stopReason != string(anthropic.StopReasonMaxTokens) {
+		return
+	}
+	logger.Warn("Claude response truncated at the output token limit; raise max_output_tokens in .github/shipitai.yml",
+		"operation", operation,
+		"max_output_tokens", maxTokens,
+	)
+}
 
-	for attempt := 0; attempt <= maxParseRetries; attempt++ {
-		claudeResp, err := callFn()
-		if err != nil {
-			return nil, nil, err
-		}
+// repairFunc asks Claude to correct a response that failed to parse.
+type repairFunc func(invalid string, parseErr error) (*ClaudeAPIResponse, error)
 
-		parsed, parseErr := ParseResponse(claudeResp.Text)
-		if parseErr == nil {
-			return parsed, claudeResp, nil
-		}
+// callAndParse calls Claude and parses the response against the severity scale. On
+// a parse failure it makes a single corrective call through repairFn (nil disables
+// it) and parses the result; the returned usage includes both calls.
+func callAndParse(logger *slog.Logger, operation string, scale config.SeverityScale, callFn func() (*ClaudeAPIResponse, error), repairFn repairFunc) (*ClaudeResponse, *ClaudeAPIResponse, error) {
+	claudeResp, err := callFn()
+	if err != nil {
+		return nil, nil, err
+	}
 
-		if attempt < maxParseRetries {
-			logger.Warn("parse failure, retrying Claude call",
-				"operation", operation,
-				"attempt", attempt+1,
-				"error", parseErr,
-			)
-			continue
-		}
+	parsed, parseErr := ParseResponse(claudeResp.Text, scale)
+	if parseErr == nil {
+		return parsed, claudeResp, nil
+	}
+	logTruncation(logger, operation, claudeResp.StopReason, claudeResp.MaxTokens)
+	if repairFn == nil {
+		return nil, nil, parseErr
+	}
 
-		return nil, nil, fmt.Errorf("failed to parse Claude response after %d attempts: %w", attempt+1, parseErr)
+	logger.Warn("parse failure, asking Claude to correct its JSON",
+		"operation", operation,
+		"error", jsonErrorDetail(parseErr),
+	)
+	repaired, err := repairFn(claudeResp.Text, jsonErrorDetail(parseErr))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Claude response (%w); correction failed: %v", parseErr, err)
+	}
+	repaired.Usage = aggregateUsage([]*storage.TokenUsage{claudeResp.Usage, repaired.Usage})
+
+	parsed, err = ParseResponse(repaired.Text, scale)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Claude response after correction: %w", err)
+	}
+	return parsed, repaired, nil
+}
+
+// jsonErrorDetail returns the underlying JSON error of a ParseResponse failure,
+// without the response text ParseResponse includes in its message.
+func jsonErrorDetail(parseErr error) error {
+	if inner := errors.Unwrap(parseErr); inner != nil {
+		return inner
+	}
+	return parseErr
+}
+
+// repairJSON asks Claude to correct a response that failed to parse, using the
+// same structured output schema as the original call.
+func (r *Reviewer) repairJSON(ctx context.Context, apiKey, model string, maxTokens int64, schema map[string]any, invalid string, parseErr error) (*ClaudeAPIResponse, error) {
+	client := r.newReviewClient(apiKey)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.claudeTimeout)
+	defer cancel()
+
+	message, err := retryWithBackoff(timeoutCtx, r.logger, "repairJSON", func() (*anthropic.Message, error) {
+		return client.Messages.New(timeoutCtx, anthropic.MessageNewParams{
+			Model:     anthropic.Model(model),
+			MaxTokens: maxTokens,
+			System: []anthropic.TextBlockParam{
+				{Text: jsonRepairSystemPrompt},
+			},
+			Messages: []anthropic.MessageParam{
+				anthropic.NewUserMessage(anthropic.NewTextBlock(BuildJSONRepairPrompt(invalid, parseErr))),
+			},
+			OutputConfig: anthropic.OutputConfigParam{
+				Format: anthropic.JSONOutputFormatParam{
+					Schema: schema,
+				},
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Claude API error: %w", err)
 	}
 
-	// unreachable
-	return nil, nil, fmt.Errorf("unexpected state in callAndParse")
+	usage := &storage.TokenUsage{
+		InputTokens:              message.Usage.InputTokens,
+		OutputTokens:             message.Usage.OutputTokens,
+		CacheReadInputTokens:     message.Usage.CacheReadInputTokens,
+		CacheCreationInputTokens: message.Usage.CacheCreationInputTokens,
+	}
+	r.logger.Info("Claude API usage (JSON correction)",
+		"input_tokens", usage.InputTokens,
+		"output_tokens", usage.OutputTokens,
+	)
+
+	text, err := messageText(message)
+	if err != nil {
+		return nil, err
+	}
+	return &ClaudeAPIResponse{
+		Text:       text,
+		Usage:      usage,
+		StopReason: string(message.StopReason),
+		MaxTokens:  maxTokens,
+	}, nil
+}
+
+// EmptyResponseError is returned when Claude answers without any text, e.g. because
+// it refused the request or stopped for safety reasons. Unlike an API error it
+// isn't transient: sending the same request again gets the same answer.
+type EmptyResponseError struct {
+	// StopReason is why Claude stopped, e.g. "refusal"
+	StopReason string
+	// Detail describes what the response contained instead of text
+	Detail string
+}
+
+func (e *EmptyResponseError) Error() string {
+	msg := "no text content in Claude response: " + e.Detail
+	if e.StopReason != "" {
+		msg += " (stop_reason: " + e.StopReason + ")"
+	}
+	return msg
+}
+
+// messageText joins the text blocks of a Claude response. A response without any
+// text, or a refusal, fails with an EmptyResponseError naming the block types it
+// did contain, so a model answering with only thinking or tool_use blocks is easy
+// to spot.
+func messageText(message *anthropic.Message) (string, error) {
+	stopReason := string(message.StopReason)
+	if message.StopReason == anthropic.StopReasonRefusal {
+		return "", &EmptyResponseError{StopReason: stopReason, Detail: "Claude refused the request"}
+	}
+
+	var text strings.Builder
+	var types []string
+	hasText := false
+	for _, block := range message.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+			hasText = true
+			continue
+		}
+		types = append(types, block.Type)
+	}
+	if hasText {
+		return text.String(), nil
+	}
+	if len(types) == 0 {
+		return "", &EmptyResponseError{StopReason: stopReason, Detail: "no content blocks"}
+	}
+	return "", &EmptyResponseError{StopReason: stopReason, Detail: fmt.Sprintf("got %s blocks", strings.Join(types, ", "))}
 }
 
 // Reviewer orchestrates the code review process.
 type Reviewer struct {
-	githubClient   *github.Client
+	githubClient   Platform
 	configLoader   *config.Loader
 	storage        storage.Storage
 	claudeAPIKey   string // Default/fallback API key
@@ -218,12 +474,25 @@ type Reviewer struct {
 	modelFunc      ModelFunc
 	model          string
 	botName        string
+	instructions   string // Reviewer-wide instructions, ahead of each repo's own
 	logger         *slog.Logger
 	contextFetcher *ContextFetcher
+	limiter        *installationLimiter   // nil means no per-installation limit
+	replyDebouncer ReplyDebouncer         // nil means replies are sent immediately
+	maxChunks      int64                  // Chunks of a review sent to Claude at once
+	claudeOptions  []option.RequestOption // Extra options for every Claude request
+	claudeTimeout  time.Duration          // Limit on each Claude API call
+
+	maxChunkPromptSize int // Bound on a chunk's system prompt, context, and diff, in bytes
+
+	artifacts ArtifactSink   // nil means reviews aren't archived
+	archives  sync.WaitGroup // Artifact writes still running, for Wait
+
+	trackCommentOutcomes bool
 }
 
 // NewReviewer creates a new Reviewer instance.
-func NewReviewer(githubClient *github.Client, claudeAPIKey string, store storage.Storage, logger *slog.Logger) *Reviewer {
+func NewReviewer(githubClient Platform, claudeAPIKey string, store storage.Storage, logger *slog.Logger) *Reviewer {
 	return &Reviewer{
 		githubClient:   githubClient,
 		configLoader:   config.NewLoader(githubClient),
@@ -232,6 +501,12 @@ func NewReviewer(githubClient *github.Client, claudeAPIKey string, store storage
 		model:          DefaultModel,
 		logger:         logger,
 		contextFetcher: NewContextFetcher(githubClient, logger),
+		limiter:        newInstallationLimiter(DefaultMaxConcurrentReviewsPerInstallation),
+		replyDebouncer: NewMemoryReplyDebouncer(DefaultReplyDebounceWindow),
+		maxChunks:      DefaultMaxConcurrentChunks,
+		claudeTimeout:  ClaudeAPITimeout,
+
+		maxChunkPromptSize: MaxChunkPromptSize,
 	}
 }
 
@@ -240,6 +515,90 @@ func (r *Reviewer) SetBotName(name string) {
 	r.botName = name
 }
 
+// botNameOrDefault returns the configured bot name, falling back to "shipitai".
+func (r *Reviewer) botNameOrDefault() string {
+	if r.botName == "" {
+		return "shipitai"
+	}
+	return r.botName
+}
+
+// SetInstructions sets review instructions applied to every repository, ahead of
+// the repository's own. Useful for giving a reviewer a focus, e.g. security.
+func (r *Reviewer) SetInstructions(instructions string) {
+	r.instructions = instructions
+}
+
+// instructionsFor returns the reviewer-wide instructions followed by the repository's.
+func (r *Reviewer) instructionsFor(cfg *config.Config) string {
+	repoInstructions := reviewInstructions(cfg)
+	if r.instructions == "" {
+		return repoInstructions
+	}
+	if repoInstructions == "" {
+		return r.instructions
+	}
+	return r.instructions + "\n\n" + repoInstructions
+}
+
+// SetMaxConcurrentReviewsPerInstallation sets how many reviews a single installation
+// can run at once. Additional reviews wait for a slot. A limit of 0 or less disables it.
+func (r *Reviewer) SetMaxConcurrentReviewsPerInstallation(limit int) {
+	if limit <= 0 {
+		r.limiter = nil
+		return
+	}
+	r.limiter = newInstallationLimiter(limit)
+}
+
+// SetMaxConcurrentChunks sets how many chunks of a large review are sent to Claude
+// at once (default 5). Lower it on low Anthropic rate tiers to avoid 429s; raise it
+// for faster reviews on high ones. Values below 1 are ignored.
+func (r *Reviewer) SetMaxConcurrentChunks(n int) {
+	if n >= 1 {
+		r.maxChunks = int64(n)
+	}
+}
+
+// SetClaudeOptions sets extra request options applied to every Claude call, after
+// the defaults, e.g. option.WithHeader("anthropic-beta", ...) to opt into beta
+// features. Meant for self-hosters experimenting with new API capabilities.
+func (r *Reviewer) SetClaudeOptions(opts ...option.RequestOption) {
+	r.claudeOptions = opts
+}
+
+// SetContextFetchFraction sets the share of a review's remaining deadline that
+// rich context fetching may use (default 0.25).
+func (r *Reviewer) SetContextFetchFraction(fraction float64) {
+	r.contextFetcher.SetTimeoutFraction(fraction)
+}
+
+// SetContextFetchTimeout sets a fixed timeout for rich context fetching in place
+// of the share of the review deadline. Values of 0 or less are ignored.
+func (r *Reviewer) SetContextFetchTimeout(timeout time.Duration) {
+	r.contextFetcher.SetTimeout(timeout)
+}
+
+// SetClaudeTimeout sets how long to wait for each Claude API response (default
+// ClaudeAPITimeout). Values of 0 or less are ignored.
+func (r *Reviewer) SetClaudeTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		r.claudeTimeout = timeout
+	}
+}
+
+// SetArtifactSink sets where reviews of repositories with review_artifacts enabled
+// are archived. A nil sink disables archiving.
+func (r *Reviewer) SetArtifactSink(sink ArtifactSink) {
+	r.artifacts = sink
+}
+
+// Wait blocks until the review artifacts being written in the background
+// finish, so shutting down doesn't lose archives already linked from reviews.
+func (r *Reviewer) Wait() {
+	r.archives.Wait()
+}
+
 // SetAPIKeyFunc sets a function to resolve API keys per installation.
 func (r *Reviewer) SetAPIKeyFunc(fn APIKeyFunc) {
 	r.apiKeyFunc = fn
@@ -297,72 +656,224 @@ type ReviewInput struct {
 	PRBody         string
 	HeadSHA        string
 	DefaultBranch  string
-
+	PRCreatedAt    time.Time
+	Labels         []string
+	Draft          bool
+	Merged         bool // The PR was already merged, e.g. when reopened after a revert
+
+	// Requested is true when the review was explicitly requested (e.g. "@shipitai review")
+	// rather than triggered by a pull request event. Requested reviews bypass
+	// automatic-review gates such as the on-request trigger mode and max_pr_age.
+	Requested bool
+
+	// DryRun runs the review without writing anything: no review, check run, thread
+	// resolution, notice comment, or stored context. The result carries the comments.
+	DryRun bool
 }
 
 // ReviewResult contains the result of a review.
 type ReviewResult struct {
-	ReviewID     int64
-	ReviewURL    string
-	Summary      string
-	CommentCount int
-	Approval     string
-	Usage        *storage.TokenUsage
+	ReviewID     int64               `json:"review_id,omitempty"`
+	ReviewURL    string              `json:"review_url,omitempty"`
+	Summary      string              `json:"summary,omitempty"`
+	CommentCount int                 `json:"comment_count"`
+	Approval     string              `json:"approval,omitempty"`
+	Usage        *storage.TokenUsage `json:"usage,omitempty"`
+
+	// Extras holds additional top-level fields Claude returned beyond the standard
+	// response (see ClaudeResponse.Extras). Nil when there were none.
+	Extras map[string]json.RawMessage `json:"extras,omitempty"`
+
+	// SkipReason is set when the review was intentionally skipped (see SkipReason* constants).
+	// All other fields are empty in that case.
+	SkipReason string `json:"skip_reason,omitempty"`
+
+	// DryRun is true when nothing was posted (see ReviewInput.DryRun). ReviewID
+	// and ReviewURL are empty; Comments holds what would have been posted.
+	DryRun   bool            `json:"dry_run,omitempty"`
+	Comments []ClaudeComment `json:"comments,omitempty"`
+
+	// FailedFiles lists files that could not be reviewed (see config partial_chunk_reviews).
+	FailedFiles []string `json:"failed_files,omitempty"`
 }
 
 // ClaudeAPIResponse contains the raw text response and token usage from a Claude API call.
 type ClaudeAPIResponse struct {
 	Text  string
 	Usage *storage.TokenUsage
+
+	// StopReason is why Claude stopped generating; "max_tokens" means the response
+	// was cut off at MaxTokens, the output limit the request was sent with.
+	StopReason string
+	MaxTokens  int64
 }
 
 // Review performs a code review on a pull request.
 // It automatically detects whether this is the first review or a subsequent one
 // and handles them appropriately.
-func (r *Reviewer) Review(ctx context.Context, input *ReviewInput) (*ReviewResult, error) {
+func (r *Reviewer) Review(ctx context.Context, input *ReviewInput) (result *ReviewResult, err error) {
 	r.logger.Info("starting review",
 		"owner", input.Owner,
 		"repo", input.Repo,
 		"pr", input.PRNumber,
 	)
 
+	// A PR muted with the ignore command is never reviewed
+	if r.isPRMuted(ctx, input) {
+		r.logger.Info("review skipped: PR is muted")
+		return nil, nil
+	}
+
+	metrics.ReviewStarted(input.InstallationID)
+	defer func() {
+		if err != nil {
+			metrics.ReviewFailed(input.InstallationID)
+			return
+		}
+		var comments int
+		if result != nil {
+			if !result.DryRun {
+				comments = result.CommentCount
+			}
+			if result.Usage != nil {
+				metrics.TokensUsed(input.InstallationID, result.Usage.InputTokens, result.Usage.OutputTokens)
+			}
+		}
+		metrics.ReviewCompleted(input.InstallationID, Outcome(result, nil), comments)
+	}()
+
+	// Wait for a per-installation review slot
+	if r.limiter != nil {
+		release, err := r.limiter.acquire(ctx, input.InstallationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting for review slot: %w", err)
+		}
+		defer release()
+	}
+
+	if r.isInstallationSuspended(ctx, input.InstallationID) {
+		r.logger.Info("review skipped: installation is suspended", "installation_id", input.InstallationID)
+		return &ReviewResult{SkipReason: SkipReasonInstallationSuspended}, nil
+	}
+
+	if !input.Requested && input.Merged {
+		r.logger.Info("review skipped: pull request is already merged")
+		return &ReviewResult{SkipReason: SkipReasonAlreadyMerged}, nil
+	}
+
 	// Load repo config
-	cfg, err := r.configLoader.Load(ctx, input.InstallationID, input.Owner, input.Repo, input.DefaultBranch)
+	cfg, err := r.loadConfig(ctx, input)
 	if err != nil {
-		var parseErr *config.ConfigParseError
-		if errors.As(err, &parseErr) {
-			// Config file exists but has invalid content - this is a user error that should be surfaced
-			r.logger.Error("invalid config file, cannot proceed with review",
-				"path", parseErr.Path,
-				"error", parseErr.Err,
-			)
-			return nil, fmt.Errorf("invalid config file %s: %w", parseErr.Path, parseErr.Err)
-		}
-		// Other errors (network issues, etc.) - use defaults and continue
-		r.logger.Warn("failed to load config, using defaults", "error", err)
-		cfg = config.DefaultConfig()
+		return nil, err
 	}
 
-	if !cfg.ShouldReviewOnEvent() {
+	shouldReview := cfg.ShouldReviewOnEvent()
+	if input.Requested {
+		// Explicit requests also run in on-request mode, but never when disabled
+		shouldReview = cfg.Enabled
+	}
+	if !shouldReview {
 		r.logger.Info("review skipped due to config",
 			"enabled", cfg.Enabled,
 			"trigger", cfg.Trigger,
+			"requested", input.Requested,
 		)
 		return nil, nil
 	}
 
-	// Fetch diff
-	diff, err := r.githubClient.FetchDiff(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	r.welcomeInstallation(ctx, input, cfg)
+
+	if !input.Requested && input.Draft && cfg.ShouldSkipDrafts() {
+		r.logger.Info("review skipped: pull request is a draft")
+		return &ReviewResult{SkipReason: SkipReasonDraft}, nil
+	}
+
+	if !input.Requested && cfg.IsStalePR(input.PRCreatedAt, time.Now()) {
+		r.logger.Info("review skipped: pull request exceeds max_pr_age",
+			"created_at", input.PRCreatedAt,
+			"max_pr_age", cfg.MaxPRAge,
+		)
+		return &ReviewResult{SkipReason: SkipReasonStalePR}, nil
+	}
+
+	if !input.Requested && cfg.LightReviewReverts && isRevertPR(input.PRTitle, input.PRBody) {
+		r.logger.Info("review skipped: pull request appears to be a revert", "title", input.PRTitle)
+		if !input.DryRun {
+			r.postNoticeOnce(ctx, input, SkipReasonRevert, BuildRevertNoticeMessage(r.botNameOrDefault()))
+		}
+		return &ReviewResult{SkipReason: SkipReasonRevert}, nil
+	}
+
+	// Fetch diff (the merge result when configured)
+	diff, err := retryWithBackoff(ctx, r.logger, "fetchDiff", func() (string, error) {
+		return r.fetchReviewDiff(ctx, input, cfg)
+	})
+	if github.IsNotFound(err) {
+		r.logger.Info("PR is no longer accessible, skipping review", "error", err)
+		return &ReviewResult{SkipReason: SkipReasonPRInaccessible}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch diff: %w", err)
 	}
 
 	r.logger.Info("fetched diff", "size", len(diff))
 
-	// Filter diff based on exclude patterns
-	if len(cfg.Exclude) > 0 {
-		diff = filterDiff(diff, cfg)
-		r.logger.Info("filtered diff", "size", len(diff), "exclude_patterns", cfg.Exclude)
+	// Drop excluded, binary, and oversized files before anything reaches Claude
+	if filtered := filterDiff(diff, cfg); len(filtered) != len(diff) {
+		diff = filtered
+		r.logger.Info("filtered diff", "size", len(diff), "include_patterns", cfg.Include, "exclude_patterns", cfg.Exclude, "max_file_diff_bytes", cfg.MaxFileDiffBytes)
+	}
+
+	// A reopened PR whose changes already landed in base, or one changing only
+	// filtered files, has nothing left to review
+	if strings.TrimSpace(diff) == "" {
+		r.logger.Info("review skipped: pull request has an empty diff")
+		return &ReviewResult{SkipReason: SkipReasonEmptyDiff}, nil
+	}
+
+	// Keep only files owned by the configured owners, read from the default branch like the config
+	if len(cfg.OnlyOwnedBy) > 0 {
+		rules, err := r.fetchCodeOwners(ctx, input, input.DefaultBranch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch CODEOWNERS: %w", err)
+		}
+		if rules == nil {
+			r.logger.Warn("only_owned_by is set but the repository has no CODEOWNERS file")
+		}
+		diff = filterDiffToOwners(diff, rules, cfg.OnlyOwnedBy)
+		r.logger.Info("filtered diff to owned files", "size", len(diff), "only_owned_by", cfg.OnlyOwnedBy)
+		if diff == "" {
+			return &ReviewResult{SkipReason: SkipReasonNoOwnedFiles}, nil
+		}
+	}
+
+	diffInfo := ParseDiffInfo(diff)
+
+	if !input.Requested && cfg.OnlyTouchesSkipFiles(changedFiles(diff)) {
+		r.logger.Info("review skipped: pull request only changes skip_if_only_files files",
+			"skip_if_only_files", cfg.SkipIfOnlyFiles,
+		)
+		return &ReviewResult{SkipReason: SkipReasonOnlySkippedFiles}, nil
+	}
+
+	if !input.Requested && cfg.IsBelowMinChangedLines(diffInfo.Additions, diffInfo.Deletions) {
+		r.logger.Info("review skipped: pull request is below min_changed_lines",
+			"changed_lines", diffInfo.Additions+diffInfo.Deletions,
+			"min_changed_lines", cfg.MinChangedLines,
+		)
+		return &ReviewResult{SkipReason: SkipReasonTooSmall}, nil
+	}
+
+	if !input.Requested && cfg.IsMostlyDeletions(diffInfo.Additions, diffInfo.Deletions) {
+		r.logger.Info("review skipped: pull request is mostly deletions",
+			"additions", diffInfo.Additions,
+			"deletions", diffInfo.Deletions,
+			"skip_deletion_ratio", cfg.SkipDeletionRatio,
+		)
+		if !input.DryRun {
+			r.postNoticeOnce(ctx, input, SkipReasonMostlyDeletions, BuildDeletionNoticeMessage(r.botNameOrDefault(), diffInfo.Deletions))
+		}
+		return &ReviewResult{SkipReason: SkipReasonMostlyDeletions}, nil
 	}
 
 	// Get the appropriate API key
@@ -383,9 +894,8 @@ func (r *Reviewer) Review(ctx context.Context, input *ReviewInput) (*ReviewResul
 	var firstReview *storage.ReviewContext
 	if r.storage != nil {
 		firstReview, err = r.storage.GetFirstReviewForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
-		if err != nil {
-			r.logger.Warn("failed to check for existing reviews, treating as first review", "error", err)
-			// Continue as first review
+		if err != nil && r.skipWithoutStorage(ctx, input, cfg, err) {
+			return &ReviewResult{SkipReason: SkipReasonStorageUnavailable}, nil
 		}
 	}
 
@@ -399,6 +909,78 @@ func (r *Reviewer) Review(ctx context.Context, input *ReviewInput) (*ReviewResul
 	return r.reviewFirst(ctx, input, cfg, diff, apiKey, model)
 }
 
+// skipWithoutStorage decides whether to skip a review after reading the stored
+// review history failed with err. require_storage always skips; otherwise the PR's
+// reviews are checked for one from the bot, since reviewing it again as a first
+// review would post a duplicate.
+func (r *Reviewer) skipWithoutStorage(ctx context.Context, input *ReviewInput, cfg *config.Config, err error) bool {
+	if cfg.RequireStorage {
+		r.logger.Error("failed to check for existing reviews, skipping review (require_storage)", "error", err)
+		return true
+	}
+
+	reviews, listErr := r.githubClient.ListPRReviews(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if listErr != nil {
+		r.logger.Warn("failed to check for existing reviews, treating as first review", "error", err, "list_error", listErr)
+		return false
+	}
+	for _, review := range reviews {
+		if isBotLogin(review.User, r.botNameOrDefault()) {
+			r.logger.Warn("failed to check for existing reviews and the PR already has a bot review, skipping review",
+				"error", err, "review_id", review.ID)
+			return true
+		}
+	}
+
+	r.logger.Warn("failed to check for existing reviews, treating as first review", "error", err)
+	return false
+}
+
+// isInstallationSuspended reports whether the installation is stored as suspended.
+// Unknown installations, and lookup failures, count as active.
+func (r *Reviewer) isInstallationSuspended(ctx context.Context, installationID int64) bool {
+	if r.storage == nil {
+		return false
+	}
+	install, err := r.storage.GetInstallation(ctx, installationID)
+	if err != nil {
+		r.logger.Warn("failed to check installation status", "error", err)
+		return false
+	}
+	return install != nil && !install.Active
+}
+
+// changedFiles returns the paths of the files a diff touches.
+func changedFiles(diff string) []string {
+	files := SplitDiffByFile(diff)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// loadConfig loads the repo config for a review. An invalid config file is an
+// error; any other load failure falls back to the defaults.
+func (r *Reviewer) loadConfig(ctx context.Context, input *ReviewInput) (*config.Config, error) {
+	cfg, err := r.configLoader.Load(ctx, input.InstallationID, input.Owner, input.Repo, input.DefaultBranch)
+	if err != nil {
+		var parseErr *config.ConfigParseError
+		if errors.As(err, &parseErr) {
+			// Config file exists but has invalid content - this is a user error that should be surfaced
+			r.logger.Error("invalid config file, cannot proceed with review",
+				"path", parseErr.Path,
+				"error", parseErr.Err,
+			)
+			return nil, fmt.Errorf("invalid config file %s: %w", parseErr.Path, parseErr.Err)
+		}
+		// Other errors (network issues, etc.) - use defaults and continue
+		r.logger.Warn("failed to load config, using defaults", "error", err)
+		return config.DefaultConfig(), nil
+	}
+	return cfg, nil
+}
+
 // reviewFirst handles the first review of a PR (creates new review with inline comments).
 func (r *Reviewer) reviewFirst(ctx context.Context, input *ReviewInput, cfg *config.Config, diff, apiKey, model string) (*ReviewResult, error) {
 	r.logger.Info("performing first review")
@@ -416,6 +998,7 @@ func (r *Reviewer) reviewFirst(ctx context.Context, input *ReviewInput, cfg *con
 			Repo:           input.Repo,
 			HeadRef:        input.HeadSHA,
 			ChangedFiles:   changedFiles,
+			ChangedLines:   ParseDiffLines(diff),
 			Config:         cfg,
 		}
 		reviewCtx = r.contextFetcher.FetchContext(ctx, contextInput)
@@ -427,30 +1010,23 @@ func (r *Reviewer) reviewFirst(ctx context.Context, input *ReviewInput, cfg *con
 		)
 	}
 
-	// Check if diff needs chunking
-	var parsed *ClaudeResponse
-	var totalUsage *storage.TokenUsage
-	var err error
-
-	if len(diff) > ChunkThreshold {
-		r.logger.Info("diff exceeds chunk threshold, using chunked review",
-			"diff_size", len(diff),
-			"threshold", ChunkThreshold,
-		)
-		parsed, totalUsage, err = r.reviewChunked(ctx, apiKey, model, input, diff, cfg)
-		if err != nil {
-			return nil, fmt.Errorf("failed chunked review: %w", err)
-		}
-	} else {
-		// Standard single-call review with context (retries once on parse failure)
-		var claudeResp *ClaudeAPIResponse
-		parsed, claudeResp, err = callAndParse(r.logger, "reviewFirst", func() (*ClaudeAPIResponse, error) {
-			return r.callClaudeWithContext(ctx, apiKey, model, input.PRTitle, input.PRBody, diff, cfg.ClaudeMD, cfg.Instructions, reviewCtx)
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to get Claude review: %w", err)
+	// Run independent analyses concurrently; the Claude review comes first so its
+	// summary leads the merged result
+	analyzers := []analyzer{
+		analyzerFunc{label: "claude_review", fn: func(ctx context.Context) (*ClaudeResponse, *storage.TokenUsage, error) {
+			return r.claudeReview(ctx, input, cfg, diff, apiKey, model, reviewCtx)
+		}},
+	}
+	if cfg.DescriptionCheck {
+		analyzers = append(analyzers, descriptionCheck(input.PRBody))
+	}
+	parsed, totalUsage, err := runAnalyzers(ctx, analyzers)
+	if err != nil {
+		var emptyErr *EmptyResponseError
+		if errors.As(err, &emptyErr) {
+			r.reportEmptyResponse(ctx, input, emptyErr)
 		}
-		totalUsage = claudeResp.Usage
+		return nil, err
 	}
 
 	r.logger.Info("parsed Claude response",
@@ -460,8 +1036,46 @@ func (r *Reviewer) reviewFirst(ctx context.Context, input *ReviewInput, cfg *con
 	)
 
 	// Validate and filter comments against diff lines
-	diffLines := ParseDiffLines(diff)
-	parsed.Comments, _ = FilterValidComments(parsed.Comments, diffLines, r.logger)
+	diffLines := r.headDiffLines(ctx, input, cfg, ParseDiffLines(diff))
+	var unplaced []ClaudeComment
+	parsed.Comments, unplaced, _ = snapComments(parsed.Comments, diffLines, cfg.CommentSnapWindow(), cfg.CaseInsensitivePaths, r.logger)
+	parsed.Unplaced = append(parsed.Unplaced, unplaced...)
+	parsed.Comments, _ = FilterBySeverity(parsed.Comments, cfg.SeverityScale, cfg.MinSeverity, r.logger)
+	parsed.Comments, _ = FilterByConfidence(parsed.Comments, cfg.MinConfidence, r.logger)
+	if cfg.RespectTODOs {
+		parsed.Comments = filterAcknowledgedComments(parsed.Comments, ParseAddedLines(diff), r.logger)
+	}
+	parsed.Comments = sanitizeComments(parsed.Comments)
+	if cfg.ExplainConfidence {
+		parsed.Comments = appendConfidence(parsed.Comments)
+	}
+
+	// A request_changes_at threshold decides approval from the posted findings'
+	// severities, as subsequent reviews do
+	if cfg.RequestChangesAt != "" {
+		parsed.Approval = DetermineApprovalFromSeverity(parsed.Comments, cfg.SeverityScale, cfg.RequestChangesAt)
+	}
+
+	// Don't block the PR without a visible reason when every finding was on a line
+	// outside the diff: comment instead, listing the findings in the body
+	if parsed.Approval == config.ApprovalRequestChanges && len(parsed.Comments) == 0 && len(parsed.Unplaced) > 0 {
+		r.logger.Warn("requested changes but no comment is on a line in the diff, posting as a comment",
+			"unplaced_comments", len(parsed.Unplaced))
+		parsed.Approval = config.ApprovalComment
+		parsed.Summary = strings.TrimLeft(parsed.Summary+"\n\n"+formatUnplacedFindings(sanitizeComments(parsed.Unplaced)), "\n")
+	}
+
+	if cfg.ShowDiffStats {
+		parsed.Summary = diffStatsLine(ParseDiffInfo(diff)) + "\n\n" + parsed.Summary
+	}
+	if cfg.SummarizeSuggestions {
+		if line := suggestionSummaryLine(parsed.Comments); line != "" {
+			parsed.Summary += "\n\n" + line
+		}
+	}
+	if len(parsed.FailedFiles) > 0 {
+		parsed.Summary += "\n\n" + BuildFailedFilesNote(r.botNameOrDefault(), parsed.FailedFiles)
+	}
 
 	// Convert to GitHub review
 	reviewReq, err := ToGitHubReview(parsed, input.HeadSHA)
@@ -469,26 +1083,89 @@ func (r *Reviewer) reviewFirst(ctx context.Context, input *ReviewInput, cfg *con
 		return nil, fmt.Errorf("failed to convert to GitHub review: %w", err)
 	}
 
+	if input.DryRun {
+		r.logger.Info("dry run, not posting review", "comments", len(parsed.Comments))
+		return &ReviewResult{
+			Summary:      parsed.Summary,
+			CommentCount: len(parsed.Comments),
+			Approval:     parsed.Approval,
+			Extras:       parsed.Extras,
+			Usage:        totalUsage,
+			DryRun:       true,
+			Comments:     parsed.Comments,
+			FailedFiles:  parsed.FailedFiles,
+		}, nil
+	}
+
+	// Post findings as Check Run annotations instead of inline comments if configured
+	annotated := r.publishAnnotations(ctx, input, cfg, parsed.Summary, parsed.Approval, parsed.Comments)
+	if annotated {
+		reviewReq.Comments = nil
+		reviewReq.Body += "\n\n" + annotationsNote
+	}
+
+	// Post the findings checklist first, so inline comments are only dropped for it
+	// once it's on the PR
+	prior := &storage.ReviewContext{}
+	if cfg.UsesTriageComment() || cfg.SummaryAsComment {
+		prior = r.storedFirstReview(ctx, input)
+	}
+	var triageCommentID int64
+	if cfg.UsesTriageComment() {
+		var posted bool
+		triageCommentID, posted = r.publishPRComment(ctx, input, "triage", prior.TriageCommentID, buildTriageComment(triageItemsFromComments(parsed.Comments, cfg.SeverityScale), cfg.SeverityScale))
+		if posted && cfg.TriageComment == config.TriageCommentInstead && !annotated {
+			reviewReq.Comments = nil
+			reviewReq.Body += "\n\n" + triageNote
+		}
+	}
+
+	// Link the archived copy of the review, which is written once it's posted
+	archiveKey, archiveLinks := r.artifactLinks(input, cfg)
+	if archiveLinks != "" {
+		reviewReq.Body += "\n\n" + archiveLinks
+	}
+
+	// Record what produced the review in a hidden marker; the stored body omits it
+	storedBody := reviewReq.Body
+	r.holdApprovalForChecks(ctx, input, cfg, reviewReq)
+	reviewReq.Body += buildReproMarker(model, diffHash(diff), totalUsage)
+
 	// Post review to GitHub
-	review, err := r.githubClient.CreateReview(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, reviewReq)
+	review, err := r.postReview(ctx, input, reviewReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post review: %w", err)
 	}
 
 	r.logger.Info("posted review", "review_id", review.ID, "url", review.HTMLURL)
+	r.archiveReview(archiveKey, input, parsed)
+
+	// Report the outcome as a check run too (the annotations check run already does)
+	if !annotated {
+		r.publishCheckRun(ctx, input, cfg, parsed.Summary, parsed.Approval, parsed.Comments)
+	}
+
+	var summaryCommentID int64
+	if cfg.SummaryAsComment {
+		summaryCommentID, _ = r.publishPRComment(ctx, input, "summary", prior.SummaryCommentID, BuildSummaryComment(parsed.Summary, parsed.Comments, cfg.SeverityScale))
+	}
 
 	// Store review context (excluding raw Claude response to avoid retaining customer code)
 	if r.storage != nil {
 		storeCtx := &storage.ReviewContext{
-			InstallationID: input.InstallationID,
-			Owner:          input.Owner,
-			Repo:           input.Repo,
-			PRNumber:       input.PRNumber,
-			ReviewID:       review.ID,
-			ReviewBody:     parsed.Summary,
-			Comments:       toStorageComments(parsed.Comments),
-			Usage:          totalUsage,
-			UsageType:      "review",
+			InstallationID:   input.InstallationID,
+			Owner:            input.Owner,
+			Repo:             input.Repo,
+			PRNumber:         input.PRNumber,
+			ReviewID:         review.ID,
+			ReviewBody:       storedBody,
+			Comments:         toStorageComments(parsed.Comments),
+			Usage:            totalUsage,
+			UsageType:        "review",
+			FailedFiles:      parsed.FailedFiles,
+			SummaryCommentID: summaryCommentID,
+			TriageCommentID:  triageCommentID,
+			HeadSHA:          input.HeadSHA,
 		}
 
 		if err := r.storage.StoreReview(ctx, storeCtx); err != nil {
@@ -504,10 +1181,52 @@ func (r *Reviewer) reviewFirst(ctx context.Context, input *ReviewInput, cfg *con
 		Summary:      parsed.Summary,
 		CommentCount: len(parsed.Comments),
 		Approval:     parsed.Approval,
+		Extras:       parsed.Extras,
 		Usage:        totalUsage,
+		Comments:     parsed.Comments,
+		FailedFiles:  parsed.FailedFiles,
 	}, nil
 }
 
+// reportEmptyResponse tells the PR's author that no review could be generated
+// because Claude answered without one, once per head commit. API errors aren't
+// reported, since they are usually transient and would otherwise leave a
+// comment on every failure.
+func (r *Reviewer) reportEmptyResponse(ctx context.Context, input *ReviewInput, emptyErr *EmptyResponseError) {
+	r.logger.Warn("Claude returned no review", "stop_reason", emptyErr.StopReason, "detail", emptyErr.Detail)
+	if input.DryRun {
+		return
+	}
+	r.postNoticeOnce(ctx, input, "empty_response:"+input.HeadSHA, BuildEmptyResponseMessage(emptyErr.StopReason))
+}
+
+// claudeReview runs the Claude code review, chunking large diffs.
+func (r *Reviewer) claudeReview(ctx context.Context, input *ReviewInput, cfg *config.Config, diff, apiKey, model string, reviewCtx *ReviewContext) (*ClaudeResponse, *storage.TokenUsage, error) {
+	if len(diff) > ChunkThreshold {
+		r.logger.Info("diff exceeds chunk threshold, using chunked review",
+			"diff_size", len(diff),
+			"threshold", ChunkThreshold,
+		)
+		parsed, usage, err := r.reviewChunked(ctx, apiKey, model, input, diff, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed chunked review: %w", err)
+		}
+		return parsed, usage, nil
+	}
+
+	// Standard single-call review with context (one corrective call on parse failure)
+	maxTokens := maxOutputTokens(cfg, model)
+	parsed, claudeResp, err := callAndParse(r.logger, "reviewFirst", cfg.SeverityScale, func() (*ClaudeAPIResponse, error) {
+		return r.callClaudeWithContext(ctx, apiKey, model, input.PRTitle, prDescription(input, cfg), diff, cfg.ClaudeMD, r.instructionsFor(cfg), cfg.SystemPromptPrefix, cfg.SystemPromptSuffix, maxTokens, cfg.SeverityScale, reviewCtx)
+	}, func(invalid string, parseErr error) (*ClaudeAPIResponse, error) {
+		return r.repairJSON(ctx, apiKey, model, maxTokens, reviewResponseSchema(cfg.SeverityScale), invalid, parseErr)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get Claude review: %w", err)
+	}
+	return parsed, claudeResp.Usage, nil
+}
+
 // reviewSubsequent handles subsequent reviews by updating the original review body
 // and posting new comments separately.
 func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, firstReview *storage.ReviewContext, cfg *config.Config, diff, apiKey, model string) (*ReviewResult, error) {
@@ -515,22 +1234,45 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 		"first_review_id", firstReview.ReviewID,
 	)
 
-	// Fetch existing review threads with resolution status via GraphQL
-	threads, err := r.githubClient.FetchPRReviewThreads(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
-	if err != nil {
-		r.logger.Warn("failed to fetch review threads, falling back to first review behavior", "error", err)
-		return r.reviewFirst(ctx, input, cfg, diff, apiKey, model)
+	// Review the final head of the push batch, never an intermediate commit
+	input = r.withLatestHead(ctx, input)
+
+	// Fetch existing review threads with resolution status via GraphQL. Without
+	// earlier bot comments there's nothing to deduplicate against, so skip the call
+	// rather than risk falling back to a first review on a GraphQL failure
+	var threads []github.ReviewThread
+	var existingComments []ExistingComment
+	if r.hasPriorComments(ctx, input, firstReview) {
+		var err error
+		threads, err = r.githubClient.FetchPRReviewThreads(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+		if err != nil {
+			// Deduplicate against the comments stored for earlier reviews instead
+			existingComments = r.storedExistingComments(ctx, input)
+			if len(existingComments) == 0 {
+				r.logger.Warn("failed to fetch review threads, falling back to first review behavior", "error", err)
+				return r.reviewFirst(ctx, input, cfg, diff, apiKey, model)
+			}
+			r.logger.Warn("failed to fetch review threads, using stored comments from earlier reviews",
+				"comment_count", len(existingComments), "error", err)
+		} else {
+			// Record what happened to earlier comments before this review adds more
+			r.reconcileCommentOutcomes(ctx, input, threads)
+			existingComments = convertThreadsToExistingComments(threads)
+		}
+	} else {
+		r.logger.Info("no earlier review comments, skipping review thread fetch")
 	}
 
-	// Convert threads to ExistingComment format for the prompt
-	existingComments := convertThreadsToExistingComments(threads)
 	r.logger.Info("fetched existing comments",
 		"thread_count", len(threads),
 		"comment_count", len(existingComments),
 	)
 
+	// Review only the commits pushed since the last review when they're known
+	reviewDiff, incremental := r.incrementalDiff(ctx, input, cfg, diff)
+
 	// Extract changed file paths from the diff
-	diffInfo := ParseDiffInfo(diff)
+	diffInfo := ParseDiffInfo(reviewDiff)
 	changedFiles := diffInfo.Files
 
 	// Fetch rich context
@@ -542,25 +1284,43 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 			Repo:           input.Repo,
 			HeadRef:        input.HeadSHA,
 			ChangedFiles:   changedFiles,
+			ChangedLines:   ParseDiffLines(reviewDiff),
 			Config:         cfg,
 		}
 		reviewCtx = r.contextFetcher.FetchContext(ctx, contextInput)
 	}
 
-	// Call Claude with subsequent review prompt (retries once on parse failure)
-	parsed, claudeResp, err := callAndParse(r.logger, "reviewSubsequent", func() (*ClaudeAPIResponse, error) {
-		return r.callClaudeSubsequent(ctx, apiKey, model, input, diff, existingComments, cfg, reviewCtx)
+	// Call Claude with subsequent review prompt (one corrective call on parse failure)
+	parsed, claudeResp, err := callAndParse(r.logger, "reviewSubsequent", cfg.SeverityScale, func() (*ClaudeAPIResponse, error) {
+		return r.callClaudeSubsequent(ctx, apiKey, model, input, reviewDiff, existingComments, cfg, reviewCtx)
+	}, func(invalid string, parseErr error) (*ClaudeAPIResponse, error) {
+		return r.repairJSON(ctx, apiKey, model, maxOutputTokens(cfg, model), subsequentReviewResponseSchema(cfg.SeverityScale), invalid, parseErr)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Claude subsequent review: %w", err)
 	}
 
-	// Validate and filter comments against diff lines
-	diffLines := ParseDiffLines(diff)
-	parsed.Comments, _ = FilterValidComments(parsed.Comments, diffLines, r.logger)
+	// Validate and filter comments against diff lines. Comments on an incremental
+	// diff must also land on the PR diff, the only lines GitHub accepts them on
+	diffLines := r.headDiffLines(ctx, input, cfg, ParseDiffLines(diff))
+	if incremental {
+		diffLines = intersectDiffLines(ParseDiffLines(reviewDiff), diffLines)
+	}
+	parsed.Comments, _, _ = SnapValidComments(parsed.Comments, diffLines, cfg.CommentSnapWindow(), cfg.CaseInsensitivePaths, r.logger)
+	parsed.Comments, _ = FilterBySeverity(parsed.Comments, cfg.SeverityScale, cfg.MinSeverity, r.logger)
+	parsed.Comments, _ = FilterByConfidence(parsed.Comments, cfg.MinConfidence, r.logger)
+	if cfg.RespectTODOs {
+		parsed.Comments = filterAcknowledgedComments(parsed.Comments, ParseAddedLines(reviewDiff), r.logger)
+	}
+	// Skip lines that commits pushed during the review have already changed
+	parsed.Comments = r.dropSupersededComments(ctx, input, parsed.Comments)
+	parsed.Comments = sanitizeComments(parsed.Comments)
+	if cfg.ExplainConfidence {
+		parsed.Comments = appendConfidence(parsed.Comments)
+	}
 
 	// Determine approval based on severity of valid comments (after filtering)
-	parsed.Approval = DetermineApprovalFromSeverity(parsed.Comments)
+	parsed.Approval = DetermineApprovalFromSeverity(parsed.Comments, cfg.SeverityScale, cfg.RequestChangesAt)
 
 	r.logger.Info("parsed subsequent review response",
 		"summary", parsed.Summary,
@@ -568,11 +1328,39 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 		"approval", parsed.Approval,
 	)
 
-	// Build the updated summary that appends to the original
-	newBody := buildConsolidatedSummary(firstReview.ReviewBody, parsed.Summary, input)
+	// Build the updated summary that appends to the original, prefixed with a verdict
+	// so authors can tell at a glance whether the new findings are blocking
+	updateSummary := parsed.Summary
+	if verdict := summaryVerdict(parsed.Comments, cfg.SeverityScale, cfg.RequestChangesAt); verdict != "" {
+		updateSummary = "_" + verdict + "_ " + updateSummary
+	}
+	if cfg.ShowDiffStats {
+		updateSummary = diffStatsLine(diffInfo) + "\n\n" + updateSummary
+	}
+	if cfg.SummarizeSuggestions {
+		if line := suggestionSummaryLine(parsed.Comments); line != "" {
+			updateSummary += "\n\n" + line
+		}
+	}
+	newBody := buildConsolidatedSummary(firstReview.ReviewBody, updateSummary, input)
+
+	if input.DryRun {
+		r.logger.Info("dry run, not posting subsequent review", "new_comments", len(parsed.Comments))
+		return &ReviewResult{
+			Summary:      updateSummary,
+			CommentCount: len(parsed.Comments),
+			Approval:     parsed.Approval,
+			Extras:       parsed.Extras,
+			Usage:        claudeResp.Usage,
+			DryRun:       true,
+			Comments:     parsed.Comments,
+			FailedFiles:  parsed.FailedFiles,
+		}, nil
+	}
 
-	// Update the original review's body
-	if err := r.githubClient.UpdateReviewBody(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, firstReview.ReviewID, newBody); err != nil {
+	// Update the original review's body, its hidden marker now describing this review
+	markedBody := newBody + buildReproMarker(model, diffHash(reviewDiff), claudeResp.Usage)
+	if err := r.githubClient.UpdateReviewBody(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, firstReview.ReviewID, markedBody); err != nil {
 		r.logger.Error("failed to update original review body", "error", err)
 		// Continue to post new comments even if summary update fails
 	} else {
@@ -586,7 +1374,7 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 			Path: c.Path,
 			Line: c.Line,
 			Side: "RIGHT",
-			Body: FormatCommentWithSeverity(c.Body, c.Severity),
+			Body: FormatSeverityBadge(c.Body, c.Severity, cfg.SeverityScale, cfg.SeverityBadge, cfg.SeverityEmoji),
 		}
 	}
 
@@ -598,15 +1386,53 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 		Comments: reviewComments,
 	}
 
+	// Post findings as Check Run annotations instead of inline comments if configured
+	annotated := r.publishAnnotations(ctx, input, cfg, updateSummary, parsed.Approval, parsed.Comments)
+	if annotated {
+		reviewReq.Comments = nil
+		reviewReq.Body = annotationsNote
+	}
+
+	// Update the findings checklist, ticking items whose threads were resolved
+	if cfg.UsesTriageComment() {
+		updated := r.updateTriageComment(ctx, input, cfg, firstReview, parsed.Comments, threads, parsed.ResolvedThreads)
+		if updated && cfg.TriageComment == config.TriageCommentInstead && !annotated {
+			reviewReq.Comments = nil
+			reviewReq.Body = triageNote
+		}
+	}
+
+	// Link the archived copy of the review, which is written once it's posted
+	archiveKey, archiveLinks := r.artifactLinks(input, cfg)
+	if archiveLinks != "" {
+		if reviewReq.Body != "" {
+			reviewReq.Body += "\n\n"
+		}
+		reviewReq.Body += archiveLinks
+	}
+
+	r.holdApprovalForChecks(ctx, input, cfg, reviewReq)
+
 	var newReviewID int64
 	var newReviewURL string
-	newReview, err := r.githubClient.CreateReview(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, reviewReq)
+	newReview, err := r.postReview(ctx, input, reviewReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post subsequent review: %w", err)
 	}
 	newReviewID = newReview.ID
 	newReviewURL = newReview.HTMLURL
-	r.logger.Info("posted subsequent review", "review_id", newReview.ID, "event", event, "comment_count", len(parsed.Comments))
+	r.logger.Info("posted subsequent review", "review_id", newReview.ID, "event", reviewReq.Event, "comment_count", len(parsed.Comments))
+	r.archiveReview(archiveKey, input, parsed)
+
+	// The check run is per commit, so report on the new head too
+	if !annotated {
+		r.publishCheckRun(ctx, input, cfg, updateSummary, parsed.Approval, parsed.Comments)
+	}
+
+	// Edit the existing summary comment rather than posting another
+	if cfg.SummaryAsComment {
+		r.updateSummaryComment(ctx, input, firstReview, BuildSummaryComment(newBody, parsed.Comments, cfg.SeverityScale))
+	}
 
 	// Resolve threads that Claude identified as addressed
 	if len(parsed.ResolvedThreads) > 0 {
@@ -625,6 +1451,7 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 			Comments:       toStorageComments(parsed.Comments),
 			Usage:          claudeResp.Usage,
 			UsageType:      "review",
+			HeadSHA:        input.HeadSHA,
 		}
 
 		if err := r.storage.StoreReview(ctx, storeCtx); err != nil {
@@ -639,40 +1466,37 @@ func (r *Reviewer) reviewSubsequent(ctx context.Context, input *ReviewInput, fir
 		Summary:      parsed.Summary,
 		CommentCount: len(parsed.Comments),
 		Approval:     parsed.Approval,
+		Extras:       parsed.Extras,
 		Usage:        claudeResp.Usage,
+		Comments:     parsed.Comments,
 	}, nil
 }
 
 // callClaudeSubsequent sends the subsequent review request to Claude.
 func (r *Reviewer) callClaudeSubsequent(ctx context.Context, apiKey, model string, input *ReviewInput, diff string, existingComments []ExistingComment, cfg *config.Config, reviewCtx *ReviewContext) (*ClaudeAPIResponse, error) {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+	client := r.newReviewClient(apiKey)
 
-	// Build prompt with existing comments context
-	prompt := BuildSubsequentReviewPrompt(input.PRTitle, input.PRBody, diff, existingComments)
+	maxTokens := maxOutputTokens(cfg, model)
 
-	// Add rich context if available
-	if reviewCtx != nil && !reviewCtx.IsEmpty() {
-		prompt = formatContext(reviewCtx) + "\n\n---\n\n" + prompt
-	}
+	// Build prompt with existing comments context
+	prompt := BuildSubsequentReviewPrompt(input.PRTitle, prDescription(input, cfg), diff, existingComments)
 
 	// Add timeout
-	timeoutCtx, cancel := context.WithTimeout(ctx, ClaudeAPITimeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.claudeTimeout)
 	defer cancel()
 
 	// Retry on transient failures
 	message, err := retryWithBackoff(timeoutCtx, r.logger, "callClaudeSubsequent", func() (*anthropic.Message, error) {
 		return client.Messages.New(timeoutCtx, anthropic.MessageNewParams{
 			Model:     anthropic.Model(model),
-			MaxTokens: 4096,
-			System: []anthropic.TextBlockParam{
-				{Text: GetSubsequentReviewSystemPrompt(cfg.ClaudeMD, cfg.Instructions)},
-			},
+			MaxTokens: maxTokens,
+			System:    cachedSystemPrompt(GetSubsequentReviewSystemPrompt(cfg.ClaudeMD, r.instructionsFor(cfg), cfg.SystemPromptPrefix, cfg.SystemPromptSuffix)),
 			Messages: []anthropic.MessageParam{
-				anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+				userMessageWithContext(reviewCtx, prompt),
 			},
 			OutputConfig: anthropic.OutputConfigParam{
 				Format: anthropic.JSONOutputFormatParam{
-					Schema: subsequentReviewResponseSchema,
+					Schema: subsequentReviewResponseSchema(cfg.SeverityScale),
 				},
 			},
 		})
@@ -691,19 +1515,74 @@ func (r *Reviewer) callClaudeSubsequent(ctx context.Context, apiKey, model strin
 	r.logger.Info("Claude API usage (subsequent)",
 		"input_tokens", usage.InputTokens,
 		"output_tokens", usage.OutputTokens,
+		"cache_read_tokens", usage.CacheReadInputTokens,
+		"cache_creation_tokens", usage.CacheCreationInputTokens,
 	)
 
 	// Extract text from response (structured outputs guarantees valid JSON)
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			return &ClaudeAPIResponse{
-				Text:  block.Text,
-				Usage: usage,
-			}, nil
+	text, err := messageText(message)
+	if err != nil {
+		return nil, err
+	}
+	return &ClaudeAPIResponse{
+		Text:       text,
+		Usage:      usage,
+		StopReason: string(message.StopReason),
+		MaxTokens:  maxTokens,
+	}, nil
+}
+
+// hasPriorComments reports whether any stored review of the PR posted comments.
+// When unsure, it assumes there are some.
+func (r *Reviewer) hasPriorComments(ctx context.Context, input *ReviewInput, firstReview *storage.ReviewContext) bool {
+	if len(firstReview.Comments) > 0 || r.storage == nil {
+		return true
+	}
+	reviews, err := r.storage.ListReviewsForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		return true
+	}
+	for _, rev := range reviews {
+		if len(rev.Comments) > 0 {
+			return true
 		}
 	}
+	return false
+}
+
+// storedExistingComments returns the bot comments stored for the PR's earlier
+// reviews as ExistingComments, for deduplicating when review threads can't be
+// fetched. They carry no thread IDs, so none of them can be resolved.
+func (r *Reviewer) storedExistingComments(ctx context.Context, input *ReviewInput) []ExistingComment {
+	if r.storage == nil {
+		return nil
+	}
+	reviews, err := r.storage.ListReviewsForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil {
+		r.logger.Warn("failed to list stored reviews", "error", err)
+		return nil
+	}
 
-	return nil, fmt.Errorf("no text content in Claude response")
+	botLogin := r.botNameOrDefault() + "[bot]"
+	seen := make(map[storage.Comment]bool)
+	var comments []ExistingComment
+	for _, rev := range reviews {
+		for _, c := range rev.Comments {
+			key := storage.Comment{Path: c.Path, Line: c.Line, Body: c.Body}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			comments = append(comments, ExistingComment{
+				Path:       c.Path,
+				Line:       c.Line,
+				Body:       c.Body,
+				IsResolved: c.Outcome == storage.CommentOutcomeResolved,
+				Author:     botLogin,
+			})
+		}
+	}
+	return comments
 }
 
 // convertThreadsToExistingComments converts GitHub review threads to ExistingComment format.
@@ -772,41 +1651,65 @@ func buildConsolidatedSummary(originalSummary, newSummary string, input *ReviewI
 	return fmt.Sprintf("%s\n\n---\n\n**Update (%s, commit %s):** %s", originalSummary, timestamp, shortSHA, newSummary)
 }
 
+// newReviewClient creates a Claude client for review calls. Setting the request
+// timeout to the Claude timeout lets max_output_tokens go beyond what the SDK allows
+// for non-streaming requests with its default 10 minute estimate.
+func (r *Reviewer) newReviewClient(apiKey string) anthropic.Client {
+	opts := []option.RequestOption{option.WithAPIKey(apiKey), option.WithRequestTimeout(r.claudeTimeout)}
+	return anthropic.NewClient(append(opts, r.claudeOptions...)...)
+}
+
+// cachedSystemPrompt returns the system prompt marked as a prompt cache breakpoint,
+// so calls sharing it (the chunks of one PR, retries) read it from the cache.
+func cachedSystemPrompt(prompt string) []anthropic.TextBlockParam {
+	return []anthropic.TextBlockParam{
+		{Text: prompt, CacheControl: anthropic.NewCacheControlEphemeralParam()},
+	}
+}
+
+// userMessageWithContext builds the user message. Rich context, when present, is a
+// separate cached block ahead of the prompt, set off from it by a rule.
+func userMessageWithContext(reviewCtx *ReviewContext, prompt string) anthropic.MessageParam {
+	if reviewCtx == nil || reviewCtx.IsEmpty() {
+		return anthropic.NewUserMessage(anthropic.NewTextBlock(prompt))
+	}
+	contextBlock := anthropic.TextBlockParam{
+		Text:         formatContext(reviewCtx) + "\n\n---\n\n",
+		CacheControl: anthropic.NewCacheControlEphemeralParam(),
+	}
+	return anthropic.NewUserMessage(anthropic.ContentBlockParamUnion{OfText: &contextBlock}, anthropic.NewTextBlock(prompt))
+}
+
 // callClaudeWithContext sends the review request to Claude with optional rich context.
-func (r *Reviewer) callClaudeWithContext(ctx context.Context, apiKey, model, title, description, diff, claudeMD, instructions string, reviewCtx *ReviewContext) (*ClaudeAPIResponse, error) {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+func (r *Reviewer) callClaudeWithContext(ctx context.Context, apiKey, model, title, description, diff, claudeMD, instructions, prefix, suffix string, maxTokens int64, scale config.SeverityScale, reviewCtx *ReviewContext) (*ClaudeAPIResponse, error) {
+	client := r.newReviewClient(apiKey)
 
-	// Build prompt with or without context
-	var prompt string
+	// Rich context goes in its own cached block ahead of the prompt
+	prompt := BuildPrompt(title, description, diff)
 	hasContext := reviewCtx != nil && !reviewCtx.IsEmpty()
-	if hasContext {
-		prompt = BuildPromptWithContext(title, description, diff, reviewCtx)
-	} else {
-		prompt = BuildPrompt(title, description, diff)
-	}
 
 	// Add timeout to prevent hanging indefinitely
-	timeoutCtx, cancel := context.WithTimeout(ctx, ClaudeAPITimeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.claudeTimeout)
 	defer cancel()
 
 	// Retry on transient failures
+	start := time.Now()
 	message, err := retryWithBackoff(timeoutCtx, r.logger, "callClaude", func() (*anthropic.Message, error) {
 		return client.Messages.New(timeoutCtx, anthropic.MessageNewParams{
 			Model:     anthropic.Model(model),
-			MaxTokens: 4096,
-			System: []anthropic.TextBlockParam{
-				{Text: GetSystemPromptWithContext(claudeMD, instructions, hasContext)},
-			},
+			MaxTokens: maxTokens,
+			System:    cachedSystemPrompt(GetSystemPromptWithContext(claudeMD, instructions, prefix, suffix, hasContext)),
 			Messages: []anthropic.MessageParam{
-				anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+				userMessageWithContext(reviewCtx, prompt),
 			},
 			OutputConfig: anthropic.OutputConfigParam{
 				Format: anthropic.JSONOutputFormatParam{
-					Schema: reviewResponseSchema,
+					Schema: reviewResponseSchema(scale),
 				},
 			},
 		})
 	})
+	metrics.ClaudeRequest(time.Since(start), err)
 	if err != nil {
 		return nil, fmt.Errorf("Claude API error: %w", err)
 	}
@@ -822,19 +1725,20 @@ func (r *Reviewer) callClaudeWithContext(ctx context.Context, apiKey, model, tit
 		"input_tokens", usage.InputTokens,
 		"output_tokens", usage.OutputTokens,
 		"cache_read_tokens", usage.CacheReadInputTokens,
+		"cache_creation_tokens", usage.CacheCreationInputTokens,
 	)
 
 	// Extract text from response (structured outputs guarantees valid JSON)
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			return &ClaudeAPIResponse{
-				Text:  block.Text,
-				Usage: usage,
-			}, nil
-		}
+	text, err := messageText(message)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("no text content in Claude response")
+	return &ClaudeAPIResponse{
+		Text:       text,
+		Usage:      usage,
+		StopReason: string(message.StopReason),
+		MaxTokens:  maxTokens,
+	}, nil
 }
 
 // reviewChunked handles large diffs by splitting them into chunks and reviewing in parallel.
@@ -845,6 +1749,7 @@ func (r *Reviewer) reviewChunked(ctx context.Context, apiKey, model string, inpu
 		"chunk_count", len(chunks),
 		"diff_size", len(diff),
 	)
+	metrics.ReviewChunked(len(chunks))
 
 	if len(chunks) == 0 {
 		// Return empty usage instead of nil for consistent tracking
@@ -854,20 +1759,24 @@ func (r *Reviewer) reviewChunked(ctx context.Context, apiKey, model string, inpu
 		}, &storage.TokenUsage{}, nil
 	}
 
-	// Prepare context input for per-chunk fetching
+	// Prepare context input for per-chunk fetching, sharing fetched files across
+	// chunks for this review only
 	contextInput := &ContextInput{
 		InstallationID: input.InstallationID,
 		Owner:          input.Owner,
 		Repo:           input.Repo,
 		HeadRef:        input.HeadSHA,
+		ChangedLines:   ParseDiffLines(diff),
 		Config:         cfg,
+		Files:          NewFileCache(),
 	}
 
 	// Process chunks in parallel using errgroup with concurrency limit
 	g, gctx := errgroup.WithContext(ctx)
-	sem := semaphore.NewWeighted(MaxConcurrentChunks)
+	sem := semaphore.NewWeighted(r.maxChunks)
 	results := make([]*ChunkResult, len(chunks))
 	usages := make([]*storage.TokenUsage, len(chunks))
+	failed := make([][]string, len(chunks))
 	var usageMu sync.Mutex
 
 	for i, chunk := range chunks {
@@ -885,10 +1794,28 @@ func (r *Reviewer) reviewChunked(ctx context.Context, apiKey, model string, inpu
 				chunkFiles[j] = f.Path
 			}
 
-			// Fetch context for this chunk
-			chunkCtx := r.contextFetcher.FetchContextForChunk(gctx, contextInput, chunkFiles, i, len(chunks))
+			// Fetch context for this chunk, capped to what fits beside its diff
+			var chunkCtx *ReviewContext
+			if room := r.chunkContextRoom(input, cfg, &chunk); room > 0 {
+				chunkInput := *contextInput
+				chunkInput.MaxBudget = room
+				chunkCtx = r.contextFetcher.FetchContextForChunk(gctx, &chunkInput, chunkFiles, i, len(chunks))
+			} else {
+				r.logger.Warn("no room for rich context in chunk prompt", "chunk", i+1, "max_prompt_size", r.maxChunkPromptSize)
+			}
 
 			resp, usage, err := r.reviewChunkWithContext(gctx, apiKey, model, input, &chunk, cfg, chunkCtx)
+			if err != nil && cfg.PartialChunkReviews {
+				// Keep the other chunks; the files can be retried with "@shipitai retry"
+				r.logger.Warn("chunk review failed, continuing with remaining chunks",
+					"chunk", i+1,
+					"chunk_count", len(chunks),
+					"files", chunkFiles,
+					"error", err,
+				)
+				failed[i] = chunkFiles
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
 			}
@@ -907,6 +1834,19 @@ func (r *Reviewer) reviewChunked(ctx context.Context, apiKey, model string, inpu
 		return nil, nil, err
 	}
 
+	// Collect files from failed chunks in chunk order
+	var failedFiles []string
+	failedChunks := 0
+	for _, files := range failed {
+		if files != nil {
+			failedFiles = append(failedFiles, files...)
+			failedChunks++
+		}
+	}
+	if failedChunks == len(chunks) {
+		return nil, nil, fmt.Errorf("all %d chunks failed", len(chunks))
+	}
+
 	// Merge results
 	merged, err := MergeChunkResponses(results)
 	if err != nil {
@@ -921,65 +1861,88 @@ func (r *Reviewer) reviewChunked(ctx context.Context, apiKey, model string, inpu
 		"approval", merged.Approval,
 		"total_input_tokens", totalUsage.InputTokens,
 		"total_output_tokens", totalUsage.OutputTokens,
+		"failed_chunks", failedChunks,
 	)
 
 	return &ClaudeResponse{
-		Summary:  merged.Summary,
-		Comments: merged.Comments,
-		Approval: merged.Approval,
+		Summary:     merged.Summary,
+		Comments:    merged.Comments,
+		Approval:    merged.Approval,
+		Extras:      merged.Extras,
+		FailedFiles: failedFiles,
+		Unplaced:    merged.Unplaced,
 	}, totalUsage, nil
 }
 
 
-// reviewChunkWithContext reviews a single chunk with optional rich context.
-func (r *Reviewer) reviewChunkWithContext(ctx context.Context, apiKey, model string, input *ReviewInput, chunk *Chunk, cfg *config.Config, reviewCtx *ReviewContext) (*ClaudeResponse, *storage.TokenUsage, error) {
-	// Extract file paths for the prompt
+// chunkPrompt builds the prompt for reviewing a single chunk.
+func chunkPrompt(input *ReviewInput, cfg *config.Config, chunk *Chunk) string {
 	filePaths := make([]string, len(chunk.Files))
 	for i, f := range chunk.Files {
 		filePaths[i] = f.Path
 	}
+	return BuildChunkedPrompt(input.PRTitle, prDescription(input, cfg), ChunkToDiff(chunk), chunk.Index, chunk.Total, filePaths)
+}
+
+// chunkContextRoom returns the context budget that keeps a chunk's whole prompt
+// within maxChunkPromptSize, or 0 or less if none fits. A tenth of the room is
+// left for the headers formatContext puts around each file.
+func (r *Reviewer) chunkContextRoom(input *ReviewInput, cfg *config.Config, chunk *Chunk) int {
+	system := GetSystemPromptWithContext(cfg.ClaudeMD, r.instructionsFor(cfg), cfg.SystemPromptPrefix, cfg.SystemPromptSuffix, true)
+	room := r.maxChunkPromptSize - len(system) - len(chunkPrompt(input, cfg, chunk))
+	return room - room/10
+}
 
-	diff := ChunkToDiff(chunk)
+// reviewChunkWithContext reviews a single chunk with optional rich context.
+func (r *Reviewer) reviewChunkWithContext(ctx context.Context, apiKey, model string, input *ReviewInput, chunk *Chunk, cfg *config.Config, reviewCtx *ReviewContext) (*ClaudeResponse, *storage.TokenUsage, error) {
+	prompt := chunkPrompt(input, cfg, chunk)
 
 	r.logger.Info("reviewing chunk",
 		"chunk", chunk.Index+1,
 		"total", chunk.Total,
 		"files", len(chunk.Files),
-		"size", len(diff),
+		"size", chunk.SizeBytes,
 	)
 
-	// Build chunked prompt with or without context
-	var prompt string
+	// Rich context goes in its own cached block ahead of the chunked prompt,
+	// unless it would push the prompt past the size limit
 	hasContext := reviewCtx != nil && !reviewCtx.IsEmpty()
+	system := GetSystemPromptWithContext(cfg.ClaudeMD, r.instructionsFor(cfg), cfg.SystemPromptPrefix, cfg.SystemPromptSuffix, hasContext)
 	if hasContext {
-		prompt = BuildChunkedPromptWithContext(input.PRTitle, input.PRBody, diff, chunk.Index, chunk.Total, filePaths, reviewCtx)
-	} else {
-		prompt = BuildChunkedPrompt(input.PRTitle, input.PRBody, diff, chunk.Index, chunk.Total, filePaths)
+		if size := len(system) + len(formatContext(reviewCtx)) + len(prompt); size > r.maxChunkPromptSize {
+			r.logger.Warn("chunk prompt too large, reviewing without rich context",
+				"chunk", chunk.Index+1,
+				"size", size,
+				"max_prompt_size", r.maxChunkPromptSize,
+			)
+			reviewCtx, hasContext = nil, false
+			system = GetSystemPromptWithContext(cfg.ClaudeMD, r.instructionsFor(cfg), cfg.SystemPromptPrefix, cfg.SystemPromptSuffix, false)
+		}
 	}
 
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+	client := r.newReviewClient(apiKey)
 
 	// Add timeout to prevent hanging indefinitely
-	timeoutCtx, cancel := context.WithTimeout(ctx, ClaudeAPITimeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.claudeTimeout)
 	defer cancel()
 
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(model),
+		MaxTokens: maxOutputTokens(cfg, model),
+		System:    cachedSystemPrompt(system),
+		Messages: []anthropic.MessageParam{
+			userMessageWithContext(reviewCtx, prompt),
+		},
+		OutputConfig: anthropic.OutputConfigParam{
+			Format: anthropic.JSONOutputFormatParam{
+				Schema: reviewResponseSchema(cfg.SeverityScale),
+			},
+		},
+	}
+
 	// Retry on transient failures
 	message, err := retryWithBackoff(timeoutCtx, r.logger, fmt.Sprintf("reviewChunk_%d", chunk.Index+1), func() (*anthropic.Message, error) {
-		return client.Messages.New(timeoutCtx, anthropic.MessageNewParams{
-			Model:     anthropic.Model(model),
-			MaxTokens: 4096,
-			System: []anthropic.TextBlockParam{
-				{Text: GetSystemPromptWithContext(cfg.ClaudeMD, cfg.Instructions, hasContext)},
-			},
-			Messages: []anthropic.MessageParam{
-				anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-			},
-			OutputConfig: anthropic.OutputConfigParam{
-				Format: anthropic.JSONOutputFormatParam{
-					Schema: reviewResponseSchema,
-				},
-			},
-		})
+		return client.Messages.New(timeoutCtx, params)
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("Claude API error: %w", err)
@@ -996,74 +1959,40 @@ func (r *Reviewer) reviewChunkWithContext(ctx context.Context, apiKey, model str
 		"chunk", chunk.Index+1,
 		"input_tokens", usage.InputTokens,
 		"output_tokens", usage.OutputTokens,
+		"cache_read_tokens", usage.CacheReadInputTokens,
+		"cache_creation_tokens", usage.CacheCreationInputTokens,
 	)
 
 	// Extract text from response (structured outputs guarantees valid JSON)
-	var text string
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			text = block.Text
-			break
-		}
-	}
-
-	if text == "" {
-		return nil, nil, fmt.Errorf("no text content in Claude response for chunk %d", chunk.Index+1)
+	text, err := messageText(message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("chunk %d: %w", chunk.Index+1, err)
 	}
 
-	// Parse the response (retry once on parse failure with a fresh API call)
-	parsed, parseErr := ParseResponse(text)
+	// Parse the response (one corrective call on parse failure)
+	parsed, parseErr := ParseResponse(text, cfg.SeverityScale)
 	if parseErr != nil {
-		r.logger.Warn("chunk parse failure, retrying Claude call",
+		logTruncation(r.logger, fmt.Sprintf("reviewChunk_%d", chunk.Index+1), string(message.StopReason), params.MaxTokens)
+		r.logger.Warn("chunk parse failure, asking Claude to correct its JSON",
 			"chunk", chunk.Index+1,
-			"error", parseErr,
+			"error", jsonErrorDetail(parseErr),
 		)
 
-		// Fresh API call
-		retryMsg, retryErr := retryWithBackoff(timeoutCtx, r.logger, fmt.Sprintf("reviewChunk_%d_retry", chunk.Index+1), func() (*anthropic.Message, error) {
-			return client.Messages.New(timeoutCtx, anthropic.MessageNewParams{
-				Model:     anthropic.Model(model),
-				MaxTokens: 4096,
-				System: []anthropic.TextBlockParam{
-					{Text: GetSystemPromptWithContext(cfg.ClaudeMD, cfg.Instructions, hasContext)},
-				},
-				Messages: []anthropic.MessageParam{
-					anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-				},
-				OutputConfig: anthropic.OutputConfigParam{
-					Format: anthropic.JSONOutputFormatParam{
-						Schema: reviewResponseSchema,
-					},
-				},
-			})
-		})
-		if retryErr != nil {
+		repaired, repairErr := r.repairJSON(ctx, apiKey, model, params.MaxTokens, reviewResponseSchema(cfg.SeverityScale), text, jsonErrorDetail(parseErr))
+		if repairErr != nil {
 			return nil, nil, fmt.Errorf("failed to parse chunk %d response: %w", chunk.Index+1, parseErr)
 		}
+		usage = aggregateUsage([]*storage.TokenUsage{usage, repaired.Usage})
 
-		// Update usage with retry usage
-		usage.InputTokens += retryMsg.Usage.InputTokens
-		usage.OutputTokens += retryMsg.Usage.OutputTokens
-		usage.CacheReadInputTokens += retryMsg.Usage.CacheReadInputTokens
-		usage.CacheCreationInputTokens += retryMsg.Usage.CacheCreationInputTokens
-
-		text = ""
-		for _, block := range retryMsg.Content {
-			if block.Type == "text" {
-				text = block.Text
-				break
-			}
-		}
-
-		parsed, parseErr = ParseResponse(text)
+		parsed, parseErr = ParseResponse(repaired.Text, cfg.SeverityScale)
 		if parseErr != nil {
-			return nil, nil, fmt.Errorf("failed to parse chunk %d response after retry: %w", chunk.Index+1, parseErr)
+			return nil, nil, fmt.Errorf("failed to parse chunk %d response after correction: %w", chunk.Index+1, parseErr)
 		}
 	}
 
 	// Validate and filter comments against this chunk's diff lines
-	diffLines := ParseDiffLines(diff)
-	parsed.Comments, _ = FilterValidComments(parsed.Comments, diffLines, r.logger)
+	diffLines := ParseDiffLines(ChunkToDiff(chunk))
+	parsed.Comments, parsed.Unplaced, _ = snapComments(parsed.Comments, diffLines, cfg.CommentSnapWindow(), cfg.CaseInsensitivePaths, r.logger)
 
 	return parsed, usage, nil
 }
@@ -1096,41 +2025,71 @@ func toStorageComments(comments []ClaudeComment) []storage.Comment {
 	return result
 }
 
-// filterDiff removes files matching exclude patterns from the diff.
+// filterDiff removes files matching exclude patterns, or not matching any include
+// pattern when some are set, from the diff. It also removes binary files, which
+// have no reviewable lines, and files with more than MaxFileDiffBytes of added
+// and context lines when that's set.
 func filterDiff(diff string, cfg *config.Config) string {
 	var result strings.Builder
 	var currentFile string
-	var includeFile bool
+	var includeFile, binary, inHunk bool
+	var contentBytes int
 	var fileContent strings.Builder
 
+	// Write the current file if it was included and isn't binary or oversized
+	flush := func() {
+		if !includeFile || fileContent.Len() == 0 || binary {
+			return
+		}
+		if cfg.MaxFileDiffBytes > 0 && contentBytes > cfg.MaxFileDiffBytes {
+			return
+		}
+		result.WriteString(fileContent.String())
+	}
+
 	lines := strings.Split(diff, "\n")
 	for _, line := range lines {
 		// Detect new file in diff
 		if strings.HasPrefix(line, "diff --git") {
-			// Write previous file if it was included
-			if includeFile && fileContent.Len() > 0 {
-				result.WriteString(fileContent.String())
-			}
+			flush()
 			fileContent.Reset()
+			binary = false
+			inHunk = false
+			contentBytes = 0
 
 			// Extract file path from "diff --git a/path b/path"
 			parts := strings.Split(line, " ")
 			if len(parts) >= 4 {
 				currentFile = strings.TrimPrefix(parts[3], "b/")
 			}
-			includeFile = !cfg.ShouldExcludeFile(currentFile)
+			includeFile = cfg.ShouldIncludeFile(currentFile)
 		}
 
-		if includeFile {
-			fileContent.WriteString(line)
-			fileContent.WriteString("\n")
+		if !includeFile {
+			continue
 		}
+		// Count added and context lines; "---"/"+++" are file headers only before
+		// the first hunk, after it they're removed or added lines starting "--"/"++"
+		switch {
+		case isBinaryDiffLine(line):
+			binary = true
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk && (strings.HasPrefix(line, "+") || strings.HasPrefix(line, " ")):
+			contentBytes += len(line) - 1
+		}
+		fileContent.WriteString(line)
+		fileContent.WriteString("\n")
 	}
 
 	// Write last file if included
-	if includeFile && fileContent.Len() > 0 {
-		result.WriteString(fileContent.String())
-	}
+	flush()
 
 	return strings.TrimSuffix(result.String(), "\n")
 }
+
+// isBinaryDiffLine reports whether a diff line marks its file as binary, either
+// "Binary files a/x and b/x differ" or the start of a "GIT binary patch".
+func isBinaryDiffLine(line string) bool {
+	return (strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ")) || line == "GIT binary patch"
+}
@@ -0,0 +1,1298 @@
+package review
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+// routeTransport serves canned responses keyed by URL path suffix; anything else is a 404.
+type routeTransport map[string]*http.Response
+
+func (t routeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for suffix, resp := range t {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			return resp, nil
+		}
+	}
+	return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+}
+
+// configResponse returns a contents API response carrying the given shipitai.yml.
+func configResponse(yaml string) *http.Response {
+	body := `{"encoding":"base64","content":"` + base64.StdEncoding.EncodeToString([]byte(yaml)) + `"}`
+	return newResponse(nil, http.StatusOK, body)
+}
+
+// claudeReply is a canned Messages API response, sent after Delay. Usage
+// defaults to 100 input and 10 output tokens, and a non-zero Status sends an
+// API error instead.
+type claudeReply struct {
+	Text       string
+	StopReason string
+	Usage      map[string]any
+	Status     int
+	Delay      time.Duration
+}
+
+// claudeRequest is a Messages API request received by fakeClaude.
+type claudeRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// fakeClaude is a Messages API server answering each request with the next of
+// its replies, repeating the last, and recording what it was sent.
+type fakeClaude struct {
+	replies []claudeReply
+
+	mu             sync.Mutex
+	requests       []claudeRequest
+	failOn         string
+	inFlight, peak int
+}
+
+// newFakeClaude starts a fakeClaude replying with the given texts and points
+// the Anthropic client at it for the rest of the test.
+func newFakeClaude(t *testing.T, texts ...string) *fakeClaude {
+	t.Helper()
+	replies := make([]claudeReply, len(texts))
+	for i, text := range texts {
+		replies[i] = claudeReply{Text: text}
+	}
+	return newFakeClaudeReplies(t, replies...)
+}
+
+// newFakeClaudeReplies is newFakeClaude with full control over each reply.
+func newFakeClaudeReplies(t *testing.T, replies ...claudeReply) *fakeClaude {
+	t.Helper()
+	if len(replies) == 0 {
+		t.Fatal("newFakeClaude needs at least one reply")
+	}
+	f := &fakeClaude{replies: replies}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+	return f
+}
+
+func (f *fakeClaude) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	reply := f.replies[min(len(f.requests), len(f.replies)-1)]
+	f.requests = append(f.requests, claudeRequest{Header: r.Header.Clone(), Body: body})
+	if f.failOn != "" && strings.Contains(string(body), f.failOn) {
+		reply = claudeReply{Status: http.StatusBadRequest}
+	}
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+	time.Sleep(reply.Delay)
+
+	if reply.Status != 0 {
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"test"}}`, reply.Status)
+		return
+	}
+	content := []map[string]any{}
+	if reply.Text != "" {
+		content = append(content, map[string]any{"type": "text", "text": reply.Text})
+	}
+	stopReason, usage := reply.StopReason, reply.Usage
+	if stopReason == "" {
+		stopReason = "end_turn"
+	}
+	if usage == nil {
+		usage = map[string]any{"input_tokens": 100, "output_tokens": 10}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":          "msg_1",
+		"type":        "message",
+		"role":        "assistant",
+		"model":       "claude-test",
+		"content":     content,
+		"stop_reason": stopReason,
+		"usage":       usage,
+	})
+}
+
+// received returns the requests served so far.
+func (f *fakeClaude) received() []claudeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]claudeRequest(nil), f.requests...)
+}
+
+// prompts returns the bodies of the requests served so far.
+func (f *fakeClaude) prompts() []string {
+	var prompts []string
+	for _, req := range f.received() {
+		prompts = append(prompts, string(req.Body))
+	}
+	return prompts
+}
+
+// peakConcurrency returns the most requests served at once.
+func (f *fakeClaude) peakConcurrency() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.peak
+}
+
+// calls returns how many requests were served.
+func (f *fakeClaude) calls() int {
+	return len(f.received())
+}
+
+// reset forgets the requests served so far, restarting the replies.
+func (f *fakeClaude) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = nil
+}
+
+// failRequestsWith makes requests whose body contains s fail with an API
+// error; an empty s stops failing them.
+func (f *fakeClaude) failRequestsWith(s string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failOn = s
+}
+
+func TestReviewRequestedBypassesOnRequestTrigger(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		requested bool
+		wantRun   bool
+	}{
+		{"on-request automatic event skipped", "trigger: on-request", false, false},
+		{"on-request explicit request runs", "trigger: on-request", true, true},
+		{"disabled explicit request skipped", "enabled: false", true, false},
+	}
+
+	// The failing diff fetch is retried; keep the backoff short
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := routeTransport{
+				"/contents/.github/shipitai.yml": configResponse(tt.config),
+				// The diff fetch fails, so reaching it shows the gate let the review through
+				"/pulls/7": newResponse(nil, http.StatusInternalServerError, "boom"),
+			}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := reviewer.Review(context.Background(), &ReviewInput{
+				Owner:     "owner",
+				Repo:      "repo",
+				PRNumber:  7,
+				Requested: tt.requested,
+			})
+
+			ran := err != nil && strings.Contains(err.Error(), "failed to fetch diff")
+			if ran != tt.wantRun {
+				t.Errorf("review ran = %v (result %+v, err %v), want %v", ran, result, err, tt.wantRun)
+			}
+			if !tt.wantRun && (result != nil || err != nil) {
+				t.Errorf("Review() = %+v, %v, want nil, nil", result, err)
+			}
+		})
+	}
+}
+
+func TestReviewSkipsInaccessiblePR(t *testing.T) {
+	transport := routeTransport{
+		"/pulls/7": newResponse(nil, http.StatusNotFound, `{"message":"Not Found"}`),
+	}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7})
+	if err != nil {
+		t.Fatalf("Review() error = %v, want a skip", err)
+	}
+	if result == nil || result.SkipReason != SkipReasonPRInaccessible {
+		t.Errorf("Review() = %+v, want SkipReason %q", result, SkipReasonPRInaccessible)
+	}
+}
+
+func TestReviewSkipsReopenedPRs(t *testing.T) {
+	tests := []struct {
+		name      string
+		merged    bool
+		requested bool
+		diff      string
+		wantSkip  string
+	}{
+		{"reopened after merge", true, false, "diff --git a/a.go b/a.go\n+x\n", SkipReasonAlreadyMerged},
+		{"reopened with empty diff", false, false, "", SkipReasonEmptyDiff},
+		{"requested review of merged PR checks the diff", true, true, "\n", SkipReasonEmptyDiff},
+		{"only binary files changed", false, false, "diff --git a/logo.png b/logo.png\nindex 1234567..89abcde 100644\nBinary files a/logo.png and b/logo.png differ\n", SkipReasonEmptyDiff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffFetched := false
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if strings.HasSuffix(req.URL.Path, "/pulls/7") {
+					diffFetched = true
+					return newResponse(req, http.StatusOK, tt.diff), nil
+				}
+				return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+			})
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, Merged: tt.merged, Requested: tt.requested})
+			if err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			if result == nil || result.SkipReason != tt.wantSkip {
+				t.Errorf("Review() = %+v, want SkipReason %q", result, tt.wantSkip)
+			}
+			if wantFetch := tt.wantSkip != SkipReasonAlreadyMerged; diffFetched != wantFetch {
+				t.Errorf("diff fetched = %v, want %v", diffFetched, wantFetch)
+			}
+		})
+	}
+}
+
+func TestReviewSkipsDrafts(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		requested bool
+		wantSkip  bool
+	}{
+		{"draft skipped by default", "enabled: true", false, true},
+		{"requested draft reviewed", "enabled: true", true, false},
+		{"skip_drafts off", "skip_drafts: false", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := routeTransport{
+				"/contents/.github/shipitai.yml": configResponse(tt.config),
+				// Reaching the diff fetch shows the draft went on to be reviewed
+				"/pulls/7": newResponse(nil, http.StatusNotFound, `{"message":"Not Found"}`),
+			}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, Draft: true, Requested: tt.requested})
+			if err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			if skipped := result != nil && result.SkipReason == SkipReasonDraft; skipped != tt.wantSkip {
+				t.Errorf("Review() = %+v, want draft skip %v", result, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestRetryWithBackoffHonorsRetryAfter(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Hour // would hang if the computed backoff were used
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	calls := 0
+	got, err := retryWithBackoff(context.Background(), logger, "test", func() (string, error) {
+		calls++
+		if calls == 1 {
+			return "", &github.RateLimitError{
+				APIError:   github.APIError{StatusCode: http.StatusForbidden},
+				RetryAfter: 10 * time.Millisecond,
+			}
+		}
+		return "ok", nil
+	})
+	if err != nil || got != "ok" {
+		t.Fatalf("retryWithBackoff() = %q, %v, want ok", got, err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestRetryWithBackoffGivesUpOnLongRetryAfter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	calls := 0
+	_, err := retryWithBackoff(context.Background(), logger, "test", func() (string, error) {
+		calls++
+		return "", &github.RateLimitError{RetryAfter: MaxRetryAfter + time.Minute}
+	})
+	if err == nil {
+		t.Fatal("retryWithBackoff() expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestReviewSkipsMostlyDeletions(t *testing.T) {
+	deletionDiff := "diff --git a/old/a.go b/old/a.go\ndeleted file mode 100644\n--- a/old/a.go\n+++ /dev/null\n@@ -1,4 +0,0 @@\n-package old\n-\n-func A() {}\n-func B() {}\n"
+	balancedDiff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n-func A() {}\n-func B() {}\n+func A() error { return nil }\n+func B() error { return nil }\n"
+
+	tests := []struct {
+		name     string
+		diff     string
+		wantSkip bool
+	}{
+		{"deletion-dominated PR skipped", deletionDiff, true},
+		{"balanced PR reviewed", balancedDiff, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reaching Claude shows the review went ahead; fail the call so the test stays offline
+			claude := newFakeClaudeReplies(t, claudeReply{Status: http.StatusBadRequest})
+
+			var notices atomic.Int32
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				switch {
+				case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+					return configResponse("skip_deletion_ratio: 0.9"), nil
+				case strings.HasSuffix(req.URL.Path, "/pulls/7"):
+					return newResponse(req, http.StatusOK, tt.diff), nil
+				case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/issues/7/comments"):
+					notices.Add(1)
+					return newResponse(req, http.StatusCreated, `{"id":1}`), nil
+				}
+				return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+			})
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", memory.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			// A second push still skips the review, without repeating the notice
+			for _, head := range []string{"abc123", "def456"} {
+				result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: head})
+
+				skipped := err == nil && result != nil && result.SkipReason == SkipReasonMostlyDeletions
+				if skipped != tt.wantSkip {
+					t.Errorf("Review() = %+v, %v, skipped = %v, want %v", result, err, skipped, tt.wantSkip)
+				}
+			}
+			if reviewed := claude.calls() > 0; reviewed == tt.wantSkip {
+				t.Errorf("Claude called = %v, want %v", reviewed, !tt.wantSkip)
+			}
+			var wantNotices int32
+			if tt.wantSkip {
+				wantNotices = 1
+			}
+			if notices.Load() != wantNotices {
+				t.Errorf("posted %d deletion notices, want %d", notices.Load(), wantNotices)
+			}
+		})
+	}
+}
+
+func TestInstructionsFor(t *testing.T) {
+	reviewer := NewReviewer(nil, "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	repo := &config.Config{Instructions: "Use tabs."}
+
+	if got := reviewer.instructionsFor(repo); got != "Use tabs." {
+		t.Errorf("instructionsFor() without reviewer instructions = %q", got)
+	}
+
+	reviewer.SetInstructions("Focus on security.")
+	if got := reviewer.instructionsFor(repo); got != "Focus on security.\n\nUse tabs." {
+		t.Errorf("instructionsFor() = %q, want reviewer then repo instructions", got)
+	}
+	if got := reviewer.instructionsFor(&config.Config{}); got != "Focus on security." {
+		t.Errorf("instructionsFor() without repo instructions = %q", got)
+	}
+}
+
+// writeRecorder wraps a transport and records every non-GET request.
+type writeRecorder struct {
+	next   http.RoundTripper
+	mu     sync.Mutex
+	writes []string
+}
+
+func (t *writeRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		t.mu.Lock()
+		t.writes = append(t.writes, req.Method+" "+req.URL.Path)
+		t.mu.Unlock()
+	}
+	return t.next.RoundTrip(req)
+}
+
+func TestReviewDryRun(t *testing.T) {
+	review := `{"summary":"Errors are now returned.","approval":"comment","comments":[{"path":"a.go","line":1,"body":"Wrap this error.","severity":"medium"}]}`
+	newFakeClaudeReplies(t, claudeReply{Text: review, Usage: map[string]any{"input_tokens": 1200, "output_tokens": 80}})
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-func A() {}\n+func A() error { return nil }\n"
+	transport := &writeRecorder{next: routeTransport{
+		"/pulls/7": newResponse(nil, http.StatusOK, diff),
+	}}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123", DryRun: true})
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if len(transport.writes) != 0 {
+		t.Errorf("dry run wrote to GitHub: %v", transport.writes)
+	}
+	if !result.DryRun || result.ReviewID != 0 {
+		t.Errorf("result DryRun = %v, ReviewID = %d, want true, 0", result.DryRun, result.ReviewID)
+	}
+	if result.Summary != "Errors are now returned." || result.Approval != "comment" || result.CommentCount != 1 {
+		t.Errorf("result = %+v, want the parsed summary, approval, and one comment", result)
+	}
+	if len(result.Comments) != 1 || result.Comments[0].Path != "a.go" {
+		t.Errorf("Comments = %+v, want the a.go comment", result.Comments)
+	}
+	if result.Usage == nil || result.Usage.InputTokens != 1200 || result.Usage.OutputTokens != 80 {
+		t.Errorf("Usage = %+v, want 1200 input / 80 output tokens", result.Usage)
+	}
+}
+
+func TestSubsequentReviewDryRun(t *testing.T) {
+	newFakeClaude(t, `{"summary":"The new error path leaks the file.","comments":[{"path":"a.go","line":1,"body":"Close the file.","severity":"high"}],"resolved_threads":[],"approval":"request_changes"}`)
+
+	store := memory.New()
+	if err := store.StoreReview(context.Background(), &storage.ReviewContext{Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 100, ReviewBody: "First pass."}); err != nil {
+		t.Fatal(err)
+	}
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-func A() {}\n+func A() error { return nil }\n"
+	transport := &writeRecorder{next: routeTransport{
+		"/pulls/7": newResponse(nil, http.StatusOK, diff),
+	}}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123", DryRun: true})
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if len(transport.writes) != 0 {
+		t.Errorf("dry run wrote to GitHub: %v", transport.writes)
+	}
+	if !result.DryRun || result.Approval != "request_changes" || result.CommentCount != 1 {
+		t.Errorf("result = %+v, want a dry run requesting changes with one comment", result)
+	}
+	if want := "_" + verdictBlocking + "_ The new error path leaks the file."; result.Summary != want {
+		t.Errorf("Summary = %q, want %q, the summary the update would post", result.Summary, want)
+	}
+}
+
+func TestCallClaudeWithContextSetsCacheBreakpoints(t *testing.T) {
+	var request struct {
+		System []struct {
+			Text         string          `json:"text"`
+			CacheControl json.RawMessage `json:"cache_control"`
+		} `json:"system"`
+		Messages []struct {
+			Content []struct {
+				Text         string          `json:"text"`
+				CacheControl json.RawMessage `json:"cache_control"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	claude := newFakeClaudeReplies(t, claudeReply{
+		Text:  `{"summary":"ok","approval":"approve","comments":[]}`,
+		Usage: map[string]any{"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 900},
+	})
+
+	reviewer := NewReviewer(github.NewClientWithTransport(routeTransport{}), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	reviewCtx := &ReviewContext{FullFiles: []FileContext{{Path: "a.go", Content: "package a\n", Language: "go"}}}
+
+	resp, err := reviewer.callClaudeWithContext(context.Background(), "key", "claude-test", "Title", "", "diff", "", "", "", "", 4096, nil, reviewCtx)
+	if err != nil {
+		t.Fatalf("callClaudeWithContext() error = %v", err)
+	}
+	if resp.Usage.CacheReadInputTokens != 900 {
+		t.Errorf("CacheReadInputTokens = %d, want 900", resp.Usage.CacheReadInputTokens)
+	}
+
+	if err := json.Unmarshal(claude.received()[0].Body, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(request.System) != 1 || !strings.Contains(string(request.System[0].CacheControl), "ephemeral") {
+		t.Errorf("system = %+v, want one cached block", request.System)
+	}
+	if len(request.Messages) != 1 || len(request.Messages[0].Content) != 2 {
+		t.Fatalf("messages = %+v, want one message with context and prompt blocks", request.Messages)
+	}
+	contextBlock, promptBlock := request.Messages[0].Content[0], request.Messages[0].Content[1]
+	if !strings.Contains(contextBlock.Text, "a.go") || !strings.Contains(string(contextBlock.CacheControl), "ephemeral") {
+		t.Errorf("context block = %+v, want the cached file context", contextBlock)
+	}
+	if len(promptBlock.CacheControl) != 0 {
+		t.Errorf("prompt block cache_control = %s, want none", promptBlock.CacheControl)
+	}
+	if want := formatContext(reviewCtx) + "\n\n---\n\n"; contextBlock.Text != want {
+		t.Errorf("context block text = %q, want %q", contextBlock.Text, want)
+	}
+	if want := BuildPrompt("Title", "", "diff"); promptBlock.Text != want {
+		t.Errorf("prompt block text = %q, want %q", promptBlock.Text, want)
+	}
+}
+
+func TestCallAndParseLogsTruncation(t *testing.T) {
+	tests := []struct {
+		name       string
+		stopReason string
+		wantLog    bool
+	}{
+		{"truncated at max_tokens", "max_tokens", true},
+		{"malformed but complete", "end_turn", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs strings.Builder
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+			_, _, err := callAndParse(logger, "reviewFirst", nil, func() (*ClaudeAPIResponse, error) {
+				return &ClaudeAPIResponse{Text: `{"summary":"cut off","comments":[{"path":`, StopReason: tt.stopReason, MaxTokens: 4096}, nil
+			}, nil)
+			if err == nil {
+				t.Fatal("callAndParse() error = nil, want parse failure")
+			}
+			if got := strings.Contains(logs.String(), "raise max_output_tokens"); got != tt.wantLog {
+				t.Errorf("truncation logged = %v, want %v; logs:\n%s", got, tt.wantLog, logs.String())
+			}
+		})
+	}
+}
+
+func TestCallAndParseRepairsMalformedJSON(t *testing.T) {
+	claude := newFakeClaudeReplies(t, claudeReply{
+		Text:  `{"summary":"fixed","approval":"approve","comments":[]}`,
+		Usage: map[string]any{"input_tokens": 30, "output_tokens": 7},
+	})
+
+	reviewer := NewReviewer(github.NewClientWithTransport(routeTransport{}), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	invalid := `{"summary":"almost","approval":"approve","comments":[],}`
+
+	parsed, resp, err := callAndParse(reviewer.logger, "reviewFirst", nil, func() (*ClaudeAPIResponse, error) {
+		return &ClaudeAPIResponse{Text: invalid, Usage: &storage.TokenUsage{InputTokens: 1000, OutputTokens: 200}}, nil
+	}, func(text string, parseErr error) (*ClaudeAPIResponse, error) {
+		return reviewer.repairJSON(context.Background(), "key", "claude-test", 4096, reviewResponseSchema(nil), text, parseErr)
+	})
+	if err != nil {
+		t.Fatalf("callAndParse() error = %v", err)
+	}
+	if parsed.Summary != "fixed" {
+		t.Errorf("Summary = %q, want the corrected response", parsed.Summary)
+	}
+	requests := claude.received()
+	if len(requests) != 1 {
+		t.Fatalf("corrective calls = %d, want 1", len(requests))
+	}
+	var req struct {
+		Messages []struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(requests[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if repairPrompt := req.Messages[0].Content[0].Text; !strings.Contains(repairPrompt, invalid) || !strings.Contains(repairPrompt, "invalid character") {
+		t.Errorf("repair prompt = %q, want the invalid text and the parse error", repairPrompt)
+	}
+	if resp.Usage.InputTokens != 1030 || resp.Usage.OutputTokens != 207 {
+		t.Errorf("usage = %+v, want both calls counted", resp.Usage)
+	}
+
+	// A correction that still fails to parse is not retried again
+	_, _, err = callAndParse(reviewer.logger, "reviewFirst", nil, func() (*ClaudeAPIResponse, error) {
+		return &ClaudeAPIResponse{Text: invalid}, nil
+	}, func(string, error) (*ClaudeAPIResponse, error) {
+		return &ClaudeAPIResponse{Text: invalid}, nil
+	})
+	if err == nil {
+		t.Error("callAndParse() error = nil, want failure after one correction")
+	}
+}
+
+func TestClaudeReviewClampsMaxTokensToModel(t *testing.T) {
+	claude := newFakeClaude(t, `{"summary":"ok","approval":"approve","comments":[]}`)
+
+	reviewer := NewReviewer(github.NewClientWithTransport(routeTransport{}), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n"
+
+	type modelCase struct {
+		model      string
+		configured int
+		want       int64
+	}
+	var tests []modelCase
+	for _, m := range SupportedModels {
+		tests = append(tests,
+			modelCase{m.ID, config.MaxOutputTokensLimit, int64(m.MaxOutputTokens)},
+			modelCase{m.ID, 16000, 16000},
+		)
+	}
+	tests = append(tests,
+		modelCase{DefaultModel, config.MaxOutputTokensLimit, defaultModelMaxOutputTokens},
+		modelCase{DefaultModel, 0, config.DefaultMaxOutputTokens},
+		modelCase{"claude-unlisted", 100000, 100000},
+	)
+
+	for _, tt := range tests {
+		cfg := config.DefaultConfig()
+		cfg.MaxOutputTokens = tt.configured
+		if _, _, err := reviewer.claudeReview(context.Background(), input, cfg, diff, "key", tt.model, nil); err != nil {
+			t.Fatalf("claudeReview(%s) error = %v", tt.model, err)
+		}
+		requests := claude.received()
+		var req struct {
+			MaxTokens int64 `json:"max_tokens"`
+		}
+		if err := json.Unmarshal(requests[len(requests)-1].Body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.MaxTokens != tt.want {
+			t.Errorf("%s with max_output_tokens %d: request max_tokens = %d, want %d", tt.model, tt.configured, req.MaxTokens, tt.want)
+		}
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{"multiple text blocks joined", `[{"type":"text","text":"{\"summary\":"},{"type":"text","text":"\"ok\"}"}]`, `{"summary":"ok"}`, ""},
+		{"non-text blocks skipped", `[{"type":"thinking","thinking":"hmm","signature":"s"},{"type":"text","text":"done"}]`, "done", ""},
+		{"no text block", `[{"type":"thinking","thinking":"hmm","signature":"s"},{"type":"tool_use","id":"t","name":"f","input":{}}]`, "", "got thinking, tool_use blocks"},
+		{"no blocks", `[]`, "", "no content blocks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message anthropic.Message
+			if err := json.Unmarshal([]byte(`{"content":`+tt.content+`}`), &message); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			got, err := messageText(&message)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("messageText() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("messageText() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetMaxConcurrentChunks(t *testing.T) {
+	claude := newFakeClaudeReplies(t, claudeReply{Text: `{"summary":"Fine.","approval":"approve","comments":[]}`, Delay: 50 * time.Millisecond})
+
+	var diff string
+	for _, path := range []string{"a.go", "b.go", "c.go", "d.go"} {
+		diff += largeFileDiff(path, 1500)
+	}
+	transport := &reviewsTransport{diff: diff, config: "enabled: true"}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	reviewer.SetMaxConcurrentChunks(2)
+	reviewer.SetMaxConcurrentChunks(0) // ignored
+
+	if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if got := claude.peakConcurrency(); got != 2 {
+		t.Errorf("peak concurrent Claude calls = %d, want 2", got)
+	}
+}
+
+func TestSetClaudeOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []option.RequestOption
+		wantBeta     string
+		wantMetadata bool
+	}{
+		{"defaults", nil, "", false},
+		{
+			"beta header and request param",
+			[]option.RequestOption{
+				option.WithHeader("anthropic-beta", "context-1m-2025-08-07"),
+				option.WithJSONSet("metadata", map[string]string{"user_id": "self-hosted"}),
+			},
+			"context-1m-2025-08-07",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claude := newFakeClaude(t, `{"summary":"Fine.","approval":"approve","comments":[]}`)
+
+			transport := &reviewsTransport{diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n", config: "enabled: true"}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			reviewer.SetClaudeOptions(tt.opts...)
+
+			if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7}); err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			requests := claude.received()
+			if len(requests) == 0 {
+				t.Fatal("no Claude requests made")
+			}
+			for _, req := range requests {
+				if beta := req.Header.Get("anthropic-beta"); beta != tt.wantBeta {
+					t.Errorf("anthropic-beta header = %q, want %q", beta, tt.wantBeta)
+				}
+				var body map[string]any
+				_ = json.Unmarshal(req.Body, &body)
+				if _, ok := body["metadata"]; ok != tt.wantMetadata {
+					t.Errorf("request has metadata = %v, want %v", ok, tt.wantMetadata)
+				}
+				if body["model"] == nil {
+					t.Error("request lost its model param")
+				}
+			}
+		})
+	}
+}
+
+func TestReviewSkipsSmallPRs(t *testing.T) {
+	typoDiff := "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-Teh tool\n+The tool\n"
+	codeDiff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n-func A() {}\n-func B() {}\n+func A() error { return nil }\n+func B() error { return nil }\n"
+
+	tests := []struct {
+		name       string
+		config     string
+		diff       string
+		requested  bool
+		wantReason string
+	}{
+		{"below min_changed_lines", "min_changed_lines: 3", typoDiff, false, SkipReasonTooSmall},
+		{"at min_changed_lines", "min_changed_lines: 4", codeDiff, false, ""},
+		{"only skip_if_only_files", "skip_if_only_files: [\"*.md\"]", typoDiff, false, SkipReasonOnlySkippedFiles},
+		{"code outside skip_if_only_files", "skip_if_only_files: [\"*.md\"]", codeDiff, false, ""},
+		{"requested review not skipped", "min_changed_lines: 3", typoDiff, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reaching Claude shows the review went ahead; fail the call so the test stays offline
+			claude := newFakeClaudeReplies(t, claudeReply{Status: http.StatusBadRequest})
+
+			transport := routeTransport{
+				"/contents/.github/shipitai.yml": configResponse(tt.config),
+				"/pulls/7":                       newResponse(nil, http.StatusOK, tt.diff),
+			}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, _ := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, Requested: tt.requested})
+
+			reason := ""
+			if result != nil {
+				reason = result.SkipReason
+			}
+			if reason != tt.wantReason {
+				t.Errorf("SkipReason = %q, want %q", reason, tt.wantReason)
+			}
+			if reviewed := claude.calls() > 0; reviewed != (tt.wantReason == "") {
+				t.Errorf("Claude called = %v, want %v", reviewed, tt.wantReason == "")
+			}
+		})
+	}
+}
+
+// graphQLCounter counts GraphQL requests, failing them, and passes everything else to next.
+type graphQLCounter struct {
+	next  http.RoundTripper
+	calls atomic.Int32
+}
+
+func (t *graphQLCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		t.calls.Add(1)
+		return newResponse(req, http.StatusBadGateway, "bad gateway"), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+func TestReviewSubsequentSkipsThreadFetchWithoutPriorComments(t *testing.T) {
+	newFakeClaude(t, `{"summary":"Looks good.","approval":"approve","comments":[]}`)
+
+	reviews := &reviewsTransport{diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n", config: "enabled: true"}
+	transport := &graphQLCounter{next: reviews}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", memory.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := reviewer.Review(context.Background(), input); err != nil {
+			t.Fatalf("Review() #%d error = %v", i+1, err)
+		}
+	}
+	if got := transport.calls.Load(); got != 0 {
+		t.Errorf("GraphQL calls = %d, want none when no review posted comments", got)
+	}
+	// A subsequent review updates the first review's body; a fallback wouldn't
+	if len(reviews.updates) != 1 {
+		t.Errorf("review body updates = %d, want 1 from the subsequent review", len(reviews.updates))
+	}
+}
+
+func TestReviewSubsequentUsesStoredCommentsWhenThreadFetchFails(t *testing.T) {
+	claude := newFakeClaude(t, `{"summary":"Looks good.","approval":"approve","comments":[]}`)
+
+	store := memory.New()
+	err := store.StoreReview(context.Background(), &storage.ReviewContext{
+		Owner:      "owner",
+		Repo:       "repo",
+		PRNumber:   7,
+		ReviewID:   1,
+		ReviewBody: "First pass.",
+		Comments:   []storage.Comment{{Path: "a.go", Line: 1, Body: "Possible nil dereference"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reviews := &reviewsTransport{diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n", config: "enabled: true"}
+	transport := &graphQLCounter{next: reviews}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if transport.calls.Load() == 0 {
+		t.Fatal("expected a failed review thread fetch")
+	}
+	// A subsequent review updates the first review's body; a fallback wouldn't
+	if len(reviews.updates) != 1 {
+		t.Errorf("review body updates = %d, want 1 from the subsequent review", len(reviews.updates))
+	}
+	if prompts := claude.prompts(); len(prompts) != 1 || !strings.Contains(prompts[0], "Possible nil dereference") {
+		t.Errorf("prompt should list the stored comment as existing feedback")
+	}
+}
+
+// unavailableStorage fails review history lookups, as when the database is down.
+type unavailableStorage struct {
+	*memory.Memory
+}
+
+func (unavailableStorage) GetFirstReviewForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.ReviewContext, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestReviewWithStorageUnavailable(t *testing.T) {
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n"
+
+	tests := []struct {
+		name     string
+		config   string
+		reviews  string
+		wantSkip bool
+	}{
+		{"require_storage skips", "require_storage: true", `[]`, true},
+		{"existing bot review skips", "", `[{"id":1,"user":{"login":"shipitai[bot]"}}]`, true},
+		{"no bot review runs as first review", "", `[{"id":1,"user":{"login":"alice"}}]`, false},
+		{"review list failure runs as first review", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reaching Claude shows the review went ahead; fail the call so the test stays offline
+			claude := newFakeClaudeReplies(t, claudeReply{Status: http.StatusBadRequest})
+
+			transport := routeTransport{
+				"/contents/.github/shipitai.yml": configResponse(tt.config),
+				"/pulls/7":                       newResponse(nil, http.StatusOK, diff),
+			}
+			if tt.reviews != "" {
+				transport["/pulls/7/reviews"] = newResponse(nil, http.StatusOK, tt.reviews)
+			}
+			store := unavailableStorage{memory.New()}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7})
+
+			skipped := err == nil && result != nil && result.SkipReason == SkipReasonStorageUnavailable
+			if skipped != tt.wantSkip {
+				t.Errorf("Review() = %+v, %v, skipped = %v, want %v", result, err, skipped, tt.wantSkip)
+			}
+			if reviewed := claude.calls() > 0; reviewed == tt.wantSkip {
+				t.Errorf("Claude called = %v, want %v", reviewed, !tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestReviewSkipsSuspendedInstallation(t *testing.T) {
+	var calls atomic.Int32
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+	})
+	store := memory.New()
+	ctx := context.Background()
+	if err := store.SaveInstallation(ctx, &storage.Installation{InstallationID: 5, OrgLogin: "owner"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetInstallationActive(ctx, 5, false); err != nil {
+		t.Fatal(err)
+	}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(ctx, &ReviewInput{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: 7})
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if result.SkipReason != SkipReasonInstallationSuspended {
+		t.Errorf("SkipReason = %q, want %q", result.SkipReason, SkipReasonInstallationSuspended)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("made %d GitHub requests, want none", n)
+	}
+}
+
+func TestReviewWelcomesNewInstallation(t *testing.T) {
+	// Reviews fail at Claude, after the welcome; the test only checks comments
+	newFakeClaudeReplies(t, claudeReply{Status: http.StatusBadRequest})
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n"
+
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{"first PR is welcomed once", "", []string{"/issues/7/comments"}},
+		{"welcome_message off", "welcome_message: false", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var posted []string
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				switch {
+				case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+					return configResponse(tt.config), nil
+				case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/comments"):
+					mu.Lock()
+					posted = append(posted, strings.TrimPrefix(req.URL.Path, "/repos/owner/repo"))
+					mu.Unlock()
+					return newResponse(req, http.StatusCreated, `{"id":1}`), nil
+				case strings.HasSuffix(req.URL.Path, "/pulls/7"), strings.HasSuffix(req.URL.Path, "/pulls/8"):
+					return newResponse(req, http.StatusOK, diff), nil
+				}
+				return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+			})
+			store := memory.New()
+			ctx := context.Background()
+			if err := store.SaveInstallation(ctx, &storage.Installation{InstallationID: 5, OrgLogin: "owner"}); err != nil {
+				t.Fatal(err)
+			}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			// The installation's first PR, then a later one
+			for _, pr := range []int{7, 8} {
+				_, _ = reviewer.Review(ctx, &ReviewInput{InstallationID: 5, Owner: "owner", Repo: "repo", PRNumber: pr})
+			}
+
+			if strings.Join(posted, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("posted comments to %v, want %v", posted, tt.want)
+			}
+		})
+	}
+}
+
+func TestWelcomeMessageIsNotACommand(t *testing.T) {
+	body := BuildWelcomeMessage("shipitai")
+	if !strings.Contains(body, "@shipitai") {
+		t.Errorf("BuildWelcomeMessage() = %q, want it to name the bot", body)
+	}
+	if cmd := github.ExtractCommand(body, "shipitai"); cmd != "" {
+		t.Errorf("ExtractCommand(welcome) = %q, want none", cmd)
+	}
+}
+
+func TestReviewReportsEmptyResponse(t *testing.T) {
+	refusal := claudeReply{StopReason: "refusal", Usage: map[string]any{"input_tokens": 100, "output_tokens": 0}}
+	apiError := claudeReply{Status: http.StatusBadRequest}
+
+	tests := []struct {
+		name        string
+		claude      claudeReply
+		wantComment bool
+	}{
+		{"refusal is reported", refusal, true},
+		{"API error is not", apiError, false},
+	}
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeClaudeReplies(t, tt.claude)
+
+			var mu sync.Mutex
+			var comments []string
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				switch {
+				case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+					return configResponse(""), nil
+				case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/issues/7/comments"):
+					var comment github.IssueCommentRequest
+					_ = json.NewDecoder(req.Body).Decode(&comment)
+					mu.Lock()
+					comments = append(comments, comment.Body)
+					mu.Unlock()
+					return newResponse(req, http.StatusCreated, `{"id":1}`), nil
+				case strings.HasSuffix(req.URL.Path, "/pulls/7"):
+					return newResponse(req, http.StatusOK, diff), nil
+				}
+				return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+			})
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", memory.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			// A retry at the same head doesn't repeat the notice; a new push does
+			for _, head := range []string{"abc123", "abc123", "def456"} {
+				_, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: head})
+				if err == nil {
+					t.Fatal("Review() error = nil, want the review to fail")
+				}
+
+				var emptyErr *EmptyResponseError
+				if errors.As(err, &emptyErr) != tt.wantComment {
+					t.Errorf("Review() error = %v, want EmptyResponseError: %v", err, tt.wantComment)
+				}
+			}
+			if tt.wantComment {
+				if len(comments) != 2 || !strings.Contains(comments[0], "stop reason: `refusal`") {
+					t.Errorf("posted comments %q, want one per head naming the stop reason", comments)
+				}
+				if cmd := github.ExtractCommand(comments[0], "shipitai"); cmd != "" {
+					t.Errorf("notice parses as command %q, want none", cmd)
+				}
+			} else if len(comments) != 0 {
+				t.Errorf("posted comments %q on an API error, want none", comments)
+			}
+		})
+	}
+}
+
+func TestFilterDiffIncludeAndExclude(t *testing.T) {
+	diff := "diff --git a/src/a.go b/src/a.go\n+a\n" +
+		"diff --git a/src/a.gen.go b/src/a.gen.go\n+gen\n" +
+		"diff --git a/scripts/b.sh b/scripts/b.sh\n+b"
+
+	got := filterDiff(diff, &config.Config{Include: []string{"src/**"}, Exclude: []string{"*.gen.go"}})
+	if want := "diff --git a/src/a.go b/src/a.go\n+a"; got != want {
+		t.Errorf("filterDiff() = %q, want only the included, non-excluded file", got)
+	}
+}
+
+func TestFilterDiffDropsBinaryAndOversizedFiles(t *testing.T) {
+	small := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n package a\n-var x = 1\n+var x = 2"
+	binary := "diff --git a/logo.png b/logo.png\nindex 1234567..89abcde 100644\nBinary files a/logo.png and b/logo.png differ"
+	lockfile := "diff --git a/package-lock.json b/package-lock.json\n--- a/package-lock.json\n+++ b/package-lock.json\n@@ -1 +1,3 @@\n {\n+  \"name\": \"app\",\n+  \"lockfileVersion\": 3"
+	diff := small + "\n" + binary + "\n" + lockfile
+
+	tests := []struct {
+		name    string
+		maxSize int
+		want    string
+	}{
+		{"no size limit", 0, small + "\n" + lockfile},
+		{"lockfile over the limit", 30, small},
+		{"limit counts added and context lines only", 42, small + "\n" + lockfile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterDiff(diff, &config.Config{MaxFileDiffBytes: tt.maxSize}); got != tt.want {
+				t.Errorf("filterDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterDiffCountsAddedLinesStartingWithPlus(t *testing.T) {
+	// The added lines read "++count;", so each diff line starts with "+++"
+	diff := "diff --git a/c.c b/c.c\n--- a/c.c\n+++ b/c.c\n@@ -0,0 +1,2 @@\n+++count;\n+++count;"
+
+	if got := filterDiff(diff, &config.Config{MaxFileDiffBytes: 10}); got != "" {
+		t.Errorf("filterDiff() = %q, want the file dropped for its 16 bytes of added lines", got)
+	}
+	if got := filterDiff(diff, &config.Config{MaxFileDiffBytes: 16}); got != diff {
+		t.Errorf("filterDiff() = %q, want the file kept at the limit", got)
+	}
+}
+
+func TestReviewShowDiffStats(t *testing.T) {
+	newFakeClaude(t, `{"summary":"Looks fine.","approval":"approve","comments":[]}`)
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n package a\n-var x = 1\n+var x = 2\n+var y = 3\n" +
+		"diff --git a/vendor/lib.go b/vendor/lib.go\n--- /dev/null\n+++ b/vendor/lib.go\n@@ -0,0 +1,3 @@\n+package lib\n+\n+func F() {}\n"
+	cfgYAML := "show_diff_stats: true\nexclude: [\"vendor/**\"]"
+	transport := &reviewsTransport{diff: diff, config: cfgYAML}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if len(transport.posted) != 1 {
+		t.Fatalf("posted %d reviews, want 1", len(transport.posted))
+	}
+
+	// The stats describe the reviewed diff, without the excluded vendor file
+	info := ParseDiffInfo(filterDiff(diff, &config.Config{Exclude: []string{"vendor/**"}}))
+	want := diffStatsLine(info) + "\n\nLooks fine."
+	if info.Additions != 2 || info.Deletions != 1 || len(info.Files) != 1 {
+		t.Fatalf("ParseDiffInfo() = %+v, want +2/-1 in one file", info)
+	}
+	if body := transport.posted[0].Body; !strings.HasPrefix(body, want) {
+		t.Errorf("posted body = %q, want it to start with %q", body, want)
+	}
+	if line := diffStatsLine(info); line != "**+2/−1** across 1 file" {
+		t.Errorf("diffStatsLine() = %q", line)
+	}
+}
+
+func TestReviewChunkedBoundsPromptSize(t *testing.T) {
+	claude := newFakeClaude(t, `{"summary":"Looks fine.","approval":"approve","comments":[]}`)
+
+	// Each file gets its own chunk; big.go's contents only fit without a bound
+	files := map[string]string{
+		"big.go":   "package main\n\n// " + strings.Repeat("x", 100*1024) + "\n",
+		"small.go": "package main\n\n// " + strings.Repeat("y", 10*1024) + "\n",
+	}
+	reviews := &reviewsTransport{
+		diff:   largeFileDiff("big.go", 1500) + largeFileDiff("small.go", 1500),
+		config: "context:\n  max_file_size: 200000",
+	}
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if _, path, ok := strings.Cut(req.URL.Path, "/contents/"); ok && path != ".github/shipitai.yml" {
+			content, found := files[path]
+			if !found {
+				return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+			}
+			return newResponse(req, http.StatusOK, `{"encoding":"base64","content":"`+base64.StdEncoding.EncodeToString([]byte(content))+`"}`), nil
+		}
+		return reviews.RoundTrip(req)
+	})
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	reviewer.maxChunkPromptSize = 120 * 1024
+
+	if _, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}); err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	var sizes []int
+	var withContext []string
+	for _, r := range claude.received() {
+		var req struct {
+			System   []struct{ Text string } `json:"system"`
+			Messages []struct {
+				Content []struct{ Text string } `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.Unmarshal(r.Body, &req)
+		text := ""
+		for _, block := range req.System {
+			text += block.Text
+		}
+		for _, msg := range req.Messages {
+			for _, block := range msg.Content {
+				text += block.Text
+			}
+		}
+		sizes = append(sizes, len(text))
+		for _, path := range []string{"big.go", "small.go"} {
+			if strings.Contains(text, "### "+path) {
+				withContext = append(withContext, path)
+			}
+		}
+	}
+	if len(sizes) != 2 {
+		t.Fatalf("sent %d chunk prompts, want 2", len(sizes))
+	}
+	for i, size := range sizes {
+		if size > reviewer.maxChunkPromptSize {
+			t.Errorf("prompt %d size = %d, want at most %d", i, size, reviewer.maxChunkPromptSize)
+		}
+	}
+	if len(withContext) != 1 || withContext[0] != "small.go" {
+		t.Errorf("full file context sent for %v, want only small.go", withContext)
+	}
+}
+
+func TestReviewRequestChangesAtNever(t *testing.T) {
+	newFakeClaude(t, `{"summary":"Unsafe.","approval":"request_changes","comments":[{"path":"a.go","line":2,"body":"SQL injection.","severity":"critical"}]}`)
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n package a\n-var x = 1\n+var x = 2\n+var y = 3\n"
+	transport := &reviewsTransport{diff: diff, config: "request_changes_at: never"}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"})
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if result.Approval != "comment" {
+		t.Errorf("Approval = %q, want comment", result.Approval)
+	}
+	if len(transport.posted) != 1 || transport.posted[0].Event != "COMMENT" {
+		t.Errorf("posted reviews = %+v, want one COMMENT review", transport.posted)
+	}
+}
+
+func TestReviewDowngradesRequestChangesWithoutPlacedComments(t *testing.T) {
+	newFakeClaude(t, `{"summary":"Unsafe.","approval":"request_changes","comments":[{"path":"a.go","line":500,"body":"SQL injection.","severity":"critical"}]}`)
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n package a\n-var x = 1\n+var x = 2\n+var y = 3\n"
+	transport := &reviewsTransport{diff: diff, config: "enabled: true"}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Review(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"})
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if result.Approval != "comment" || result.CommentCount != 0 {
+		t.Errorf("result = %+v, want a comment review without inline comments", result)
+	}
+	if len(transport.posted) != 1 {
+		t.Fatalf("posted %d reviews, want 1", len(transport.posted))
+	}
+	posted := transport.posted[0]
+	if posted.Event != "COMMENT" || len(posted.Comments) != 0 {
+		t.Errorf("posted event = %s with %d comments, want COMMENT with none", posted.Event, len(posted.Comments))
+	}
+	if !strings.Contains(posted.Body, unplacedCommentsHeading+"\n- `a.go:500`: SQL injection.") {
+		t.Errorf("posted body = %q, want the filtered finding listed", posted.Body)
+	}
+}
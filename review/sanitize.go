@@ -0,0 +1,98 @@
+package review
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches an @mention of a user or team ("@alice", "@org/team"),
+// but not an email address or a mention already inside a word.
+var mentionPattern = regexp.MustCompile(`(^|[^\w@/` + "`" + `])(@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:/[A-Za-z0-9][A-Za-z0-9_.-]*)?)\b`)
+
+// issueRefPattern matches an issue or PR reference ("#123", "owner/repo#123"),
+// but not an HTML entity like "&#123;" or a URL fragment.
+var issueRefPattern = regexp.MustCompile(`(^|[^\w&#/` + "`" + `])((?:[A-Za-z0-9][A-Za-z0-9-]*/[A-Za-z0-9_.-]+)?#\d+)\b`)
+
+// sanitizeBody neutralizes @mentions and issue references in Claude-generated text
+// by wrapping them in backticks, so a comment can't notify people or cross-link
+// issues by accident. Fenced code blocks and inline code are left untouched.
+func sanitizeBody(body string) string {
+	var b strings.Builder
+	var fence string
+	lines := strings.SplitAfter(body, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			b.WriteString(line)
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			b.WriteString(line)
+			continue
+		}
+		b.WriteString(sanitizeLine(line))
+	}
+	return b.String()
+}
+
+// fenceMarker returns the run of backticks or tildes opening a fenced code block
+// on a trimmed line, or "" if the line doesn't open one.
+func fenceMarker(trimmed string) string {
+	for _, c := range []string{"`", "~"} {
+		marker := strings.Repeat(c, 3)
+		if !strings.HasPrefix(trimmed, marker) {
+			continue
+		}
+		for strings.HasPrefix(trimmed[len(marker):], c) {
+			marker += c
+		}
+		return marker
+	}
+	return ""
+}
+
+// sanitizeLine neutralizes mentions and references on a line outside fenced code,
+// skipping inline code spans.
+func sanitizeLine(line string) string {
+	var b strings.Builder
+	for line != "" {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			b.WriteString(escapeReferences(line))
+			break
+		}
+		b.WriteString(escapeReferences(line[:start]))
+
+		// A code span closes with a backtick run of the same length
+		run := len(line[start:]) - len(strings.TrimLeft(line[start:], "`"))
+		delim := line[start : start+run]
+		end := strings.Index(line[start+run:], delim)
+		if end < 0 {
+			b.WriteString(delim)
+			line = line[start+run:]
+			continue
+		}
+		spanEnd := start + run + end + run
+		b.WriteString(line[start:spanEnd])
+		line = line[spanEnd:]
+	}
+	return b.String()
+}
+
+// escapeReferences wraps mentions and issue references in backticks.
+func escapeReferences(text string) string {
+	text = mentionPattern.ReplaceAllString(text, "$1`$2`")
+	return issueRefPattern.ReplaceAllString(text, "$1`$2`")
+}
+
+// sanitizeComments applies sanitizeBody to each comment body.
+func sanitizeComments(comments []ClaudeComment) []ClaudeComment {
+	for i, c := range comments {
+		comments[i].Body = sanitizeBody(c.Body)
+	}
+	return comments
+}
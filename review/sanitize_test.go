@@ -0,0 +1,60 @@
+package review
+
+import "testing"
+
+func TestSanitizeBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "mention escaped",
+			body: "Ask @alice before changing this.",
+			want: "Ask `@alice` before changing this.",
+		},
+		{
+			name: "team mention and broadcast escaped",
+			body: "@org/security and @here should know.",
+			want: "`@org/security` and `@here` should know.",
+		},
+		{
+			name: "issue references escaped",
+			body: "This reintroduces #123 (see octo/repo#45).",
+			want: "This reintroduces `#123` (see `octo/repo#45`).",
+		},
+		{
+			name: "inline code untouched",
+			body: "Use `@Override` here and `#42` stays, but @bob doesn't.",
+			want: "Use `@Override` here and `#42` stays, but `@bob` doesn't.",
+		},
+		{
+			name: "fenced code untouched",
+			body: "Decorate it, as @carol did:\n```python\n@cache\ndef f(): # 1\n    return issues[#2]\n```\nThen ping #7.",
+			want: "Decorate it, as `@carol` did:\n```python\n@cache\ndef f(): # 1\n    return issues[#2]\n```\nThen ping `#7`.",
+		},
+		{
+			name: "suggestion block untouched",
+			body: "Fix:\n```suggestion\n// See @dave and #9\n```",
+			want: "Fix:\n```suggestion\n// See @dave and #9\n```",
+		},
+		{
+			name: "emails, entities, and headings left alone",
+			body: "## Notes\nMail dev@example.com; &#123; is a brace; url.html#12 anchors.",
+			want: "## Notes\nMail dev@example.com; &#123; is a brace; url.html#12 anchors.",
+		},
+		{
+			name: "unclosed backtick",
+			body: "A stray ` then @erin",
+			want: "A stray ` then `@erin`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeBody(tt.body); got != tt.want {
+				t.Errorf("sanitizeBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/shipitai/shipitai/metrics"
+	"github.com/shipitai/shipitai/storage"
+)
+
+// summaryMaxTokens is the output limit for a PR summary.
+const summaryMaxTokens = 2048
+
+// SummaryResult contains the result of summarizing a PR.
+type SummaryResult struct {
+	// Summary is Claude's overview of the PR, as posted (without the comment heading).
+	Summary    string
+	CommentID  int64
+	CommentURL string
+	Usage      *storage.TokenUsage
+	DryRun     bool
+
+	// SkipReason is set when no summary was generated; it uses the review skip reasons.
+	SkipReason string
+}
+
+// Summarize handles "@shipitai summarize": it sends the PR's diff to Claude for a
+// high-level overview of what the PR does and posts it as a PR comment. Unlike a
+// review it leaves no inline comments and no approval state. Returns nil when
+// reviews are disabled for the repository.
+func (r *Reviewer) Summarize(ctx context.Context, input *ReviewInput) (*SummaryResult, error) {
+	r.logger.Info("summarizing pull request",
+		"owner", input.Owner,
+		"repo", input.Repo,
+		"pr", input.PRNumber,
+	)
+
+	cfg, err := r.loadConfig(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		r.logger.Info("summary skipped: reviews are disabled")
+		return nil, nil
+	}
+
+	diff, err := retryWithBackoff(ctx, r.logger, "fetchDiff", func() (string, error) {
+		return r.fetchReviewDiff(ctx, input, cfg)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch diff: %w", err)
+	}
+	diff = filterDiff(diff, cfg)
+	if strings.TrimSpace(diff) == "" {
+		r.logger.Info("summary skipped: pull request has an empty diff")
+		return &SummaryResult{SkipReason: SkipReasonEmptyDiff, DryRun: input.DryRun}, nil
+	}
+
+	apiKey, _, err := r.getAPIKey(ctx, input.InstallationID)
+	if err != nil {
+		r.logger.Warn("failed to get API key for summary, using default", "error", err)
+		apiKey = r.claudeAPIKey
+	}
+	model := r.getModel(ctx, input.InstallationID)
+
+	prompt := BuildSummaryPrompt(input.PRTitle, prDescription(input, cfg), diff)
+	claudeResp, err := r.generateSummary(ctx, apiKey, model, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	summary := sanitizeBody(claudeResp.Text)
+	result := &SummaryResult{Summary: summary, Usage: claudeResp.Usage, DryRun: input.DryRun}
+	if input.DryRun {
+		return result, nil
+	}
+
+	comment, err := r.githubClient.CreateIssueComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, buildPRSummaryComment(summary))
+	if err != nil {
+		return nil, fmt.Errorf("failed to post summary: %w", err)
+	}
+	result.CommentID = comment.ID
+	result.CommentURL = comment.HTMLURL
+	return result, nil
+}
+
+// generateSummary calls Claude to summarize the PR and returns usage info.
+func (r *Reviewer) generateSummary(ctx context.Context, apiKey, model, prompt string) (*ClaudeAPIResponse, error) {
+	client := r.newReviewClient(apiKey)
+
+	// Add timeout to prevent hanging indefinitely
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.claudeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	message, err := retryWithBackoff(timeoutCtx, r.logger, "generateSummary", func() (*anthropic.Message, error) {
+		return client.Messages.New(timeoutCtx, anthropic.MessageNewParams{
+			Model:     anthropic.Model(model),
+			MaxTokens: summaryMaxTokens,
+			System: []anthropic.TextBlockParam{
+				{Text: summarySystemPrompt},
+			},
+			Messages: []anthropic.MessageParam{
+				anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+			},
+		})
+	})
+	metrics.ClaudeRequest(time.Since(start), err)
+	if err != nil {
+		return nil, fmt.Errorf("Claude API error: %w", err)
+	}
+
+	usage := &storage.TokenUsage{
+		InputTokens:              message.Usage.InputTokens,
+		OutputTokens:             message.Usage.OutputTokens,
+		CacheReadInputTokens:     message.Usage.CacheReadInputTokens,
+		CacheCreationInputTokens: message.Usage.CacheCreationInputTokens,
+	}
+	r.logger.Info("Claude API usage (summary)",
+		"input_tokens", usage.InputTokens,
+		"output_tokens", usage.OutputTokens,
+		"cache_read_tokens", usage.CacheReadInputTokens,
+	)
+	logTruncation(r.logger, "generateSummary", string(message.StopReason), summaryMaxTokens)
+
+	text, err := messageText(message)
+	if err != nil {
+		return nil, err
+	}
+	return &ClaudeAPIResponse{
+		Text:       text,
+		Usage:      usage,
+		StopReason: string(message.StopReason),
+		MaxTokens:  summaryMaxTokens,
+	}, nil
+}
+
+// buildPRSummaryComment formats a PR summary as a PR comment.
+func buildPRSummaryComment(summary string) string {
+	return "## ShipItAI PR Summary\n\n" + summary + "\n\n---\n*[ShipItAI](https://shipitai.dev) - AI Code Reviews*"
+}
@@ -0,0 +1,117 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+func TestBuildSummaryPrompt(t *testing.T) {
+	prompt := BuildSummaryPrompt("Add caching", "", "diff --git a/a.go b/a.go\n+x\n")
+	for _, want := range []string{"Add caching", "(No description provided)", "+x"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if strings.Contains(prompt, "truncated") {
+		t.Error("small diff should not be marked truncated")
+	}
+
+	line := "+" + strings.Repeat("x", 99) + "\n"
+	prompt = BuildSummaryPrompt("Big", "desc", strings.Repeat(line, maxSummaryDiffSize/len(line)+10))
+	if !strings.Contains(prompt, "truncated") {
+		t.Error("oversized diff should be marked truncated")
+	}
+	if len(prompt) > maxSummaryDiffSize+1024 {
+		t.Errorf("prompt length = %d, want the diff capped at %d", len(prompt), maxSummaryDiffSize)
+	}
+	if !strings.HasSuffix(prompt, "x\n\n</diff>") {
+		t.Error("truncated diff should end on a line boundary")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	newFakeClaudeReplies(t, claudeReply{
+		Text:  "Adds a cache in front of the store, as discussed in #12.",
+		Usage: map[string]any{"input_tokens": 100, "output_tokens": 20},
+	})
+
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n"
+	var mu sync.Mutex
+	var comments []string
+	var reviewPosted bool
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+			return configResponse(""), nil
+		case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/issues/7/comments"):
+			var comment github.IssueCommentRequest
+			_ = json.NewDecoder(req.Body).Decode(&comment)
+			comments = append(comments, comment.Body)
+			return newResponse(req, http.StatusCreated, `{"id":21,"html_url":"https://github.com/owner/repo/pull/7#issuecomment-21"}`), nil
+		case strings.HasSuffix(req.URL.Path, "/pulls/7/reviews"):
+			reviewPosted = true
+		case strings.HasSuffix(req.URL.Path, "/pulls/7"):
+			return newResponse(req, http.StatusOK, diff), nil
+		}
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+	})
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Summarize(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, PRTitle: "Add cache"})
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+
+	if result.CommentID != 21 {
+		t.Errorf("CommentID = %d, want 21", result.CommentID)
+	}
+	if result.Usage == nil || result.Usage.InputTokens != 100 || result.Usage.OutputTokens != 20 {
+		t.Errorf("Usage = %+v, want 100 input and 20 output tokens", result.Usage)
+	}
+	if len(comments) != 1 {
+		t.Fatalf("posted %d comments, want 1", len(comments))
+	}
+	if !strings.HasPrefix(comments[0], "## ShipItAI PR Summary") || !strings.Contains(comments[0], "as discussed in `#12`.") {
+		t.Errorf("comment = %q, want the sanitized summary under the heading", comments[0])
+	}
+	if reviewPosted {
+		t.Error("Summarize should not create a review")
+	}
+}
+
+func TestSummarizeSkipsBinaryOnlyPR(t *testing.T) {
+	claude := newFakeClaudeReplies(t, claudeReply{Status: http.StatusInternalServerError})
+
+	diff := "diff --git a/logo.png b/logo.png\nindex 1234567..89abcde 100644\nBinary files a/logo.png and b/logo.png differ\n"
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/contents/.github/shipitai.yml"):
+			return configResponse(""), nil
+		case strings.HasSuffix(req.URL.Path, "/pulls/7"):
+			return newResponse(req, http.StatusOK, diff), nil
+		}
+		return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+	})
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := reviewer.Summarize(context.Background(), &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7})
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if result == nil || result.SkipReason != SkipReasonEmptyDiff {
+		t.Errorf("Summarize() = %+v, want SkipReason %q", result, SkipReasonEmptyDiff)
+	}
+	if n := claude.calls(); n != 0 {
+		t.Errorf("Claude calls = %d, want none for a PR with nothing to summarize", n)
+	}
+}
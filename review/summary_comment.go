@@ -0,0 +1,86 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/storage"
+)
+
+// BuildSummaryComment formats a review summary as a standalone PR comment with
+// a table counting the review's findings by severity, most severe first.
+func BuildSummaryComment(summary string, comments []ClaudeComment, scale config.SeverityScale) string {
+	var b strings.Builder
+	b.WriteString("## ShipItAI Review Summary\n\n")
+	b.WriteString(summary)
+	b.WriteString("\n\n")
+
+	if len(comments) == 0 {
+		b.WriteString("No findings.")
+	} else {
+		counts := make(map[string]int)
+		for _, c := range comments {
+			counts[scale.Level(c.Severity).Name]++
+		}
+		b.WriteString("| Severity | Findings |\n|----------|----------|\n")
+		for _, s := range scale.Names() {
+			if counts[s] > 0 {
+				fmt.Fprintf(&b, "| %s | %d |\n", s, counts[s])
+			}
+		}
+	}
+
+	b.WriteString("\n\n---\n*[ShipItAI](https://shipitai.dev) - AI Code Reviews*")
+	return b.String()
+}
+
+// publishPRComment posts a bot-maintained PR comment of the given kind ("summary",
+// "triage"), or edits it when commentID is set, and returns its ID and whether it
+// was published. Failures are logged and return commentID unchanged, so they
+// never block the review.
+func (r *Reviewer) publishPRComment(ctx context.Context, input *ReviewInput, kind string, commentID int64, body string) (int64, bool) {
+	if commentID != 0 {
+		if err := r.githubClient.UpdateIssueComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, commentID, body); err != nil {
+			r.logger.Warn("failed to update "+kind+" comment", "comment_id", commentID, "error", err)
+			return commentID, false
+		}
+		return commentID, true
+	}
+
+	comment, err := r.githubClient.CreateIssueComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, body)
+	if err != nil {
+		r.logger.Warn("failed to post "+kind+" comment", "error", err)
+		return 0, false
+	}
+	r.logger.Info("posted "+kind+" comment", "comment_id", comment.ID)
+	return comment.ID, true
+}
+
+// storedFirstReview returns the first review stored for the PR, or an empty one,
+// so a first review run again (e.g. after a fallback) edits the comments it
+// already posted instead of posting second ones.
+func (r *Reviewer) storedFirstReview(ctx context.Context, input *ReviewInput) *storage.ReviewContext {
+	if r.storage == nil {
+		return &storage.ReviewContext{}
+	}
+	first, err := r.storage.GetFirstReviewForPR(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil || first == nil {
+		return &storage.ReviewContext{}
+	}
+	return first
+}
+
+// updateSummaryComment refreshes the summary comment after a subsequent review,
+// posting it if the first review has none yet, and records its ID on the first review.
+func (r *Reviewer) updateSummaryComment(ctx context.Context, input *ReviewInput, firstReview *storage.ReviewContext, body string) {
+	id, _ := r.publishPRComment(ctx, input, "summary", firstReview.SummaryCommentID, body)
+	if id == firstReview.SummaryCommentID || r.storage == nil {
+		return
+	}
+	firstReview.SummaryCommentID = id
+	if err := r.storage.StoreReview(ctx, firstReview); err != nil {
+		r.logger.Warn("failed to store summary comment ID", "error", err)
+	}
+}
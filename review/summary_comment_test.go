@@ -0,0 +1,124 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestBuildSummaryComment(t *testing.T) {
+	got := BuildSummaryComment("Adds retries.", []ClaudeComment{
+		{Severity: "high"}, {Severity: "low"}, {Severity: "high"}, {Severity: ""},
+	}, nil)
+	for _, want := range []string{"Adds retries.", "| high | 2 |", "| medium | 1 |", "| low | 1 |"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("BuildSummaryComment() missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "critical") {
+		t.Errorf("BuildSummaryComment() lists a severity with no findings:\n%s", got)
+	}
+	if strings.Index(got, "| high") > strings.Index(got, "| medium") {
+		t.Errorf("BuildSummaryComment() doesn't list the most severe first:\n%s", got)
+	}
+
+	if got := BuildSummaryComment("Looks good.", nil, nil); !strings.Contains(got, "No findings.") {
+		t.Errorf("BuildSummaryComment() with no comments = %q, want a no-findings note", got)
+	}
+}
+
+// issueCommentsTransport records comments posted and edited on PR 7, serves
+// their latest bodies, and serves an empty thread list, passing everything else
+// to next.
+type issueCommentsTransport struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	created []string
+	edited  map[int64]string
+}
+
+func (t *issueCommentsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var body struct {
+		Body string `json:"body"`
+	}
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return newResponse(req, http.StatusOK, `{"data":{"repository":{"pullRequest":{"reviewThreads":{"nodes":[],"pageInfo":{"hasNextPage":false}}}}}}`), nil
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/issues/7/comments"):
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		t.created = append(t.created, body.Body)
+		return newResponse(req, http.StatusCreated, fmt.Sprintf(`{"id":%d}`, 900+len(t.created))), nil
+	case req.Method == http.MethodPatch && strings.Contains(req.URL.Path, "/issues/comments/"):
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		t.edited[issueCommentID(req)] = body.Body
+		return newResponse(req, http.StatusOK, `{}`), nil
+	case req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/issues/comments/"):
+		id := issueCommentID(req)
+		latest, ok := t.edited[id]
+		if !ok && id > 900 && id <= 900+int64(len(t.created)) {
+			latest, ok = t.created[id-901], true
+		}
+		if !ok {
+			return newResponse(req, http.StatusNotFound, `{"message":"Not Found"}`), nil
+		}
+		resp, _ := json.Marshal(map[string]any{"id": id, "body": latest})
+		return newResponse(req, http.StatusOK, string(resp)), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+func issueCommentID(req *http.Request) int64 {
+	var id int64
+	fmt.Sscanf(req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:], "%d", &id)
+	return id
+}
+
+func TestReviewSummaryAsComment(t *testing.T) {
+	review := func(summary string) string {
+		return `{"summary":"` + summary + `","approval":"comment","comments":[{"path":"a.go","line":1,"body":"Check this.","severity":"high"}]}`
+	}
+	newFakeClaude(t, review("First pass."), review("Second pass."))
+
+	transport := &issueCommentsTransport{
+		next:   &reviewsTransport{diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1 @@\n+x\n", config: "summary_as_comment: true"},
+		edited: make(map[int64]string),
+	}
+	store := memory.New()
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}
+	ctx := context.Background()
+
+	if _, err := reviewer.Review(ctx, input); err != nil {
+		t.Fatalf("first Review() error = %v", err)
+	}
+	if len(transport.created) != 1 || !strings.Contains(transport.created[0], "First pass.") || !strings.Contains(transport.created[0], "| high | 1 |") {
+		t.Fatalf("summary comments posted = %q, want one with the summary and severity table", transport.created)
+	}
+	first, _ := store.GetFirstReviewForPR(ctx, 0, "owner", "repo", 7)
+	if first == nil || first.SummaryCommentID != 901 {
+		t.Fatalf("stored first review = %+v, want SummaryCommentID 901", first)
+	}
+
+	if _, err := reviewer.Review(ctx, input); err != nil {
+		t.Fatalf("subsequent Review() error = %v", err)
+	}
+	if len(transport.created) != 1 {
+		t.Errorf("subsequent review posted another summary comment: %q", transport.created)
+	}
+	if got := transport.edited[901]; !strings.Contains(got, "Second pass.") {
+		t.Errorf("edited summary comment = %q, want it to carry the new summary", got)
+	}
+}
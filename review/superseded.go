@@ -0,0 +1,118 @@
+package review
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// ParseModifiedBaseLines parses a unified diff and returns, per file, the line
+// numbers in the OLD version that were modified or deleted. Pure insertions
+// don't touch existing lines and are not included.
+func ParseModifiedBaseLines(diff string) DiffLineMap {
+	result := make(DiffLineMap)
+
+	var currentFile string
+	var oldLine int
+	var inHunk bool
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "--- a/") {
+			currentFile = strings.TrimPrefix(line, "--- a/")
+			inHunk = false
+			continue
+		}
+		if strings.HasPrefix(line, "--- /dev/null") {
+			currentFile = ""
+			inHunk = false
+			continue
+		}
+		if strings.HasPrefix(line, "+++ ") {
+			continue
+		}
+		if matches := hunkHeaderRegex.FindStringSubmatch(line); matches != nil {
+			if currentFile == "" {
+				continue
+			}
+			// matches[1] is the starting line in the old file
+			oldLine, _ = strconv.Atoi(matches[1])
+			inHunk = true
+			continue
+		}
+		if !inHunk || currentFile == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "-"):
+			if result[currentFile] == nil {
+				result[currentFile] = make(map[int]bool)
+			}
+			result[currentFile][oldLine] = true
+			oldLine++
+		case strings.HasPrefix(line, " "), line == "":
+			oldLine++
+		case strings.HasPrefix(line, "diff --git"):
+			inHunk = false
+		}
+	}
+
+	return result
+}
+
+// filterSupersededComments drops comments on lines that a later commit modified,
+// given the old-side modified lines of the diff from the reviewed commit onward.
+func filterSupersededComments(comments []ClaudeComment, modified DiffLineMap, logger *slog.Logger) []ClaudeComment {
+	kept := make([]ClaudeComment, 0, len(comments))
+	for _, c := range comments {
+		if modified.IsValidCommentLine(c.Path, c.Line) {
+			if logger != nil {
+				logger.Info("dropped comment on line modified by a later commit",
+					"path", c.Path,
+					"line", c.Line,
+				)
+			}
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// withLatestHead returns the input pinned to the pull request's current head.
+// The PR diff always reflects the latest head, which can be newer than the push
+// that triggered the review when several pushes arrive together; anchoring
+// comments to that head keeps them on the lines the diff describes.
+func (r *Reviewer) withLatestHead(ctx context.Context, input *ReviewInput) *ReviewInput {
+	pr, err := r.githubClient.GetPullRequest(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil || pr.Head == nil || pr.Head.SHA == "" || pr.Head.SHA == input.HeadSHA {
+		return input
+	}
+	r.logger.Info("pull request head moved, reviewing latest head",
+		"event_head", input.HeadSHA,
+		"latest_head", pr.Head.SHA,
+	)
+	pinned := *input
+	pinned.HeadSHA = pr.Head.SHA
+	return &pinned
+}
+
+// dropSupersededComments removes comments on lines changed by commits pushed
+// while the review was running. Errors are non-fatal: comments are kept.
+func (r *Reviewer) dropSupersededComments(ctx context.Context, input *ReviewInput, comments []ClaudeComment) []ClaudeComment {
+	if len(comments) == 0 {
+		return comments
+	}
+	pr, err := r.githubClient.GetPullRequest(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber)
+	if err != nil || pr.Head == nil || pr.Head.SHA == "" || pr.Head.SHA == input.HeadSHA {
+		return comments
+	}
+
+	diff, err := r.githubClient.CompareDiff(ctx, input.InstallationID, input.Owner, input.Repo, input.HeadSHA, pr.Head.SHA)
+	if err != nil {
+		r.logger.Warn("failed to fetch diff of later commits, keeping all comments", "error", err)
+		return comments
+	}
+	return filterSupersededComments(comments, ParseModifiedBaseLines(diff), r.logger)
+}
@@ -0,0 +1,126 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/shipitai/shipitai/github"
+)
+
+// laterCommitsDiff is the diff from the reviewed head to a commit pushed during
+// the review: main.go line 10 is rewritten, util.go line 30 is deleted, and a
+// line is inserted in main.go without touching existing lines.
+const laterCommitsDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -9,3 +9,3 @@ func main() {
+ 	cfg := load()
+-	f, _ := os.Open(cfg.Path)
++	f, err := os.Open(cfg.Path)
+ 	defer f.Close()
+@@ -40,2 +40,3 @@ func run() {
+ 	start()
++	wait()
+ 	stop()
+diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -29,3 +29,2 @@ func helper() {
+ 	a := 1
+-	b := 2
+ 	return a
+`
+
+func TestParseModifiedBaseLines(t *testing.T) {
+	modified := ParseModifiedBaseLines(laterCommitsDiff)
+
+	tests := []struct {
+		path string
+		line int
+		want bool
+	}{
+		{"main.go", 10, true},  // rewritten
+		{"main.go", 9, false},  // context
+		{"main.go", 41, false}, // insertion point, existing line untouched
+		{"util.go", 30, true},  // deleted
+		{"util.go", 31, false},
+		{"other.go", 10, false},
+	}
+
+	for _, tt := range tests {
+		if got := modified.IsValidCommentLine(tt.path, tt.line); got != tt.want {
+			t.Errorf("modified[%s:%d] = %v, want %v", tt.path, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDropSupersededCommentsMultiCommitPush(t *testing.T) {
+	comments := []ClaudeComment{
+		{Path: "main.go", Line: 10, Body: "Handle the error from os.Open"},
+		{Path: "main.go", Line: 20, Body: "Consider a timeout"},
+		{Path: "util.go", Line: 30, Body: "Unused variable b"},
+	}
+
+	tests := []struct {
+		name      string
+		latest    string
+		wantPaths []string
+	}{
+		{
+			name:      "later commit pushed during review",
+			latest:    "ccc333",
+			wantPaths: []string{"main.go:20"},
+		},
+		{
+			name:      "head unchanged",
+			latest:    "bbb222",
+			wantPaths: []string{"main.go:10", "main.go:20", "util.go:30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := routeTransport{
+				"/pulls/7":                 newResponse(nil, http.StatusOK, `{"number":7,"head":{"sha":"`+tt.latest+`"}}`),
+				"/compare/bbb222...ccc333": newResponse(nil, http.StatusOK, laterCommitsDiff),
+			}
+			reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "bbb222"}
+
+			got := reviewer.dropSupersededComments(context.Background(), input, comments)
+
+			var paths []string
+			for _, c := range got {
+				paths = append(paths, c.Path+":"+itoa(c.Line))
+			}
+			if len(paths) != len(tt.wantPaths) {
+				t.Fatalf("kept %v, want %v", paths, tt.wantPaths)
+			}
+			for i := range paths {
+				if paths[i] != tt.wantPaths[i] {
+					t.Errorf("kept %v, want %v", paths, tt.wantPaths)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWithLatestHead(t *testing.T) {
+	transport := routeTransport{
+		"/pulls/7": newResponse(nil, http.StatusOK, `{"number":7,"head":{"sha":"ccc333"}}`),
+	}
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "aaa111"}
+
+	got := reviewer.withLatestHead(context.Background(), input)
+	if got.HeadSHA != "ccc333" {
+		t.Errorf("HeadSHA = %q, want latest head ccc333", got.HeadSHA)
+	}
+	if input.HeadSHA != "aaa111" {
+		t.Errorf("input was mutated: HeadSHA = %q", input.HeadSHA)
+	}
+}
@@ -0,0 +1,151 @@
+package review
+
+import (
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// todoWindow is how many lines above or below a comment are checked for
+// an author-written TODO/FIXME marker.
+const todoWindow = 3
+
+// todoMarkerRegex matches TODO/FIXME markers and captures the note that follows.
+var todoMarkerRegex = regexp.MustCompile(`\b(?:TODO|FIXME)\b(?:\([^)]*\))?:?\s*(.*)`)
+
+// todoStopWords are common words ignored when comparing a TODO note to a comment.
+var todoStopWords = map[string]bool{
+	"this": true, "that": true, "here": true, "there": true, "should": true,
+	"would": true, "could": true, "with": true, "from": true, "into": true,
+	"when": true, "what": true, "which": true, "will": true, "need": true,
+	"needs": true, "make": true, "sure": true, "consider": true, "later": true,
+	"maybe": true, "also": true, "have": true, "been": true, "some": true,
+}
+
+// AddedLineMap maps file paths to the content of lines added in the diff,
+// keyed by line number in the new version of the file.
+type AddedLineMap map[string]map[int]string
+
+// ParseAddedLines parses a unified diff and returns the content of added lines.
+func ParseAddedLines(diff string) AddedLineMap {
+	result := make(AddedLineMap)
+
+	var currentFile string
+	var currentLine int
+	var inHunk bool
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ b/") {
+			currentFile = strings.TrimPrefix(line, "+++ b/")
+			inHunk = false
+			continue
+		}
+		if strings.HasPrefix(line, "+++ /dev/null") {
+			currentFile = ""
+			inHunk = false
+			continue
+		}
+		if matches := hunkHeaderRegex.FindStringSubmatch(line); matches != nil {
+			if currentFile == "" {
+				continue
+			}
+			currentLine, _ = strconv.Atoi(matches[3])
+			inHunk = true
+			continue
+		}
+		if !inHunk || currentFile == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "-"), strings.HasPrefix(line, "\\"):
+			// Deleted lines and "\ No newline" markers don't exist in the new file
+		case strings.HasPrefix(line, "+"):
+			if result[currentFile] == nil {
+				result[currentFile] = make(map[int]string)
+			}
+			result[currentFile][currentLine] = strings.TrimPrefix(line, "+")
+			currentLine++
+		case strings.HasPrefix(line, " "), line == "":
+			currentLine++
+		case strings.HasPrefix(line, "diff --git"):
+			inHunk = false
+		}
+	}
+
+	return result
+}
+
+// isAcknowledgedByTODO reports whether an added line near the comment carries a
+// TODO/FIXME note describing the same concern, e.g. "// TODO: handle error" next
+// to a comment asking to handle the error. Bare markers with no note don't count.
+func isAcknowledgedByTODO(comment ClaudeComment, added AddedLineMap) bool {
+	fileLines := added[comment.Path]
+	if len(fileLines) == 0 {
+		return false
+	}
+
+	commentWords := keywords(comment.Body)
+	for line := comment.Line - todoWindow; line <= comment.Line+todoWindow; line++ {
+		content, ok := fileLines[line]
+		if !ok {
+			continue
+		}
+		matches := todoMarkerRegex.FindStringSubmatch(content)
+		if matches == nil {
+			continue
+		}
+		noteWords := keywords(matches[1])
+		if len(noteWords) == 0 {
+			continue
+		}
+		overlap := 0
+		for w := range noteWords {
+			if commentWords[w] {
+				overlap++
+			}
+		}
+		// Require at least half of the note's keywords to appear in the comment
+		if overlap > 0 && overlap*2 >= len(noteWords) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterAcknowledgedComments drops comments whose concern the author already
+// acknowledged with a nearby TODO/FIXME in the added lines.
+func filterAcknowledgedComments(comments []ClaudeComment, added AddedLineMap, logger *slog.Logger) []ClaudeComment {
+	kept := make([]ClaudeComment, 0, len(comments))
+	for _, c := range comments {
+		if isAcknowledgedByTODO(c, added) {
+			if logger != nil {
+				logger.Info("dropped comment acknowledged by TODO",
+					"path", c.Path,
+					"line", c.Line,
+					"body_preview", truncateString(c.Body, 50),
+				)
+			}
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// keywords extracts normalized significant words (4+ letters, not stop words) from text.
+func keywords(text string) map[string]bool {
+	words := make(map[string]bool)
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	for _, f := range fields {
+		if len(f) < 4 || todoStopWords[f] {
+			continue
+		}
+		// Crude plural folding so "errors" matches "error"
+		words[strings.TrimSuffix(f, "s")] = true
+	}
+	return words
+}
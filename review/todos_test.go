@@ -0,0 +1,81 @@
+package review
+
+import "testing"
+
+func TestIsAcknowledgedByTODO(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -10,3 +10,6 @@ func main() {
+ 	cfg := load()
++	// TODO: handle error from open
++	f, _ := os.Open(cfg.Path)
++	// TODO
++	data := read(f)
+ 	process(data)
+`
+	added := ParseAddedLines(diff)
+
+	tests := []struct {
+		name    string
+		comment ClaudeComment
+		want    bool
+	}{
+		{
+			name:    "TODO present for same concern",
+			comment: ClaudeComment{Path: "main.go", Line: 12, Body: "You should handle the error returned by os.Open here."},
+			want:    true,
+		},
+		{
+			name:    "TODO present for different concern",
+			comment: ClaudeComment{Path: "main.go", Line: 12, Body: "Consider closing the file with defer."},
+			want:    false,
+		},
+		{
+			name:    "bare TODO without note",
+			comment: ClaudeComment{Path: "main.go", Line: 14, Body: "Reading the whole file into memory may be expensive."},
+			want:    false,
+		},
+		{
+			name:    "TODO absent in file",
+			comment: ClaudeComment{Path: "other.go", Line: 12, Body: "You should handle the error returned by os.Open here."},
+			want:    false,
+		},
+		{
+			name:    "TODO outside window",
+			comment: ClaudeComment{Path: "main.go", Line: 30, Body: "You should handle the error returned by os.Open here."},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAcknowledgedByTODO(tt.comment, added); got != tt.want {
+				t.Errorf("isAcknowledgedByTODO() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterAcknowledgedComments(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,3 @@
+ package main
++// FIXME: validate input length
++func parse(s string) {}
+`
+	comments := []ClaudeComment{
+		{Path: "main.go", Line: 3, Body: "Input length is not validated."},
+		{Path: "main.go", Line: 3, Body: "parse should return an error."},
+	}
+
+	got := filterAcknowledgedComments(comments, ParseAddedLines(diff), nil)
+	if len(got) != 1 {
+		t.Fatalf("filterAcknowledgedComments() kept %d comments, want 1", len(got))
+	}
+	if got[0].Body != "parse should return an error." {
+		t.Errorf("kept comment = %q, want the unacknowledged one", got[0].Body)
+	}
+}
@@ -0,0 +1,172 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage"
+)
+
+// triageNote is the review body line pointing authors at the triage comment when
+// it replaces inline comments.
+const triageNote = "Findings are listed in the triage checklist comment on this PR."
+
+// triageItem is one finding in the triage checklist.
+type triageItem struct {
+	Severity string
+	Path     string
+	Line     int
+	Title    string
+	Done     bool
+}
+
+// triageItemPattern matches a checklist line written by buildTriageComment.
+var triageItemPattern = regexp.MustCompile("^- \\[([ xX])\\] \\*\\*([^*]+)\\*\\* `([^`]+):(\\d+)` (.+)$")
+
+// triageItemsFromComments turns review comments into open checklist items.
+func triageItemsFromComments(comments []ClaudeComment, scale config.SeverityScale) []triageItem {
+	items := make([]triageItem, 0, len(comments))
+	for _, c := range comments {
+		items = append(items, triageItem{
+			Severity: scale.Level(c.Severity).Name,
+			Path:     c.Path,
+			Line:     c.Line,
+			Title:    triageTitle(c.Body),
+		})
+	}
+	return items
+}
+
+// triageTitle returns the first non-empty line of a comment body.
+func triageTitle(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return "(no description)"
+}
+
+// buildTriageComment formats the findings as a checklist, most severe first.
+func buildTriageComment(items []triageItem, scale config.SeverityScale) string {
+	sorted := make([]triageItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return scale.Rank(sorted[i].Severity) > scale.Rank(sorted[j].Severity)
+	})
+
+	done := 0
+	for _, item := range sorted {
+		if item.Done {
+			done++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("## ShipItAI Triage\n\n")
+	if len(sorted) == 0 {
+		b.WriteString("No findings.")
+	} else {
+		fmt.Fprintf(&b, "%d of %d findings addressed. Tick items off as you address them.\n\n", done, len(sorted))
+		for _, item := range sorted {
+			box := " "
+			if item.Done {
+				box = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] **%s** `%s:%d` %s\n", box, item.Severity, item.Path, item.Line, item.Title)
+		}
+	}
+
+	b.WriteString("\n\n---\n*[ShipItAI](https://shipitai.dev) - AI Code Reviews*")
+	return b.String()
+}
+
+// parseTriageComment reads the checklist items back out of a triage comment,
+// including any boxes reviewers ticked by hand.
+func parseTriageComment(body string) []triageItem {
+	var items []triageItem
+	for _, line := range strings.Split(body, "\n") {
+		m := triageItemPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(m[4])
+		items = append(items, triageItem{
+			Severity: m[2],
+			Path:     m[3],
+			Line:     lineNum,
+			Title:    m[5],
+			Done:     m[1] != " ",
+		})
+	}
+	return items
+}
+
+// mergeTriageItems updates the checklist after a subsequent review: earlier items
+// keep their ticks and are ticked when their review thread was resolved, by a
+// reviewer or by this review, and new findings are added unticked unless they
+// repeat an item already listed.
+func mergeTriageItems(prior []triageItem, comments []ClaudeComment, threads []github.ReviewThread, resolvedThreads []string, scale config.SeverityScale) []triageItem {
+	resolvedIDs := make(map[string]bool, len(resolvedThreads))
+	for _, id := range resolvedThreads {
+		resolvedIDs[id] = true
+	}
+
+	items := make([]triageItem, 0, len(prior)+len(comments))
+	listed := make(map[string]bool)
+	for _, item := range prior {
+		if !item.Done {
+			for _, t := range threads {
+				if (t.IsResolved || resolvedIDs[t.ID]) && t.Path == item.Path && len(t.Comments) > 0 && strings.Contains(t.Comments[0].Body, item.Title) {
+					item.Done = true
+					break
+				}
+			}
+		}
+		items = append(items, item)
+		listed[item.Path+"\x00"+item.Title] = true
+	}
+
+	for _, item := range triageItemsFromComments(comments, scale) {
+		if key := item.Path + "\x00" + item.Title; !listed[key] {
+			items = append(items, item)
+			listed[key] = true
+		}
+	}
+	return items
+}
+
+// updateTriageComment refreshes the triage checklist after a subsequent review,
+// posting it if the first review has none yet, and records its ID on the first
+// review. Reports whether the checklist is up to date on the PR.
+func (r *Reviewer) updateTriageComment(ctx context.Context, input *ReviewInput, cfg *config.Config, firstReview *storage.ReviewContext, comments []ClaudeComment, threads []github.ReviewThread, resolvedThreads []string) bool {
+	var prior []triageItem
+	if id := firstReview.TriageCommentID; id != 0 {
+		existing, err := r.githubClient.GetIssueComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, id)
+		if err != nil {
+			// Editing without the current checklist would drop reviewers' ticks
+			r.logger.Warn("failed to fetch triage comment", "comment_id", id, "error", err)
+			return false
+		}
+		prior = parseTriageComment(existing.Body)
+	}
+
+	body := buildTriageComment(mergeTriageItems(prior, comments, threads, resolvedThreads, cfg.SeverityScale), cfg.SeverityScale)
+	id, ok := r.publishPRComment(ctx, input, "triage", firstReview.TriageCommentID, body)
+	if !ok {
+		return false
+	}
+	if id != firstReview.TriageCommentID && r.storage != nil {
+		firstReview.TriageCommentID = id
+		if err := r.storage.StoreReview(ctx, firstReview); err != nil {
+			r.logger.Warn("failed to store triage comment ID", "error", err)
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package review
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/shipitai/shipitai/config"
+	"github.com/shipitai/shipitai/github"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+func TestBuildTriageComment(t *testing.T) {
+	got := buildTriageComment(triageItemsFromComments([]ClaudeComment{
+		{Path: "a.go", Line: 3, Body: "Consider renaming.", Severity: "low"},
+		{Path: "b.go", Line: 10, Body: "\nNil pointer dereference.\n\nDetails follow.", Severity: "critical"},
+		{Path: "a.go", Line: 7, Body: "Missing error check.", Severity: ""},
+	}, nil), nil)
+
+	want := []string{
+		"0 of 3 findings addressed.",
+		"- [ ] **critical** `b.go:10` Nil pointer dereference.",
+		"- [ ] **medium** `a.go:7` Missing error check.",
+		"- [ ] **low** `a.go:3` Consider renaming.",
+	}
+	last := -1
+	for _, w := range want {
+		i := strings.Index(got, w)
+		if i < 0 {
+			t.Fatalf("buildTriageComment() missing %q:\n%s", w, got)
+		}
+		if i < last {
+			t.Errorf("buildTriageComment() lists %q out of severity order:\n%s", w, got)
+		}
+		last = i
+	}
+	if strings.Contains(got, "Details follow.") {
+		t.Errorf("buildTriageComment() includes more than the first line of a finding:\n%s", got)
+	}
+
+	if got := buildTriageComment(nil, nil); !strings.Contains(got, "No findings.") {
+		t.Errorf("buildTriageComment() with no items = %q, want a no-findings note", got)
+	}
+}
+
+func TestParseTriageCommentRoundTrip(t *testing.T) {
+	items := []triageItem{
+		{Severity: "high", Path: "a.go", Line: 1, Title: "Check this.", Done: true},
+		{Severity: "low", Path: "pkg/b.go", Line: 20, Title: "Use `strings.Cut` here."},
+	}
+	got := parseTriageComment(buildTriageComment(items, nil))
+	if len(got) != len(items) {
+		t.Fatalf("parseTriageComment() = %+v, want %+v", got, items)
+	}
+	for i := range items {
+		if got[i] != items[i] {
+			t.Errorf("item %d = %+v, want %+v", i, got[i], items[i])
+		}
+	}
+}
+
+func TestMergeTriageItems(t *testing.T) {
+	prior := []triageItem{
+		{Severity: "high", Path: "a.go", Line: 1, Title: "Ticked by hand.", Done: true},
+		{Severity: "high", Path: "a.go", Line: 5, Title: "Thread resolved."},
+		{Severity: "medium", Path: "a.go", Line: 9, Title: "Resolved by this review."},
+		{Severity: "low", Path: "b.go", Line: 2, Title: "Still open."},
+	}
+	threads := []github.ReviewThread{
+		{ID: "T1", IsResolved: true, Path: "a.go", Comments: []github.ThreadComment{{Body: "**high**: Thread resolved."}}},
+		{ID: "T2", Path: "a.go", Comments: []github.ThreadComment{{Body: "Resolved by this review."}}},
+		{ID: "T3", Path: "b.go", Comments: []github.ThreadComment{{Body: "Still open."}}},
+	}
+	comments := []ClaudeComment{
+		{Path: "b.go", Line: 4, Body: "Still open.", Severity: "low"}, // repeats an item on a moved line
+		{Path: "c.go", Line: 1, Body: "New finding.", Severity: "high"},
+	}
+
+	got := mergeTriageItems(prior, comments, threads, []string{"T2"}, nil)
+
+	wantDone := map[string]bool{
+		"Ticked by hand.":          true,
+		"Thread resolved.":         true,
+		"Resolved by this review.": true,
+		"Still open.":              false,
+		"New finding.":             false,
+	}
+	if len(got) != len(wantDone) {
+		t.Fatalf("mergeTriageItems() = %+v, want %d items", got, len(wantDone))
+	}
+	for _, item := range got {
+		if item.Done != wantDone[item.Title] {
+			t.Errorf("item %q Done = %v, want %v", item.Title, item.Done, wantDone[item.Title])
+		}
+	}
+}
+
+func TestReviewTriageCommentUpdatesInPlace(t *testing.T) {
+	responses := []string{
+		`{"summary":"First pass.","approval":"comment","comments":[{"path":"a.go","line":1,"body":"Check this.","severity":"high"},{"path":"a.go","line":2,"body":"Nil deref.","severity":"critical"}]}`,
+		`{"summary":"Second pass.","approval":"comment","comments":[{"path":"a.go","line":2,"body":"Handle the error.","severity":"medium"}]}`,
+	}
+	newFakeClaude(t, responses...)
+
+	reviews := &reviewsTransport{
+		diff:   "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		config: "triage_comment: " + config.TriageCommentInstead,
+	}
+	transport := &issueCommentsTransport{next: reviews, edited: make(map[int64]string)}
+	store := memory.New()
+	reviewer := NewReviewer(github.NewClientWithTransport(transport), "key", store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	input := &ReviewInput{Owner: "owner", Repo: "repo", PRNumber: 7, HeadSHA: "abc123"}
+	ctx := context.Background()
+
+	if _, err := reviewer.Review(ctx, input); err != nil {
+		t.Fatalf("first Review() error = %v", err)
+	}
+	if len(transport.created) != 1 || !strings.Contains(transport.created[0], "- [ ] **critical** `a.go:2` Nil deref.") {
+		t.Fatalf("triage comments posted = %q, want one listing the findings", transport.created)
+	}
+	if len(reviews.posted) != 1 || len(reviews.posted[0].Comments) != 0 || !strings.Contains(reviews.posted[0].Body, triageNote) {
+		t.Fatalf("posted reviews = %+v, want one without inline comments pointing at the checklist", reviews.posted)
+	}
+	if first, _ := store.GetFirstReviewForPR(ctx, 0, "owner", "repo", 7); first == nil || first.TriageCommentID != 901 {
+		t.Fatalf("stored first review = %+v, want TriageCommentID 901", first)
+	}
+
+	// A reviewer ticks off the first finding
+	transport.edited[901] = strings.Replace(transport.created[0], "- [ ] **high**", "- [x] **high**", 1)
+
+	if _, err := reviewer.Review(ctx, input); err != nil {
+		t.Fatalf("subsequent Review() error = %v", err)
+	}
+	if len(transport.created) != 1 {
+		t.Errorf("subsequent review posted another triage comment: %q", transport.created)
+	}
+	got := transport.edited[901]
+	for _, want := range []string{
+		"1 of 3 findings addressed.",
+		"- [x] **high** `a.go:1` Check this.",
+		"- [ ] **critical** `a.go:2` Nil deref.",
+		"- [ ] **medium** `a.go:2` Handle the error.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("edited triage comment missing %q:\n%s", want, got)
+		}
+	}
+	if len(reviews.posted) != 2 || len(reviews.posted[1].Comments) != 0 {
+		t.Errorf("subsequent review = %+v, want no inline comments", reviews.posted)
+	}
+}
@@ -0,0 +1,32 @@
+package review
+
+import (
+	"context"
+
+	"github.com/shipitai/shipitai/config"
+)
+
+// welcomeInstallation posts the one-time welcome comment on the first pull request
+// a new installation sends, unless welcome_message is off. Storage tracks which
+// installations were welcomed; without it no welcome is posted. Failures are
+// logged and never block the review.
+func (r *Reviewer) welcomeInstallation(ctx context.Context, input *ReviewInput, cfg *config.Config) {
+	if r.storage == nil || input.DryRun || input.PRNumber == 0 || !cfg.IsWelcomeMessageEnabled() {
+		return
+	}
+
+	first, err := r.storage.MarkInstallationWelcomed(ctx, input.InstallationID)
+	if err != nil {
+		r.logger.Warn("failed to check whether installation was welcomed", "error", err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	if _, err := r.githubClient.CreateIssueComment(ctx, input.InstallationID, input.Owner, input.Repo, input.PRNumber, BuildWelcomeMessage(r.botNameOrDefault())); err != nil {
+		r.logger.Warn("failed to post welcome comment", "error", err)
+		return
+	}
+	r.logger.Info("posted welcome comment", "installation_id", input.InstallationID, "pr", input.PRNumber)
+}
@@ -0,0 +1,298 @@
+// Package buffered provides a write-behind wrapper around a storage backend that
+// batches review writes. It trades durability for throughput: reviews stored
+// since the last flush are lost if the process dies without closing the store.
+package buffered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shipitai/shipitai/storage"
+)
+
+const (
+	// DefaultFlushInterval is how often buffered reviews are written.
+	DefaultFlushInterval = 2 * time.Second
+
+	// DefaultMaxBatch is how many buffered reviews trigger a flush before the interval.
+	DefaultMaxBatch = 50
+
+	// flushTimeout bounds a single flush.
+	flushTimeout = 30 * time.Second
+)
+
+// Store wraps a storage backend, buffering StoreReview calls and writing them in
+// batches periodically or once maxBatch are pending. Storing the same review again
+// before a flush replaces the pending write. Reads of reviews the buffer holds
+// flush it first, so callers always see their own writes. All other operations
+// go straight to the wrapped backend.
+type Store struct {
+	storage.Storage
+
+	logger   *slog.Logger
+	maxBatch int
+
+	mu      sync.Mutex
+	pending []*storage.ReviewContext // oldest first, one per review
+	closed  bool
+
+	flushMu sync.Mutex    // Held while writing a batch, so reads wait for it to land
+	kick    chan struct{} // Signals a full batch
+	done    chan struct{} // Closed to stop the flush loop
+	stopped chan struct{} // Closed when the flush loop exits
+}
+
+// New creates a buffered store around inner and starts its flush loop. A
+// flushInterval or maxBatch of 0 or less uses the default. Close must be called
+// on shutdown to write the remaining reviews.
+func New(inner storage.Storage, flushInterval time.Duration, maxBatch int, logger *slog.Logger) *Store {
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBatch
+	}
+	s := &Store{
+		Storage:  inner,
+		logger:   logger,
+		maxBatch: maxBatch,
+		kick:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go s.run(flushInterval)
+	return s
+}
+
+// run flushes on every tick and whenever a batch fills up, until Close.
+func (s *Store) run(interval time.Duration) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		if err := s.Flush(ctx); err != nil {
+			s.logger.Error("failed to flush buffered reviews", "error", err)
+		}
+		cancel()
+	}
+}
+
+// StoreReview buffers a review for the next flush. After Close it writes through.
+func (s *Store) StoreReview(ctx context.Context, review *storage.ReviewContext) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return s.Storage.StoreReview(ctx, review)
+	}
+
+	rev := copyReview(review)
+	replaced := false
+	for i, p := range s.pending {
+		if sameReview(p, rev) {
+			s.pending[i] = rev
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.pending = append(s.pending, rev)
+	}
+	full := len(s.pending) >= s.maxBatch
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush writes all buffered reviews to the wrapped backend. A failed batch is
+// dropped, since retrying it could block later writes indefinitely.
+func (s *Store) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	return s.flushLocked(ctx)
+}
+
+// flushLocked writes the pending batch. Callers hold flushMu.
+func (s *Store) flushLocked(ctx context.Context) error {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if batcher, ok := s.Storage.(storage.ReviewBatcher); ok {
+		if err := batcher.StoreReviews(ctx, batch); err != nil {
+			return fmt.Errorf("dropped %d reviews: %w", len(batch), err)
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, review := range batch {
+		if err := s.Storage.StoreReview(ctx, review); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("dropped %d of %d reviews: %w", len(errs), len(batch), errors.Join(errs...))
+	}
+	return nil
+}
+
+// flushFor flushes the buffer before a read if it holds a review the read would
+// return. It always waits for an in-progress flush to land first.
+func (s *Store) flushFor(ctx context.Context, match func(*storage.ReviewContext) bool) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	hit := false
+	for _, p := range s.pending {
+		if match(p) {
+			hit = true
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if !hit {
+		return nil
+	}
+	if err := s.flushLocked(ctx); err != nil {
+		return fmt.Errorf("failed to flush buffered reviews: %w", err)
+	}
+	return nil
+}
+
+// Close stops the flush loop and writes the remaining reviews. Reviews stored
+// after Close are written through. It does not close the wrapped backend.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	<-s.stopped
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	return s.Flush(ctx)
+}
+
+// GetReview flushes a pending write of the review, then reads it.
+func (s *Store) GetReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, reviewID int64) (*storage.ReviewContext, error) {
+	if err := s.flushFor(ctx, forPR(installationID, owner, repo, prNumber)); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetReview(ctx, installationID, owner, repo, prNumber, reviewID)
+}
+
+// ListReviewsForPR flushes pending writes for the PR, then lists its reviews.
+func (s *Store) ListReviewsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]*storage.ReviewContext, error) {
+	if err := s.flushFor(ctx, forPR(installationID, owner, repo, prNumber)); err != nil {
+		return nil, err
+	}
+	return s.Storage.ListReviewsForPR(ctx, installationID, owner, repo, prNumber)
+}
+
+// GetFirstReviewForPR flushes pending writes for the PR, then reads its first review.
+func (s *Store) GetFirstReviewForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.ReviewContext, error) {
+	if err := s.flushFor(ctx, forPR(installationID, owner, repo, prNumber)); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetFirstReviewForPR(ctx, installationID, owner, repo, prNumber)
+}
+
+// ListRecentReviews flushes pending writes for the repository, then lists its reviews.
+func (s *Store) ListRecentReviews(ctx context.Context, installationID int64, owner, repo string, limit int) ([]*storage.ReviewContext, error) {
+	match := func(r *storage.ReviewContext) bool {
+		return r.InstallationID == installationID && r.Owner == owner && r.Repo == repo
+	}
+	if err := s.flushFor(ctx, match); err != nil {
+		return nil, err
+	}
+	return s.Storage.ListRecentReviews(ctx, installationID, owner, repo, limit)
+}
+
+// GetUsageTotalsForPR flushes pending writes for the PR, then sums its usage.
+func (s *Store) GetUsageTotalsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.TokenUsage, error) {
+	if err := s.flushFor(ctx, forPR(installationID, owner, repo, prNumber)); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetUsageTotalsForPR(ctx, installationID, owner, repo, prNumber)
+}
+
+// GetUsageTotalsForInstallation flushes pending writes for the installation, then sums its usage.
+func (s *Store) GetUsageTotalsForInstallation(ctx context.Context, installationID int64, since time.Time) (*storage.TokenUsage, error) {
+	if err := s.flushFor(ctx, forInstallation(installationID)); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetUsageTotalsForInstallation(ctx, installationID, since)
+}
+
+// GetCommentOutcomes flushes pending writes for the installation, then counts outcomes.
+func (s *Store) GetCommentOutcomes(ctx context.Context, installationID int64, since time.Time) (*storage.CommentOutcomes, error) {
+	if err := s.flushFor(ctx, forInstallation(installationID)); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetCommentOutcomes(ctx, installationID, since)
+}
+
+// forPR matches reviews of a pull request.
+func forPR(installationID int64, owner, repo string, prNumber int) func(*storage.ReviewContext) bool {
+	return func(r *storage.ReviewContext) bool {
+		return r.InstallationID == installationID && r.Owner == owner && r.Repo == repo && r.PRNumber == prNumber
+	}
+}
+
+// forInstallation matches reviews of an installation.
+func forInstallation(installationID int64) func(*storage.ReviewContext) bool {
+	return func(r *storage.ReviewContext) bool {
+		return r.InstallationID == installationID
+	}
+}
+
+// sameReview reports whether a and b are writes of the same stored review.
+func sameReview(a, b *storage.ReviewContext) bool {
+	return a.InstallationID == b.InstallationID && a.Owner == b.Owner && a.Repo == b.Repo &&
+		a.PRNumber == b.PRNumber && a.ReviewID == b.ReviewID
+}
+
+// copyReview copies a review so later changes by the caller don't alter the pending write.
+func copyReview(review *storage.ReviewContext) *storage.ReviewContext {
+	c := *review
+	c.Comments = append([]storage.Comment(nil), review.Comments...)
+	c.FailedFiles = append([]string(nil), review.FailedFiles...)
+	if review.Usage != nil {
+		usage := *review.Usage
+		c.Usage = &usage
+	}
+	return &c
+}
+
+// Verify Store implements Storage at compile time.
+var _ storage.Storage = (*Store)(nil)
@@ -0,0 +1,175 @@
+package buffered
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shipitai/shipitai/storage"
+	"github.com/shipitai/shipitai/storage/memory"
+)
+
+// batchingStore records the batches written to it.
+type batchingStore struct {
+	*memory.Memory
+
+	mu      sync.Mutex
+	batches [][]int64 // review IDs per batch
+	flushed chan struct{}
+}
+
+func newBatchingStore() *batchingStore {
+	return &batchingStore{Memory: memory.New(), flushed: make(chan struct{}, 10)}
+}
+
+func (b *batchingStore) StoreReviews(ctx context.Context, reviews []*storage.ReviewContext) error {
+	ids := make([]int64, 0, len(reviews))
+	for _, r := range reviews {
+		if err := b.Memory.StoreReview(ctx, r); err != nil {
+			return err
+		}
+		ids = append(ids, r.ReviewID)
+	}
+	b.mu.Lock()
+	b.batches = append(b.batches, ids)
+	b.mu.Unlock()
+	b.flushed <- struct{}{}
+	return nil
+}
+
+func (b *batchingStore) batchSizes() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sizes := make([]int, len(b.batches))
+	for i, batch := range b.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func review(prNumber int, id int64, body string) *storage.ReviewContext {
+	return &storage.ReviewContext{InstallationID: 1, Owner: "owner", Repo: "repo", PRNumber: prNumber, ReviewID: id, ReviewBody: body}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestFlushesFullBatch(t *testing.T) {
+	ctx := context.Background()
+	inner := newBatchingStore()
+	s := New(inner, time.Hour, 3, discardLogger())
+	defer s.Close()
+
+	for i := int64(1); i <= 3; i++ {
+		if err := s.StoreReview(ctx, review(int(i), i, "body")); err != nil {
+			t.Fatalf("StoreReview() error = %v", err)
+		}
+	}
+
+	select {
+	case <-inner.flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("full batch was not flushed")
+	}
+	if got := inner.batchSizes(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("batch sizes = %v, want one batch of 3", got)
+	}
+}
+
+func TestFlushesOnInterval(t *testing.T) {
+	inner := newBatchingStore()
+	s := New(inner, 10*time.Millisecond, 100, discardLogger())
+	defer s.Close()
+
+	if err := s.StoreReview(context.Background(), review(7, 1, "body")); err != nil {
+		t.Fatalf("StoreReview() error = %v", err)
+	}
+
+	select {
+	case <-inner.flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("buffered review was not flushed on the interval")
+	}
+}
+
+func TestCoalescesWritesOfSameReview(t *testing.T) {
+	ctx := context.Background()
+	inner := newBatchingStore()
+	s := New(inner, time.Hour, 100, discardLogger())
+
+	rev := review(7, 1, "first")
+	_ = s.StoreReview(ctx, rev)
+	rev.ReviewBody = "second" // changed after storing: the pending write keeps "first"
+	_ = s.StoreReview(ctx, review(7, 2, "other"))
+	_ = s.StoreReview(ctx, review(7, 1, "third"))
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := inner.batchSizes(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("batch sizes = %v, want one batch of 2", got)
+	}
+	stored, _ := inner.GetReview(ctx, 1, "owner", "repo", 7, 1)
+	if stored == nil || stored.ReviewBody != "third" {
+		t.Errorf("stored review = %+v, want the latest write", stored)
+	}
+}
+
+func TestReadsSeePendingWrites(t *testing.T) {
+	ctx := context.Background()
+	inner := newBatchingStore()
+	s := New(inner, time.Hour, 100, discardLogger())
+	defer s.Close()
+
+	_ = s.StoreReview(ctx, review(7, 1, "body"))
+	_ = s.StoreReview(ctx, review(8, 2, "body"))
+
+	first, err := s.GetFirstReviewForPR(ctx, 1, "owner", "repo", 7)
+	if err != nil {
+		t.Fatalf("GetFirstReviewForPR() error = %v", err)
+	}
+	if first == nil || first.ReviewID != 1 {
+		t.Errorf("GetFirstReviewForPR() = %+v, want the buffered review", first)
+	}
+
+	// Reads of a PR with nothing pending don't flush
+	inner.mu.Lock()
+	inner.batches = nil
+	inner.mu.Unlock()
+	_ = s.StoreReview(ctx, review(9, 3, "body"))
+	if _, err := s.GetFirstReviewForPR(ctx, 1, "owner", "repo", 10); err != nil {
+		t.Fatalf("GetFirstReviewForPR() error = %v", err)
+	}
+	if got := inner.batchSizes(); len(got) != 0 {
+		t.Errorf("batch sizes = %v, want no flush", got)
+	}
+}
+
+func TestCloseFlushesPendingWrites(t *testing.T) {
+	ctx := context.Background()
+	inner := memory.New()
+	s := New(inner, time.Hour, 100, discardLogger())
+
+	_ = s.StoreReview(ctx, review(7, 1, "body"))
+	_ = s.StoreReview(ctx, review(7, 2, "body"))
+	if got, _ := inner.ListReviewsForPR(ctx, 1, "owner", "repo", 7); len(got) != 0 {
+		t.Fatalf("stored %d reviews before Close, want 0", len(got))
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, _ := inner.ListReviewsForPR(ctx, 1, "owner", "repo", 7); len(got) != 2 {
+		t.Errorf("stored %d reviews after Close, want 2", len(got))
+	}
+
+	// After Close, writes go straight through
+	_ = s.StoreReview(ctx, review(7, 3, "body"))
+	if got, _ := inner.ListReviewsForPR(ctx, 1, "owner", "repo", 7); len(got) != 3 {
+		t.Errorf("stored %d reviews after a write following Close, want 3", len(got))
+	}
+}
@@ -3,6 +3,7 @@ package storage
 
 import (
 	"context"
+	"time"
 )
 
 // Storage defines the interface for ShipItAI storage backends.
@@ -13,8 +14,45 @@ type Storage interface {
 	GetReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, reviewID int64) (*ReviewContext, error)
 	ListReviewsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]*ReviewContext, error)
 	GetFirstReviewForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*ReviewContext, error)
+	ListRecentReviews(ctx context.Context, installationID int64, owner, repo string, limit int) ([]*ReviewContext, error)
+
+	// Usage reporting
+	GetUsageTotalsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*TokenUsage, error)
+	GetUsageTotalsForInstallation(ctx context.Context, installationID int64, since time.Time) (*TokenUsage, error)
+
+	// Comment outcome reporting
+	GetCommentOutcomes(ctx context.Context, installationID int64, since time.Time) (*CommentOutcomes, error)
+
+	// Muted pull requests, which are never reviewed
+	SetPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int, muted bool) error
+	IsPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int) (bool, error)
+
+	// Replies to @mentions posted on each pull request, for max_replies_per_pr
+	IncrementReplyCount(ctx context.Context, installationID int64, owner, repo string, prNumber int) error
+	GetReplyCount(ctx context.Context, installationID int64, owner, repo string, prNumber int) (int, error)
+
+	// MarkPRNoticePosted records that a notice keyed by notice is being posted on
+	// a pull request. It reports true only for the call that marked it, so each
+	// notice is posted once even when events race.
+	MarkPRNoticePosted(ctx context.Context, installationID int64, owner, repo string, prNumber int, notice string) (bool, error)
+
+	// Event analytics
+	LogEvent(ctx context.Context, event *Event) error
+	SummarizeEvents(ctx context.Context, since time.Time) ([]EventSummary, error)
 
 	// Installation operations
 	SaveInstallation(ctx context.Context, install *Installation) error
 	GetInstallation(ctx context.Context, installationID int64) (*Installation, error)
+	SetInstallationActive(ctx context.Context, installationID int64, active bool) error
+	DeleteInstallation(ctx context.Context, installationID int64) error
+	// MarkInstallationWelcomed records that the installation's welcome comment is
+	// being posted. It reports true only for the call that marked it, so the comment
+	// is posted once even when events race; unknown installations report false.
+	MarkInstallationWelcomed(ctx context.Context, installationID int64) (bool, error)
+}
+
+// ReviewBatcher is implemented by backends that can store several reviews in one
+// round trip. The buffered store uses it to flush, falling back to StoreReview.
+type ReviewBatcher interface {
+	StoreReviews(ctx context.Context, reviews []*ReviewContext) error
 }
@@ -0,0 +1,429 @@
+// Package memory provides an in-memory implementation of the storage interface.
+// This is intended for tests, local development, and ephemeral deployments;
+// all data is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shipitai/shipitai/storage"
+)
+
+// reviewKey identifies a stored review, mirroring the PostgreSQL unique constraint.
+type reviewKey struct {
+	installationID int64
+	owner          string
+	repo           string
+	prNumber       int
+	reviewID       int64
+}
+
+// prKey identifies a pull request.
+type prKey struct {
+	installationID int64
+	owner          string
+	repo           string
+	prNumber       int
+}
+
+// noticeKey identifies a notice posted on a pull request.
+type noticeKey struct {
+	pr     prKey
+	notice string
+}
+
+// reviewRecord is a stored review along with its insertion time.
+type reviewRecord struct {
+	review    storage.ReviewContext
+	createdAt time.Time
+}
+
+// Memory provides storage operations backed by in-process maps.
+type Memory struct {
+	mu            sync.RWMutex
+	reviews       []*reviewRecord // insertion order, oldest first
+	reviewIndex   map[reviewKey]*reviewRecord
+	installations map[int64]*storage.Installation
+	mutedPRs      map[prKey]bool
+	replyCounts   map[prKey]int
+	notices       map[noticeKey]bool
+	events        []eventRecord
+	now           func() time.Time
+}
+
+// eventRecord is a logged event along with its insertion time.
+type eventRecord struct {
+	event     storage.Event
+	createdAt time.Time
+}
+
+// New creates a new, empty in-memory storage instance.
+func New() *Memory {
+	return &Memory{
+		reviewIndex:   make(map[reviewKey]*reviewRecord),
+		installations: make(map[int64]*storage.Installation),
+		mutedPRs:      make(map[prKey]bool),
+		replyCounts:   make(map[prKey]int),
+		notices:       make(map[noticeKey]bool),
+		now:           time.Now,
+	}
+}
+
+// StoreReview stores a review context. Storing a review with the same
+// installation, repository, PR, and review ID replaces it in place, keeping
+// its original creation time.
+func (m *Memory) StoreReview(ctx context.Context, review *storage.ReviewContext) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := reviewKey{review.InstallationID, review.Owner, review.Repo, review.PRNumber, review.ReviewID}
+	rec, ok := m.reviewIndex[key]
+	if !ok {
+		rec = &reviewRecord{createdAt: m.now()}
+		m.reviews = append(m.reviews, rec)
+		m.reviewIndex[key] = rec
+	}
+
+	rec.review = *review
+	rec.review.Comments = copyComments(review.Comments)
+	rec.review.Usage = copyUsage(review.Usage)
+	rec.review.FailedFiles = copyFiles(review.FailedFiles)
+	rec.review.CreatedAt = rec.createdAt.Format(time.RFC3339)
+	return nil
+}
+
+// GetReview retrieves a review context. Returns nil if not found.
+func (m *Memory) GetReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, reviewID int64) (*storage.ReviewContext, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, ok := m.reviewIndex[reviewKey{installationID, owner, repo, prNumber, reviewID}]
+	if !ok {
+		return nil, nil
+	}
+	return rec.copy(), nil
+}
+
+// ListReviewsForPR retrieves all reviews for a pull request, oldest first.
+func (m *Memory) ListReviewsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]*storage.ReviewContext, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var reviews []*storage.ReviewContext
+	for _, rec := range m.reviews {
+		if rec.matchesPR(installationID, owner, repo, prNumber) {
+			reviews = append(reviews, rec.copy())
+		}
+	}
+	return reviews, nil
+}
+
+// ListRecentReviews retrieves up to limit of a repository's reviews across all
+// its pull requests, newest first. A limit of 0 or less returns them all.
+func (m *Memory) ListRecentReviews(ctx context.Context, installationID int64, owner, repo string, limit int) ([]*storage.ReviewContext, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var reviews []*storage.ReviewContext
+	for i := len(m.reviews) - 1; i >= 0 && (limit <= 0 || len(reviews) < limit); i-- {
+		r := m.reviews[i].review
+		if r.InstallationID == installationID && r.Owner == owner && r.Repo == repo {
+			reviews = append(reviews, m.reviews[i].copy())
+		}
+	}
+	return reviews, nil
+}
+
+// GetFirstReviewForPR retrieves the first (oldest) review for a pull request.
+// Returns nil if the PR has no stored reviews.
+func (m *Memory) GetFirstReviewForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.ReviewContext, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rec := range m.reviews {
+		if rec.matchesPR(installationID, owner, repo, prNumber) {
+			return rec.copy(), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUsageTotalsForPR returns the token usage summed across all reviews of a pull request.
+func (m *Memory) GetUsageTotalsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.TokenUsage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	total := &storage.TokenUsage{}
+	for _, rec := range m.reviews {
+		if rec.matchesPR(installationID, owner, repo, prNumber) {
+			addUsage(total, rec.review.Usage)
+		}
+	}
+	return total, nil
+}
+
+// GetUsageTotalsForInstallation returns the token usage summed across all reviews
+// for an installation created at or after since.
+func (m *Memory) GetUsageTotalsForInstallation(ctx context.Context, installationID int64, since time.Time) (*storage.TokenUsage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	total := &storage.TokenUsage{}
+	for _, rec := range m.reviews {
+		if rec.review.InstallationID == installationID && !rec.createdAt.Before(since) {
+			addUsage(total, rec.review.Usage)
+		}
+	}
+	return total, nil
+}
+
+// GetCommentOutcomes counts the comments of reviews for an installation created at
+// or after since by outcome.
+func (m *Memory) GetCommentOutcomes(ctx context.Context, installationID int64, since time.Time) (*storage.CommentOutcomes, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	outcomes := &storage.CommentOutcomes{}
+	for _, rec := range m.reviews {
+		if rec.review.InstallationID != installationID || rec.createdAt.Before(since) {
+			continue
+		}
+		for _, c := range rec.review.Comments {
+			switch c.Outcome {
+			case storage.CommentOutcomeResolved:
+				outcomes.Resolved++
+			case storage.CommentOutcomeUnresolved:
+				outcomes.Unresolved++
+			case storage.CommentOutcomeDismissed:
+				outcomes.Dismissed++
+			default:
+				outcomes.Pending++
+			}
+		}
+	}
+	return outcomes, nil
+}
+
+// LogEvent records a handled webhook event.
+func (m *Memory) LogEvent(ctx context.Context, event *storage.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := eventRecord{event: *event, createdAt: m.now()}
+	rec.event.CreatedAt = rec.createdAt.Format(time.RFC3339)
+	m.events = append(m.events, rec)
+	return nil
+}
+
+// SummarizeEvents returns event counts and average durations grouped by event type,
+// action, and result for events logged at or after since, most frequent first.
+func (m *Memory) SummarizeEvents(ctx context.Context, since time.Time) ([]storage.EventSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type groupKey struct{ eventType, action, result string }
+	groups := make(map[groupKey]*storage.EventSummary)
+	totals := make(map[groupKey]int64)
+	var order []groupKey
+	for _, rec := range m.events {
+		if rec.createdAt.Before(since) {
+			continue
+		}
+		key := groupKey{rec.event.EventType, rec.event.Action, rec.event.Result}
+		s, ok := groups[key]
+		if !ok {
+			s = &storage.EventSummary{EventType: key.eventType, Action: key.action, Result: key.result}
+			groups[key] = s
+			order = append(order, key)
+		}
+		s.Count++
+		totals[key] += rec.event.DurationMs
+	}
+
+	summaries := make([]storage.EventSummary, 0, len(order))
+	for _, key := range order {
+		s := groups[key]
+		s.AvgDurationMs = float64(totals[key]) / float64(s.Count)
+		summaries = append(summaries, *s)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Count > summaries[j].Count
+	})
+	return summaries, nil
+}
+
+// SetPRMuted mutes or unmutes a pull request.
+func (m *Memory) SetPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int, muted bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := prKey{installationID, owner, repo, prNumber}
+	if muted {
+		m.mutedPRs[key] = true
+	} else {
+		delete(m.mutedPRs, key)
+	}
+	return nil
+}
+
+// IsPRMuted reports whether a pull request is muted.
+func (m *Memory) IsPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.mutedPRs[prKey{installationID, owner, repo, prNumber}], nil
+}
+
+// IncrementReplyCount records a reply posted on a pull request.
+func (m *Memory) IncrementReplyCount(ctx context.Context, installationID int64, owner, repo string, prNumber int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replyCounts[prKey{installationID, owner, repo, prNumber}]++
+	return nil
+}
+
+// GetReplyCount returns how many replies have been posted on a pull request.
+func (m *Memory) GetReplyCount(ctx context.Context, installationID int64, owner, repo string, prNumber int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.replyCounts[prKey{installationID, owner, repo, prNumber}], nil
+}
+
+// MarkPRNoticePosted marks a notice posted on a pull request, reporting whether
+// it wasn't already.
+func (m *Memory) MarkPRNoticePosted(ctx context.Context, installationID int64, owner, repo string, prNumber int, notice string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := noticeKey{prKey{installationID, owner, repo, prNumber}, notice}
+	if m.notices[key] {
+		return false, nil
+	}
+	m.notices[key] = true
+	return true, nil
+}
+
+// SaveInstallation stores a new installation, marked active. Saving an existing
+// installation only updates its org login, matching the PostgreSQL backend.
+func (m *Memory) SaveInstallation(ctx context.Context, install *storage.Installation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.installations[install.InstallationID]; ok {
+		existing.OrgLogin = install.OrgLogin
+		return nil
+	}
+
+	saved := *install
+	saved.Active = true
+	if saved.InstalledAt == "" {
+		saved.InstalledAt = m.now().UTC().Format(time.RFC3339)
+	}
+	m.installations[install.InstallationID] = &saved
+	return nil
+}
+
+// GetInstallation retrieves an installation. Returns nil if not found.
+func (m *Memory) GetInstallation(ctx context.Context, installationID int64) (*storage.Installation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	install, ok := m.installations[installationID]
+	if !ok {
+		return nil, nil
+	}
+	result := *install
+	return &result, nil
+}
+
+// SetInstallationActive marks an installation active or suspended. Unknown
+// installations are ignored.
+func (m *Memory) SetInstallationActive(ctx context.Context, installationID int64, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if install, ok := m.installations[installationID]; ok {
+		install.Active = active
+	}
+	return nil
+}
+
+// MarkInstallationWelcomed marks an installation welcomed, reporting whether it
+// wasn't already.
+func (m *Memory) MarkInstallationWelcomed(ctx context.Context, installationID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	install, ok := m.installations[installationID]
+	if !ok || install.Welcomed {
+		return false, nil
+	}
+	install.Welcomed = true
+	return true, nil
+}
+
+// DeleteInstallation removes an installation. Its reviews are kept.
+func (m *Memory) DeleteInstallation(ctx context.Context, installationID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.installations, installationID)
+	return nil
+}
+
+// matchesPR reports whether the record belongs to the given pull request.
+func (r *reviewRecord) matchesPR(installationID int64, owner, repo string, prNumber int) bool {
+	return r.review.InstallationID == installationID &&
+		r.review.Owner == owner &&
+		r.review.Repo == repo &&
+		r.review.PRNumber == prNumber
+}
+
+// copy returns a copy of the stored review that callers may modify freely.
+func (r *reviewRecord) copy() *storage.ReviewContext {
+	review := r.review
+	review.Comments = copyComments(r.review.Comments)
+	review.Usage = copyUsage(r.review.Usage)
+	review.FailedFiles = copyFiles(r.review.FailedFiles)
+	return &review
+}
+
+func copyComments(comments []storage.Comment) []storage.Comment {
+	if comments == nil {
+		return nil
+	}
+	return append([]storage.Comment(nil), comments...)
+}
+
+func copyFiles(files []string) []string {
+	if files == nil {
+		return nil
+	}
+	return append([]string(nil), files...)
+}
+
+func copyUsage(usage *storage.TokenUsage) *storage.TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	u := *usage
+	return &u
+}
+
+func addUsage(total, u *storage.TokenUsage) {
+	if u == nil {
+		return
+	}
+	total.InputTokens += u.InputTokens
+	total.OutputTokens += u.OutputTokens
+	total.CacheReadInputTokens += u.CacheReadInputTokens
+	total.CacheCreationInputTokens += u.CacheCreationInputTokens
+}
+
+// Verify Memory implements Storage at compile time.
+var _ storage.Storage = (*Memory)(nil)
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shipitai/shipitai/storage"
+)
+
+func TestFirstReviewAndUpsert(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+
+	review := func(id int64, body string, input int64) *storage.ReviewContext {
+		return &storage.ReviewContext{
+			InstallationID: 1, Owner: "owner", Repo: "repo", PRNumber: 7,
+			ReviewID: id, ReviewBody: body,
+			Usage: &storage.TokenUsage{InputTokens: input},
+		}
+	}
+
+	// Insert out of review-ID order to check ordering is by insertion time
+	for _, r := range []*storage.ReviewContext{review(20, "first", 100), review(10, "second", 50)} {
+		if err := m.StoreReview(ctx, r); err != nil {
+			t.Fatalf("StoreReview() error = %v", err)
+		}
+	}
+
+	// Upsert the first review; it must stay first and keep a single row
+	if err := m.StoreReview(ctx, review(20, "first (updated)", 150)); err != nil {
+		t.Fatalf("StoreReview() error = %v", err)
+	}
+
+	first, err := m.GetFirstReviewForPR(ctx, 1, "owner", "repo", 7)
+	if err != nil || first == nil {
+		t.Fatalf("GetFirstReviewForPR() = %v, %v", first, err)
+	}
+	if first.ReviewID != 20 || first.ReviewBody != "first (updated)" {
+		t.Errorf("first review = %d %q, want 20 %q", first.ReviewID, first.ReviewBody, "first (updated)")
+	}
+
+	reviews, _ := m.ListReviewsForPR(ctx, 1, "owner", "repo", 7)
+	if len(reviews) != 2 {
+		t.Fatalf("ListReviewsForPR() returned %d reviews, want 2", len(reviews))
+	}
+
+	totals, _ := m.GetUsageTotalsForPR(ctx, 1, "owner", "repo", 7)
+	if totals.InputTokens != 200 {
+		t.Errorf("InputTokens = %d, want 200", totals.InputTokens)
+	}
+
+	// Mutating returned values must not affect stored data
+	first.ReviewBody = "mutated"
+	again, _ := m.GetReview(ctx, 1, "owner", "repo", 7, 20)
+	if again.ReviewBody != "first (updated)" {
+		t.Errorf("stored review was mutated through returned copy")
+	}
+
+	if none, _ := m.GetFirstReviewForPR(ctx, 1, "owner", "repo", 8); none != nil {
+		t.Errorf("GetFirstReviewForPR() for unknown PR = %+v, want nil", none)
+	}
+}
+
+func TestListRecentReviews(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	for i, pr := range []int{1, 2, 1, 3} {
+		_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "o", Repo: "r", PRNumber: pr, ReviewID: int64(i + 1)})
+	}
+	_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "o", Repo: "other", PRNumber: 1, ReviewID: 9})
+
+	got, err := m.ListRecentReviews(ctx, 1, "o", "r", 3)
+	if err != nil {
+		t.Fatalf("ListRecentReviews() error = %v", err)
+	}
+	var ids []int64
+	for _, r := range got {
+		ids = append(ids, r.ReviewID)
+	}
+	if want := []int64{4, 3, 2}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("ListRecentReviews() review IDs = %v, want %v", ids, want)
+	}
+
+	if all, _ := m.ListRecentReviews(ctx, 1, "o", "r", 0); len(all) != 4 {
+		t.Errorf("ListRecentReviews() with no limit = %d reviews, want 4", len(all))
+	}
+}
+
+func TestUsageTotalsForInstallationSince(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	times := []time.Time{
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	m.now = func() time.Time {
+		t := times[0]
+		times = times[1:]
+		return t
+	}
+
+	_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "o", Repo: "r", PRNumber: 1, ReviewID: 1, Usage: &storage.TokenUsage{OutputTokens: 10}})
+	_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "o", Repo: "r", PRNumber: 2, ReviewID: 2, Usage: &storage.TokenUsage{OutputTokens: 5}})
+
+	totals, _ := m.GetUsageTotalsForInstallation(ctx, 1, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC))
+	if totals.OutputTokens != 5 {
+		t.Errorf("OutputTokens = %d, want 5", totals.OutputTokens)
+	}
+}
+
+func TestGetCommentOutcomes(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "o", Repo: "r", PRNumber: 1, ReviewID: 1, Comments: []storage.Comment{
+		{Path: "a.go", Line: 1, Body: "x", Outcome: storage.CommentOutcomeResolved},
+		{Path: "a.go", Line: 2, Body: "y", Outcome: storage.CommentOutcomeResolved},
+		{Path: "b.go", Line: 3, Body: "z", Outcome: storage.CommentOutcomeDismissed},
+	}})
+	_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 1, Owner: "o", Repo: "r", PRNumber: 1, ReviewID: 2, Comments: []storage.Comment{
+		{Path: "c.go", Line: 4, Body: "w", Outcome: storage.CommentOutcomeUnresolved},
+		{Path: "c.go", Line: 5, Body: "v"},
+	}})
+	_ = m.StoreReview(ctx, &storage.ReviewContext{InstallationID: 2, Owner: "o", Repo: "r", PRNumber: 1, ReviewID: 3, Comments: []storage.Comment{
+		{Path: "d.go", Line: 1, Body: "u", Outcome: storage.CommentOutcomeResolved},
+	}})
+
+	got, err := m.GetCommentOutcomes(ctx, 1, time.Time{})
+	if err != nil {
+		t.Fatalf("GetCommentOutcomes() error = %v", err)
+	}
+	want := storage.CommentOutcomes{Resolved: 2, Unresolved: 1, Dismissed: 1, Pending: 1}
+	if *got != want {
+		t.Errorf("GetCommentOutcomes() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestStoreReviewUpsertReplacesAllFields(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	original := &storage.ReviewContext{InstallationID: 1, Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 20, ReviewBody: "first"}
+	if err := m.StoreReview(ctx, original); err != nil {
+		t.Fatalf("StoreReview() error = %v", err)
+	}
+	before, _ := m.GetReview(ctx, 1, "owner", "repo", 7, 20)
+
+	updated := &storage.ReviewContext{
+		InstallationID: 1, Owner: "owner", Repo: "repo", PRNumber: 7, ReviewID: 20,
+		ReviewBody:       "first (updated)",
+		UsageType:        "custom",
+		FailedFiles:      []string{"a.go"},
+		SummaryCommentID: 31,
+		TriageCommentID:  32,
+		HeadSHA:          "abc123",
+	}
+	if err := m.StoreReview(ctx, updated); err != nil {
+		t.Fatalf("StoreReview() error = %v", err)
+	}
+
+	got, _ := m.GetReview(ctx, 1, "owner", "repo", 7, 20)
+	if got.ReviewBody != updated.ReviewBody || got.UsageType != updated.UsageType || len(got.FailedFiles) != 1 ||
+		got.SummaryCommentID != 31 || got.TriageCommentID != 32 || got.HeadSHA != "abc123" {
+		t.Errorf("upserted review = %+v, want every field from %+v", got, updated)
+	}
+	if got.CreatedAt != before.CreatedAt {
+		t.Errorf("CreatedAt = %q, want the original %q", got.CreatedAt, before.CreatedAt)
+	}
+	if reviews, _ := m.ListReviewsForPR(ctx, 1, "owner", "repo", 7); len(reviews) != 1 {
+		t.Errorf("ListReviewsForPR() returned %d reviews, want 1", len(reviews))
+	}
+}
+
+func TestSetPRMuted(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	_ = m.SetPRMuted(ctx, 1, "o", "r", 1, true)
+	_ = m.SetPRMuted(ctx, 1, "o", "r", 1, true)
+	if muted, _ := m.IsPRMuted(ctx, 1, "o", "r", 1); !muted {
+		t.Error("IsPRMuted() = false after muting, want true")
+	}
+	if muted, _ := m.IsPRMuted(ctx, 1, "o", "r", 2); muted {
+		t.Error("IsPRMuted() = true for another PR, want false")
+	}
+
+	_ = m.SetPRMuted(ctx, 1, "o", "r", 1, false)
+	if muted, _ := m.IsPRMuted(ctx, 1, "o", "r", 1); muted {
+		t.Error("IsPRMuted() = true after unmuting, want false")
+	}
+}
+
+func TestMarkPRNoticePosted(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	if first, _ := m.MarkPRNoticePosted(ctx, 1, "o", "r", 1, "revert"); !first {
+		t.Error("MarkPRNoticePosted() = false for a new notice, want true")
+	}
+	if first, _ := m.MarkPRNoticePosted(ctx, 1, "o", "r", 1, "revert"); first {
+		t.Error("MarkPRNoticePosted() = true for a posted notice, want false")
+	}
+	if first, _ := m.MarkPRNoticePosted(ctx, 1, "o", "r", 2, "revert"); !first {
+		t.Error("MarkPRNoticePosted() = false on another PR, want true")
+	}
+	if first, _ := m.MarkPRNoticePosted(ctx, 1, "o", "r", 1, "mostly_deletions"); !first {
+		t.Error("MarkPRNoticePosted() = false for another notice, want true")
+	}
+}
+
+func TestReplyCount(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	for range 3 {
+		_ = m.IncrementReplyCount(ctx, 1, "o", "r", 1)
+	}
+	if n, _ := m.GetReplyCount(ctx, 1, "o", "r", 1); n != 3 {
+		t.Errorf("GetReplyCount() = %d, want 3", n)
+	}
+	if n, _ := m.GetReplyCount(ctx, 1, "o", "r", 2); n != 0 {
+		t.Errorf("GetReplyCount() for another PR = %d, want 0", n)
+	}
+}
+
+func TestSummarizeEvents(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time {
+		clock = clock.Add(time.Hour)
+		return clock
+	}
+
+	events := []storage.Event{
+		{EventType: "pull_request", Action: "opened", Result: "failed", DurationMs: 900}, // before the window
+		{EventType: "pull_request", Action: "opened", Result: "posted", DurationMs: 100},
+		{EventType: "pull_request", Action: "opened", Result: "posted", DurationMs: 300},
+		{EventType: "pull_request", Action: "synchronize", Result: "posted", DurationMs: 50},
+		{EventType: "pull_request", Action: "opened", Result: "posted", DurationMs: 200},
+	}
+	for i := range events {
+		if err := m.LogEvent(ctx, &events[i]); err != nil {
+			t.Fatalf("LogEvent() error = %v", err)
+		}
+	}
+
+	summaries, err := m.SummarizeEvents(ctx, time.Date(2025, 1, 1, 1, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SummarizeEvents() error = %v", err)
+	}
+
+	want := []storage.EventSummary{
+		{EventType: "pull_request", Action: "opened", Result: "posted", Count: 3, AvgDurationMs: 200},
+		{EventType: "pull_request", Action: "synchronize", Result: "posted", Count: 1, AvgDurationMs: 50},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("SummarizeEvents() = %+v, want %+v", summaries, want)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("summaries[%d] = %+v, want %+v", i, summaries[i], want[i])
+		}
+	}
+}
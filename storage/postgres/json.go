@@ -47,3 +47,24 @@ func usageFromJSON(s string) *storage.TokenUsage {
 	}
 	return &usage
 }
+
+// filesToJSON converts a list of file paths to a JSON string for storage.
+func filesToJSON(files []string) string {
+	if len(files) == 0 {
+		return "null"
+	}
+	b, _ := json.Marshal(files)
+	return string(b)
+}
+
+// filesFromJSON parses a JSON string into a list of file paths.
+func filesFromJSON(s string) []string {
+	if s == "" || s == "null" {
+		return nil
+	}
+	var files []string
+	if err := json.Unmarshal([]byte(s), &files); err != nil {
+		return nil
+	}
+	return files
+}
@@ -41,6 +41,14 @@ func (p *PostgreSQL) Close() error {
 	return p.db.Close()
 }
 
+// Ping verifies the database connection is still alive.
+func (p *PostgreSQL) Ping(ctx context.Context) error {
+	if err := p.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
 // Migrate creates the required database tables.
 func (p *PostgreSQL) Migrate(ctx context.Context) error {
 	schema := `
@@ -69,7 +77,59 @@ func (p *PostgreSQL) Migrate(ctx context.Context) error {
 			UNIQUE(installation_id, owner, repo, pr_number, review_id)
 		);
 
+		ALTER TABLE installations ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+		-- Installations from before welcome comments count as welcomed; new ones aren't
+		ALTER TABLE installations ADD COLUMN IF NOT EXISTS welcomed BOOLEAN NOT NULL DEFAULT TRUE;
+		ALTER TABLE installations ALTER COLUMN welcomed SET DEFAULT FALSE;
+
+		ALTER TABLE reviews ADD COLUMN IF NOT EXISTS failed_files JSONB;
+		ALTER TABLE reviews ADD COLUMN IF NOT EXISTS summary_comment_id BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE reviews ADD COLUMN IF NOT EXISTS triage_comment_id BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE reviews ADD COLUMN IF NOT EXISTS head_sha TEXT NOT NULL DEFAULT '';
+
 		CREATE INDEX IF NOT EXISTS idx_reviews_pr ON reviews(installation_id, owner, repo, pr_number);
+		CREATE INDEX IF NOT EXISTS idx_reviews_repo_created_at ON reviews(installation_id, owner, repo, created_at);
+
+		CREATE TABLE IF NOT EXISTS events (
+			id BIGSERIAL PRIMARY KEY,
+			event_type TEXT NOT NULL,
+			action TEXT,
+			owner TEXT,
+			repo TEXT,
+			result TEXT NOT NULL,
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_events_created_at ON events(created_at);
+
+		CREATE TABLE IF NOT EXISTS muted_prs (
+			installation_id BIGINT NOT NULL,
+			owner TEXT NOT NULL,
+			repo TEXT NOT NULL,
+			pr_number INTEGER NOT NULL,
+			muted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY(installation_id, owner, repo, pr_number)
+		);
+
+		CREATE TABLE IF NOT EXISTS pr_reply_counts (
+			installation_id BIGINT NOT NULL,
+			owner TEXT NOT NULL,
+			repo TEXT NOT NULL,
+			pr_number INTEGER NOT NULL,
+			replies INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY(installation_id, owner, repo, pr_number)
+		);
+
+		CREATE TABLE IF NOT EXISTS pr_notices (
+			installation_id BIGINT NOT NULL,
+			owner TEXT NOT NULL,
+			repo TEXT NOT NULL,
+			pr_number INTEGER NOT NULL,
+			notice TEXT NOT NULL,
+			posted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY(installation_id, owner, repo, pr_number, notice)
+		);
 	`
 
 	_, err := p.db.ExecContext(ctx, schema)
@@ -80,19 +140,58 @@ func (p *PostgreSQL) Migrate(ctx context.Context) error {
 	return nil
 }
 
-// StoreReview stores a review context in PostgreSQL.
-func (p *PostgreSQL) StoreReview(ctx context.Context, review *storage.ReviewContext) error {
-	query := `
-		INSERT INTO reviews (installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+// storeReviewQuery inserts a review, updating it in place if it's already stored.
+const storeReviewQuery = `
+		INSERT INTO reviews (installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, failed_files, summary_comment_id, triage_comment_id, head_sha, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
 		ON CONFLICT (installation_id, owner, repo, pr_number, review_id) DO UPDATE SET
 			review_body = EXCLUDED.review_body,
 			comments = EXCLUDED.comments,
 			usage = EXCLUDED.usage,
-			usage_type = EXCLUDED.usage_type
+			usage_type = EXCLUDED.usage_type,
+			failed_files = EXCLUDED.failed_files,
+			summary_comment_id = EXCLUDED.summary_comment_id,
+			triage_comment_id = EXCLUDED.triage_comment_id,
+			head_sha = EXCLUDED.head_sha
 	`
 
-	_, err := p.db.ExecContext(ctx, query,
+// StoreReview stores a review context in PostgreSQL.
+func (p *PostgreSQL) StoreReview(ctx context.Context, review *storage.ReviewContext) error {
+	if _, err := p.db.ExecContext(ctx, storeReviewQuery, reviewArgs(review)...); err != nil {
+		return fmt.Errorf("failed to store review: %w", err)
+	}
+	return nil
+}
+
+// StoreReviews stores several review contexts in one transaction.
+func (p *PostgreSQL) StoreReviews(ctx context.Context, reviews []*storage.ReviewContext) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, storeReviewQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare review insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, review := range reviews {
+		if _, err := stmt.ExecContext(ctx, reviewArgs(review)...); err != nil {
+			return fmt.Errorf("failed to store review: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reviews: %w", err)
+	}
+	return nil
+}
+
+// reviewArgs returns the storeReviewQuery arguments for a review.
+func reviewArgs(review *storage.ReviewContext) []any {
+	return []any{
 		review.InstallationID,
 		review.Owner,
 		review.Repo,
@@ -102,24 +201,23 @@ func (p *PostgreSQL) StoreReview(ctx context.Context, review *storage.ReviewCont
 		commentsToJSON(review.Comments),
 		usageToJSON(review.Usage),
 		review.UsageType,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to store review: %w", err)
+		filesToJSON(review.FailedFiles),
+		review.SummaryCommentID,
+		review.TriageCommentID,
+		review.HeadSHA,
 	}
-
-	return nil
 }
 
 // GetReview retrieves a review context from PostgreSQL.
 func (p *PostgreSQL) GetReview(ctx context.Context, installationID int64, owner, repo string, prNumber int, reviewID int64) (*storage.ReviewContext, error) {
 	query := `
-		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, created_at
+		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, failed_files, summary_comment_id, triage_comment_id, head_sha, created_at
 		FROM reviews
 		WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4 AND review_id = $5
 	`
 
 	var review storage.ReviewContext
-	var commentsJSON, usageJSON sql.NullString
+	var commentsJSON, usageJSON, failedFilesJSON sql.NullString
 	var createdAt time.Time
 
 	err := p.db.QueryRowContext(ctx, query, installationID, owner, repo, prNumber, reviewID).Scan(
@@ -132,6 +230,10 @@ func (p *PostgreSQL) GetReview(ctx context.Context, installationID int64, owner,
 		&commentsJSON,
 		&usageJSON,
 		&review.UsageType,
+		&failedFilesJSON,
+		&review.SummaryCommentID,
+		&review.TriageCommentID,
+		&review.HeadSHA,
 		&createdAt,
 	)
 	if err == sql.ErrNoRows {
@@ -143,6 +245,7 @@ func (p *PostgreSQL) GetReview(ctx context.Context, installationID int64, owner,
 
 	review.Comments = commentsFromJSON(commentsJSON.String)
 	review.Usage = usageFromJSON(usageJSON.String)
+	review.FailedFiles = filesFromJSON(failedFilesJSON.String)
 	review.CreatedAt = createdAt.Format(time.RFC3339)
 
 	return &review, nil
@@ -151,10 +254,10 @@ func (p *PostgreSQL) GetReview(ctx context.Context, installationID int64, owner,
 // ListReviewsForPR retrieves all reviews for a pull request.
 func (p *PostgreSQL) ListReviewsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) ([]*storage.ReviewContext, error) {
 	query := `
-		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, created_at
+		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, failed_files, summary_comment_id, triage_comment_id, head_sha, created_at
 		FROM reviews
 		WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4
-		ORDER BY created_at ASC
+		ORDER BY created_at ASC, id ASC
 	`
 
 	rows, err := p.db.QueryContext(ctx, query, installationID, owner, repo, prNumber)
@@ -163,10 +266,41 @@ func (p *PostgreSQL) ListReviewsForPR(ctx context.Context, installationID int64,
 	}
 	defer rows.Close()
 
+	return scanReviews(rows)
+}
+
+// ListRecentReviews retrieves up to limit of a repository's reviews across all
+// its pull requests, newest first. A limit of 0 or less returns them all.
+func (p *PostgreSQL) ListRecentReviews(ctx context.Context, installationID int64, owner, repo string, limit int) ([]*storage.ReviewContext, error) {
+	query := `
+		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, failed_files, summary_comment_id, triage_comment_id, head_sha, created_at
+		FROM reviews
+		WHERE installation_id = $1 AND owner = $2 AND repo = $3
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+
+	// LIMIT NULL is no limit
+	var limitArg sql.NullInt64
+	if limit > 0 {
+		limitArg = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, installationID, owner, repo, limitArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent reviews: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReviews(rows)
+}
+
+// scanReviews reads review rows selected with the standard column list.
+func scanReviews(rows *sql.Rows) ([]*storage.ReviewContext, error) {
 	var reviews []*storage.ReviewContext
 	for rows.Next() {
 		var review storage.ReviewContext
-		var commentsJSON, usageJSON sql.NullString
+		var commentsJSON, usageJSON, failedFilesJSON sql.NullString
 		var createdAt time.Time
 
 		if err := rows.Scan(
@@ -179,6 +313,10 @@ func (p *PostgreSQL) ListReviewsForPR(ctx context.Context, installationID int64,
 			&commentsJSON,
 			&usageJSON,
 			&review.UsageType,
+			&failedFilesJSON,
+			&review.SummaryCommentID,
+			&review.TriageCommentID,
+			&review.HeadSHA,
 			&createdAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan review: %w", err)
@@ -186,6 +324,7 @@ func (p *PostgreSQL) ListReviewsForPR(ctx context.Context, installationID int64,
 
 		review.Comments = commentsFromJSON(commentsJSON.String)
 		review.Usage = usageFromJSON(usageJSON.String)
+		review.FailedFiles = filesFromJSON(failedFilesJSON.String)
 		review.CreatedAt = createdAt.Format(time.RFC3339)
 		reviews = append(reviews, &review)
 	}
@@ -196,15 +335,15 @@ func (p *PostgreSQL) ListReviewsForPR(ctx context.Context, installationID int64,
 // GetFirstReviewForPR retrieves the first (oldest) review for a pull request.
 func (p *PostgreSQL) GetFirstReviewForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.ReviewContext, error) {
 	query := `
-		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, created_at
+		SELECT installation_id, owner, repo, pr_number, review_id, review_body, comments, usage, usage_type, failed_files, summary_comment_id, triage_comment_id, head_sha, created_at
 		FROM reviews
 		WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4
-		ORDER BY created_at ASC
+		ORDER BY created_at ASC, id ASC
 		LIMIT 1
 	`
 
 	var review storage.ReviewContext
-	var commentsJSON, usageJSON sql.NullString
+	var commentsJSON, usageJSON, failedFilesJSON sql.NullString
 	var createdAt time.Time
 
 	err := p.db.QueryRowContext(ctx, query, installationID, owner, repo, prNumber).Scan(
@@ -217,6 +356,10 @@ func (p *PostgreSQL) GetFirstReviewForPR(ctx context.Context, installationID int
 		&commentsJSON,
 		&usageJSON,
 		&review.UsageType,
+		&failedFilesJSON,
+		&review.SummaryCommentID,
+		&review.TriageCommentID,
+		&review.HeadSHA,
 		&createdAt,
 	)
 	if err == sql.ErrNoRows {
@@ -228,12 +371,230 @@ func (p *PostgreSQL) GetFirstReviewForPR(ctx context.Context, installationID int
 
 	review.Comments = commentsFromJSON(commentsJSON.String)
 	review.Usage = usageFromJSON(usageJSON.String)
+	review.FailedFiles = filesFromJSON(failedFilesJSON.String)
 	review.CreatedAt = createdAt.Format(time.RFC3339)
 
 	return &review, nil
 }
 
-// SaveInstallation stores a new installation.
+// usageTotalsColumns sums token usage across the stored reviews' usage JSON.
+const usageTotalsColumns = `
+	COALESCE(SUM((usage->>'input_tokens')::BIGINT), 0),
+	COALESCE(SUM((usage->>'output_tokens')::BIGINT), 0),
+	COALESCE(SUM((usage->>'cache_read_input_tokens')::BIGINT), 0),
+	COALESCE(SUM((usage->>'cache_creation_input_tokens')::BIGINT), 0)
+`
+
+// GetUsageTotalsForPR returns the token usage summed across all reviews of a pull request.
+func (p *PostgreSQL) GetUsageTotalsForPR(ctx context.Context, installationID int64, owner, repo string, prNumber int) (*storage.TokenUsage, error) {
+	query := `SELECT ` + usageTotalsColumns + `
+		FROM reviews
+		WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4
+	`
+
+	var usage storage.TokenUsage
+	err := p.db.QueryRowContext(ctx, query, installationID, owner, repo, prNumber).Scan(
+		&usage.InputTokens,
+		&usage.OutputTokens,
+		&usage.CacheReadInputTokens,
+		&usage.CacheCreationInputTokens,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage totals for PR: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// GetUsageTotalsForInstallation returns the token usage summed across all reviews
+// for an installation created at or after since.
+func (p *PostgreSQL) GetUsageTotalsForInstallation(ctx context.Context, installationID int64, since time.Time) (*storage.TokenUsage, error) {
+	query := `SELECT ` + usageTotalsColumns + `
+		FROM reviews
+		WHERE installation_id = $1 AND created_at >= $2
+	`
+
+	var usage storage.TokenUsage
+	err := p.db.QueryRowContext(ctx, query, installationID, since).Scan(
+		&usage.InputTokens,
+		&usage.OutputTokens,
+		&usage.CacheReadInputTokens,
+		&usage.CacheCreationInputTokens,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage totals for installation: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// GetCommentOutcomes counts the comments of reviews for an installation created at
+// or after since by outcome.
+func (p *PostgreSQL) GetCommentOutcomes(ctx context.Context, installationID int64, since time.Time) (*storage.CommentOutcomes, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE c->>'outcome' = 'resolved'),
+			COUNT(*) FILTER (WHERE c->>'outcome' = 'unresolved'),
+			COUNT(*) FILTER (WHERE c->>'outcome' = 'dismissed'),
+			COUNT(*) FILTER (WHERE COALESCE(c->>'outcome', '') NOT IN ('resolved', 'unresolved', 'dismissed'))
+		FROM reviews,
+			jsonb_array_elements(CASE WHEN jsonb_typeof(comments) = 'array' THEN comments ELSE '[]'::jsonb END) AS c
+		WHERE installation_id = $1 AND created_at >= $2
+	`
+
+	var outcomes storage.CommentOutcomes
+	err := p.db.QueryRowContext(ctx, query, installationID, since).Scan(
+		&outcomes.Resolved,
+		&outcomes.Unresolved,
+		&outcomes.Dismissed,
+		&outcomes.Pending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment outcomes: %w", err)
+	}
+
+	return &outcomes, nil
+}
+
+// LogEvent records a handled webhook event.
+func (p *PostgreSQL) LogEvent(ctx context.Context, event *storage.Event) error {
+	query := `
+		INSERT INTO events (event_type, action, owner, repo, result, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := p.db.ExecContext(ctx, query,
+		event.EventType,
+		event.Action,
+		event.Owner,
+		event.Repo,
+		event.Result,
+		event.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+
+	return nil
+}
+
+// SummarizeEvents returns event counts and average durations grouped by event type,
+// action, and result for events logged at or after since.
+func (p *PostgreSQL) SummarizeEvents(ctx context.Context, since time.Time) ([]storage.EventSummary, error) {
+	query := `
+		SELECT event_type, COALESCE(action, ''), result, COUNT(*), COALESCE(AVG(duration_ms), 0)
+		FROM events
+		WHERE created_at >= $1
+		GROUP BY event_type, action, result
+		ORDER BY COUNT(*) DESC, event_type, action, result
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize events: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []storage.EventSummary
+	for rows.Next() {
+		var s storage.EventSummary
+		if err := rows.Scan(&s.EventType, &s.Action, &s.Result, &s.Count, &s.AvgDurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan event summary: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// SetPRMuted mutes or unmutes a pull request.
+func (p *PostgreSQL) SetPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int, muted bool) error {
+	query := `
+		DELETE FROM muted_prs
+		WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4
+	`
+	if muted {
+		query = `
+			INSERT INTO muted_prs (installation_id, owner, repo, pr_number)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`
+	}
+
+	if _, err := p.db.ExecContext(ctx, query, installationID, owner, repo, prNumber); err != nil {
+		return fmt.Errorf("failed to set muted pull request: %w", err)
+	}
+	return nil
+}
+
+// IsPRMuted reports whether a pull request is muted.
+func (p *PostgreSQL) IsPRMuted(ctx context.Context, installationID int64, owner, repo string, prNumber int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM muted_prs
+			WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4
+		)
+	`
+
+	var muted bool
+	if err := p.db.QueryRowContext(ctx, query, installationID, owner, repo, prNumber).Scan(&muted); err != nil {
+		return false, fmt.Errorf("failed to check muted pull request: %w", err)
+	}
+	return muted, nil
+}
+
+// IncrementReplyCount records a reply posted on a pull request.
+func (p *PostgreSQL) IncrementReplyCount(ctx context.Context, installationID int64, owner, repo string, prNumber int) error {
+	query := `
+		INSERT INTO pr_reply_counts (installation_id, owner, repo, pr_number, replies)
+		VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (installation_id, owner, repo, pr_number)
+		DO UPDATE SET replies = pr_reply_counts.replies + 1
+	`
+
+	if _, err := p.db.ExecContext(ctx, query, installationID, owner, repo, prNumber); err != nil {
+		return fmt.Errorf("failed to increment reply count: %w", err)
+	}
+	return nil
+}
+
+// GetReplyCount returns how many replies have been posted on a pull request.
+func (p *PostgreSQL) GetReplyCount(ctx context.Context, installationID int64, owner, repo string, prNumber int) (int, error) {
+	query := `
+		SELECT COALESCE((
+			SELECT replies FROM pr_reply_counts
+			WHERE installation_id = $1 AND owner = $2 AND repo = $3 AND pr_number = $4
+		), 0)
+	`
+
+	var replies int
+	if err := p.db.QueryRowContext(ctx, query, installationID, owner, repo, prNumber).Scan(&replies); err != nil {
+		return 0, fmt.Errorf("failed to get reply count: %w", err)
+	}
+	return replies, nil
+}
+
+// MarkPRNoticePosted marks a notice posted on a pull request, reporting whether
+// it wasn't already. The conflicting insert makes concurrent calls mark it once.
+func (p *PostgreSQL) MarkPRNoticePosted(ctx context.Context, installationID int64, owner, repo string, prNumber int, notice string) (bool, error) {
+	query := `
+		INSERT INTO pr_notices (installation_id, owner, repo, pr_number, notice)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`
+	res, err := p.db.ExecContext(ctx, query, installationID, owner, repo, prNumber, notice)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notice posted: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notice posted: %w", err)
+	}
+	return n == 1, nil
+}
+
+// SaveInstallation stores a new installation, marked active. Saving an existing
+// installation only updates its org login.
 func (p *PostgreSQL) SaveInstallation(ctx context.Context, install *storage.Installation) error {
 	query := `
 		INSERT INTO installations (installation_id, account_id, org_login, installed_by, installed_at)
@@ -267,7 +628,7 @@ func (p *PostgreSQL) SaveInstallation(ctx context.Context, install *storage.Inst
 // GetInstallation retrieves an installation.
 func (p *PostgreSQL) GetInstallation(ctx context.Context, installationID int64) (*storage.Installation, error) {
 	query := `
-		SELECT installation_id, account_id, org_login, installed_at, installed_by
+		SELECT installation_id, account_id, org_login, installed_at, installed_by, active, welcomed
 		FROM installations
 		WHERE installation_id = $1
 	`
@@ -283,6 +644,8 @@ func (p *PostgreSQL) GetInstallation(ctx context.Context, installationID int64)
 		&install.OrgLogin,
 		&installedAt,
 		&installedBy,
+		&install.Active,
+		&install.Welcomed,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -298,5 +661,46 @@ func (p *PostgreSQL) GetInstallation(ctx context.Context, installationID int64)
 	return &install, nil
 }
 
+// SetInstallationActive marks an installation active or suspended.
+func (p *PostgreSQL) SetInstallationActive(ctx context.Context, installationID int64, active bool) error {
+	query := `
+		UPDATE installations
+		SET active = $2, updated_at = NOW()
+		WHERE installation_id = $1
+	`
+	if _, err := p.db.ExecContext(ctx, query, installationID, active); err != nil {
+		return fmt.Errorf("failed to set installation active: %w", err)
+	}
+	return nil
+}
+
+// MarkInstallationWelcomed marks an installation welcomed, reporting whether it
+// wasn't already. The conditional update makes concurrent calls mark it once.
+func (p *PostgreSQL) MarkInstallationWelcomed(ctx context.Context, installationID int64) (bool, error) {
+	query := `
+		UPDATE installations
+		SET welcomed = TRUE, updated_at = NOW()
+		WHERE installation_id = $1 AND NOT welcomed
+	`
+	res, err := p.db.ExecContext(ctx, query, installationID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark installation welcomed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark installation welcomed: %w", err)
+	}
+	return n == 1, nil
+}
+
+// DeleteInstallation removes an installation. Its reviews are kept.
+func (p *PostgreSQL) DeleteInstallation(ctx context.Context, installationID int64) error {
+	query := `DELETE FROM installations WHERE installation_id = $1`
+	if _, err := p.db.ExecContext(ctx, query, installationID); err != nil {
+		return fmt.Errorf("failed to delete installation: %w", err)
+	}
+	return nil
+}
+
 // Verify PostgreSQL implements Storage at compile time.
 var _ storage.Storage = (*PostgreSQL)(nil)
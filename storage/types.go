@@ -7,6 +7,11 @@ type Installation struct {
 	OrgLogin       string `json:"org_login"`
 	InstalledAt    string `json:"installed_at"`
 	InstalledBy    string `json:"installed_by"`
+	// Active is false while the installation is suspended. Saving a new
+	// installation always marks it active.
+	Active bool `json:"active"`
+	// Welcomed is set once the installation's welcome comment has been posted.
+	Welcomed bool `json:"welcomed"`
 }
 
 // Comment represents a review comment for storage.
@@ -14,6 +19,24 @@ type Comment struct {
 	Path string `json:"path"`
 	Line int    `json:"line"`
 	Body string `json:"body"`
+	// Outcome records what happened to the comment's review thread (one of the
+	// CommentOutcome constants); empty until a later review reconciles it.
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// Comment outcomes, reconciled from the review thread of a posted comment.
+const (
+	CommentOutcomeResolved   = "resolved"   // the thread was resolved
+	CommentOutcomeUnresolved = "unresolved" // the thread is still open
+	CommentOutcomeDismissed  = "dismissed"  // the comment no longer exists on the PR
+)
+
+// CommentOutcomes counts stored comments by outcome.
+type CommentOutcomes struct {
+	Resolved   int64 `json:"resolved"`
+	Unresolved int64 `json:"unresolved"`
+	Dismissed  int64 `json:"dismissed"`
+	Pending    int64 `json:"pending"` // not reconciled yet
 }
 
 // TokenUsage represents Claude API token usage for a single call.
@@ -36,4 +59,37 @@ type ReviewContext struct {
 	CreatedAt      string      `json:"created_at"`
 	Usage          *TokenUsage `json:"usage,omitempty"`
 	UsageType      string      `json:"usage_type,omitempty"`
+	// FailedFiles lists files whose review chunk failed (partial chunk reviews),
+	// kept so "@shipitai retry" can re-review just those.
+	FailedFiles []string `json:"failed_files,omitempty"`
+	// SummaryCommentID is the PR comment carrying the review summary
+	// (summary_as_comment). Kept on the first review so later reviews edit it.
+	SummaryCommentID int64 `json:"summary_comment_id,omitempty"`
+	// TriageCommentID is the PR comment carrying the findings checklist
+	// (triage_comment). Kept on the first review so later reviews edit it.
+	TriageCommentID int64 `json:"triage_comment_id,omitempty"`
+	// HeadSHA is the commit the review covered, so the next review can diff
+	// just the commits pushed since. Empty for replies and older reviews.
+	HeadSHA string `json:"head_sha,omitempty"`
+}
+
+// Event is a lightweight record of a handled webhook for analytics.
+// It never includes the payload.
+type Event struct {
+	EventType  string `json:"event_type"`
+	Action     string `json:"action,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+	Repo       string `json:"repo,omitempty"`
+	Result     string `json:"result"`
+	DurationMs int64  `json:"duration_ms"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// EventSummary aggregates logged events sharing an event type, action, and result.
+type EventSummary struct {
+	EventType     string  `json:"event_type"`
+	Action        string  `json:"action,omitempty"`
+	Result        string  `json:"result"`
+	Count         int64   `json:"count"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
 }